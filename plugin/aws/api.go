@@ -9,9 +9,25 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/organizations"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
 	rdstype "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	sptypes "github.com/aws/aws-sdk-go-v2/service/savingsplans/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
+// InventoryProvider lists the resources the processors analyse, AWS talks to the real account.
+type InventoryProvider interface {
+	Identify(ctx context.Context) (map[string]string, error)
+	ListAllRegions(ctx context.Context) ([]string, error)
+	ListInstances(ctx context.Context, region string) ([]types.Instance, error)
+	GetImage(ctx context.Context, region, imageId string) (*types.Image, error)
+	ListAttachedVolumes(ctx context.Context, region string, instance types.Instance) ([]types.Volume, error)
+	ListRDSInstance(ctx context.Context, region string) ([]rdstype.DBInstance, error)
+	ListRDSInstanceByCluster(ctx context.Context, region, clusterId string) ([]rdstype.DBInstance, error)
+	ListRDSClusters(ctx context.Context, region string) ([]rdstype.DBCluster, error)
+	ListReservedInstances(ctx context.Context, region string) ([]types.ReservedInstances, error)
+	ListReservedDBInstances(ctx context.Context, region string) ([]rdstype.ReservedDBInstance, error)
+	ListSavingsPlans(ctx context.Context) ([]sptypes.SavingsPlan, error)
+}
+
 type AWS struct {
 	cfg aws.Config
 }
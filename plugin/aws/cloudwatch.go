@@ -9,11 +9,13 @@ import (
 )
 
 type CloudWatch struct {
-	cfg aws.Config
+	cfg   aws.Config
+	cache *MetricsCache
 }
 
-func NewCloudWatch(cfg aws.Config) (*CloudWatch, error) {
-	return &CloudWatch{cfg: cfg}, nil
+// NewCloudWatch creates the CloudWatch metrics provider, cache is optional and only used for GetDayByDayMetrics.
+func NewCloudWatch(cfg aws.Config, cache *MetricsCache) (*CloudWatch, error) {
+	return &CloudWatch{cfg: cfg, cache: cache}, nil
 }
 
 func (cw *CloudWatch) GetMetrics(
@@ -86,6 +88,10 @@ func (cw *CloudWatch) GetDayByDayMetrics(
 	statistics []types2.Statistic,
 	extendedStatistics []string,
 ) (map[string][]types2.Datapoint, error) {
+	if cw.cache != nil {
+		return cw.cache.GetDayByDayMetrics(ctx, cw, region, namespace, metricNames, filters, days, interval, statistics, extendedStatistics)
+	}
+
 	datapoints := make(map[string][]types2.Datapoint)
 	for i := 1; i <= days; i++ {
 		startTime := time.Now().Add(-time.Duration(24*i) * time.Hour)
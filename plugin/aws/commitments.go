@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstype "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/savingsplans"
+	sptypes "github.com/aws/aws-sdk-go-v2/service/savingsplans/types"
+)
+
+func (s *AWS) ListReservedInstances(ctx context.Context, region string) ([]types.ReservedInstances, error) {
+	localCfg := s.cfg
+	localCfg.Region = region
+
+	client := ec2.NewFromConfig(localCfg)
+	out, err := client.DescribeReservedInstances(ctx, &ec2.DescribeReservedInstancesInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("state"),
+				Values: []string{string(types.ReservedInstanceStateActive)},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.ReservedInstances, nil
+}
+
+func (s *AWS) ListReservedDBInstances(ctx context.Context, region string) ([]rdstype.ReservedDBInstance, error) {
+	localCfg := s.cfg
+	localCfg.Region = region
+
+	var reserved []rdstype.ReservedDBInstance
+	client := rds.NewFromConfig(localCfg)
+	paginator := rds.NewDescribeReservedDBInstancesPaginator(client, &rds.DescribeReservedDBInstancesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range page.ReservedDBInstances {
+			if r.State != nil && *r.State == "active" {
+				reserved = append(reserved, r)
+			}
+		}
+	}
+	return reserved, nil
+}
+
+// ListSavingsPlans returns the active savings plans of the account. The savings plans API is global, its
+// endpoint is in us-east-1.
+func (s *AWS) ListSavingsPlans(ctx context.Context) ([]sptypes.SavingsPlan, error) {
+	localCfg := s.cfg
+	localCfg.Region = "us-east-1"
+
+	var plans []sptypes.SavingsPlan
+	client := savingsplans.NewFromConfig(localCfg)
+	input := &savingsplans.DescribeSavingsPlansInput{
+		States: []sptypes.SavingsPlanState{sptypes.SavingsPlanStateActive},
+	}
+	for {
+		out, err := client.DescribeSavingsPlans(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, out.SavingsPlans...)
+		if aws.ToString(out.NextToken) == "" {
+			return plans, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
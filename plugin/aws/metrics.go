@@ -0,0 +1,289 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type MetricsProvider interface {
+	GetMetrics(
+		ctx context.Context,
+		region string,
+		namespace string,
+		metricNames []string,
+		filters map[string][]string,
+		startTime, endTime time.Time,
+		interval time.Duration,
+		statistics []types2.Statistic,
+		extendedStatistics []string,
+	) (map[string][]types2.Datapoint, error)
+	GetDayByDayMetrics(
+		ctx context.Context,
+		region string,
+		namespace string,
+		metricNames []string,
+		filters map[string][]string,
+		days int,
+		interval time.Duration,
+		statistics []types2.Statistic,
+		extendedStatistics []string,
+	) (map[string][]types2.Datapoint, error)
+}
+
+const metricsFixtureVersion = 1
+
+type MetricsCall string
+
+const (
+	MetricsCallGetMetrics         MetricsCall = "GetMetrics"
+	MetricsCallGetDayByDayMetrics MetricsCall = "GetDayByDayMetrics"
+)
+
+// MetricsRecord is a single metrics request and the datapoints it returned. Time ranges are stored
+// relative to the request (window length or number of days) so a replay matches regardless of when it runs.
+type MetricsRecord struct {
+	Call               MetricsCall                   `json:"call"`
+	Region             string                        `json:"region"`
+	Namespace          string                        `json:"namespace"`
+	MetricNames        []string                      `json:"metricNames"`
+	Filters            map[string][]string           `json:"filters"`
+	Window             time.Duration                 `json:"window,omitempty"`
+	Days               int                           `json:"days,omitempty"`
+	Interval           time.Duration                 `json:"interval"`
+	Statistics         []types2.Statistic            `json:"statistics,omitempty"`
+	ExtendedStatistics []string                      `json:"extendedStatistics,omitempty"`
+	Datapoints         map[string][]types2.Datapoint `json:"datapoints"`
+}
+
+type MetricsFixture struct {
+	Version    int             `json:"version"`
+	RecordedAt time.Time       `json:"recordedAt"`
+	Records    []MetricsRecord `json:"records"`
+}
+
+func (r MetricsRecord) key() string {
+	metricNames := append([]string{}, r.MetricNames...)
+	sort.Strings(metricNames)
+
+	var filters []string
+	for k, v := range r.Filters {
+		filters = append(filters, fmt.Sprintf("%s=%s", k, strings.Join(v, "|")))
+	}
+	sort.Strings(filters)
+
+	var statistics []string
+	for _, s := range r.Statistics {
+		statistics = append(statistics, string(s))
+	}
+
+	return strings.Join([]string{
+		string(r.Call),
+		r.Region,
+		r.Namespace,
+		strings.Join(metricNames, ","),
+		strings.Join(filters, ","),
+		r.Window.String(),
+		fmt.Sprintf("%d", r.Days),
+		r.Interval.String(),
+		strings.Join(statistics, ","),
+		strings.Join(r.ExtendedStatistics, ","),
+	}, "/")
+}
+
+// MetricsRecorder passes every request through to the wrapped provider and keeps the returned
+// datapoints so they can be written to a fixture file and replayed with MetricsReplay.
+type MetricsRecorder struct {
+	provider MetricsProvider
+
+	lock    sync.Mutex
+	records []MetricsRecord
+}
+
+func NewMetricsRecorder(provider MetricsProvider) *MetricsRecorder {
+	return &MetricsRecorder{provider: provider}
+}
+
+func (r *MetricsRecorder) GetMetrics(
+	ctx context.Context,
+	region string,
+	namespace string,
+	metricNames []string,
+	filters map[string][]string,
+	startTime, endTime time.Time,
+	interval time.Duration,
+	statistics []types2.Statistic,
+	extendedStatistics []string,
+) (map[string][]types2.Datapoint, error) {
+	metrics, err := r.provider.GetMetrics(ctx, region, namespace, metricNames, filters, startTime, endTime, interval, statistics, extendedStatistics)
+	if err != nil {
+		return nil, err
+	}
+	r.record(MetricsRecord{
+		Call:               MetricsCallGetMetrics,
+		Region:             region,
+		Namespace:          namespace,
+		MetricNames:        metricNames,
+		Filters:            filters,
+		Window:             endTime.Sub(startTime),
+		Interval:           interval,
+		Statistics:         statistics,
+		ExtendedStatistics: extendedStatistics,
+	}, metrics)
+	return metrics, nil
+}
+
+func (r *MetricsRecorder) GetDayByDayMetrics(
+	ctx context.Context,
+	region string,
+	namespace string,
+	metricNames []string,
+	filters map[string][]string,
+	days int,
+	interval time.Duration,
+	statistics []types2.Statistic,
+	extendedStatistics []string,
+) (map[string][]types2.Datapoint, error) {
+	metrics, err := r.provider.GetDayByDayMetrics(ctx, region, namespace, metricNames, filters, days, interval, statistics, extendedStatistics)
+	if err != nil {
+		return nil, err
+	}
+	r.record(MetricsRecord{
+		Call:               MetricsCallGetDayByDayMetrics,
+		Region:             region,
+		Namespace:          namespace,
+		MetricNames:        metricNames,
+		Filters:            filters,
+		Days:               days,
+		Interval:           interval,
+		Statistics:         statistics,
+		ExtendedStatistics: extendedStatistics,
+	}, metrics)
+	return metrics, nil
+}
+
+func (r *MetricsRecorder) record(record MetricsRecord, metrics map[string][]types2.Datapoint) {
+	// callers post-process the returned slices in place, keep a copy of what the provider returned
+	record.Datapoints = make(map[string][]types2.Datapoint, len(metrics))
+	for k, v := range metrics {
+		record.Datapoints[k] = append([]types2.Datapoint{}, v...)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.records = append(r.records, record)
+}
+
+func (r *MetricsRecorder) Save(path string) error {
+	r.lock.Lock()
+	fixture := MetricsFixture{
+		Version:    metricsFixtureVersion,
+		RecordedAt: time.Now().UTC(),
+		Records:    append([]MetricsRecord{}, r.records...),
+	}
+	r.lock.Unlock()
+
+	content, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		return fmt.Errorf("failed to write metrics fixture: %w", err)
+	}
+	return nil
+}
+
+// MetricsReplay serves datapoints from a fixture written by MetricsRecorder instead of calling CloudWatch.
+type MetricsReplay struct {
+	records map[string]MetricsRecord
+}
+
+func NewMetricsReplay(path string) (*MetricsReplay, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics fixture: %w", err)
+	}
+	var fixture MetricsFixture
+	if err := json.Unmarshal(content, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse metrics fixture: %w", err)
+	}
+	if fixture.Version != metricsFixtureVersion {
+		return nil, fmt.Errorf("unsupported metrics fixture version %d", fixture.Version)
+	}
+	return NewMetricsReplayFromRecords(fixture.Records), nil
+}
+
+func NewMetricsReplayFromRecords(records []MetricsRecord) *MetricsReplay {
+	r := &MetricsReplay{records: map[string]MetricsRecord{}}
+	for _, record := range records {
+		r.records[record.key()] = record
+	}
+	return r
+}
+
+func (r *MetricsReplay) GetMetrics(
+	_ context.Context,
+	region string,
+	namespace string,
+	metricNames []string,
+	filters map[string][]string,
+	startTime, endTime time.Time,
+	interval time.Duration,
+	statistics []types2.Statistic,
+	extendedStatistics []string,
+) (map[string][]types2.Datapoint, error) {
+	return r.lookup(MetricsRecord{
+		Call:               MetricsCallGetMetrics,
+		Region:             region,
+		Namespace:          namespace,
+		MetricNames:        metricNames,
+		Filters:            filters,
+		Window:             endTime.Sub(startTime),
+		Interval:           interval,
+		Statistics:         statistics,
+		ExtendedStatistics: extendedStatistics,
+	})
+}
+
+func (r *MetricsReplay) GetDayByDayMetrics(
+	_ context.Context,
+	region string,
+	namespace string,
+	metricNames []string,
+	filters map[string][]string,
+	days int,
+	interval time.Duration,
+	statistics []types2.Statistic,
+	extendedStatistics []string,
+) (map[string][]types2.Datapoint, error) {
+	return r.lookup(MetricsRecord{
+		Call:               MetricsCallGetDayByDayMetrics,
+		Region:             region,
+		Namespace:          namespace,
+		MetricNames:        metricNames,
+		Filters:            filters,
+		Days:               days,
+		Interval:           interval,
+		Statistics:         statistics,
+		ExtendedStatistics: extendedStatistics,
+	})
+}
+
+func (r *MetricsReplay) lookup(request MetricsRecord) (map[string][]types2.Datapoint, error) {
+	record, ok := r.records[request.key()]
+	if !ok {
+		return nil, fmt.Errorf("no recorded metrics for %s %s %v in %s (%v)", request.Call, request.Namespace, request.MetricNames, request.Region, request.Filters)
+	}
+
+	metrics := make(map[string][]types2.Datapoint, len(record.Datapoints))
+	for k, v := range record.Datapoints {
+		metrics[k] = append([]types2.Datapoint{}, v...)
+	}
+	return metrics, nil
+}
@@ -0,0 +1,279 @@
+package aws
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	metricsCacheVersion = 1
+	metricsCacheDayKey  = "2006-01-02"
+	// CloudWatch keeps accepting late datapoints for a while, a day is only stored permanently
+	// once it was fetched this long after it ended.
+	metricsCacheSettleTime = 3 * time.Hour
+)
+
+type metricsCacheDay struct {
+	FetchedAt  time.Time          `json:"fetchedAt"`
+	Complete   bool               `json:"complete"`
+	Datapoints []types2.Datapoint `json:"datapoints"`
+}
+
+type metricsCacheFile struct {
+	Version int                        `json:"version"`
+	Key     string                     `json:"key"`
+	Days    map[string]metricsCacheDay `json:"days"`
+}
+
+// MetricsCache keeps the datapoints of GetDayByDayMetrics on disk, one file per account, region, namespace,
+// metric, dimensions, period and statistics holding the fetched UTC days. Complete past days are kept for as
+// long as they are in the observability window, the current partial day is reused until TTL expires.
+type MetricsCache struct {
+	dir     string
+	account string
+	ttl     time.Duration
+	now     func() time.Time
+
+	lock  sync.Mutex
+	prune sync.Once
+}
+
+func NewMetricsCache(dir, account string, ttl time.Duration) (*MetricsCache, error) {
+	if dir == "" {
+		var err error
+		dir, err = DefaultMetricsCacheDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create metrics cache directory: %w", err)
+	}
+	return &MetricsCache{dir: dir, account: account, ttl: ttl, now: time.Now}, nil
+}
+
+func DefaultMetricsCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "kaytu", "plugin-aws", "metrics"), nil
+}
+
+// SetClock replaces the clock used to decide which days are complete, for tests.
+func (c *MetricsCache) SetClock(now func() time.Time) {
+	c.now = now
+}
+
+// GetDayByDayMetrics returns the same window as CloudWatch.GetDayByDayMetrics, the last days*24 hours.
+// The window is split into UTC days and only days missing from the cache are requested from the provider.
+func (c *MetricsCache) GetDayByDayMetrics(
+	ctx context.Context,
+	provider MetricsProvider,
+	region string,
+	namespace string,
+	metricNames []string,
+	filters map[string][]string,
+	days int,
+	interval time.Duration,
+	statistics []types2.Statistic,
+	extendedStatistics []string,
+) (map[string][]types2.Datapoint, error) {
+	now := c.now().UTC()
+	start := now.Add(-time.Duration(24*days) * time.Hour)
+	today := now.Truncate(24 * time.Hour)
+	c.prune.Do(func() { c.deleteStaleFiles(start) })
+
+	files := make(map[string]*metricsCacheFile, len(metricNames))
+	for _, metricName := range metricNames {
+		file, err := c.load(c.key(region, namespace, metricName, filters, interval, statistics, extendedStatistics))
+		if err != nil {
+			return nil, err
+		}
+		files[metricName] = file
+	}
+
+	datapoints := make(map[string][]types2.Datapoint)
+	for _, metricName := range metricNames {
+		datapoints[metricName] = []types2.Datapoint{}
+	}
+	for day := today; day.Add(24 * time.Hour).After(start); day = day.Add(-24 * time.Hour) {
+		dayEnd := day.Add(24 * time.Hour)
+		if dayEnd.After(now) {
+			dayEnd = now
+		}
+		if !dayEnd.After(day) {
+			continue
+		}
+
+		var missing []string
+		for _, metricName := range metricNames {
+			if _, ok := c.cached(files[metricName], day, now); !ok {
+				missing = append(missing, metricName)
+			}
+		}
+		if len(missing) > 0 {
+			metrics, err := provider.GetMetrics(ctx, region, namespace, missing, filters, day, dayEnd, interval, statistics, extendedStatistics)
+			if err != nil {
+				return nil, err
+			}
+			for _, metricName := range missing {
+				files[metricName].Days[day.Format(metricsCacheDayKey)] = metricsCacheDay{
+					FetchedAt:  now,
+					Complete:   !now.Before(day.Add(24*time.Hour + metricsCacheSettleTime)),
+					Datapoints: append([]types2.Datapoint{}, metrics[metricName]...),
+				}
+			}
+		}
+
+		for _, metricName := range metricNames {
+			for _, dp := range files[metricName].Days[day.Format(metricsCacheDayKey)].Datapoints {
+				if dp.Timestamp != nil && dp.Timestamp.Before(start) {
+					continue
+				}
+				datapoints[metricName] = append(datapoints[metricName], dp)
+			}
+		}
+	}
+
+	for _, file := range files {
+		for key := range file.Days {
+			if day, err := time.Parse(metricsCacheDayKey, key); err != nil || !day.Add(24*time.Hour).After(start) {
+				delete(file.Days, key)
+			}
+		}
+		if err := c.save(file); err != nil {
+			return nil, err
+		}
+	}
+	return datapoints, nil
+}
+
+// deleteStaleFiles removes the files of the account not written since the window started, of resources that
+// are gone or no longer analysed, and temporary files left behind by interrupted runs. It is best effort, a
+// file that can't be removed is tried again on the next run.
+func (c *MetricsCache) deleteStaleFiles(start time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	dir := filepath.Join(c.dir, c.account)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || (filepath.Ext(entry.Name()) != ".json" && !strings.HasPrefix(entry.Name(), ".metrics-")) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(start) {
+			continue
+		}
+		_ = os.Remove(filepath.Join(dir, entry.Name()))
+	}
+}
+
+func (c *MetricsCache) cached(file *metricsCacheFile, day, now time.Time) (metricsCacheDay, bool) {
+	entry, ok := file.Days[day.Format(metricsCacheDayKey)]
+	if !ok {
+		return metricsCacheDay{}, false
+	}
+	if entry.Complete {
+		return entry, true
+	}
+	return entry, now.Sub(entry.FetchedAt) < c.ttl
+}
+
+func (c *MetricsCache) key(region, namespace, metricName string, filters map[string][]string, interval time.Duration, statistics []types2.Statistic, extendedStatistics []string) string {
+	var dimensions []string
+	for k, v := range filters {
+		dimensions = append(dimensions, fmt.Sprintf("%s=%s", k, strings.Join(v, "|")))
+	}
+	sort.Strings(dimensions)
+
+	var stats []string
+	for _, s := range statistics {
+		stats = append(stats, string(s))
+	}
+	sort.Strings(stats)
+	extended := append([]string{}, extendedStatistics...)
+	sort.Strings(extended)
+
+	return strings.Join([]string{
+		c.account,
+		region,
+		namespace,
+		metricName,
+		strings.Join(dimensions, ","),
+		interval.String(),
+		strings.Join(stats, ","),
+		strings.Join(extended, ","),
+	}, "/")
+}
+
+func (c *MetricsCache) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, c.account, hex.EncodeToString(hash[:])+".json")
+}
+
+func (c *MetricsCache) load(key string) (*metricsCacheFile, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	empty := &metricsCacheFile{Version: metricsCacheVersion, Key: key, Days: map[string]metricsCacheDay{}}
+	content, err := os.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return empty, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read metrics cache: %w", err)
+	}
+
+	var file metricsCacheFile
+	// a corrupted or outdated entry is refetched instead of failing the run
+	if err := json.Unmarshal(content, &file); err != nil || file.Version != metricsCacheVersion || file.Key != key || file.Days == nil {
+		return empty, nil
+	}
+	return &file, nil
+}
+
+func (c *MetricsCache) save(file *metricsCacheFile) error {
+	content, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	path := c.path(file.Key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create metrics cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".metrics-*")
+	if err != nil {
+		return fmt.Errorf("failed to write metrics cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write metrics cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package chargeback
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/summary"
+	"os"
+	"sort"
+	"strings"
+)
+
+// topOffenders is how many of the resources saving the most are listed per group.
+const topOffenders = 5
+
+// Offender is a resource of a group with savings, its instance, volume or RDS compute or storage.
+type Offender struct {
+	ResourceType    string  `json:"resourceType"`
+	ResourceId      string  `json:"resourceId"`
+	ResourceName    string  `json:"resourceName"`
+	Region          string  `json:"region"`
+	CurrentSpec     string  `json:"currentSpec"`
+	RecommendedSpec string  `json:"recommendedSpec"`
+	CurrentCost     float64 `json:"currentCost"`
+	Savings         float64 `json:"savings"`
+}
+
+// Group is the total monthly cost and savings of the resources sharing the value of the tag. An instance
+// counts as one resource with its volumes or storage, a cluster with its members.
+type Group struct {
+	Value        string     `json:"value"`
+	Resources    int        `json:"resources"`
+	CurrentCost  float64    `json:"currentCost"`
+	Savings      float64    `json:"savings"`
+	TopOffenders []Offender `json:"topOffenders,omitempty"`
+}
+
+// Report charges the current cost and savings of the EC2 instances, EBS volumes and RDS instances back to
+// the teams, or any other owner, the values of a cost allocation tag stand for. Costs are in the currency,
+// USD until converted.
+type Report struct {
+	Account  string  `json:"account"`
+	Tag      string  `json:"tag"`
+	Currency string  `json:"currency"`
+	Total    Group   `json:"total"`
+	Groups   []Group `json:"groups"`
+}
+
+// NewReport groups records by the value of the tag key, like the tag breakdown of the results summary, the
+// ones without it in the summary.Untagged group. Groups saving the most come first, each listing its
+// resources saving the most.
+func NewReport(account, tagKey string, records []history.Record) *Report {
+	report := &Report{Account: account, Tag: tagKey, Currency: currency.USD, Total: Group{Value: "Total"}}
+	groups := map[string]*Group{}
+	owners := map[*Group]map[string]bool{}
+	offenders := map[string][]Offender{}
+	for _, r := range records {
+		value := summary.TagValue(r.Tags, tagKey)
+		g, ok := groups[value]
+		if !ok {
+			g = &Group{Value: value}
+			groups[value] = g
+		}
+		for _, g := range []*Group{g, &report.Total} {
+			if owners[g] == nil {
+				owners[g] = map[string]bool{}
+			}
+			if !owners[g][r.Owner()] {
+				owners[g][r.Owner()] = true
+				g.Resources++
+			}
+			g.CurrentCost += r.CurrentCost
+			g.Savings += r.Savings
+		}
+		if r.Savings > 0 {
+			offenders[value] = append(offenders[value], Offender{
+				ResourceType:    r.ResourceType,
+				ResourceId:      r.ResourceId,
+				ResourceName:    r.ResourceName,
+				Region:          r.Region,
+				CurrentSpec:     r.CurrentSpec,
+				RecommendedSpec: r.RecommendedSpec,
+				CurrentCost:     r.CurrentCost,
+				Savings:         r.Savings,
+			})
+		}
+	}
+
+	for value, g := range groups {
+		o := offenders[value]
+		sort.Slice(o, func(i, j int) bool {
+			if o[i].Savings != o[j].Savings {
+				return o[i].Savings > o[j].Savings
+			}
+			return o[i].ResourceId < o[j].ResourceId
+		})
+		if len(o) > topOffenders {
+			o = o[:topOffenders]
+		}
+		g.TopOffenders = o
+		report.Groups = append(report.Groups, *g)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if report.Groups[i].Savings != report.Groups[j].Savings {
+			return report.Groups[i].Savings > report.Groups[j].Savings
+		}
+		return report.Groups[i].Value < report.Groups[j].Value
+	})
+	return report
+}
+
+// SavingsPercent is the part of the current cost the group saves.
+func (g Group) SavingsPercent() float64 {
+	if g.CurrentCost <= 0 {
+		return 0
+	}
+	return g.Savings / g.CurrentCost * 100
+}
+
+var csvHeaders = []string{
+	"AccountID", "Tag", "Tag Value", "Resources", "Current Cost", "Savings", "Savings %", "Rank", "Resource Type",
+	"Resource ID", "Resource Name", "Region", "Current Spec", "Suggested Spec", "Resource Current Cost", "Resource Savings",
+}
+
+// Rows returns the report as CSV rows, headers first, then every group followed by its top offenders and
+// the total last. Costs are converted to the currency.
+func (r *Report) Rows(c *currency.Currency) [][]string {
+	rows := [][]string{csvHeaders}
+	for _, g := range r.Groups {
+		rows = append(rows, r.groupRow(g, c))
+		for idx, o := range g.TopOffenders {
+			rows = append(rows, []string{
+				r.Account, r.Tag, g.Value, "", "", "", "", fmt.Sprintf("%d", idx+1), o.ResourceType, o.ResourceId,
+				o.ResourceName, o.Region, o.CurrentSpec, o.RecommendedSpec, c.Format(o.CurrentCost), c.Format(o.Savings),
+			})
+		}
+	}
+	return append(rows, r.groupRow(r.Total, c))
+}
+
+func (r *Report) groupRow(g Group, c *currency.Currency) []string {
+	return []string{
+		r.Account, r.Tag, g.Value, fmt.Sprintf("%d", g.Resources), c.Format(g.CurrentCost), c.Format(g.Savings),
+		fmt.Sprintf("%.1f", g.SavingsPercent()), "", "", "", "", "", "", "", "", "",
+	}
+}
+
+// WriteCSV writes the report to a CSV file, costs converted to the currency.
+func (r *Report) WriteCSV(path string, c *currency.Currency) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write chargeback report: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(r.Rows(c)); err != nil {
+		return fmt.Errorf("failed to write chargeback report: %w", err)
+	}
+	return f.Close()
+}
+
+// Convert returns a copy of the report with the costs converted from USD to the currency, the report itself
+// when it is nil.
+func (r *Report) Convert(c *currency.Currency) *Report {
+	if c == nil {
+		return r
+	}
+	group := func(g Group) Group {
+		g.CurrentCost, g.Savings = c.Convert(g.CurrentCost), c.Convert(g.Savings)
+		offenders := make([]Offender, len(g.TopOffenders))
+		for i, o := range g.TopOffenders {
+			o.CurrentCost, o.Savings = c.Convert(o.CurrentCost), c.Convert(o.Savings)
+			offenders[i] = o
+		}
+		if g.TopOffenders != nil {
+			g.TopOffenders = offenders
+		}
+		return g
+	}
+	converted := &Report{Account: r.Account, Tag: r.Tag, Currency: c.Code, Total: group(r.Total)}
+	for _, g := range r.Groups {
+		converted.Groups = append(converted.Groups, group(g))
+	}
+	return converted
+}
+
+// WriteJSON writes the report to a JSON file, costs converted to the currency like the CSV and the currency
+// noted in the file.
+func (r *Report) WriteJSON(path string, c *currency.Currency) error {
+	content, err := json.MarshalIndent(r.Convert(c), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode chargeback report: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write chargeback report: %w", err)
+	}
+	return nil
+}
+
+// Summary describes the savings of each group, the ones saving the most first.
+func (r *Report) Summary(c *currency.Currency) string {
+	var groups []string
+	for _, g := range r.Groups {
+		groups = append(groups, fmt.Sprintf("%s saves %s of %s (%.1f%%)", g.Value, c.Format(g.Savings), c.Format(g.CurrentCost), g.SavingsPercent()))
+	}
+	if len(groups) == 0 {
+		return fmt.Sprintf("Chargeback by %s: no resource analysed", r.Tag)
+	}
+	return fmt.Sprintf("Chargeback by %s: %s", r.Tag, strings.Join(groups, ", "))
+}
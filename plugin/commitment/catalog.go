@@ -0,0 +1,224 @@
+package commitment
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strings"
+)
+
+const catalogVersion = 1
+
+// defaultMinRuntimeHours is how long ago an instance must have been created to be part of the steady fleet, a month.
+const defaultMinRuntimeHours = 720
+
+const (
+	ResourceEC2Instance = "EC2Instance"
+	ResourceRDSInstance = "RDSInstance"
+)
+
+type OfferType string
+
+const (
+	OfferReservedInstance       OfferType = "reserved-instance"
+	OfferComputeSavingsPlan     OfferType = "compute-savings-plan"
+	OfferEC2InstanceSavingsPlan OfferType = "ec2-instance-savings-plan"
+)
+
+type Payment string
+
+const (
+	PaymentNoUpfront      Payment = "no-upfront"
+	PaymentPartialUpfront Payment = "partial-upfront"
+	PaymentAllUpfront     Payment = "all-upfront"
+)
+
+// Catalog is a local file of on-demand and commitment prices, in USD per instance, the planner reads
+// instead of querying the pricing API:
+//
+//	version: 1
+//	minRuntimeHours: 720
+//	prices:
+//	  - resource: EC2Instance
+//	    region: us-east-1
+//	    instanceType: m5.large
+//	    platform: Linux/UNIX
+//	    onDemand: 0.096
+//	    offers:
+//	      - type: reserved-instance
+//	        term: 1
+//	        payment: no-upfront
+//	        hourly: 0.06
+//	      - type: compute-savings-plan
+//	        term: 3
+//	        payment: all-upfront
+//	        upfront: 1077
+//	  - resource: RDSInstance
+//	    region: us-east-1
+//	    instanceType: db.m5.large
+//	    platform: mysql
+//	    multiAZ: true
+//	    onDemand: 0.342
+//	    offers:
+//	      - type: reserved-instance
+//	        term: 1
+//	        payment: partial-upfront
+//	        upfront: 1000
+//	        hourly: 0.114
+//
+// Prices are hourly and upfront fees are for the whole term, in years. The platform is the operating
+// system of EC2 instances as in their platform details, Linux/UNIX when empty, or the engine of RDS
+// instances. Savings plans only apply to EC2 instances. Instances created less than minRuntimeHours ago,
+// 720 by default, are left out of the plan.
+type Catalog struct {
+	Version         int     `yaml:"version"`
+	MinRuntimeHours float64 `yaml:"minRuntimeHours"`
+	Prices          []Price `yaml:"prices"`
+
+	index map[string]Price
+}
+
+type Price struct {
+	Resource     string  `yaml:"resource"`
+	Region       string  `yaml:"region"`
+	InstanceType string  `yaml:"instanceType"`
+	Platform     string  `yaml:"platform"`
+	MultiAZ      bool    `yaml:"multiAZ"`
+	OnDemand     float64 `yaml:"onDemand"`
+	Offers       []Offer `yaml:"offers"`
+}
+
+type Offer struct {
+	Type    OfferType `yaml:"type"`
+	Term    int       `yaml:"term"`
+	Payment Payment   `yaml:"payment"`
+	Upfront float64   `yaml:"upfront"`
+	Hourly  float64   `yaml:"hourly"`
+}
+
+// LoadCatalog reads and validates a price catalog file.
+func LoadCatalog(path string) (*Catalog, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price catalog: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	var c Catalog
+	if err := decoder.Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to parse price catalog %s: %w", path, err)
+	}
+	if c.Version != catalogVersion {
+		return nil, fmt.Errorf("price catalog %s: unsupported version %d, expected %d", path, c.Version, catalogVersion)
+	}
+	if len(c.Prices) == 0 {
+		return nil, fmt.Errorf("price catalog %s has no prices", path)
+	}
+	if c.MinRuntimeHours < 0 {
+		return nil, fmt.Errorf("price catalog %s: minRuntimeHours must not be negative", path)
+	}
+	if c.MinRuntimeHours == 0 {
+		c.MinRuntimeHours = defaultMinRuntimeHours
+	}
+
+	c.index = map[string]Price{}
+	var errs []error
+	for i, p := range c.Prices {
+		name := fmt.Sprintf("price %d (%s %s)", i+1, p.InstanceType, p.Region)
+		if problems := p.validate(); len(problems) > 0 {
+			for _, err := range problems {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+			continue
+		}
+		key := priceKey(p.Resource, p.Region, p.InstanceType, p.Platform, p.MultiAZ)
+		if _, ok := c.index[key]; ok {
+			errs = append(errs, fmt.Errorf("%s: the instance type is priced more than once", name))
+			continue
+		}
+		c.index[key] = p
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("price catalog %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+func (p Price) validate() []error {
+	var errs []error
+	if p.Resource != ResourceEC2Instance && p.Resource != ResourceRDSInstance {
+		errs = append(errs, fmt.Errorf("unknown resource %q, expected %s or %s", p.Resource, ResourceEC2Instance, ResourceRDSInstance))
+	}
+	if p.Region == "" || p.InstanceType == "" {
+		errs = append(errs, fmt.Errorf("region and instanceType are required"))
+	}
+	if p.Resource == ResourceRDSInstance && p.Platform == "" {
+		errs = append(errs, fmt.Errorf("platform, the engine, is required for %s", ResourceRDSInstance))
+	}
+	if p.OnDemand <= 0 {
+		errs = append(errs, fmt.Errorf("onDemand must be positive"))
+	}
+	if len(p.Offers) == 0 {
+		errs = append(errs, fmt.Errorf("has no offers"))
+	}
+	for j, o := range p.Offers {
+		if err := o.validate(p.Resource); err != nil {
+			errs = append(errs, fmt.Errorf("offer %d: %w", j+1, err))
+		}
+	}
+	return errs
+}
+
+func (o Offer) validate(resource string) error {
+	switch o.Type {
+	case OfferReservedInstance:
+	case OfferComputeSavingsPlan, OfferEC2InstanceSavingsPlan:
+		if resource != ResourceEC2Instance {
+			return fmt.Errorf("%s only applies to %s", o.Type, ResourceEC2Instance)
+		}
+	default:
+		return fmt.Errorf("unknown type %q, expected %s, %s or %s", o.Type, OfferReservedInstance, OfferComputeSavingsPlan, OfferEC2InstanceSavingsPlan)
+	}
+	if o.Term != 1 && o.Term != 3 {
+		return fmt.Errorf("term must be 1 or 3 years, got %d", o.Term)
+	}
+	if o.Upfront < 0 || o.Hourly < 0 {
+		return fmt.Errorf("upfront and hourly must not be negative")
+	}
+	switch o.Payment {
+	case PaymentNoUpfront:
+		if o.Upfront != 0 {
+			return fmt.Errorf("%s offers have no upfront fee", PaymentNoUpfront)
+		}
+	case PaymentPartialUpfront:
+	case PaymentAllUpfront:
+		if o.Hourly != 0 {
+			return fmt.Errorf("%s offers have no hourly fee", PaymentAllUpfront)
+		}
+	default:
+		return fmt.Errorf("unknown payment %q, expected %s, %s or %s", o.Payment, PaymentNoUpfront, PaymentPartialUpfront, PaymentAllUpfront)
+	}
+	return nil
+}
+
+// lookup returns the price of an instance type, nil when the catalog does not have it.
+func (c *Catalog) lookup(resource, region, instanceType, platform string, multiAZ bool) *Price {
+	p, ok := c.index[priceKey(resource, region, instanceType, platform, multiAZ)]
+	if !ok {
+		return nil
+	}
+	return &p
+}
+
+func priceKey(resource, region, instanceType, platform string, multiAZ bool) string {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	if resource == ResourceEC2Instance {
+		if platform == "" {
+			platform = "linux/unix"
+		}
+		multiAZ = false
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/%v", resource, region, instanceType, platform, multiAZ)
+}
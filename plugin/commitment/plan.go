@@ -0,0 +1,292 @@
+package commitment
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"os"
+	"sort"
+	"strings"
+)
+
+const hoursPerMonth = 730
+
+var terms = []int{1, 3}
+
+// Purchase is the commitment recommended for the steady instances of one type in a term, the offer of the
+// catalog saving the most. Costs are monthly for all the instances, Coverage is the percentage of the
+// rightsized cost of every analysed instance the purchase covers.
+type Purchase struct {
+	Resource        string
+	Region          string
+	InstanceType    string
+	Platform        string
+	MultiAZ         bool
+	Instances       int
+	Offer           Offer
+	OnDemandCost    float64
+	CommitmentCost  float64
+	Savings         float64
+	BreakEvenMonths float64
+	Coverage        float64
+}
+
+// Plan is the set of purchases covering the fleet once the recommendations are applied. Cost is the
+// monthly rightsized cost of every analysed instance, Steady the number of instances the purchases are
+// planned for, and the others the instances left out.
+type Plan struct {
+	Account   string
+	Purchases []Purchase
+	Cost      float64
+	Steady    int
+	Transient int
+	Covered   int
+	Unpriced  int
+
+	minRuntimeHours float64
+}
+
+// group is the steady instances of one type, hours the sum of their monthly runtimes.
+type group struct {
+	resource, region, instanceType, platform string
+	multiAZ                                  bool
+	instances                                int
+	hours                                    float64
+	cost                                     float64
+	price                                    *Price
+}
+
+// NewPlan plans the purchases for the EC2 and RDS instances of records, with the instance type they are
+// recommended or their current one. Instances running for less than the minimum runtime of the catalog
+// and the ones already covered by reserved instances or savings plans are left out, like the ones the
+// catalog has no price for. For every type and term the offer saving the most is recommended, one per
+// term so both can be compared, savings plans rows add up to a single commitment when bought together.
+//
+// The on-demand cost is priced for the observed monthly runtime of the instances, the commitment is paid
+// whether they run or not, so part-time instances save less or nothing. The break-even is the number of
+// months the instances have to keep running like this for the commitment to cost less than paying on
+// demand, the whole commitment is paid even when they stop earlier.
+func NewPlan(account string, records []history.Record, catalog *Catalog) *Plan {
+	plan := &Plan{Account: account, minRuntimeHours: catalog.MinRuntimeHours}
+	groups := map[string]*group{}
+	for _, r := range records {
+		var resource string
+		switch r.ResourceType {
+		case "EC2 Instance":
+			resource = ResourceEC2Instance
+		case "RDS Instance Compute":
+			resource = ResourceRDSInstance
+		default:
+			continue
+		}
+		instanceType, cost := r.CurrentType, r.CurrentCost
+		if r.RecommendedSpec != "" && r.RecommendedType != "" {
+			instanceType, cost = r.RecommendedType, r.RecommendedCost
+		}
+		plan.Cost += cost
+		if r.AgeHours < catalog.MinRuntimeHours {
+			plan.Transient++
+			continue
+		}
+		if r.Coverage != nil {
+			plan.Covered++
+			continue
+		}
+		price := catalog.lookup(resource, r.Region, instanceType, r.Platform, r.MultiAZ)
+		if price == nil {
+			plan.Unpriced++
+			continue
+		}
+		key := priceKey(resource, r.Region, instanceType, r.Platform, r.MultiAZ)
+		if _, ok := groups[key]; !ok {
+			groups[key] = &group{resource: resource, region: r.Region, instanceType: instanceType, platform: price.Platform, multiAZ: price.MultiAZ, price: price}
+		}
+		runtime := r.RuntimeHours
+		if runtime <= 0 {
+			// runs stored before the runtime was observed
+			runtime = hoursPerMonth
+		}
+		groups[key].instances++
+		groups[key].hours += runtime
+		groups[key].cost += cost
+		plan.Steady++
+	}
+
+	var keys []string
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		g := groups[key]
+		for _, term := range terms {
+			var best *Purchase
+			for _, offer := range g.price.Offers {
+				if offer.Term != term {
+					continue
+				}
+				p := g.purchase(offer)
+				if p.Savings > 0 && (best == nil || p.Savings > best.Savings) {
+					best = &p
+				}
+			}
+			if best == nil {
+				continue
+			}
+			if plan.Cost > 0 {
+				best.Coverage = g.cost / plan.Cost * 100
+			}
+			plan.Purchases = append(plan.Purchases, *best)
+		}
+	}
+	return plan
+}
+
+func (g *group) purchase(offer Offer) Purchase {
+	onDemand := g.price.OnDemand * g.hours
+	commitment := (offer.Upfront/float64(offer.Term*12) + offer.Hourly*hoursPerMonth) * float64(g.instances)
+	purchase := Purchase{
+		Resource:       g.resource,
+		Region:         g.region,
+		InstanceType:   g.instanceType,
+		Platform:       g.platform,
+		MultiAZ:        g.multiAZ,
+		Instances:      g.instances,
+		Offer:          offer,
+		OnDemandCost:   onDemand,
+		CommitmentCost: commitment,
+		Savings:        onDemand - commitment,
+	}
+	if onDemand > 0 {
+		purchase.BreakEvenMonths = commitment * float64(offer.Term*12) / onDemand
+	}
+	return purchase
+}
+
+// SavingsPercent is the part of the on-demand cost the purchase saves.
+func (p Purchase) SavingsPercent() float64 {
+	if p.OnDemandCost <= 0 {
+		return 0
+	}
+	return p.Savings / p.OnDemandCost * 100
+}
+
+// Upfront is the fee paid for all the instances when buying.
+func (p Purchase) Upfront() float64 {
+	return p.Offer.Upfront * float64(p.Instances)
+}
+
+// HourlyCommitment is the hourly cost of the purchase with the upfront fee spread over the term, the
+// commitment to buy for savings plans.
+func (p Purchase) HourlyCommitment() float64 {
+	return (p.Offer.Upfront/float64(p.Offer.Term*12*hoursPerMonth) + p.Offer.Hourly) * float64(p.Instances)
+}
+
+func (p Purchase) resourceType() string {
+	if p.Resource == ResourceRDSInstance {
+		return "RDS Instance"
+	}
+	return "EC2 Instance"
+}
+
+func (p Purchase) platform() string {
+	platform := p.Platform
+	if platform == "" && p.Resource == ResourceEC2Instance {
+		platform = "Linux/UNIX"
+	}
+	if p.MultiAZ {
+		platform += " (Multi-AZ)"
+	}
+	return platform
+}
+
+func (o Offer) name() string {
+	switch o.Type {
+	case OfferComputeSavingsPlan:
+		return "Compute Savings Plan"
+	case OfferEC2InstanceSavingsPlan:
+		return "EC2 Instance Savings Plan"
+	default:
+		return "Reserved Instance"
+	}
+}
+
+var csvHeaders = []string{
+	"AccountID", "Region", "Resource Type", "Instance Type", "Platform", "Instances", "Commitment", "Term",
+	"Payment", "Upfront", "Hourly Commitment", "On-Demand Cost (Monthly)", "Commitment Cost (Monthly)",
+	"Savings (Monthly)", "Savings %", "Break-even (Months)", "Coverage %",
+}
+
+// Rows returns the purchases as CSV rows, headers first, costs converted to the currency.
+func (p *Plan) Rows(c *currency.Currency) [][]string {
+	rows := [][]string{csvHeaders}
+	for _, purchase := range p.Purchases {
+		rows = append(rows, []string{
+			p.Account, purchase.Region, purchase.resourceType(), purchase.InstanceType, purchase.platform(),
+			fmt.Sprintf("%d", purchase.Instances), purchase.Offer.name(), fmt.Sprintf("%d year", purchase.Offer.Term),
+			string(purchase.Offer.Payment), c.Format(purchase.Upfront()),
+			c.FormatHourly(purchase.HourlyCommitment()), c.Format(purchase.OnDemandCost),
+			c.Format(purchase.CommitmentCost), c.Format(purchase.Savings),
+			fmt.Sprintf("%.1f", purchase.SavingsPercent()), fmt.Sprintf("%.1f", purchase.BreakEvenMonths),
+			fmt.Sprintf("%.1f", purchase.Coverage),
+		})
+	}
+	return rows
+}
+
+// Write writes the purchases to a CSV file, costs converted to the currency.
+func (p *Plan) Write(path string, c *currency.Currency) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write commitment plan: %w", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(p.Rows(c)); err != nil {
+		return fmt.Errorf("failed to write commitment plan: %w", err)
+	}
+	return f.Close()
+}
+
+// Summary describes the savings and coverage of each term and the instances left out of the plan, costs
+// converted to the currency.
+func (p *Plan) Summary(c *currency.Currency) string {
+	var summary string
+	if len(p.Purchases) == 0 {
+		summary = fmt.Sprintf("Commitment plan: no purchase saves money for the %d steady instances", p.Steady)
+	} else {
+		var parts []string
+		for _, term := range terms {
+			var savings, coverage float64
+			var purchases int
+			for _, purchase := range p.Purchases {
+				if purchase.Offer.Term == term {
+					savings += purchase.Savings
+					coverage += purchase.Coverage
+					purchases++
+				}
+			}
+			if purchases > 0 {
+				parts = append(parts, fmt.Sprintf("%d-year purchases save %s/month covering %.1f%% of the rightsized instance cost",
+					term, c.Format(savings), coverage))
+			}
+		}
+		summary = fmt.Sprintf("Commitment plan for %d steady instances: %s", p.Steady, strings.Join(parts, ", "))
+	}
+
+	var left []string
+	if p.Transient > 0 {
+		left = append(left, fmt.Sprintf("%d created less than %.0f hours ago", p.Transient, p.minRuntimeHours))
+	}
+	if p.Covered > 0 {
+		left = append(left, fmt.Sprintf("%d already covered by commitments", p.Covered))
+	}
+	if p.Unpriced > 0 {
+		left = append(left, fmt.Sprintf("%d without a price in the catalog", p.Unpriced))
+	}
+	if len(left) > 0 {
+		summary += "; left out: " + strings.Join(left, ", ")
+	}
+	return summary
+}
@@ -0,0 +1,434 @@
+package coverage
+
+import (
+	"fmt"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	sptypes "github.com/aws/aws-sdk-go-v2/service/savingsplans/types"
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/policy"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	EC2Instance = "EC2Instance"
+	RDSInstance = "RDSInstance"
+)
+
+const hoursPerMonth = 730
+
+// Inventory collects the reserved instances and savings plans of an account while the regions are listed.
+type Inventory struct {
+	lock         sync.Mutex
+	reservations []reservation
+	savingsPlans []savingsPlan
+	currency     *currency.Currency
+}
+
+// reservation is a reserved instance of count instances. Size flexible reservations cover any instance of
+// their family by normalized units, the others only instances of their type.
+type reservation struct {
+	id               string
+	kind             string
+	region           string
+	availabilityZone string
+	instanceType     string
+	platform         string
+	tenancy          string
+	multiAZ          bool
+	count            int32
+	flexible         bool
+}
+
+// savingsPlan is an EC2 instance savings plan of family in region, or a compute savings plan when family
+// is empty.
+type savingsPlan struct {
+	id         string
+	family     string
+	region     string
+	commitment float64
+}
+
+// Resource is an instance to match with the commitments, costs are the monthly on-demand compute costs.
+// Platform is the platform details of EC2 instances and the engine of RDS instances. RecommendedType is
+// empty when there is no recommendation.
+type Resource struct {
+	Kind             string
+	Id               string
+	Region           string
+	AvailabilityZone string
+	InstanceType     string
+	RecommendedType  string
+	Platform         string
+	Tenancy          string
+	MultiAZ          bool
+	CurrentCost      float64
+	RecommendedCost  float64
+}
+
+// NewInventory returns an empty inventory, the costs of its warnings are formatted in the currency.
+func NewInventory(c *currency.Currency) *Inventory {
+	return &Inventory{currency: c}
+}
+
+func (inv *Inventory) AddReservedInstances(region string, reserved []ec2types.ReservedInstances) {
+	inv.lock.Lock()
+	defer inv.lock.Unlock()
+	for _, ri := range reserved {
+		if ri.ReservedInstancesId == nil || ri.InstanceCount == nil {
+			continue
+		}
+		r := reservation{
+			id:           *ri.ReservedInstancesId,
+			kind:         EC2Instance,
+			region:       region,
+			instanceType: string(ri.InstanceType),
+			platform:     strings.TrimSuffix(string(ri.ProductDescription), " (Amazon VPC)"),
+			tenancy:      string(ri.InstanceTenancy),
+			count:        *ri.InstanceCount,
+		}
+		if ri.Scope == ec2types.ScopeAvailabilityZone && ri.AvailabilityZone != nil {
+			r.availabilityZone = *ri.AvailabilityZone
+		}
+		// regional Linux reservations of the default tenancy apply to any size of their family
+		r.flexible = r.availabilityZone == "" && r.platform == "Linux/UNIX" && r.tenancy == string(ec2types.TenancyDefault)
+		inv.reservations = append(inv.reservations, r)
+	}
+}
+
+// rdsProductEngines maps the product descriptions of reserved DB instances, without their license model, to
+// the engine of the instances they apply to where the two differ.
+var rdsProductEngines = map[string]string{
+	"postgresql": "postgres",
+}
+
+// rdsEngine normalizes the product description of a reserved DB instance or the engine of an instance to
+// the engine they are matched on.
+func rdsEngine(s string) string {
+	engine := strings.ToLower(strings.TrimSpace(s))
+	engine = strings.TrimSuffix(strings.TrimSuffix(engine, "(li)"), "(byol)")
+	if e, ok := rdsProductEngines[engine]; ok {
+		return e
+	}
+	return engine
+}
+
+func (inv *Inventory) AddReservedDBInstances(region string, reserved []rdstypes.ReservedDBInstance) {
+	inv.lock.Lock()
+	defer inv.lock.Unlock()
+	for _, ri := range reserved {
+		if ri.ReservedDBInstanceId == nil || ri.DBInstanceClass == nil || ri.DBInstanceCount == nil || ri.ProductDescription == nil {
+			continue
+		}
+		product := strings.ToLower(*ri.ProductDescription)
+		inv.reservations = append(inv.reservations, reservation{
+			id:           *ri.ReservedDBInstanceId,
+			kind:         RDSInstance,
+			region:       region,
+			instanceType: *ri.DBInstanceClass,
+			platform:     rdsEngine(product),
+			multiAZ:      ri.MultiAZ != nil && *ri.MultiAZ,
+			count:        *ri.DBInstanceCount,
+			// license included and SQL Server reservations are not size flexible
+			flexible: !strings.HasSuffix(product, "(li)") && !strings.HasPrefix(product, "sqlserver"),
+		})
+	}
+}
+
+// AddSavingsPlans adds the EC2 instance and compute savings plans, other savings plans do not apply to
+// the resources the plugin analyses.
+func (inv *Inventory) AddSavingsPlans(plans []sptypes.SavingsPlan) {
+	inv.lock.Lock()
+	defer inv.lock.Unlock()
+	for _, plan := range plans {
+		if plan.SavingsPlanId == nil || plan.Commitment == nil {
+			continue
+		}
+		commitment, err := strconv.ParseFloat(*plan.Commitment, 64)
+		if err != nil {
+			continue
+		}
+		switch plan.SavingsPlanType {
+		case sptypes.SavingsPlanTypeCompute:
+			inv.savingsPlans = append(inv.savingsPlans, savingsPlan{id: *plan.SavingsPlanId, commitment: commitment})
+		case sptypes.SavingsPlanTypeEc2Instance:
+			inv.savingsPlans = append(inv.savingsPlans, savingsPlan{
+				id: *plan.SavingsPlanId, family: utils.PString(plan.Ec2InstanceFamily), region: utils.PString(plan.Region), commitment: commitment,
+			})
+		}
+	}
+}
+
+// allocation is the share of a commitment given to a resource, normalized units of reservations and
+// monthly cost of savings plans.
+type allocation struct {
+	index  int
+	amount float64
+}
+
+// Allocate matches the resources with the commitments and returns the coverage of those that have some,
+// keyed by resource id. Reservations go first, zonal then regional ones of the same type and then size
+// flexible ones, savings plans pay for what is left, EC2 instance ones before compute ones. Resources are
+// served in order of region and id, so the result does not depend on the order they were analysed in.
+//
+// The effective savings are the on-demand cost left after the change minus the one left now. A
+// reservation the recommended type can not use and the savings plan commitment it frees are warned
+// about, they are only saved when other usage takes them up. Savings plans are treated as paying for
+// on-demand cost, their discount is not known.
+func (inv *Inventory) Allocate(resources []Resource) map[string]history.Coverage {
+	if inv == nil {
+		return nil
+	}
+	inv.lock.Lock()
+	defer inv.lock.Unlock()
+	if len(inv.reservations) == 0 && len(inv.savingsPlans) == 0 {
+		return nil
+	}
+
+	resources = append([]Resource{}, resources...)
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Region != resources[j].Region {
+			return resources[i].Region < resources[j].Region
+		}
+		return resources[i].Id < resources[j].Id
+	})
+	reservations := append([]reservation{}, inv.reservations...)
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].id < reservations[j].id
+	})
+	plans := append([]savingsPlan{}, inv.savingsPlans...)
+	sort.Slice(plans, func(i, j int) bool {
+		if (plans[i].family == "") != (plans[j].family == "") {
+			return plans[i].family != ""
+		}
+		return plans[i].id < plans[j].id
+	})
+
+	unitsLeft := make([]float64, len(reservations))
+	for i, r := range reservations {
+		unitsLeft[i] = float64(r.count) * units(r.instanceType)
+	}
+	reserved := make([][]allocation, len(resources))
+	for _, match := range []func(Resource, reservation) bool{
+		func(res Resource, r reservation) bool {
+			return r.availabilityZone != "" && r.availabilityZone == res.AvailabilityZone && r.instanceType == res.InstanceType
+		},
+		func(res Resource, r reservation) bool {
+			return r.availabilityZone == "" && r.instanceType == res.InstanceType
+		},
+		func(res Resource, r reservation) bool {
+			return r.availabilityZone == "" && r.flexible && sizeFlexible(r.instanceType, res.InstanceType)
+		},
+	} {
+		for i, res := range resources {
+			needed := units(res.InstanceType) - allocated(reserved[i])
+			for j, r := range reservations {
+				if needed <= 0 {
+					break
+				}
+				if unitsLeft[j] <= 0 || !r.covers(res) || !match(res, r) {
+					continue
+				}
+				amount := math.Min(needed, unitsLeft[j])
+				if !r.flexible && amount < units(res.InstanceType) {
+					continue
+				}
+				reserved[i] = append(reserved[i], allocation{index: j, amount: amount})
+				unitsLeft[j] -= amount
+				needed -= amount
+			}
+		}
+	}
+
+	// like AWS, every EC2 instance savings plan is applied to all the usage before the compute ones
+	onDemand := make([]float64, len(resources))
+	for i, res := range resources {
+		onDemand[i] = res.CurrentCost * (1 - math.Min(allocated(reserved[i])/units(res.InstanceType), 1))
+	}
+	paid := make([][]allocation, len(resources))
+	for j, plan := range plans {
+		commitmentLeft := plan.commitment * hoursPerMonth
+		for i, res := range resources {
+			if commitmentLeft <= 0 {
+				break
+			}
+			if res.Kind != EC2Instance || onDemand[i] <= 0 || !plan.covers(res.Region, res.InstanceType) {
+				continue
+			}
+			amount := math.Min(onDemand[i], commitmentLeft)
+			paid[i] = append(paid[i], allocation{index: j, amount: amount})
+			onDemand[i] -= amount
+			commitmentLeft -= amount
+		}
+	}
+
+	result := map[string]history.Coverage{}
+	for i, res := range resources {
+		if res.CurrentCost <= 0 {
+			continue
+		}
+		var coverage history.Coverage
+		hasRecommendation := res.RecommendedType != ""
+
+		recommendedUnits := units(res.RecommendedType)
+		recommendedUnitsLeft := recommendedUnits
+		for _, a := range reserved[i] {
+			r := reservations[a.index]
+			coverage.Commitments = append(coverage.Commitments, r.id)
+			if !hasRecommendation {
+				continue
+			}
+			kept := 0.0
+			if res.RecommendedType == res.InstanceType || (r.flexible && sizeFlexible(r.instanceType, res.RecommendedType)) {
+				kept = math.Min(a.amount, recommendedUnitsLeft)
+			}
+			recommendedUnitsLeft -= kept
+			if unused := a.amount - kept; unused > 0 {
+				if r.flexible {
+					coverage.Warnings = append(coverage.Warnings, fmt.Sprintf("%s leaves %s of the %s normalized units of reserved instance %s given to it unused",
+						res.RecommendedType, formatUnits(unused), formatUnits(a.amount), r.id))
+				} else {
+					coverage.Warnings = append(coverage.Warnings, fmt.Sprintf("%s leaves reserved instance %s (%s) unused", res.RecommendedType, r.id, r.instanceType))
+				}
+			}
+		}
+		recommendedOnDemand := res.RecommendedCost
+		if recommendedUnits > 0 {
+			recommendedOnDemand = res.RecommendedCost * (recommendedUnitsLeft / recommendedUnits)
+		}
+
+		for _, a := range paid[i] {
+			plan := plans[a.index]
+			coverage.Commitments = append(coverage.Commitments, plan.id)
+			if !hasRecommendation {
+				continue
+			}
+			kept := 0.0
+			if plan.covers(res.Region, res.RecommendedType) {
+				kept = math.Min(a.amount, recommendedOnDemand)
+			}
+			recommendedOnDemand -= kept
+			if freed := a.amount - kept; freed > 0.005 {
+				coverage.Warnings = append(coverage.Warnings, fmt.Sprintf("%s frees %s/month of savings plan %s commitment",
+					res.RecommendedType, inv.currency.Format(freed), plan.id))
+			}
+		}
+
+		if len(coverage.Commitments) == 0 {
+			continue
+		}
+		coverage.CoveredCost = res.CurrentCost - onDemand[i]
+		if hasRecommendation {
+			coverage.EffectiveSavings = onDemand[i] - recommendedOnDemand
+		}
+		result[res.Id] = coverage
+	}
+	return result
+}
+
+func (r reservation) covers(res Resource) bool {
+	if r.kind != res.Kind || r.region != res.Region {
+		return false
+	}
+	if r.kind == RDSInstance {
+		return r.platform == rdsEngine(res.Platform) && r.multiAZ == res.MultiAZ
+	}
+	tenancy := res.Tenancy
+	if tenancy == "" {
+		tenancy = string(ec2types.TenancyDefault)
+	}
+	return r.platform == res.Platform && r.tenancy == tenancy
+}
+
+func (p savingsPlan) covers(region, instanceType string) bool {
+	if p.family == "" {
+		return true
+	}
+	family, _ := policy.SplitInstanceType(instanceType)
+	return p.region == region && p.family == family
+}
+
+func allocated(allocations []allocation) float64 {
+	var total float64
+	for _, a := range allocations {
+		total += a.amount
+	}
+	return total
+}
+
+func sizeFlexible(reserved, instanceType string) bool {
+	reservedFamily, reservedSize := policy.SplitInstanceType(reserved)
+	family, size := policy.SplitInstanceType(instanceType)
+	_, reservedOk := normalizationFactor(reservedSize)
+	_, ok := normalizationFactor(size)
+	return reservedFamily == family && reservedOk && ok
+}
+
+// units is the normalization factor of the size of an instance type, 1 when the size has none so that
+// the reservations of these sizes count instances.
+func units(instanceType string) float64 {
+	_, size := policy.SplitInstanceType(instanceType)
+	if factor, ok := normalizationFactor(size); ok {
+		return factor
+	}
+	return 1
+}
+
+// normalizationFactor is the footprint of an instance size in the units used by size flexible reservations.
+func normalizationFactor(size string) (float64, bool) {
+	switch size {
+	case "nano":
+		return 0.25, true
+	case "micro":
+		return 0.5, true
+	case "small":
+		return 1, true
+	case "medium":
+		return 2, true
+	case "large":
+		return 4, true
+	case "xlarge":
+		return 8, true
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(size, "xlarge")); err == nil && strings.HasSuffix(size, "xlarge") && n > 0 {
+		return float64(8 * n), true
+	}
+	return 0, false
+}
+
+func formatUnits(u float64) string {
+	return strconv.FormatFloat(u, 'f', -1, 64)
+}
+
+// Summary describes the coverage of the records of a run, empty when no resource is covered. Costs are
+// converted to the currency.
+func Summary(records []history.Record, c *currency.Currency) string {
+	var covered, warnings int
+	var savings, effectiveSavings float64
+	for _, r := range records {
+		if r.Coverage == nil {
+			continue
+		}
+		covered++
+		warnings += len(r.Coverage.Warnings)
+		if r.HasWaste() {
+			savings += r.Savings
+			effectiveSavings += r.Coverage.EffectiveSavings
+		}
+	}
+	if covered == 0 {
+		return ""
+	}
+	summary := fmt.Sprintf("Commitments: %d resources covered by reserved instances or savings plans, effective savings %s/month instead of %s/month on demand",
+		covered, c.Format(effectiveSavings), c.Format(savings))
+	if warnings > 0 {
+		summary += fmt.Sprintf(", %d warnings about commitments left unused", warnings)
+	}
+	return summary
+}
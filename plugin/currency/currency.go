@@ -0,0 +1,183 @@
+package currency
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"gopkg.in/yaml.v3"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const fileVersion = 1
+
+const USD = "USD"
+
+var codePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+var symbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"CNY": "CN¥",
+	"INR": "₹",
+	"KRW": "₩",
+	"AUD": "A$",
+	"CAD": "CA$",
+	"BRL": "R$",
+}
+
+// currencies without minor units, shown without decimals
+var wholeUnits = map[string]bool{
+	"JPY": true,
+	"KRW": true,
+}
+
+// Rates is a file of exchange rates, in units of each currency per US dollar, the costs of the optimization
+// service are converted with:
+//
+//	version: 1
+//	rates:
+//	  EUR: 0.92
+//	  JPY: 151.4
+//
+// Currencies are ISO 4217 codes. The rates are the ones of the file, never fetched, so exports are
+// reproducible and match the rates finance reports with.
+type Rates struct {
+	Version int                `yaml:"version"`
+	Rates   map[string]float64 `yaml:"rates"`
+}
+
+// Currency converts USD costs and formats them with the symbol of the currency. A nil Currency keeps them
+// in USD.
+type Currency struct {
+	Code   string
+	Symbol string
+	Rate   float64
+}
+
+// LoadRates reads and validates an exchange rates file.
+func LoadRates(path string) (*Rates, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	var r Rates
+	if err := decoder.Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates file %s: %w", path, err)
+	}
+	if r.Version != fileVersion {
+		return nil, fmt.Errorf("exchange rates file %s: unsupported version %d, expected %d", path, r.Version, fileVersion)
+	}
+	if len(r.Rates) == 0 {
+		return nil, fmt.Errorf("exchange rates file %s has no rates", path)
+	}
+
+	var codes []string
+	for code := range r.Rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	var errs []error
+	for _, code := range codes {
+		if !codePattern.MatchString(code) {
+			errs = append(errs, fmt.Errorf("rate %s: not an ISO 4217 currency code", code))
+		}
+		if r.Rates[code] <= 0 {
+			errs = append(errs, fmt.Errorf("rate %s: must be positive, got %g", code, r.Rates[code]))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("exchange rates file %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// Load returns the currency costs are shown in, nil for USD. Other currencies need their rate in the
+// exchange rates file.
+func Load(code, ratesPath string) (*Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" || code == USD {
+		return nil, nil
+	}
+	if ratesPath == "" {
+		return nil, fmt.Errorf("currency %s needs exchange-rates-file with its rate to USD", code)
+	}
+	rates, err := LoadRates(ratesPath)
+	if err != nil {
+		return nil, err
+	}
+	rate, ok := rates.Rates[code]
+	if !ok {
+		return nil, fmt.Errorf("exchange rates file %s has no rate for %s", ratesPath, code)
+	}
+	symbol, ok := symbols[code]
+	if !ok {
+		symbol = code + " "
+	}
+	return &Currency{Code: code, Symbol: symbol, Rate: rate}, nil
+}
+
+// Convert returns a USD cost in the currency.
+func (c *Currency) Convert(usd float64) float64 {
+	if c == nil {
+		return usd
+	}
+	return usd * c.Rate
+}
+
+// Format converts a USD cost and formats it with the symbol of the currency, dollars with
+// utils.FormatPriceFloat and the other currencies with the same thousands separators.
+func (c *Currency) Format(usd float64) string {
+	if c == nil {
+		return utils.FormatPriceFloat(usd)
+	}
+	if wholeUnits[c.Code] {
+		return c.Symbol + group(c.Convert(usd), 0)
+	}
+	return c.Symbol + group(c.Convert(usd), 2)
+}
+
+// FormatHourly is Format for hourly prices, with four decimals, two for currencies without minor units.
+func (c *Currency) FormatHourly(usd float64) string {
+	if c == nil {
+		return "$" + group(usd, 4)
+	}
+	if wholeUnits[c.Code] {
+		return c.Symbol + group(c.Convert(usd), 2)
+	}
+	return c.Symbol + group(c.Convert(usd), 4)
+}
+
+// group formats an amount with the decimals, separating the thousands with commas like
+// utils.FormatPriceFloat.
+func group(amount float64, decimals int) string {
+	formatted := strconv.FormatFloat(amount, 'f', decimals, 64)
+	sign, integer, fraction := "", formatted, ""
+	if strings.HasPrefix(integer, "-") {
+		sign, integer = "-", integer[1:]
+	}
+	if idx := strings.IndexByte(integer, '.'); idx >= 0 {
+		integer, fraction = integer[:idx], integer[idx:]
+	}
+	var b strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String() + fraction
+}
+
+// ExportRow is the row of the CSV export noting the currency and rate of its costs, after the resources.
+func (c *Currency) ExportRow() []string {
+	return []string{"Currency", c.Code, fmt.Sprintf("1 USD = %g %s", c.Rate, c.Code)}
+}
@@ -0,0 +1,173 @@
+package history
+
+import (
+	"fmt"
+	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"sort"
+)
+
+type ChangeKind string
+
+const (
+	ChangeNewWaste ChangeKind = "New Waste"
+	ChangeResolved ChangeKind = "Resolved"
+	ChangeChanged  ChangeKind = "Changed"
+	ChangeRemoved  ChangeKind = "Removed"
+	// ChangeNotAnalysed is a resource with a record in the previous run that the current run still lists
+	// but did not analyse.
+	ChangeNotAnalysed ChangeKind = "Not Analysed"
+)
+
+var changeKindOrder = map[ChangeKind]int{
+	ChangeNewWaste:    0,
+	ChangeChanged:     1,
+	ChangeResolved:    2,
+	ChangeRemoved:     3,
+	ChangeNotAnalysed: 4,
+}
+
+type Change struct {
+	Kind     ChangeKind
+	Previous *Record
+	Current  *Record
+	// RealizedSavings is how much changing the current spec since the previous run lowered the cost, both
+	// priced for the current runtime
+	RealizedSavings float64
+}
+
+func (c Change) record() Record {
+	if c.Current != nil {
+		return *c.Current
+	}
+	return *c.Previous
+}
+
+type Diff struct {
+	Previous        *Run
+	Current         Run
+	Changes         []Change
+	RealizedSavings float64
+}
+
+// Compare reports what changed between two runs of the same command. Without a previous run
+// every resource with savings is new waste. Resources of the previous run without a record in the current
+// one are only removed when the current run no longer lists them, the ones it did not analyse are reported
+// apart.
+func Compare(previous *Run, current Run) Diff {
+	diff := Diff{Previous: previous, Current: current}
+
+	before := map[string]Record{}
+	if previous != nil {
+		for _, r := range previous.Records {
+			before[r.Key()] = r
+		}
+	}
+	seen := map[string]bool{}
+
+	for idx := range current.Records {
+		cur := current.Records[idx]
+		seen[cur.Key()] = true
+		prev, ok := before[cur.Key()]
+
+		var change *Change
+		switch {
+		case (!ok || !prev.HasWaste()) && cur.HasWaste():
+			change = &Change{Kind: ChangeNewWaste, Current: &cur}
+			if ok {
+				change.Previous = &prev
+			}
+		case ok && prev.HasWaste() && !cur.HasWaste():
+			change = &Change{Kind: ChangeResolved, Previous: &prev, Current: &cur}
+		case ok && prev.HasWaste() && cur.HasWaste() &&
+			(prev.CurrentSpec != cur.CurrentSpec || prev.RecommendedSpec != cur.RecommendedSpec):
+			change = &Change{Kind: ChangeChanged, Previous: &prev, Current: &cur}
+		}
+		if change == nil {
+			continue
+		}
+		// cost drops without a spec change come from pricing, the runtime or the policy, not from a change made
+		if prev := change.Previous; prev != nil && prev.CurrentSpec != cur.CurrentSpec {
+			if saved := prev.costAt(cur.runtimeHours()) - cur.CurrentCost; saved > 0 {
+				change.RealizedSavings = saved
+				diff.RealizedSavings += saved
+			}
+		}
+		diff.Changes = append(diff.Changes, *change)
+	}
+
+	if previous != nil {
+		unanalysed := map[string]bool{}
+		for _, id := range current.Unanalysed {
+			unanalysed[id] = true
+		}
+		for idx := range previous.Records {
+			prev := previous.Records[idx]
+			if seen[prev.Key()] {
+				continue
+			}
+			kind := ChangeRemoved
+			if unanalysed[prev.Owner()] {
+				kind = ChangeNotAnalysed
+			}
+			diff.Changes = append(diff.Changes, Change{Kind: kind, Previous: &prev})
+		}
+	}
+
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		if diff.Changes[i].Kind != diff.Changes[j].Kind {
+			return changeKindOrder[diff.Changes[i].Kind] < changeKindOrder[diff.Changes[j].Kind]
+		}
+		return diff.Changes[i].record().Key() < diff.Changes[j].record().Key()
+	})
+	return diff
+}
+
+func (d Diff) Count(kind ChangeKind) int {
+	count := 0
+	for _, c := range d.Changes {
+		if c.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// Summary counts the changes of the diff, the realized savings converted to the currency.
+func (d Diff) Summary(c *currency.Currency) string {
+	if d.Previous == nil {
+		return fmt.Sprintf("No previous run to compare with, %d resources with waste", d.Count(ChangeNewWaste))
+	}
+	return fmt.Sprintf("Since %s: %d new waste, %d changed, %d resolved, %d removed, %d not analysed, realized savings: %s",
+		d.Previous.StartedAt.Local().Format("2006-01-02 15:04"), d.Count(ChangeNewWaste), d.Count(ChangeChanged),
+		d.Count(ChangeResolved), d.Count(ChangeRemoved), d.Count(ChangeNotAnalysed), c.Format(d.RealizedSavings))
+}
+
+// ExportCsv returns the changes as CSV rows, headers first, costs converted to the currency.
+func (d Diff) ExportCsv(cur *currency.Currency) []*golang.CSVRow {
+	headers := []string{
+		"Change", "AccountID", "Region / AZ", "Resource Type", "Device ID", "Device Name", "Previous Spec", "Current Spec",
+		"Previous Suggested Spec", "Suggested Spec", "Previous Net Savings", "Net Savings", "Realized Savings",
+	}
+	rows := []*golang.CSVRow{{Row: headers}}
+	for _, c := range d.Changes {
+		r := c.record()
+		var prevSpec, prevRecSpec, prevSavings, curSpec, curRecSpec, curSavings string
+		if c.Previous != nil {
+			prevSpec, prevRecSpec, prevSavings = c.Previous.CurrentSpec, c.Previous.RecommendedSpec, cur.Format(c.Previous.Savings)
+		}
+		if c.Current != nil {
+			curSpec, curRecSpec, curSavings = c.Current.CurrentSpec, c.Current.RecommendedSpec, cur.Format(c.Current.Savings)
+		}
+		rows = append(rows, &golang.CSVRow{Row: []string{
+			string(c.Kind), d.Current.Account, r.Region, r.ResourceType, r.ResourceId, r.ResourceName, prevSpec, curSpec,
+			prevRecSpec, curRecSpec, prevSavings, curSavings, cur.Format(c.RealizedSavings),
+		}})
+	}
+	return rows
+}
+
+// Write saves the rows of the diff as CSV, costs converted to the currency.
+func (d Diff) Write(path string, c *currency.Currency) error {
+	return writeCsv(path, "diff", d.ExportCsv(c))
+}
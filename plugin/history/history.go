@@ -0,0 +1,274 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	runVersion   = 1
+	runFileTime  = "20060102T150405.000000000Z"
+	runExtension = ".json"
+)
+
+const UnitPercent = "%"
+
+// Utilization is the observed usage of one resource dimension during the observability window.
+type Utilization struct {
+	Avg  *float64 `json:"avg,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+	Unit string   `json:"unit,omitempty"`
+}
+
+// Record is the outcome of a single resource in a run, one per row of the CSV export. CurrentType and
+// RecommendedType are the part of the spec a recommendation changes, the instance type or class of
+// instances and the tier or storage type of volumes. The current and recommended size (GiB), IOPS and
+// throughput (MiB/s) of storage are only set when the type supports configuring them. Platform, the
+// operating system of instances or engine of databases, MultiAZ, RuntimeHours, the monthly runtime
+// observed over the observability window the costs are priced for, and AgeHours, the hours since the
+// instance was created, are only set on instances, ClusterId on the instances of a cluster. Tags are the tags of the resource, volumes and the
+// instances of a cluster inheriting the ones of their instance or cluster they do not set themselves.
+type Record struct {
+	ResourceType          string                 `json:"resourceType"`
+	ResourceId            string                 `json:"resourceId"`
+	ResourceName          string                 `json:"resourceName"`
+	Region                string                 `json:"region"`
+	Platform              string                 `json:"platform,omitempty"`
+	MultiAZ               bool                   `json:"multiAZ,omitempty"`
+	RuntimeHours          float64                `json:"runtimeHours,omitempty"`
+	AgeHours              float64                `json:"ageHours,omitempty"`
+	ParentId              string                 `json:"parentId,omitempty"`
+	ClusterId             string                 `json:"clusterId,omitempty"`
+	CurrentSpec           string                 `json:"currentSpec"`
+	RecommendedSpec       string                 `json:"recommendedSpec,omitempty"`
+	CurrentType           string                 `json:"currentType,omitempty"`
+	RecommendedType       string                 `json:"recommendedType,omitempty"`
+	CurrentSize           *int32                 `json:"currentSize,omitempty"`
+	RecommendedSize       *int32                 `json:"recommendedSize,omitempty"`
+	RecommendedIops       *int32                 `json:"recommendedIops,omitempty"`
+	RecommendedThroughput *float64               `json:"recommendedThroughput,omitempty"`
+	CurrentCost           float64                `json:"currentCost"`
+	RecommendedCost       float64                `json:"recommendedCost,omitempty"`
+	Savings               float64                `json:"savings"`
+	Description           string                 `json:"description,omitempty"`
+	Utilization           map[string]Utilization `json:"utilization,omitempty"`
+	Coverage              *Coverage              `json:"coverage,omitempty"`
+	Tags                  map[string]string      `json:"tags,omitempty"`
+}
+
+// Coverage is the part of the current cost of an instance paid for by reserved instances and savings plans,
+// and the savings of its recommendation once they are taken into account.
+type Coverage struct {
+	Commitments      []string `json:"commitments"`
+	CoveredCost      float64  `json:"coveredCost"`
+	EffectiveSavings float64  `json:"effectiveSavings"`
+	Warnings         []string `json:"warnings,omitempty"`
+}
+
+func (r Record) Key() string {
+	return r.ResourceType + "/" + r.ResourceId
+}
+
+// Owner is the id of the resource the processors list the record under, the cluster of the instances of a
+// cluster and the instance of volumes and storage.
+func (r Record) Owner() string {
+	switch {
+	case r.ClusterId != "":
+		return r.ClusterId
+	case r.ParentId != "":
+		return r.ParentId
+	default:
+		return r.ResourceId
+	}
+}
+
+// hoursPerMonth is the runtime of records without an observed one, storage paid for the whole month and
+// the instances of runs stored before the runtime was observed.
+const hoursPerMonth = 730
+
+func (r Record) runtimeHours() float64 {
+	if r.RuntimeHours > 0 {
+		return r.RuntimeHours
+	}
+	return hoursPerMonth
+}
+
+// costAt is the current cost of the record priced for a monthly runtime instead of its own, to compare records
+// of runs that observed different runtimes.
+func (r Record) costAt(hours float64) float64 {
+	return r.CurrentCost / r.runtimeHours() * hours
+}
+
+// HasWaste reports whether the resource had a recommendation that saves money.
+func (r Record) HasWaste() bool {
+	return r.RecommendedSpec != "" && r.Savings > 0
+}
+
+// Run is the records of a run of a command. Unanalysed is the ids of the resources the run listed but did not
+// get a recommendation for, skipped, left to load on demand or failed, which have no record.
+type Run struct {
+	Version    int       `json:"version"`
+	Account    string    `json:"account"`
+	Command    string    `json:"command"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Records    []Record  `json:"records"`
+	Unanalysed []string  `json:"unanalysed,omitempty"`
+}
+
+// DefaultMaxRuns is how many runs of a command in an account the store keeps by default.
+const DefaultMaxRuns = 100
+
+// Store keeps the runs as JSON files under <dir>/<account>/<command>, the most recent maxRuns of each
+// command in an account, all of them when maxRuns is 0.
+type Store struct {
+	dir     string
+	maxRuns int
+}
+
+func NewStore(dir string, maxRuns int) (*Store, error) {
+	if dir == "" {
+		var err error
+		dir, err = DefaultDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	return &Store{dir: dir, maxRuns: maxRuns}, nil
+}
+
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "kaytu", "plugin-aws", "history"), nil
+}
+
+func (s *Store) runsDir(account, command string) string {
+	return filepath.Join(s.dir, account, command)
+}
+
+func (s *Store) Save(run Run) error {
+	run.Version = runVersion
+	content, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := s.runsDir(run.Account, run.Command)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	path := filepath.Join(dir, run.StartedAt.UTC().Format(runFileTime)+runExtension)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		return fmt.Errorf("failed to write run history: %w", err)
+	}
+	return s.prune(run.Account, run.Command)
+}
+
+// prune deletes the oldest runs of the command in the account beyond maxRuns.
+func (s *Store) prune(account, command string) error {
+	if s.maxRuns <= 0 {
+		return nil
+	}
+	names, err := s.runFiles(account, command)
+	if err != nil {
+		return err
+	}
+	for len(names) > s.maxRuns {
+		if err := os.Remove(filepath.Join(s.runsDir(account, command), names[0])); err != nil {
+			return fmt.Errorf("failed to delete old run history: %w", err)
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// runFiles returns the names of the run files of the command in the account, oldest first.
+func (s *Store) runFiles(account, command string) ([]string, error) {
+	entries, err := os.ReadDir(s.runsDir(account, command))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read run history: %w", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), runExtension) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Runs returns the stored runs of the command in the account, oldest first.
+func (s *Store) Runs(account, command string) ([]Run, error) {
+	names, err := s.runFiles(account, command)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []Run
+	for _, name := range names {
+		content, err := os.ReadFile(filepath.Join(s.runsDir(account, command), name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read run history: %w", err)
+		}
+		var run Run
+		if err := json.Unmarshal(content, &run); err != nil {
+			return nil, fmt.Errorf("failed to parse run history %s: %w", name, err)
+		}
+		if run.Version != runVersion {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// Latest returns the most recent run started before the given time, nil if there is none.
+func (s *Store) Latest(account, command string, before time.Time) (*Run, error) {
+	runs, err := s.Runs(account, command)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].StartedAt.Before(before) {
+			return &runs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func writeCsv(path, name string, rows []*golang.CSVRow) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	for _, row := range rows {
+		if err := w.Write(row.Row); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return f.Close()
+}
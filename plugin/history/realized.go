@@ -0,0 +1,149 @@
+package history
+
+import (
+	"fmt"
+	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UnderProvisionedThreshold is the peak utilization, in percent, above which a resource that was
+// resized following a recommendation is reported as a possible regression.
+const UnderProvisionedThreshold = 90.0
+
+// RealizedSaving is a recommendation of an earlier run that has been applied since.
+type RealizedSaving struct {
+	// Baseline is the resource as it was in the run that made the recommendation, Current as it is now
+	Baseline        Record
+	Current         Record
+	RecommendedAt   time.Time
+	AppliedBy       time.Time
+	ExpectedSavings float64
+	RealizedSavings float64
+	// Regressions lists the utilization dimensions peaking above UnderProvisionedThreshold after the change
+	Regressions []string
+}
+
+// RealizedSavings finds the resources of the current run whose type now matches what one of the
+// earlier runs recommended. Runs are expected oldest first, as returned by Store.Runs. The realized savings
+// are the cost of the baseline priced for the current runtime less the current cost, the price drop of the
+// change rather than of the hours the resource ran.
+func RealizedSavings(runs []Run, current Run) []RealizedSaving {
+	var result []RealizedSaving
+	for _, cur := range current.Records {
+		if cur.CurrentType == "" {
+			continue
+		}
+
+		appliedBy := current.StartedAt
+		for i := len(runs) - 1; i >= 0; i-- {
+			if !runs[i].StartedAt.Before(current.StartedAt) {
+				continue
+			}
+			prev, ok := runs[i].record(cur.Key())
+			if !ok {
+				continue
+			}
+			if prev.CurrentType == cur.CurrentType {
+				// already applied at that point, keep looking for the run that recommended it
+				appliedBy = runs[i].StartedAt
+				continue
+			}
+			if prev.HasWaste() && prev.RecommendedType == cur.CurrentType {
+				saving := RealizedSaving{
+					Baseline:        prev,
+					Current:         cur,
+					RecommendedAt:   runs[i].StartedAt,
+					AppliedBy:       appliedBy,
+					ExpectedSavings: prev.Savings,
+					RealizedSavings: prev.costAt(cur.runtimeHours()) - cur.CurrentCost,
+				}
+				for name, u := range cur.Utilization {
+					if u.Unit == UnitPercent && u.Max != nil && *u.Max >= UnderProvisionedThreshold {
+						saving.Regressions = append(saving.Regressions, name)
+					}
+				}
+				sort.Strings(saving.Regressions)
+				result = append(result, saving)
+			}
+			break
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Current.Key() < result[j].Current.Key()
+	})
+	return result
+}
+
+func (r Run) record(key string) (Record, bool) {
+	for _, rec := range r.Records {
+		if rec.Key() == key {
+			return rec, true
+		}
+	}
+	return Record{}, false
+}
+
+// RealizedSavingsSummary totals the realized savings, converted to the currency.
+func RealizedSavingsSummary(savings []RealizedSaving, c *currency.Currency) string {
+	var total float64
+	regressions := 0
+	for _, s := range savings {
+		total += s.RealizedSavings
+		if len(s.Regressions) > 0 {
+			regressions++
+		}
+	}
+	return fmt.Sprintf("%d applied recommendations, realized savings: %s (monthly), %d possibly under-provisioned",
+		len(savings), c.Format(total), regressions)
+}
+
+// RealizedSavingsCsv returns the realized savings as CSV rows, headers first, costs converted to the currency.
+func RealizedSavingsCsv(account string, savings []RealizedSaving, c *currency.Currency) []*golang.CSVRow {
+	headers := []string{
+		"AccountID", "Region / AZ", "Resource Type", "Device ID", "Device Name", "Recommended At", "Applied By",
+		"Previous Spec", "Current Spec", "Previous Cost", "Current Cost", "Expected Savings", "Realized Savings",
+		"Post-change Utilization", "Under-provisioned",
+	}
+	rows := []*golang.CSVRow{{Row: headers}}
+	for _, s := range savings {
+		var names []string
+		for name := range s.Current.Utilization {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var utilization []string
+		for _, name := range names {
+			utilization = append(utilization, fmt.Sprintf("%s:: Avg: %s - Max: %s", name,
+				formatUtilization(s.Current.Utilization[name].Avg, s.Current.Utilization[name].Unit),
+				formatUtilization(s.Current.Utilization[name].Max, s.Current.Utilization[name].Unit)))
+		}
+
+		rows = append(rows, &golang.CSVRow{Row: []string{
+			account, s.Current.Region, s.Current.ResourceType, s.Current.ResourceId, s.Current.ResourceName,
+			s.RecommendedAt.UTC().Format(time.RFC3339), s.AppliedBy.UTC().Format(time.RFC3339),
+			s.Baseline.CurrentSpec, s.Current.CurrentSpec, c.Format(s.Baseline.CurrentCost),
+			c.Format(s.Current.CurrentCost), c.Format(s.ExpectedSavings),
+			c.Format(s.RealizedSavings), strings.Join(utilization, "---"), strings.Join(s.Regressions, ","),
+		}})
+	}
+	return rows
+}
+
+func formatUtilization(v *float64, unit string) string {
+	if v == nil {
+		return ""
+	}
+	if unit == UnitPercent {
+		return fmt.Sprintf("%.2f%%", *v)
+	}
+	return fmt.Sprintf("%.2f %s", *v, unit)
+}
+
+// WriteRealizedSavings saves the rows of the realized savings report as CSV, costs converted to the currency.
+func WriteRealizedSavings(path, account string, savings []RealizedSaving, c *currency.Currency) error {
+	return writeCsv(path, "realized savings", RealizedSavingsCsv(account, savings, c))
+}
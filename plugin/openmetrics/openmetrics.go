@@ -0,0 +1,176 @@
+package openmetrics
+
+import (
+	"fmt"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/report"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MetricCurrentMonthlyCost     = "kaytu_aws_current_monthly_cost"
+	MetricPotentialSavings       = "kaytu_aws_potential_savings"
+	MetricResourceUtilizationP99 = "kaytu_aws_resource_utilization_p99"
+)
+
+var help = map[string]string{
+	MetricCurrentMonthlyCost:     "Current monthly cost of the resource in USD.",
+	MetricPotentialSavings:       "Monthly savings of the recommendation for the resource in USD.",
+	MetricResourceUtilizationP99: "99th percentile of the utilization metric of the resource over the observability window, in the unit of the metric.",
+}
+
+// Sample is a value of a gauge and its labels.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Exposition is the gauges of a run in the OpenMetrics text format, to chart waste over time from the
+// textfile collector of node_exporter. Every device of the CSV export is a resource, labelled by account,
+// region, resource type, resource id and instance type, the type of its instance for volumes and storage.
+type Exposition struct {
+	Gauges map[string][]Sample
+}
+
+// New returns the gauges of the records and the utilization series of the items of the run.
+func New(account string, records []history.Record, items []report.Item) *Exposition {
+	instanceTypes := map[string]string{}
+	for _, r := range records {
+		switch r.ResourceType {
+		case "EC2 Instance":
+			instanceTypes[r.ResourceId] = r.CurrentType
+		case "RDS Instance Compute":
+			instanceTypes[r.ParentId] = r.CurrentType
+		}
+	}
+	utilization := map[string][]report.Series{}
+	for _, i := range items {
+		for rowId, series := range i.Utilization {
+			utilization[rowId] = series
+		}
+	}
+
+	e := &Exposition{Gauges: map[string][]Sample{}}
+	for _, r := range records {
+		instanceType := instanceTypes[r.ResourceId]
+		if r.ParentId != "" {
+			instanceType = instanceTypes[r.ParentId]
+		}
+		labels := map[string]string{
+			"account":       account,
+			"region":        r.Region,
+			"resource_type": r.ResourceType,
+			"resource_id":   r.ResourceId,
+			"instance_type": instanceType,
+		}
+		e.add(MetricCurrentMonthlyCost, labels, r.CurrentCost)
+		e.add(MetricPotentialSavings, labels, r.Savings)
+		for _, s := range utilization[r.ResourceId] {
+			values := s.Values()
+			if len(values) == 0 {
+				continue
+			}
+			withMetric := map[string]string{"metric": s.Metric}
+			for k, v := range labels {
+				withMetric[k] = v
+			}
+			e.add(MetricResourceUtilizationP99, withMetric, Percentile(values, 99))
+		}
+	}
+	return e
+}
+
+func (e *Exposition) add(metric string, labels map[string]string, value float64) {
+	e.Gauges[metric] = append(e.Gauges[metric], Sample{Labels: labels, Value: value})
+}
+
+// Percentile is the nearest-rank percentile p of values.
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Samples is the number of samples of all the gauges.
+func (e *Exposition) Samples() int {
+	var count int
+	for _, samples := range e.Gauges {
+		count += len(samples)
+	}
+	return count
+}
+
+// String is the exposition in the OpenMetrics text format, the samples of every gauge sorted by labels.
+func (e *Exposition) String() string {
+	var b strings.Builder
+	for _, metric := range []string{MetricCurrentMonthlyCost, MetricPotentialSavings, MetricResourceUtilizationP99} {
+		samples := e.Gauges[metric]
+		if len(samples) == 0 {
+			continue
+		}
+		lines := make([]string, 0, len(samples))
+		for _, s := range samples {
+			lines = append(lines, fmt.Sprintf("%s{%s} %s", metric, formatLabels(s.Labels), strconv.FormatFloat(s.Value, 'g', -1, 64)))
+		}
+		sort.Strings(lines)
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", metric, help[metric], metric)
+		for _, line := range lines {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("# EOF\n")
+	return b.String()
+}
+
+// Write writes the exposition to a file, through a temporary file renamed over it so the textfile collector
+// never reads a partial one.
+func (e *Exposition) Write(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write OpenMetrics file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(e.String()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write OpenMetrics file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write OpenMetrics file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write OpenMetrics file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write OpenMetrics file: %w", err)
+	}
+	return nil
+}
+
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escaper.Replace(labels[name])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
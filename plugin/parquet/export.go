@@ -0,0 +1,279 @@
+package parquet
+
+import (
+	"encoding/json"
+	"fmt"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/report"
+	"github.com/opengovern/plugin-aws/plugin/version"
+	parquetgo "github.com/parquet-go/parquet-go"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Datasets of the export, the top directories of its partitions.
+const (
+	DatasetRecommendations = "recommendations"
+	DatasetUtilization     = "utilization"
+)
+
+// rowGroupRows is the most rows of a row group, the utilization of a large account for a long observability
+// period is split so readers don't have to load it whole. Pages are split by the writer at its page buffer
+// size.
+const rowGroupRows = 100_000
+
+// Recommendation is a row of the recommendation files, an EC2 instance, EBS volume and RDS instance or cluster
+// member compute and storage. ClusterId is the cluster of RDS cluster members, empty for the others. Tags are
+// a JSON object.
+type Recommendation struct {
+	GeneratedAt           time.Time `parquet:"generated_at,timestamp(millisecond)"`
+	ResourceType          string    `parquet:"resource_type"`
+	ResourceId            string    `parquet:"resource_id"`
+	ResourceName          string    `parquet:"resource_name"`
+	ParentId              string    `parquet:"parent_id"`
+	ClusterId             string    `parquet:"cluster_id"`
+	Platform              string    `parquet:"platform"`
+	MultiAZ               bool      `parquet:"multi_az"`
+	RuntimeHours          float64   `parquet:"runtime_hours"`
+	CurrentSpec           string    `parquet:"current_spec"`
+	RecommendedSpec       string    `parquet:"recommended_spec"`
+	CurrentType           string    `parquet:"current_type"`
+	RecommendedType       string    `parquet:"recommended_type"`
+	CurrentSize           *int64    `parquet:"current_size,optional"`
+	RecommendedSize       *int64    `parquet:"recommended_size,optional"`
+	RecommendedIops       *int64    `parquet:"recommended_iops,optional"`
+	RecommendedThroughput *float64  `parquet:"recommended_throughput,optional"`
+	CurrentCost           float64   `parquet:"current_cost"`
+	RecommendedCost       float64   `parquet:"recommended_cost"`
+	Savings               float64   `parquet:"savings"`
+	Description           string    `parquet:"description"`
+	Tags                  string    `parquet:"tags"`
+}
+
+// Datapoint is a row of the utilization files, a CloudWatch datapoint of a device, statistics that were not
+// collected null.
+type Datapoint struct {
+	GeneratedAt  time.Time `parquet:"generated_at,timestamp(millisecond)"`
+	ResourceType string    `parquet:"resource_type"`
+	ResourceId   string    `parquet:"resource_id"`
+	Metric       string    `parquet:"metric"`
+	Timestamp    time.Time `parquet:"timestamp,timestamp(millisecond)"`
+	Average      *float64  `parquet:"average,optional"`
+	Maximum      *float64  `parquet:"maximum,optional"`
+	Minimum      *float64  `parquet:"minimum,optional"`
+	Sum          *float64  `parquet:"sum,optional"`
+	SampleCount  *float64  `parquet:"sample_count,optional"`
+	Unit         string    `parquet:"unit"`
+}
+
+// Partition is a Hive style partition of a dataset, the account, region and day of the run.
+type Partition struct {
+	Dataset string
+	Account string
+	Region  string
+	Date    string
+}
+
+// Dir is the directory of the partition under the export directory.
+func (p Partition) Dir() string {
+	return filepath.Join(p.Dataset, "account="+p.Account, "region="+p.Region, "date="+p.Date)
+}
+
+// Export is the recommendation records of a run and, optionally, the utilization datapoints of their
+// devices, as Parquet files partitioned by account, region and date for data lake ingestion. The date is
+// the UTC day of the run and each run writes its own file, so every run is a snapshot. Costs are in USD.
+type Export struct {
+	GeneratedAt     time.Time
+	Recommendations map[Partition][]Recommendation
+	Utilization     map[Partition][]Datapoint
+}
+
+// New returns the export of the records and, when utilization, the series of the items of the run.
+func New(account string, records []history.Record, items []report.Item, utilization bool, now time.Time) (*Export, error) {
+	e := &Export{GeneratedAt: now.UTC(), Recommendations: map[Partition][]Recommendation{}, Utilization: map[Partition][]Datapoint{}}
+	date := e.GeneratedAt.Format("2006-01-02")
+	devices := map[string]history.Record{}
+	for _, r := range records {
+		devices[r.ResourceId] = r
+		tags := "{}"
+		if len(r.Tags) > 0 {
+			content, err := json.Marshal(r.Tags)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode tags of %s: %w", r.ResourceId, err)
+			}
+			tags = string(content)
+		}
+		partition := Partition{Dataset: DatasetRecommendations, Account: account, Region: r.Region, Date: date}
+		e.Recommendations[partition] = append(e.Recommendations[partition], Recommendation{
+			GeneratedAt:           e.GeneratedAt,
+			ResourceType:          r.ResourceType,
+			ResourceId:            r.ResourceId,
+			ResourceName:          r.ResourceName,
+			ParentId:              r.ParentId,
+			ClusterId:             r.ClusterId,
+			Platform:              r.Platform,
+			MultiAZ:               r.MultiAZ,
+			RuntimeHours:          r.RuntimeHours,
+			CurrentSpec:           r.CurrentSpec,
+			RecommendedSpec:       r.RecommendedSpec,
+			CurrentType:           r.CurrentType,
+			RecommendedType:       r.RecommendedType,
+			CurrentSize:           int64Of(r.CurrentSize),
+			RecommendedSize:       int64Of(r.RecommendedSize),
+			RecommendedIops:       int64Of(r.RecommendedIops),
+			RecommendedThroughput: r.RecommendedThroughput,
+			CurrentCost:           r.CurrentCost,
+			RecommendedCost:       r.RecommendedCost,
+			Savings:               r.Savings,
+			Description:           r.Description,
+			Tags:                  tags,
+		})
+	}
+	if !utilization {
+		return e, nil
+	}
+
+	for _, i := range items {
+		rowIds := make([]string, 0, len(i.Utilization))
+		for rowId := range i.Utilization {
+			rowIds = append(rowIds, rowId)
+		}
+		sort.Strings(rowIds)
+		for _, rowId := range rowIds {
+			device, ok := devices[rowId]
+			if !ok {
+				continue
+			}
+			partition := Partition{Dataset: DatasetUtilization, Account: account, Region: device.Region, Date: date}
+			for _, s := range i.Utilization[rowId] {
+				var datapoints []types2.Datapoint
+				for _, dp := range s.Datapoints {
+					if dp.Timestamp != nil {
+						datapoints = append(datapoints, dp)
+					}
+				}
+				sort.SliceStable(datapoints, func(a, b int) bool { return datapoints[a].Timestamp.Before(*datapoints[b].Timestamp) })
+				for _, dp := range datapoints {
+					e.Utilization[partition] = append(e.Utilization[partition], Datapoint{
+						GeneratedAt:  e.GeneratedAt,
+						ResourceType: device.ResourceType,
+						ResourceId:   device.ResourceId,
+						Metric:       s.Metric,
+						Timestamp:    dp.Timestamp.UTC(),
+						Average:      dp.Average,
+						Maximum:      dp.Maximum,
+						Minimum:      dp.Minimum,
+						Sum:          dp.Sum,
+						SampleCount:  dp.SampleCount,
+						Unit:         string(dp.Unit),
+					})
+				}
+			}
+		}
+	}
+	return e, nil
+}
+
+func int64Of(v *int32) *int64 {
+	if v == nil {
+		return nil
+	}
+	i := int64(*v)
+	return &i
+}
+
+// Rows is the number of rows of the dataset across its partitions.
+func (e *Export) Rows(dataset string) int {
+	var rows int
+	switch dataset {
+	case DatasetRecommendations:
+		for _, r := range e.Recommendations {
+			rows += len(r)
+		}
+	case DatasetUtilization:
+		for _, d := range e.Utilization {
+			rows += len(d)
+		}
+	}
+	return rows
+}
+
+// Files are the paths of the files of the partitions of the export under the directory.
+func (e *Export) Files(dir string) map[Partition]string {
+	name := fmt.Sprintf("part-%s.parquet", e.GeneratedAt.Format("20060102T150405Z"))
+	files := map[Partition]string{}
+	for p := range e.Recommendations {
+		files[p] = filepath.Join(dir, p.Dir(), name)
+	}
+	for p := range e.Utilization {
+		files[p] = filepath.Join(dir, p.Dir(), name)
+	}
+	return files
+}
+
+// Write writes a Parquet file per partition under the directory, leaving the files of earlier runs in place.
+func (e *Export) Write(dir string) error {
+	files := e.Files(dir)
+	partitions := make([]Partition, 0, len(files))
+	for p := range files {
+		partitions = append(partitions, p)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return files[partitions[i]] < files[partitions[j]] })
+	for _, p := range partitions {
+		var err error
+		if p.Dataset == DatasetRecommendations {
+			err = writeFile(files[p], e.Recommendations[p])
+		} else {
+			err = writeFile(files[p], e.Utilization[p])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes the rows to a Parquet file, through a temporary dotfile renamed over it so ingestion never
+// picks up a partial one.
+func writeFile[T any](path string, rows []T) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to write Parquet file: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write Parquet file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	w := parquetgo.NewGenericWriter[T](tmp, parquetgo.CreatedBy("plugin-aws", version.VERSION, ""), parquetgo.MaxRowsPerRowGroup(rowGroupRows))
+	if _, err := w.Write(rows); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write Parquet file: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write Parquet file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write Parquet file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write Parquet file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write Parquet file: %w", err)
+	}
+	return nil
+}
+
+// Summary describes the rows and files of the export.
+func (e *Export) Summary() string {
+	message := fmt.Sprintf("%d recommendation records", e.Rows(DatasetRecommendations))
+	if rows := e.Rows(DatasetUtilization); rows > 0 {
+		message += fmt.Sprintf(" and %d utilization datapoints", rows)
+	}
+	return fmt.Sprintf("%s in %d Parquet files", message, len(e.Recommendations)+len(e.Utilization))
+}
@@ -0,0 +1,261 @@
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strings"
+)
+
+const fileVersion = 1
+
+const (
+	ResourceEC2Instance = "EC2Instance"
+	ResourceRDSInstance = "RDSInstance"
+)
+
+type Action string
+
+const (
+	ActionReject Action = "reject"
+	ActionWarn   Action = "warn"
+)
+
+// Policy is a file of rules evaluated on every right sizing recommendation, to reject those that are never
+// acceptable or to annotate them with warnings:
+//
+//	version: 1
+//	rules:
+//	  - name: stay on nitro
+//	    resource: EC2Instance
+//	    when: family(recommended.instance_type) in ["t2", "m4", "c4", "r4"]
+//	    action: reject
+//	    message: previous generation families are not nitro based
+//	  - name: per core licenses
+//	    resource: EC2Instance
+//	    when: '"license" in tags && current.processor != recommended.processor'
+//	    action: reject
+//	  - name: minimum memory
+//	    resource: RDSInstance
+//	    when: recommended.memory_gb < 4
+//	    action: warn
+//
+// A rule's when is a CEL expression over current and recommended, the RightsizingEC2Instance or
+// RightsizingAwsRds messages with their proto field names, tags, the tags of the resource, and
+// family(instance_type), the family of an instance type such as m5 for m5.large or db.m5.large.
+// A nil Policy accepts every recommendation.
+type Policy struct {
+	Version int    `yaml:"version"`
+	Rules   []Rule `yaml:"rules"`
+}
+
+type Rule struct {
+	Name     string `yaml:"name"`
+	Resource string `yaml:"resource"`
+	When     string `yaml:"when"`
+	Action   Action `yaml:"action"`
+	Message  string `yaml:"message"`
+
+	program cel.Program
+}
+
+// Decision is the outcome of the rules for one recommendation. Rejection names the first rule rejecting
+// it, empty when it is accepted.
+type Decision struct {
+	Rejection string
+	Warnings  []string
+}
+
+// Load reads a policy file and compiles the expressions of its rules.
+func Load(path string) (*Policy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	var p Policy
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	if p.Version != fileVersion {
+		return nil, fmt.Errorf("policy file %s: unsupported version %d, expected %d", path, p.Version, fileVersion)
+	}
+	if len(p.Rules) == 0 {
+		return nil, fmt.Errorf("policy file %s has no rules", path)
+	}
+
+	envs := map[string]*cel.Env{}
+	names := map[string]bool{}
+	var errs []error
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i+1)
+			errs = append(errs, fmt.Errorf("rule %s: has no name", name))
+		} else if names[name] {
+			errs = append(errs, fmt.Errorf("rule %s: name is used by another rule", name))
+		}
+		names[name] = true
+		if rule.Action != ActionReject && rule.Action != ActionWarn {
+			errs = append(errs, fmt.Errorf("rule %s: action must be %s or %s, got %q", name, ActionReject, ActionWarn, rule.Action))
+		}
+		if _, ok := envs[rule.Resource]; !ok {
+			env, err := newEnv(rule.Resource)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("rule %s: %w", name, err))
+				continue
+			}
+			envs[rule.Resource] = env
+		}
+		if rule.program, err = compile(envs[rule.Resource], rule.When); err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", name, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("policy file %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+func newEnv(resource string) (*cel.Env, error) {
+	var message proto.Message
+	switch resource {
+	case ResourceEC2Instance:
+		message = &golang2.RightsizingEC2Instance{}
+	case ResourceRDSInstance:
+		message = &golang2.RightsizingAwsRds{}
+	default:
+		return nil, fmt.Errorf("unknown resource %q, expected %s or %s", resource, ResourceEC2Instance, ResourceRDSInstance)
+	}
+	messageType := cel.ObjectType(string(message.ProtoReflect().Descriptor().FullName()))
+	return cel.NewEnv(
+		cel.Types(message),
+		cel.Variable("current", messageType),
+		cel.Variable("recommended", messageType),
+		cel.Variable("tags", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Function("family",
+			cel.Overload("family_string", []*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(func(value ref.Val) ref.Val {
+					return types.String(Family(string(value.(types.String))))
+				}),
+			),
+		),
+	)
+}
+
+func compile(env *cel.Env, expression string) (cel.Program, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, fmt.Errorf("has no when expression")
+	}
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("invalid when expression: %w", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("when expression must be a bool, got %s", ast.OutputType())
+	}
+	return env.Program(ast)
+}
+
+// Family is the family of an instance type or RDS instance class, the part before the size: m5 for both
+// m5.large and db.m5.large.
+func Family(instanceType string) string {
+	family, _ := SplitInstanceType(instanceType)
+	return family
+}
+
+// SplitInstanceType splits an instance type or RDS instance class into its family and size.
+func SplitInstanceType(instanceType string) (string, string) {
+	family, size, _ := strings.Cut(strings.TrimPrefix(instanceType, "db."), ".")
+	return family, size
+}
+
+// ApplyEC2Instance evaluates the EC2Instance rules on rec. A rejected recommendation is replaced by the
+// current instance so it shows no change and no savings, the rejection and warnings are put before the
+// description of rec.
+func (p *Policy) ApplyEC2Instance(rec *golang2.EC2InstanceRightSizingRecommendation, tags map[string]string) Decision {
+	if p == nil || rec == nil || rec.Current == nil || rec.Recommended == nil {
+		return Decision{}
+	}
+	d := p.evaluate(ResourceEC2Instance, rec.Current, rec.Recommended, tags)
+	if d.Rejection != "" {
+		rec.Recommended = proto.Clone(rec.Current).(*golang2.RightsizingEC2Instance)
+	}
+	rec.Description = d.annotate(rec.Description)
+	return d
+}
+
+// ApplyRDSInstance evaluates the RDSInstance rules on rec like ApplyEC2Instance, a rejection keeps both the
+// current instance class and storage.
+func (p *Policy) ApplyRDSInstance(rec *golang2.RDSInstanceRightSizingRecommendation, tags map[string]string) Decision {
+	if p == nil || rec == nil || rec.Current == nil || rec.Recommended == nil {
+		return Decision{}
+	}
+	d := p.evaluate(ResourceRDSInstance, rec.Current, rec.Recommended, tags)
+	if d.Rejection != "" {
+		rec.Recommended = proto.Clone(rec.Current).(*golang2.RightsizingAwsRds)
+	}
+	rec.Description = d.annotate(rec.Description)
+	return d
+}
+
+func (p *Policy) evaluate(resource string, current, recommended proto.Message, tags map[string]string) Decision {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	vars := map[string]any{"current": current, "recommended": recommended, "tags": tags}
+	var d Decision
+	for _, rule := range p.Rules {
+		if rule.Resource != resource {
+			continue
+		}
+		out, _, err := rule.program.Eval(vars)
+		if err != nil {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("policy rule %s could not be evaluated: %v", rule.Name, err))
+			continue
+		}
+		if out != types.True {
+			continue
+		}
+		switch rule.Action {
+		case ActionReject:
+			if d.Rejection == "" {
+				d.Rejection = fmt.Sprintf("Rejected by policy rule %s: %s", rule.Name, rule.message())
+			}
+		case ActionWarn:
+			d.Warnings = append(d.Warnings, fmt.Sprintf("Policy warning %s: %s", rule.Name, rule.message()))
+		}
+	}
+	return d
+}
+
+func (r Rule) message() string {
+	if r.Message != "" {
+		return r.Message
+	}
+	return r.When
+}
+
+func (d Decision) annotate(description string) string {
+	var lines []string
+	if d.Rejection != "" {
+		lines = append(lines, d.Rejection)
+	}
+	lines = append(lines, d.Warnings...)
+	if len(lines) == 0 {
+		return description
+	}
+	if description != "" {
+		lines = append(lines, description)
+	}
+	return strings.Join(lines, "\n")
+}
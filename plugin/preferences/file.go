@@ -0,0 +1,270 @@
+package preferences
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const fileVersion = 1
+
+// File is a preferences file holding named profiles, so team standards can be kept in git:
+//
+//	version: 1
+//	profiles:
+//	  prod:
+//	    EC2Instance:
+//	      CPUBreathingRoom: 30
+//	      Region: {pinned: true}
+//	      ProcessorArchitecture: {pinned: true}
+//	    RDSInstance:
+//	      CpuBreathingRoom: 30
+//
+// A scalar sets the value of a preference, a mapping can set value or pinned. Services and keys are those
+// of DefaultEC2Preferences and DefaultRDSPreferences. Rules override the preferences of the resources
+// carrying their tags on top of the profile:
+//
+//	rules:
+//	  - name: prod databases
+//	    tags: {env: prod}
+//	    preferences:
+//	      RDSInstance:
+//	        CpuBreathingRoom: 30
+type File struct {
+	Version  int                `yaml:"version"`
+	Profiles map[string]Profile `yaml:"profiles"`
+	Rules    Rules              `yaml:"rules"`
+}
+
+// Profile maps services to the settings of their preference keys.
+type Profile map[string]map[string]Setting
+
+type Setting struct {
+	Value  *string `yaml:"value"`
+	Pinned bool    `yaml:"pinned"`
+
+	line int
+}
+
+func (s *Setting) UnmarshalYAML(node *yaml.Node) error {
+	s.line = node.Line
+	switch node.Kind {
+	case yaml.ScalarNode:
+		s.Value = &node.Value
+		return nil
+	case yaml.MappingNode:
+		var setting struct {
+			Value  *string `yaml:"value"`
+			Pinned bool    `yaml:"pinned"`
+		}
+		if err := node.Decode(&setting); err != nil {
+			return err
+		}
+		s.Value, s.Pinned = setting.Value, setting.Pinned
+		return nil
+	}
+	return fmt.Errorf("line %d: a setting is a value or a mapping of value and pinned", node.Line)
+}
+
+// LoadFile reads and validates every profile of a preferences file.
+func LoadFile(path string) (*File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preferences file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	var file File
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse preferences file %s: %w", path, err)
+	}
+	if file.Version != fileVersion {
+		return nil, fmt.Errorf("preferences file %s: unsupported version %d, expected %d", path, file.Version, fileVersion)
+	}
+	if len(file.Profiles) == 0 && len(file.Rules) == 0 {
+		return nil, fmt.Errorf("preferences file %s has no profiles or rules", path)
+	}
+	if err := file.validate(); err != nil {
+		return nil, fmt.Errorf("preferences file %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// validate checks the settings against the known keys, their PossibleValues and whether they can be pinned.
+func (f *File) validate() error {
+	known := map[string]map[string]*golang.PreferenceItem{}
+	for _, item := range append(append([]*golang.PreferenceItem{}, DefaultEC2Preferences...), DefaultRDSPreferences...) {
+		if known[item.Service] == nil {
+			known[item.Service] = map[string]*golang.PreferenceItem{}
+		}
+		known[item.Service][item.Key] = item
+	}
+
+	var errs []error
+	for _, name := range f.ProfileNames() {
+		errs = append(errs, validateProfile("profile "+name, f.Profiles[name], known)...)
+	}
+	for i, rule := range f.Rules {
+		name := fmt.Sprintf("rule %d", i+1)
+		if rule.Name != "" {
+			name = "rule " + rule.Name
+		}
+		if len(rule.Tags) == 0 {
+			errs = append(errs, fmt.Errorf("%s: has no tags, put preferences for every resource in a profile", name))
+		}
+		if len(rule.Preferences) == 0 {
+			errs = append(errs, fmt.Errorf("%s: has no preferences", name))
+		}
+		errs = append(errs, validateProfile(name, rule.Preferences, known)...)
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	return errors.Join(errs...)
+}
+
+func validateProfile(name string, profile Profile, known map[string]map[string]*golang.PreferenceItem) []error {
+	var errs []error
+	for service, settings := range profile {
+		keys, ok := known[service]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown service %s, expected one of %s", name, service, strings.Join(sortedKeys(known), ", ")))
+			continue
+		}
+		for key, setting := range settings {
+			item, ok := keys[key]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: %sunknown %s preference %s", name, setting.position(), service, key))
+				continue
+			}
+			if err := setting.validate(item); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s%s.%s %w", name, setting.position(), service, key, err))
+			}
+		}
+	}
+	return errs
+}
+
+// position is the line of the setting for errors, empty settings are never unmarshalled and have none.
+func (s Setting) position() string {
+	if s.line == 0 {
+		return ""
+	}
+	return fmt.Sprintf("line %d: ", s.line)
+}
+
+func (s Setting) validate(item *golang.PreferenceItem) error {
+	if s.Pinned && s.Value != nil {
+		return fmt.Errorf("sets both a value and pinned, a pinned preference keeps the value of the current resource")
+	}
+	if !s.Pinned && s.Value == nil {
+		return fmt.Errorf("sets neither a value nor pinned, use \"\" for any value")
+	}
+	if s.Pinned && item.PreventPinning {
+		return fmt.Errorf("can not be pinned")
+	}
+	if s.Value == nil || *s.Value == "" {
+		return nil
+	}
+	if item.IsNumber {
+		if _, err := strconv.ParseFloat(*s.Value, 64); err != nil {
+			return fmt.Errorf("must be a number, got %q", *s.Value)
+		}
+	}
+	if len(item.PossibleValues) > 0 {
+		for _, v := range item.PossibleValues {
+			if v == *s.Value {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %q, got %q", item.PossibleValues, *s.Value)
+	}
+	return nil
+}
+
+func (f *File) ProfileNames() []string {
+	return sortedKeys(f.Profiles)
+}
+
+// Profile returns the named profile, an empty name selects the profile named default or the only one.
+// A file with only rules has no profile to select, an empty profile is returned.
+func (f *File) Profile(name string) (Profile, error) {
+	if name == "" {
+		if len(f.Profiles) == 0 {
+			return Profile{}, nil
+		} else if _, ok := f.Profiles["default"]; ok {
+			name = "default"
+		} else if len(f.Profiles) == 1 {
+			name = f.ProfileNames()[0]
+		} else {
+			return nil, fmt.Errorf("select one of the preference profiles %s", strings.Join(f.ProfileNames(), ", "))
+		}
+	}
+	profile, ok := f.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown preference profile %s, expected one of %s", name, strings.Join(f.ProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// Apply returns a copy of items with the settings of the profile, items themselves are not modified.
+// Settings of services not in items are ignored, a profile can serve EC2 and RDS runs alike.
+func (p Profile) Apply(items []*golang.PreferenceItem) []*golang.PreferenceItem {
+	var result []*golang.PreferenceItem
+	for _, item := range items {
+		setting, ok := p[item.Service][item.Key]
+		if !ok {
+			result = append(result, item)
+			continue
+		}
+		result = append(result, setting.apply(item))
+	}
+	return result
+}
+
+func (s Setting) apply(item *golang.PreferenceItem) *golang.PreferenceItem {
+	updated := &golang.PreferenceItem{
+		Service:        item.Service,
+		Key:            item.Key,
+		Alias:          item.Alias,
+		Value:          item.Value,
+		PossibleValues: item.PossibleValues,
+		Pinned:         s.Pinned,
+		PreventPinning: item.PreventPinning,
+		IsNumber:       item.IsNumber,
+		Unit:           item.Unit,
+	}
+	if s.Value != nil {
+		updated.Value = wrapperspb.String(*s.Value)
+		if *s.Value == "" {
+			updated.Value = nil
+		}
+	}
+	return updated
+}
+
+func (s Setting) String() string {
+	if s.Pinned {
+		return "pinned"
+	}
+	if s.Value == nil || *s.Value == "" {
+		return "any"
+	}
+	return *s.Value
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,78 @@
+package preferences
+
+import (
+	"fmt"
+	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
+	"sort"
+	"strings"
+)
+
+// Rule overrides the preferences of the resources carrying all of its tags, a tag value of * matches any
+// value of the tag.
+type Rule struct {
+	Name        string            `yaml:"name"`
+	Tags        map[string]string `yaml:"tags"`
+	Preferences Profile           `yaml:"preferences"`
+}
+
+type Rules []Rule
+
+func (r Rule) Matches(tags map[string]string) bool {
+	for key, value := range r.Tags {
+		tag, ok := tags[key]
+		if !ok || (value != "*" && value != tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// Selector is the tags of the rule as key=value pairs, used to name rules without one.
+func (r Rule) Selector() string {
+	var pairs []string
+	for _, key := range sortedKeys(r.Tags) {
+		pairs = append(pairs, key+"="+r.Tags[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Apply returns a copy of items with the overrides of the rules matching tags, later rules win over earlier
+// ones. The overrides are described as Service.Key=value (rule) for the export, nil when no rule matched.
+func (r Rules) Apply(items []*golang.PreferenceItem, tags map[string]string) ([]*golang.PreferenceItem, []string) {
+	type override struct {
+		setting Setting
+		rule    string
+	}
+	overrides := map[string]override{}
+	for _, rule := range r {
+		if !rule.Matches(tags) {
+			continue
+		}
+		name := rule.Name
+		if name == "" {
+			name = rule.Selector()
+		}
+		for service, settings := range rule.Preferences {
+			for key, setting := range settings {
+				overrides[service+"."+key] = override{setting: setting, rule: name}
+			}
+		}
+	}
+	if len(overrides) == 0 {
+		return items, nil
+	}
+
+	var result []*golang.PreferenceItem
+	var descriptions []string
+	for _, item := range items {
+		o, ok := overrides[item.Service+"."+item.Key]
+		if !ok {
+			result = append(result, item)
+			continue
+		}
+		result = append(result, o.setting.apply(item))
+		descriptions = append(descriptions, fmt.Sprintf("%s.%s=%s (%s)", item.Service, item.Key, o.setting, o.rule))
+	}
+	sort.Strings(descriptions)
+	return result, descriptions
+}
@@ -0,0 +1,246 @@
+package pricing
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+const fileVersion = 1
+
+const (
+	ServiceEC2Instance = "EC2Instance"
+	ServiceEBSVolume   = "EBSVolume"
+	ServiceRDSInstance = "RDSInstance"
+)
+
+// Adjustments is a file of discounts and private prices applied to the list prices of the optimization
+// service, so every cost shown is the one actually paid:
+//
+//	version: 1
+//	adjustments:
+//	  - name: private m5 pricing
+//	    service: EC2Instance
+//	    region: us-east-1
+//	    instanceType: m5.*
+//	    usageType: Compute
+//	    override: 55
+//	  - name: EDP
+//	    discount: 12
+//
+// An adjustment applies to the costs of a service, EC2Instance, EBSVolume or RDSInstance, in a region and
+// of an instance type, volume type or instance class, all of them when left empty and matched as globs.
+// The usage type is the name of a cost component, a discount without one applies to the whole cost. A
+// discount is a percentage off the cost, an override the monthly cost of the matched usage types, for
+// which a usage type is needed. Adjustments are applied in order, so a discount after an override is
+// taken off the overridden price. A nil Adjustments keeps the list prices.
+type Adjustments struct {
+	Version     int          `yaml:"version"`
+	Adjustments []Adjustment `yaml:"adjustments"`
+
+	lock    sync.Mutex
+	applied map[int]bool
+}
+
+type Adjustment struct {
+	Name         string   `yaml:"name"`
+	Service      string   `yaml:"service"`
+	Region       string   `yaml:"region"`
+	InstanceType string   `yaml:"instanceType"`
+	UsageType    string   `yaml:"usageType"`
+	Discount     *float64 `yaml:"discount"`
+	Override     *float64 `yaml:"override"`
+}
+
+// Load reads and validates an adjustments file.
+func Load(filePath string) (*Adjustments, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	var a Adjustments
+	if err := decoder.Decode(&a); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing file %s: %w", filePath, err)
+	}
+	if a.Version != fileVersion {
+		return nil, fmt.Errorf("pricing file %s: unsupported version %d, expected %d", filePath, a.Version, fileVersion)
+	}
+	if len(a.Adjustments) == 0 {
+		return nil, fmt.Errorf("pricing file %s has no adjustments", filePath)
+	}
+
+	var errs []error
+	for i, adjustment := range a.Adjustments {
+		name := adjustment.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i+1)
+			errs = append(errs, fmt.Errorf("adjustment %s: has no name", name))
+		}
+		for _, err := range adjustment.validate() {
+			errs = append(errs, fmt.Errorf("adjustment %s: %w", name, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("pricing file %s: %w", filePath, err)
+	}
+	a.applied = map[int]bool{}
+	return &a, nil
+}
+
+func (a Adjustment) validate() []error {
+	var errs []error
+	switch a.Service {
+	case "", ServiceEC2Instance, ServiceEBSVolume, ServiceRDSInstance:
+	default:
+		errs = append(errs, fmt.Errorf("unknown service %q, expected %s, %s or %s", a.Service, ServiceEC2Instance, ServiceEBSVolume, ServiceRDSInstance))
+	}
+	for _, pattern := range []string{a.Region, a.InstanceType, a.UsageType} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid pattern %q: %w", pattern, err))
+		}
+	}
+	switch {
+	case (a.Discount == nil) == (a.Override == nil):
+		errs = append(errs, fmt.Errorf("needs either a discount or an override"))
+	case a.Discount != nil && (*a.Discount <= 0 || *a.Discount > 100):
+		errs = append(errs, fmt.Errorf("discount must be a percentage above 0 and up to 100, got %g", *a.Discount))
+	case a.Override != nil && *a.Override < 0:
+		errs = append(errs, fmt.Errorf("override must not be negative"))
+	case a.Override != nil && a.UsageType == "":
+		errs = append(errs, fmt.Errorf("override needs the usage type it sets the cost of"))
+	}
+	return errs
+}
+
+// ApplyEC2Instance adjusts the costs of the current and recommended instance and volumes of res.
+func (a *Adjustments) ApplyEC2Instance(res *golang2.EC2InstanceOptimizationResponse, region string) {
+	if a == nil || res == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if res.RightSizing != nil {
+		for _, instance := range []*golang2.RightsizingEC2Instance{res.RightSizing.Current, res.RightSizing.Recommended} {
+			if instance != nil {
+				a.adjust(ServiceEC2Instance, region, instance.InstanceType, &instance.Cost, instance.CostComponents)
+			}
+		}
+	}
+	for _, volume := range res.VolumeRightSizing {
+		if volume == nil {
+			continue
+		}
+		for _, v := range []*golang2.RightsizingEBSVolume{volume.Current, volume.Recommended} {
+			if v != nil {
+				a.adjust(ServiceEBSVolume, region, v.Tier, &v.Cost, v.CostComponents)
+			}
+		}
+	}
+}
+
+// ApplyRDSInstance adjusts the total, compute and storage costs of the current and recommended instance of rec.
+func (a *Adjustments) ApplyRDSInstance(rec *golang2.RDSInstanceRightSizingRecommendation, region string) {
+	if a == nil || rec == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for _, instance := range []*golang2.RightsizingAwsRds{rec.Current, rec.Recommended} {
+		if instance == nil {
+			continue
+		}
+		a.adjust(ServiceRDSInstance, region, instance.InstanceType, &instance.Cost, instance.CostComponents)
+		a.adjust(ServiceRDSInstance, region, instance.InstanceType, &instance.ComputeCost, instance.ComputeCostComponents)
+		a.adjust(ServiceRDSInstance, region, instance.InstanceType, &instance.StorageCost, instance.StorageCostComponents)
+	}
+}
+
+func (a *Adjustments) adjust(service, region, instanceType string, cost *float64, components map[string]float64) {
+	for i, adjustment := range a.Adjustments {
+		if !match(adjustment.Service, service) || !match(adjustment.Region, region) || !match(adjustment.InstanceType, instanceType) {
+			continue
+		}
+		if adjustment.UsageType == "" {
+			factor := 1 - *adjustment.Discount/100
+			*cost *= factor
+			for k := range components {
+				components[k] *= factor
+			}
+			a.applied[i] = true
+			continue
+		}
+		for k, v := range components {
+			if !match(adjustment.UsageType, k) {
+				continue
+			}
+			var adjusted float64
+			if adjustment.Discount != nil {
+				adjusted = v * (1 - *adjustment.Discount/100)
+			} else {
+				adjusted = *adjustment.Override
+			}
+			components[k] = adjusted
+			*cost += adjusted - v
+			a.applied[i] = true
+		}
+	}
+}
+
+func match(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value))
+	return matched
+}
+
+// ExportRow is the row of the CSV export, after the resources, listing the adjustments and whether they applied
+// to any cost. Overrides are converted to the currency.
+func (a *Adjustments) ExportRow(c *currency.Currency) []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	row := []string{"Pricing Adjustments"}
+	for i, adjustment := range a.Adjustments {
+		description := adjustment.Describe(c)
+		if !a.applied[i] {
+			description += " (not applied to any cost)"
+		}
+		row = append(row, description)
+	}
+	return row
+}
+
+// Describe describes the adjustment, an override converted to the currency.
+func (a Adjustment) Describe(c *currency.Currency) string {
+	var change string
+	if a.Discount != nil {
+		change = fmt.Sprintf("%g%% discount on", *a.Discount)
+	} else {
+		change = fmt.Sprintf("%s/month for", c.Format(*a.Override))
+	}
+	usage := a.UsageType
+	if usage == "" {
+		usage = "all usage types"
+	}
+	service := a.Service
+	if service == "" {
+		service = "all services"
+	}
+	if a.InstanceType != "" {
+		service += " " + a.InstanceType
+	}
+	region := a.Region
+	if region == "" {
+		region = "all regions"
+	}
+	return fmt.Sprintf("%s: %s %s of %s in %s", a.Name, change, usage, service, region)
+}
@@ -0,0 +1,39 @@
+package ec2_instance
+
+import (
+	"github.com/opengovern/plugin-aws/plugin/coverage"
+	"github.com/opengovern/plugin-aws/plugin/history"
+)
+
+// coverage matches the optimized instances with the reserved instances and savings plans of the account.
+func (m *Processor) coverage() map[string]history.Coverage {
+	var resources []coverage.Resource
+	m.items.Range(func(_ string, i EC2InstanceItem) bool {
+		if i.Skipped || i.OptimizationLoading || i.Wastage == nil || i.Wastage.RightSizing == nil || i.Wastage.RightSizing.Current == nil {
+			return true
+		}
+		resource := coverage.Resource{
+			Kind:         coverage.EC2Instance,
+			Id:           *i.Instance.InstanceId,
+			Region:       i.Region,
+			InstanceType: i.Wastage.RightSizing.Current.InstanceType,
+			CurrentCost:  i.Wastage.RightSizing.Current.Cost,
+		}
+		if i.Instance.Placement != nil {
+			resource.Tenancy = string(i.Instance.Placement.Tenancy)
+			if i.Instance.Placement.AvailabilityZone != nil {
+				resource.AvailabilityZone = *i.Instance.Placement.AvailabilityZone
+			}
+		}
+		if i.Instance.PlatformDetails != nil {
+			resource.Platform = *i.Instance.PlatformDetails
+		}
+		if i.Wastage.RightSizing.Recommended != nil {
+			resource.RecommendedType = i.Wastage.RightSizing.Recommended.InstanceType
+			resource.RecommendedCost = i.Wastage.RightSizing.Recommended.Cost
+		}
+		resources = append(resources, resource)
+		return true
+	})
+	return m.commitments.Allocate(resources)
+}
@@ -9,16 +9,23 @@ import (
 	"github.com/kaytu-io/kaytu/pkg/style"
 	"github.com/kaytu-io/kaytu/pkg/utils"
 	aws2 "github.com/opengovern/plugin-aws/plugin/aws"
+	"github.com/opengovern/plugin-aws/plugin/coverage"
+	"github.com/opengovern/plugin-aws/plugin/currency"
 	kaytu2 "github.com/opengovern/plugin-aws/plugin/kaytu"
+	"github.com/opengovern/plugin-aws/plugin/policy"
+	"github.com/opengovern/plugin-aws/plugin/preferences"
+	"github.com/opengovern/plugin-aws/plugin/pricing"
 	"github.com/opengovern/plugin-aws/plugin/processor/shared"
 	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	summary2 "github.com/opengovern/plugin-aws/plugin/summary"
 	"strings"
 	"sync/atomic"
 )
 
 type Processor struct {
-	provider                *aws2.AWS
-	metricProvider          *aws2.CloudWatch
+	provider                aws2.InventoryProvider
+	metricProvider          aws2.MetricsProvider
+	metricsSource           MetricsSource
 	identification          map[string]string
 	items                   utils.ConcurrentMap[string, EC2InstanceItem]
 	publishOptimizationItem func(item *golang.ChartOptimizationItem)
@@ -29,14 +36,20 @@ type Processor struct {
 	lazyloadCounter         atomic.Uint32
 	observabilityDays       int
 	defaultPreferences      []*golang.PreferenceItem
+	preferenceRules         preferences.Rules
+	policy                  *policy.Policy
+	pricing                 *pricing.Adjustments
+	currency                *currency.Currency
 	client                  golang2.OptimizationClient
+	commitments             *coverage.Inventory
 
-	summary utils.ConcurrentMap[string, EC2InstanceSummary]
+	summary utils.ConcurrentMap[string, summary2.Resource]
 }
 
 func NewProcessor(
-	prv *aws2.AWS,
-	metric *aws2.CloudWatch,
+	prv aws2.InventoryProvider,
+	metric aws2.MetricsProvider,
+	metricsSource MetricsSource,
 	identification map[string]string,
 	publishOptimizationItem func(item *golang.ChartOptimizationItem),
 	publishResultSummary func(summary *golang.ResultSummary),
@@ -45,11 +58,16 @@ func NewProcessor(
 	configurations *kaytu2.Configuration,
 	observabilityDays int,
 	defaultPreferences []*golang.PreferenceItem,
+	preferenceRules preferences.Rules,
+	recommendationPolicy *policy.Policy,
+	pricingAdjustments *pricing.Adjustments,
+	displayCurrency *currency.Currency,
 	client golang2.OptimizationClient,
 ) *Processor {
 	r := &Processor{
 		provider:                prv,
 		metricProvider:          metric,
+		metricsSource:           metricsSource,
 		identification:          identification,
 		items:                   utils.NewConcurrentMap[string, EC2InstanceItem](),
 		publishOptimizationItem: publishOptimizationItem,
@@ -59,11 +77,16 @@ func NewProcessor(
 		configuration:           configurations,
 		observabilityDays:       observabilityDays,
 		defaultPreferences:      defaultPreferences,
+		preferenceRules:         preferenceRules,
+		policy:                  recommendationPolicy,
+		pricing:                 pricingAdjustments,
+		currency:                displayCurrency,
 		client:                  client,
+		commitments:             coverage.NewInventory(displayCurrency),
 
 		lazyloadCounter: atomic.Uint32{},
 
-		summary: utils.NewConcurrentMap[string, EC2InstanceSummary](),
+		summary: utils.NewConcurrentMap[string, summary2.Resource](),
 	}
 	jobQueue.Push(NewListAllRegionsJob(r))
 	return r
@@ -90,7 +113,8 @@ func (m *Processor) exportCsv() []*golang.CSVRow {
 	}
 	var rows []*golang.CSVRow
 	rows = append(rows, &golang.CSVRow{Row: headers})
-	m.summary.Range(func(id string, _ EC2InstanceSummary) bool {
+	commitments := m.coverage()
+	m.summary.Range(func(id string, _ summary2.Resource) bool {
 		if _, ok := m.items.Get(id); !ok {
 			fmt.Println("Skipping item", id)
 			return true
@@ -112,8 +136,8 @@ func (m *Processor) exportCsv() []*golang.CSVRow {
 		var additionalDetails []string
 		var rightSizingCost, saving, recSpec string
 		if i.Wastage.RightSizing.Recommended != nil {
-			rightSizingCost = utils.FormatPriceFloat(i.Wastage.RightSizing.Recommended.Cost)
-			saving = utils.FormatPriceFloat(i.Wastage.RightSizing.Current.Cost - i.Wastage.RightSizing.Recommended.Cost)
+			rightSizingCost = m.currency.Format(i.Wastage.RightSizing.Recommended.Cost)
+			saving = m.currency.Format(i.Wastage.RightSizing.Current.Cost - i.Wastage.RightSizing.Recommended.Cost)
 			recSpec = i.Wastage.RightSizing.Recommended.InstanceType
 
 			additionalDetails = append(additionalDetails,
@@ -121,7 +145,7 @@ func (m *Processor) exportCsv() []*golang.CSVRow {
 					i.Wastage.RightSizing.Recommended.InstanceType))
 			additionalDetails = append(additionalDetails,
 				fmt.Sprintf("vCPU:: Current: %d - Avg: %s - Recommended: %d", i.Wastage.RightSizing.Current.Vcpu,
-					utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.Vcpu.Avg)), i.Wastage.RightSizing.Recommended.Vcpu))
+					utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.GetVcpu().GetAvg())), i.Wastage.RightSizing.Recommended.Vcpu))
 			additionalDetails = append(additionalDetails,
 				fmt.Sprintf("Processor(s):: Current: %s - Recommended: %s", i.Wastage.RightSizing.Current.Processor,
 					i.Wastage.RightSizing.Recommended.Processor))
@@ -129,17 +153,17 @@ func (m *Processor) exportCsv() []*golang.CSVRow {
 				fmt.Sprintf("Architecture:: Current: %s - Recommended: %s", i.Wastage.RightSizing.Current.Architecture,
 					i.Wastage.RightSizing.Recommended.Architecture))
 			additionalDetails = append(additionalDetails,
-				fmt.Sprintf("License Cost:: Current: $%.2f - Recommended: $%.2f", i.Wastage.RightSizing.Current.LicensePrice,
-					i.Wastage.RightSizing.Recommended.LicensePrice))
+				fmt.Sprintf("License Cost:: Current: %s - Recommended: %s", m.currency.Format(i.Wastage.RightSizing.Current.LicensePrice),
+					m.currency.Format(i.Wastage.RightSizing.Recommended.LicensePrice)))
 			additionalDetails = append(additionalDetails,
 				fmt.Sprintf("Memory:: Current: %.1f GB - Avg: %s - Recommended: %.1f GB", i.Wastage.RightSizing.Current.Memory,
-					utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.Memory.Avg)), i.Wastage.RightSizing.Recommended.Memory))
+					utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.GetMemory().GetAvg())), i.Wastage.RightSizing.Recommended.Memory))
 			additionalDetails = append(additionalDetails,
 				fmt.Sprintf("EBS Bandwidth:: Current: %s - Avg: %s - Recommended: %s", i.Wastage.RightSizing.Current.EbsBandwidth,
-					PNetworkThroughputMBps(shared.WrappedToFloat64(i.Wastage.RightSizing.EbsBandwidth.Avg)), i.Wastage.RightSizing.Recommended.EbsBandwidth))
+					PNetworkThroughputMBps(shared.WrappedToFloat64(i.Wastage.RightSizing.GetEbsBandwidth().GetAvg())), i.Wastage.RightSizing.Recommended.EbsBandwidth))
 			additionalDetails = append(additionalDetails,
 				fmt.Sprintf("EBS IOPS:: Current: %s - Avg: %s io/s - Recommended: %s", i.Wastage.RightSizing.Current.EbsIops,
-					utils.PFloat64ToString(shared.WrappedToFloat64(i.Wastage.RightSizing.EbsIops.Avg)), i.Wastage.RightSizing.Recommended.EbsIops))
+					utils.PFloat64ToString(shared.WrappedToFloat64(i.Wastage.RightSizing.GetEbsIops().GetAvg())), i.Wastage.RightSizing.Recommended.EbsIops))
 
 			enaSupportChange := i.Wastage.RightSizing.Current.EnaSupported != i.Wastage.RightSizing.Recommended.EnaSupported
 			additionalDetails = append(additionalDetails,
@@ -151,8 +175,12 @@ func (m *Processor) exportCsv() []*golang.CSVRow {
 			}
 
 		}
+		additionalDetails = append(additionalDetails, shared.PreferenceOverridesDetails(i.PreferenceOverrides, "EC2Instance")...)
+		if c, ok := commitments[*i.Instance.InstanceId]; ok {
+			additionalDetails = append(additionalDetails, shared.CoverageDetails(c, m.currency)...)
+		}
 		row := []string{m.identification["account"], i.Region, "EC2 Instance", *i.Instance.InstanceId, name, platform,
-			"730 hours", utils.FormatPriceFloat(i.Wastage.RightSizing.Current.Cost), rightSizingCost, saving,
+			i.Runtime.String(), m.currency.Format(i.Wastage.RightSizing.Current.Cost), rightSizingCost, saving,
 			i.Wastage.RightSizing.Current.InstanceType, recSpec, "None", i.Wastage.RightSizing.Description, strings.Join(additionalDetails, "---")}
 		rows = append(rows, &golang.CSVRow{Row: row})
 		for _, v := range i.Volumes {
@@ -173,19 +201,19 @@ func (m *Processor) exportCsv() []*golang.CSVRow {
 			var ebsAdditionalDetails []string
 			var ebsRightSizingCost, ebsSaving, ebsRecSpec string
 			if vs.Recommended != nil {
-				ebsRightSizingCost = utils.FormatPriceFloat(vs.Recommended.Cost)
-				ebsSaving = utils.FormatPriceFloat(vs.Current.Cost - vs.Recommended.Cost)
-				ebsRecSpec = fmt.Sprintf("%s/%s/%d IOPS", vs.Recommended.Tier, utils.SizeByteToGB(shared.WrappedToInt32(vs.Recommended.VolumeSize)), getRightsizingEBSVolumeIOPS(vs.Recommended))
+				ebsRightSizingCost = m.currency.Format(vs.Recommended.Cost)
+				ebsSaving = m.currency.Format(vs.Current.Cost - vs.Recommended.Cost)
+				ebsRecSpec = ebsVolumeSpec(vs.Recommended)
 
 				ebsAdditionalDetails = append(ebsAdditionalDetails,
 					fmt.Sprintf("EBS Storage Tier:: Current: %s - Recommended: %s", vs.Current.Tier,
 						vs.Recommended.Tier))
 				ebsAdditionalDetails = append(ebsAdditionalDetails,
-					fmt.Sprintf("Volume Size (GB):: Current: %d - Recommended: %d", *vs.Current.VolumeSize,
-						*vs.Recommended.VolumeSize))
+					fmt.Sprintf("Volume Size (GB):: Current: %d - Recommended: %d", vs.Current.VolumeSize.GetValue(),
+						vs.Recommended.VolumeSize.GetValue()))
 				ebsAdditionalDetails = append(ebsAdditionalDetails,
 					fmt.Sprintf("IOPS:: Current: %d - Avg: %s - Recommended: %d", getRightsizingEBSVolumeIOPS(vs.Current),
-						utils.PFloat64ToString(shared.WrappedToFloat64(vs.Iops.Avg)), getRightsizingEBSVolumeIOPS(vs.Recommended)))
+						utils.PFloat64ToString(shared.WrappedToFloat64(vs.GetIops().GetAvg())), getRightsizingEBSVolumeIOPS(vs.Recommended)))
 				ebsAdditionalDetails = append(ebsAdditionalDetails,
 					fmt.Sprintf("Baseline IOPS:: Current: %d - Recommended: %d", vs.Current.BaselineIops,
 						vs.Recommended.BaselineIops))
@@ -193,8 +221,8 @@ func (m *Processor) exportCsv() []*golang.CSVRow {
 					fmt.Sprintf("Provisioned IOPS:: Current: %s - Recommended: %s", utils.PInt32ToString(shared.WrappedToInt32(vs.Current.ProvisionedIops)),
 						utils.PInt32ToString(shared.WrappedToInt32(vs.Recommended.ProvisionedIops))))
 				ebsAdditionalDetails = append(ebsAdditionalDetails,
-					fmt.Sprintf("Throughput (MB/s):: Current: %.2f GB - Avg: %s - Recommended: %.2f GB", getRightsizingEBSVolumeThroughput(vs.Current),
-						PNetworkThroughputMBps(shared.WrappedToFloat64(vs.Throughput.Avg)), getRightsizingEBSVolumeThroughput(vs.Recommended)))
+					fmt.Sprintf("Throughput (MB/s):: Current: %s - Avg: %s - Recommended: %s", PNetworkThroughputMBps(aws.Float64(getRightsizingEBSVolumeThroughput(vs.Current))),
+						PNetworkThroughputMBps(shared.WrappedToFloat64(vs.GetThroughput().GetAvg())), PNetworkThroughputMBps(aws.Float64(getRightsizingEBSVolumeThroughput(vs.Recommended)))))
 				ebsAdditionalDetails = append(ebsAdditionalDetails,
 					fmt.Sprintf("Baseline Throughput:: Current: %s - Recommended: %s", PNetworkThroughputMBps(&vs.Current.BaselineThroughput),
 						PNetworkThroughputMBps(&vs.Recommended.BaselineThroughput)))
@@ -207,9 +235,10 @@ func (m *Processor) exportCsv() []*golang.CSVRow {
 					fmt.Sprintf("VolumeSizeChange:: %v", vs.Current.VolumeSize.GetValue() != vs.Recommended.VolumeSize.GetValue()))
 			}
 
+			ebsAdditionalDetails = append(ebsAdditionalDetails, shared.PreferenceOverridesDetails(i.PreferenceOverrides, "EBSVolume")...)
 			vRow := []string{m.identification["account"], i.Region, "EBS Volume", *v.VolumeId, vName, "N/A",
-				"730 hours", utils.FormatPriceFloat(vs.Current.Cost), ebsRightSizingCost, ebsSaving,
-				fmt.Sprintf("%s/%s/%d IOPS", vs.Current.Tier, utils.SizeByteToGB(shared.WrappedToInt32(vs.Current.VolumeSize)), getRightsizingEBSVolumeIOPS(vs.Current)),
+				"730 hours", m.currency.Format(vs.Current.Cost), ebsRightSizingCost, ebsSaving,
+				ebsVolumeSpec(vs.Current),
 				ebsRecSpec, *i.Instance.InstanceId, i.Wastage.RightSizing.Description, strings.Join(ebsAdditionalDetails, "---")}
 			rows = append(rows, &golang.CSVRow{Row: vRow})
 		}
@@ -238,14 +267,14 @@ func toEBSVolume(v types.Volume) *golang2.EC2Volume {
 func (m *Processor) ResultsSummary() *golang.ResultSummary {
 	summary := &golang.ResultSummary{}
 	var totalCost, savings float64
-	m.summary.Range(func(_ string, item EC2InstanceSummary) bool {
-		totalCost += item.CurrentRuntimeCost
+	m.summary.Range(func(_ string, item summary2.Resource) bool {
+		totalCost += item.CurrentCost
 		savings += item.Savings
 		return true
 	})
 
 	summary.Message = fmt.Sprintf("Current runtime cost: %s, Savings: %s",
-		style.CostStyle.Render(fmt.Sprintf("%s", utils.FormatPriceFloat(totalCost))), style.SavingStyle.Render(fmt.Sprintf("%s", utils.FormatPriceFloat(savings))))
+		style.CostStyle.Render(fmt.Sprintf("%s", m.currency.Format(totalCost))), style.SavingStyle.Render(fmt.Sprintf("%s", m.currency.Format(savings))))
 	return summary
 }
 
@@ -255,16 +284,57 @@ func (m *Processor) UpdateSummary(itemId string) {
 		totalSaving := 0.0
 		totalCurrentCost := 0.0
 		for _, v := range i.Wastage.VolumeRightSizing {
-			totalSaving += v.Current.Cost - v.Recommended.Cost
+			if v.Recommended != nil {
+				totalSaving += v.Current.Cost - v.Recommended.Cost
+			}
 			totalCurrentCost += v.Current.Cost
 		}
 		totalSaving += i.Wastage.RightSizing.Current.Cost - i.Wastage.RightSizing.Recommended.Cost
 		totalCurrentCost += i.Wastage.RightSizing.Current.Cost
 
-		m.summary.Set(itemId, EC2InstanceSummary{
-			CurrentRuntimeCost: totalCurrentCost,
-			Savings:            totalSaving,
-		})
+		resource := m.summaryResource(i, summary2.StatusAnalysed)
+		resource.CurrentCost = totalCurrentCost
+		resource.Savings = totalSaving
+		m.summary.Set(itemId, resource)
 	}
 	m.publishResultSummary(m.ResultsSummary())
 }
+
+// SummaryResources returns every instance of the run for the results summary, the ones still loading
+// failed to get their metrics or optimization.
+func (m *Processor) SummaryResources() []summary2.Resource {
+	var resources []summary2.Resource
+	m.items.Range(func(id string, i EC2InstanceItem) bool {
+		if resource, ok := m.summary.Get(id); ok {
+			resources = append(resources, resource)
+			return true
+		}
+		switch {
+		case i.Skipped || i.LazyLoadingEnabled:
+			resource := m.summaryResource(i, summary2.StatusSkipped)
+			resource.SkipReason = i.SkipReason
+			if resource.SkipReason == "" {
+				resource.SkipReason = summary2.ReasonNotLoaded
+			}
+			resources = append(resources, resource)
+		case i.OptimizationLoading:
+			resources = append(resources, m.summaryResource(i, summary2.StatusFailed))
+		default:
+			resources = append(resources, m.summaryResource(i, summary2.StatusAnalysed))
+		}
+		return true
+	})
+	return resources
+}
+
+func (m *Processor) summaryResource(i EC2InstanceItem, status summary2.Status) summary2.Resource {
+	return summary2.Resource{
+		Id:             *i.Instance.InstanceId,
+		Account:        m.identification["account"],
+		Region:         i.Region,
+		ResourceType:   "EC2 Instance",
+		InstanceFamily: policy.Family(string(i.Instance.InstanceType)),
+		Tags:           shared.EC2Tags(i.Instance.Tags),
+		Status:         status,
+	}
+}
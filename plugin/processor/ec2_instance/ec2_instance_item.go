@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
 	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/currency"
 	"github.com/opengovern/plugin-aws/plugin/processor/shared"
 	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
 	"google.golang.org/protobuf/types/known/wrapperspb"
@@ -19,6 +20,10 @@ type EC2InstanceItem struct {
 	Region              string
 	OptimizationLoading bool
 	Preferences         []*golang.PreferenceItem
+	// Currency is the currency costs are shown in
+	Currency *currency.Currency
+	// PreferenceOverrides describes the preferences changed by tag rules for the last optimization
+	PreferenceOverrides []string
 	Skipped             bool
 	LazyLoadingEnabled  bool
 	SkipReason          string
@@ -26,6 +31,8 @@ type EC2InstanceItem struct {
 	Metrics             map[string][]types2.Datapoint
 	VolumeMetrics       map[string]map[string][]types2.Datapoint
 	Wastage             *golang2.EC2InstanceOptimizationResponse
+	// Runtime is the observed runtime the instance costs are projected for
+	Runtime shared.Runtime
 }
 
 func (i EC2InstanceItem) EC2InstanceDevice() (*golang.ChartRow, map[string]*golang.Properties) {
@@ -54,10 +61,10 @@ func (i EC2InstanceItem) EC2InstanceDevice() (*golang.ChartRow, map[string]*gola
 		Value: "EC2 Instance",
 	}
 	row.Values["runtime"] = &golang.ChartRowItem{
-		Value: "730 hours",
+		Value: i.Runtime.String(),
 	}
 	row.Values["current_cost"] = &golang.ChartRowItem{
-		Value: utils.FormatPriceFloat(i.Wastage.RightSizing.Current.Cost),
+		Value: i.Currency.Format(i.Wastage.RightSizing.Current.Cost),
 	}
 
 	props := make(map[string]*golang.Properties)
@@ -74,8 +81,8 @@ func (i EC2InstanceItem) EC2InstanceDevice() (*golang.ChartRow, map[string]*gola
 	vCPUProperty := &golang.Property{
 		Key:     "  vCPU",
 		Current: fmt.Sprintf("%d", i.Wastage.RightSizing.Current.Vcpu),
-		Average: utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.Vcpu.Avg)),
-		Max:     utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.Vcpu.Max)),
+		Average: utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.GetVcpu().GetAvg())),
+		Max:     utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.GetVcpu().GetMax())),
 	}
 	processorProperty := &golang.Property{
 		Key:     "  Processor(s)",
@@ -87,38 +94,38 @@ func (i EC2InstanceItem) EC2InstanceDevice() (*golang.ChartRow, map[string]*gola
 	}
 	licenseCostProperty := &golang.Property{
 		Key:     "  License Cost",
-		Current: fmt.Sprintf("$%.2f", i.Wastage.RightSizing.Current.LicensePrice),
+		Current: i.Currency.Format(i.Wastage.RightSizing.Current.LicensePrice),
 	}
 	memoryProperty := &golang.Property{
 		Key:     "  Memory",
 		Current: fmt.Sprintf("%.1f GiB", i.Wastage.RightSizing.Current.Memory),
-		Average: utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.Memory.Avg)),
-		Max:     utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.Memory.Max)),
+		Average: utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.GetMemory().GetAvg())),
+		Max:     utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.GetMemory().GetMax())),
 	}
 	ebsProperty := &golang.Property{
 		Key:     "EBS Bandwidth",
 		Current: fmt.Sprintf("%s", i.Wastage.RightSizing.Current.EbsBandwidth),
-		Average: PNetworkThroughputMBps(shared.WrappedToFloat64(i.Wastage.RightSizing.EbsBandwidth.Avg)),
-		Max:     PNetworkThroughputMBps(shared.WrappedToFloat64(i.Wastage.RightSizing.EbsBandwidth.Max)),
+		Average: PNetworkThroughputMBps(shared.WrappedToFloat64(i.Wastage.RightSizing.GetEbsBandwidth().GetAvg())),
+		Max:     PNetworkThroughputMBps(shared.WrappedToFloat64(i.Wastage.RightSizing.GetEbsBandwidth().GetMax())),
 	}
 	iopsProperty := &golang.Property{
 		Key:     "EBS IOPS",
 		Current: fmt.Sprintf("%s", i.Wastage.RightSizing.Current.EbsIops),
-		Average: fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(i.Wastage.RightSizing.EbsIops.Avg))),
-		Max:     fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(i.Wastage.RightSizing.EbsIops.Max))),
+		Average: fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(i.Wastage.RightSizing.GetEbsIops().GetAvg()))),
+		Max:     fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(i.Wastage.RightSizing.GetEbsIops().GetMax()))),
 	}
-	if i.Wastage.RightSizing.EbsIops.Avg == nil {
+	if i.Wastage.RightSizing.GetEbsIops().GetAvg() == nil {
 		iopsProperty.Average = ""
 	}
-	if i.Wastage.RightSizing.EbsIops.Max == nil {
+	if i.Wastage.RightSizing.GetEbsIops().GetMax() == nil {
 		iopsProperty.Max = ""
 	}
 
 	netThroughputProperty := &golang.Property{
 		Key:     "  Throughput",
 		Current: fmt.Sprintf("%s", i.Wastage.RightSizing.Current.NetworkThroughput),
-		Average: utils.PNetworkThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.NetworkThroughput.Avg)),
-		Max:     utils.PNetworkThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.NetworkThroughput.Max)),
+		Average: utils.PNetworkThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.GetNetworkThroughput().GetAvg())),
+		Max:     utils.PNetworkThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.GetNetworkThroughput().GetMax())),
 	}
 	enaProperty := &golang.Property{
 		Key:     "  ENASupportChangeInInstanceType",
@@ -129,23 +136,23 @@ func (i EC2InstanceItem) EC2InstanceDevice() (*golang.ChartRow, map[string]*gola
 	for k, v := range i.Wastage.RightSizing.Current.CostComponents {
 		costComponentPropertiesMap[k] = &golang.Property{
 			Key:     fmt.Sprintf("  %s", k),
-			Current: fmt.Sprintf("$%.2f", v),
+			Current: i.Currency.Format(v),
 		}
 	}
 
 	if i.Wastage.RightSizing.Recommended != nil {
 		row.Values["right_sized_cost"] = &golang.ChartRowItem{
-			Value: utils.FormatPriceFloat(i.Wastage.RightSizing.Recommended.Cost),
+			Value: i.Currency.Format(i.Wastage.RightSizing.Recommended.Cost),
 		}
 		row.Values["savings"] = &golang.ChartRowItem{
-			Value: utils.FormatPriceFloat(i.Wastage.RightSizing.Current.Cost - i.Wastage.RightSizing.Recommended.Cost),
+			Value: i.Currency.Format(i.Wastage.RightSizing.Current.Cost - i.Wastage.RightSizing.Recommended.Cost),
 		}
 		regionProperty.Recommended = i.Wastage.RightSizing.Recommended.Region
 		instanceSizeProperty.Recommended = i.Wastage.RightSizing.Recommended.InstanceType
 		vCPUProperty.Recommended = fmt.Sprintf("%d", i.Wastage.RightSizing.Recommended.Vcpu)
 		processorProperty.Recommended = i.Wastage.RightSizing.Recommended.Processor
 		architectureProperty.Recommended = i.Wastage.RightSizing.Recommended.Architecture
-		licenseCostProperty.Recommended = fmt.Sprintf("$%.2f", i.Wastage.RightSizing.Recommended.LicensePrice)
+		licenseCostProperty.Recommended = i.Currency.Format(i.Wastage.RightSizing.Recommended.LicensePrice)
 		memoryProperty.Recommended = fmt.Sprintf("%.1f GiB", i.Wastage.RightSizing.Recommended.Memory)
 		ebsProperty.Recommended = i.Wastage.RightSizing.Recommended.EbsBandwidth
 		iopsProperty.Recommended = i.Wastage.RightSizing.Recommended.EbsIops
@@ -157,7 +164,7 @@ func (i EC2InstanceItem) EC2InstanceDevice() (*golang.ChartRow, map[string]*gola
 					Key: fmt.Sprintf("  %s", k),
 				}
 			}
-			costComponentPropertiesMap[k].Recommended = fmt.Sprintf("$%.2f", v)
+			costComponentPropertiesMap[k].Recommended = i.Currency.Format(v)
 		}
 	}
 	properties.Properties = append(properties.Properties, regionProperty)
@@ -234,7 +241,7 @@ func (i EC2InstanceItem) EBSVolumeDevice(v types.Volume, vs *golang2.EBSVolumeRe
 		Value: "730 hours",
 	}
 	row.Values["current_cost"] = &golang.ChartRowItem{
-		Value: utils.FormatPriceFloat(vs.Current.Cost),
+		Value: i.Currency.Format(vs.Current.Cost),
 	}
 
 	props := make(map[string]*golang.Properties)
@@ -251,8 +258,8 @@ func (i EC2InstanceItem) EBSVolumeDevice(v types.Volume, vs *golang2.EBSVolumeRe
 	iopsProp := &golang.Property{
 		Key:     "IOPS",
 		Current: fmt.Sprintf("%d", getRightsizingEBSVolumeIOPS(vs.Current)),
-		Average: utils.PFloat64ToString(shared.WrappedToFloat64(vs.Iops.Avg)),
-		Max:     utils.PFloat64ToString(shared.WrappedToFloat64(vs.Iops.Max)),
+		Average: utils.PFloat64ToString(shared.WrappedToFloat64(vs.GetIops().GetAvg())),
+		Max:     utils.PFloat64ToString(shared.WrappedToFloat64(vs.GetIops().GetMax())),
 	}
 	baselineIOPSProp := &golang.Property{
 		Key:     "  Baseline IOPS",
@@ -265,7 +272,7 @@ func (i EC2InstanceItem) EBSVolumeDevice(v types.Volume, vs *golang2.EBSVolumeRe
 	throughputProp := &golang.Property{
 		Key:     "Throughput (MB/s)",
 		Current: fmt.Sprintf("%.2f", getRightsizingEBSVolumeThroughput(vs.Current)),
-		Average: PNetworkThroughputMBps(shared.WrappedToFloat64(vs.Throughput.Avg)),
+		Average: PNetworkThroughputMBps(shared.WrappedToFloat64(vs.GetThroughput().GetAvg())),
 	}
 	baselineThroughputProp := &golang.Property{
 		Key:     "  Baseline Throughput",
@@ -279,16 +286,16 @@ func (i EC2InstanceItem) EBSVolumeDevice(v types.Volume, vs *golang2.EBSVolumeRe
 	for k, vv := range vs.Current.CostComponents {
 		costComponentPropertiesMap[k] = &golang.Property{
 			Key:     fmt.Sprintf("  %s", k),
-			Current: fmt.Sprintf("$%.2f", vv),
+			Current: i.Currency.Format(vv),
 		}
 	}
 
 	if vs.Recommended != nil {
 		row.Values["right_sized_cost"] = &golang.ChartRowItem{
-			Value: utils.FormatPriceFloat(vs.Recommended.Cost),
+			Value: i.Currency.Format(vs.Recommended.Cost),
 		}
 		row.Values["savings"] = &golang.ChartRowItem{
-			Value: utils.FormatPriceFloat(vs.Current.Cost - vs.Recommended.Cost),
+			Value: i.Currency.Format(vs.Current.Cost - vs.Recommended.Cost),
 		}
 		storageTierProp.Recommended = vs.Recommended.Tier
 		volumeSizeProp.Recommended = utils.SizeByteToGB(shared.WrappedToInt32(vs.Recommended.VolumeSize))
@@ -304,7 +311,7 @@ func (i EC2InstanceItem) EBSVolumeDevice(v types.Volume, vs *golang2.EBSVolumeRe
 					Key: fmt.Sprintf("  %s", k),
 				}
 			}
-			costComponentPropertiesMap[k].Recommended = fmt.Sprintf("$%.2f", vv)
+			costComponentPropertiesMap[k].Recommended = i.Currency.Format(vv)
 		}
 	}
 
@@ -406,16 +413,18 @@ func (i EC2InstanceItem) ToOptimizationItem() *golang.ChartOptimizationItem {
 		status = "press enter to load"
 	} else if i.OptimizationLoading {
 		status = "loading"
-	} else if i.Wastage.RightSizing.Recommended != nil {
+	} else if i.Wastage != nil && i.Wastage.RightSizing != nil && i.Wastage.RightSizing.Recommended != nil {
 		totalSaving := 0.0
 		totalCurrentCost := 0.0
 		for _, v := range i.Wastage.VolumeRightSizing {
-			totalSaving += v.Current.Cost - v.Recommended.Cost
+			if v.Recommended != nil {
+				totalSaving += v.Current.Cost - v.Recommended.Cost
+			}
 			totalCurrentCost += v.Current.Cost
 		}
 		totalSaving += i.Wastage.RightSizing.Current.Cost - i.Wastage.RightSizing.Recommended.Cost
 		totalCurrentCost += i.Wastage.RightSizing.Current.Cost
-		status = fmt.Sprintf("%s (%.2f%%)", utils.FormatPriceFloat(totalSaving), (totalSaving/totalCurrentCost)*100)
+		status = fmt.Sprintf("%s (%.2f%%)", i.Currency.Format(totalSaving), (totalSaving/totalCurrentCost)*100)
 	}
 
 	oi := &golang.ChartOptimizationItem{
@@ -7,8 +7,6 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/kaytu-io/kaytu/pkg/plugin/sdk"
 	"github.com/kaytu-io/kaytu/pkg/utils"
-	aws2 "github.com/opengovern/plugin-aws/plugin/aws"
-	"time"
 )
 
 type GetEC2InstanceMetricsJob struct {
@@ -54,150 +52,13 @@ func (j *GetEC2InstanceMetricsJob) Run(ctx context.Context) error {
 		return err
 	}
 
-	instanceMetrics := map[string][]types2.Datapoint{}
-
-	cwMetrics, err := j.processor.metricProvider.GetDayByDayMetrics(
-		ctx,
-		j.region,
-		"AWS/EC2",
-		[]string{
-			"CPUUtilization",
-		},
-		map[string][]string{
-			"InstanceId": {*j.instance.InstanceId},
-		},
-		j.processor.observabilityDays,
-		time.Minute,
-		nil,
-		[]string{"tm99"},
-	)
+	instanceMetrics, metricsByVolume, err := j.processor.metricsSource.GetInstanceMetrics(ctx, j.region, j.instance, volumes, j.processor.observabilityDays)
 	if err != nil {
 		return err
 	}
-	for k, v := range cwMetrics {
-		for idx, vv := range v {
-			tmp := vv.ExtendedStatistics["tm99"]
-			vv.Average = &tmp
-			v[idx] = vv
-		}
-
-		instanceMetrics[k] = v
-	}
-
-	cwPerSecondMetrics, err := j.processor.metricProvider.GetDayByDayMetrics(
-		ctx,
-		j.region,
-		"AWS/EC2",
-		[]string{
-			"NetworkIn",
-			"NetworkOut",
-		},
-		map[string][]string{
-			"InstanceId": {*j.instance.InstanceId},
-		},
-		j.processor.observabilityDays,
-		time.Minute,
-		[]types2.Statistic{
-			types2.StatisticSum,
-			types2.StatisticSampleCount,
-		},
-		nil,
-	)
-	if err != nil {
-		return err
-	}
-	for k, v := range cwPerSecondMetrics {
-		instanceMetrics[k] = aws2.GetDatapointsAvgFromSum(v, 60)
-	}
-
-	cwaMetrics, err := j.processor.metricProvider.GetDayByDayMetrics(
-		ctx,
-		j.region,
-		"CWAgent",
-		[]string{
-			"mem_used_percent",
-		},
-		map[string][]string{
-			"InstanceId": {*j.instance.InstanceId},
-		},
-		j.processor.observabilityDays,
-		time.Minute,
-		[]types2.Statistic{
-			types2.StatisticAverage,
-			types2.StatisticMaximum,
-		},
-		nil,
-	)
-	if err != nil {
-		return err
-	}
-	for k, v := range cwaMetrics {
-		instanceMetrics[k] = v
-	}
-
-	var volumeIDs []string
-	for _, v := range j.instance.BlockDeviceMappings {
-		if v.Ebs != nil {
-			volumeIDs = append(volumeIDs, *v.Ebs.VolumeId)
-		}
-	}
 
 	volumeMetrics := map[string]map[string][]types2.Datapoint{}
-	for _, v := range volumeIDs {
-		volumeMetricsMap, err := j.processor.metricProvider.GetDayByDayMetrics(
-			ctx,
-			j.region,
-			"AWS/EBS",
-			[]string{
-				"VolumeReadBytes",
-				"VolumeWriteBytes",
-			},
-			map[string][]string{
-				"VolumeId": {v},
-			},
-			j.processor.observabilityDays,
-			time.Minute,
-			[]types2.Statistic{
-				types2.StatisticSum,
-				types2.StatisticSampleCount,
-			},
-			nil,
-		)
-		if err != nil {
-			return err
-		}
-
-		for k, val := range volumeMetricsMap {
-			volumeMetricsMap[k] = aws2.GetDatapointsAvgFromSumPeriod(val, int32(time.Minute/time.Second))
-		}
-
-		volumeIops, err := j.processor.metricProvider.GetDayByDayMetrics(
-			ctx,
-			j.region,
-			"AWS/EBS",
-			[]string{
-				"VolumeReadOps",
-				"VolumeWriteOps",
-			},
-			map[string][]string{
-				"VolumeId": {v},
-			},
-			j.processor.observabilityDays,
-			time.Minute,
-			[]types2.Statistic{
-				types2.StatisticSum,
-			},
-			nil,
-		)
-		if err != nil {
-			return err
-		}
-
-		for k, val := range volumeIops {
-			val = aws2.GetDatapointsAvgFromSumPeriod(val, int32(time.Minute/time.Second))
-			volumeMetricsMap[k] = val
-		}
-
+	for v, volumeMetricsMap := range metricsByVolume {
 		// Hash v
 		hashedId := utils.HashString(v)
 		volumeMetrics[hashedId] = volumeMetricsMap
@@ -213,6 +74,7 @@ func (j *GetEC2InstanceMetricsJob) Run(ctx context.Context) error {
 		OptimizationLoading: true,
 		LazyLoadingEnabled:  false,
 		Preferences:         j.processor.defaultPreferences,
+		Currency:            j.processor.currency,
 	}
 	if j.instance.State.Name != types.InstanceStateNameRunning ||
 		j.instance.InstanceLifecycle == types.InstanceLifecycleTypeSpot ||
@@ -28,8 +28,10 @@ func (j *ListAllRegionsJob) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	j.processor.jobQueue.Push(NewListSavingsPlansJob(j.processor))
 	for _, region := range regions {
 		j.processor.jobQueue.Push(NewListEC2InstancesInRegionJob(j.processor, region))
+		j.processor.jobQueue.Push(NewListReservedInstancesJob(j.processor, region))
 	}
 	return nil
 }
@@ -0,0 +1,63 @@
+package ec2_instance
+
+import (
+	"context"
+	"fmt"
+	"github.com/kaytu-io/kaytu/pkg/plugin/sdk"
+)
+
+type ListReservedInstancesJob struct {
+	region    string
+	processor *Processor
+}
+
+func NewListReservedInstancesJob(processor *Processor, region string) *ListReservedInstancesJob {
+	return &ListReservedInstancesJob{
+		processor: processor,
+		region:    region,
+	}
+}
+
+func (j *ListReservedInstancesJob) Properties() sdk.JobProperties {
+	return sdk.JobProperties{
+		ID:          fmt.Sprintf("list_reserved_instances_in_%s", j.region),
+		Description: fmt.Sprintf("Listing all Reserved Instances in %s", j.region),
+		MaxRetry:    0,
+	}
+}
+
+func (j *ListReservedInstancesJob) Run(ctx context.Context) error {
+	reserved, err := j.processor.provider.ListReservedInstances(ctx, j.region)
+	if err != nil {
+		return fmt.Errorf("failed to list reserved instances in %s, savings ignore them: %w", j.region, err)
+	}
+	j.processor.commitments.AddReservedInstances(j.region, reserved)
+	return nil
+}
+
+type ListSavingsPlansJob struct {
+	processor *Processor
+}
+
+func NewListSavingsPlansJob(processor *Processor) *ListSavingsPlansJob {
+	return &ListSavingsPlansJob{
+		processor: processor,
+	}
+}
+
+func (j *ListSavingsPlansJob) Properties() sdk.JobProperties {
+	return sdk.JobProperties{
+		ID:          "list_savings_plans",
+		Description: "Listing all Savings Plans",
+		MaxRetry:    0,
+	}
+}
+
+func (j *ListSavingsPlansJob) Run(ctx context.Context) error {
+	plans, err := j.processor.provider.ListSavingsPlans(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list savings plans, savings ignore them: %w", err)
+	}
+	j.processor.commitments.AddSavingsPlans(plans)
+	return nil
+}
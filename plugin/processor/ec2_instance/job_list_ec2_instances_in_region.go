@@ -58,6 +58,7 @@ func (j *ListEC2InstancesInRegionJob) Run(ctx context.Context) error {
 			OptimizationLoading: true,
 			LazyLoadingEnabled:  false,
 			Preferences:         j.processor.defaultPreferences,
+			Currency:            j.processor.currency,
 		}
 
 		isAutoScaling := false
@@ -3,6 +3,7 @@ package ec2_instance
 import (
 	"context"
 	"fmt"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/google/uuid"
 	"github.com/kaytu-io/kaytu/pkg/plugin/sdk"
@@ -14,6 +15,7 @@ import (
 	"github.com/opengovern/plugin-aws/plugin/version"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/wrapperspb"
+	"time"
 )
 
 type OptimizeEC2InstanceJob struct {
@@ -82,8 +84,9 @@ func (j *OptimizeEC2InstanceJob) Run(ctx context.Context) error {
 		}
 	}
 
+	itemPreferences, preferenceOverrides := j.processor.preferenceRules.Apply(j.item.Preferences, shared.EC2Tags(j.item.Instance.Tags))
 	preferencesMap := map[string]*wrapperspb.StringValue{}
-	for k, v := range preferences.Export(j.item.Preferences) {
+	for k, v := range preferences.Export(itemPreferences) {
 		preferencesMap[k] = nil
 		if v != nil {
 			preferencesMap[k] = wrapperspb.String(*v)
@@ -172,6 +175,10 @@ func (j *OptimizeEC2InstanceJob) Run(ctx context.Context) error {
 		j.processor.UpdateSummary(*j.item.Instance.InstanceId)
 		return nil
 	}
+	j.processor.pricing.ApplyEC2Instance(res, j.item.Region)
+	runtime := observeRuntime(j.item.Instance, j.item.Metrics, j.processor.observabilityDays)
+	runtime.ScaleEC2Instance(res.RightSizing)
+	j.processor.policy.ApplyEC2Instance(res.RightSizing, shared.EC2Tags(j.item.Instance.Tags))
 
 	j.item = EC2InstanceItem{
 		Instance:            j.item.Instance,
@@ -179,15 +186,48 @@ func (j *OptimizeEC2InstanceJob) Run(ctx context.Context) error {
 		Region:              j.item.Region,
 		OptimizationLoading: false,
 		Preferences:         j.item.Preferences,
+		Currency:            j.processor.currency,
 		Skipped:             false,
 		SkipReason:          "",
 		Volumes:             j.item.Volumes,
 		Metrics:             j.item.Metrics,
 		VolumeMetrics:       j.item.VolumeMetrics,
 		Wastage:             res,
+		Runtime:             runtime,
+		PreferenceOverrides: preferenceOverrides,
 	}
 	j.processor.items.Set(*j.item.Instance.InstanceId, j.item)
 	j.processor.publishOptimizationItem(j.item.ToOptimizationItem())
 	j.processor.UpdateSummary(*j.item.Instance.InstanceId)
 	return nil
 }
+
+// instanceCreated is when the instance was created. LaunchTime is reset by every start, so the instance
+// exists since its root volume was attached.
+func instanceCreated(instance types.Instance) *time.Time {
+	created := instance.LaunchTime
+	for _, m := range instance.BlockDeviceMappings {
+		if m.Ebs != nil && m.Ebs.AttachTime != nil && (created == nil || m.Ebs.AttachTime.Before(*created)) {
+			created = m.Ebs.AttachTime
+		}
+	}
+	return created
+}
+
+// observeRuntime is the runtime of the instance over the observability window. It runs since its last
+// launch if it is still running, or ran until the time of the state transition that stopped it.
+func observeRuntime(instance types.Instance, metrics map[string][]types2.Datapoint, days int) shared.Runtime {
+	created := instanceCreated(instance)
+	var launched, stopped *time.Time
+	if instance.State != nil {
+		switch instance.State.Name {
+		case types.InstanceStateNameRunning:
+			launched = instance.LaunchTime
+		case types.InstanceStateNameStopping, types.InstanceStateNameStopped:
+			if stopped = shared.EC2StateTransitionTime(utils.PString(instance.StateTransitionReason)); stopped != nil {
+				launched = instance.LaunchTime
+			}
+		}
+	}
+	return shared.ObserveRuntime(metrics["CPUUtilization"], created, launched, stopped, days, time.Now())
+}
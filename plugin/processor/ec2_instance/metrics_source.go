@@ -0,0 +1,175 @@
+package ec2_instance
+
+import (
+	"context"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	aws2 "github.com/opengovern/plugin-aws/plugin/aws"
+	"time"
+)
+
+// MetricsSource provides the utilization datapoints of an EC2 instance and its attached volumes.
+// Instance metrics are keyed by metric name, volume metrics by volume id and then metric name,
+// using the CloudWatch metric names the optimization service expects.
+type MetricsSource interface {
+	GetInstanceMetrics(ctx context.Context, region string, instance types.Instance, volumes []types.Volume, days int) (map[string][]types2.Datapoint, map[string]map[string][]types2.Datapoint, error)
+}
+
+type CloudWatchMetricsSource struct {
+	metricProvider aws2.MetricsProvider
+}
+
+func NewCloudWatchMetricsSource(metricProvider aws2.MetricsProvider) *CloudWatchMetricsSource {
+	return &CloudWatchMetricsSource{metricProvider: metricProvider}
+}
+
+func (s *CloudWatchMetricsSource) GetInstanceMetrics(ctx context.Context, region string, instance types.Instance, _ []types.Volume, days int) (map[string][]types2.Datapoint, map[string]map[string][]types2.Datapoint, error) {
+	instanceMetrics := map[string][]types2.Datapoint{}
+
+	cwMetrics, err := s.metricProvider.GetDayByDayMetrics(
+		ctx,
+		region,
+		"AWS/EC2",
+		[]string{
+			"CPUUtilization",
+		},
+		map[string][]string{
+			"InstanceId": {*instance.InstanceId},
+		},
+		days,
+		time.Minute,
+		nil,
+		[]string{"tm99"},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range cwMetrics {
+		for idx, vv := range v {
+			tmp := vv.ExtendedStatistics["tm99"]
+			vv.Average = &tmp
+			v[idx] = vv
+		}
+
+		instanceMetrics[k] = v
+	}
+
+	cwPerSecondMetrics, err := s.metricProvider.GetDayByDayMetrics(
+		ctx,
+		region,
+		"AWS/EC2",
+		[]string{
+			"NetworkIn",
+			"NetworkOut",
+		},
+		map[string][]string{
+			"InstanceId": {*instance.InstanceId},
+		},
+		days,
+		time.Minute,
+		[]types2.Statistic{
+			types2.StatisticSum,
+			types2.StatisticSampleCount,
+		},
+		nil,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range cwPerSecondMetrics {
+		instanceMetrics[k] = aws2.GetDatapointsAvgFromSum(v, 60)
+	}
+
+	cwaMetrics, err := s.metricProvider.GetDayByDayMetrics(
+		ctx,
+		region,
+		"CWAgent",
+		[]string{
+			"mem_used_percent",
+		},
+		map[string][]string{
+			"InstanceId": {*instance.InstanceId},
+		},
+		days,
+		time.Minute,
+		[]types2.Statistic{
+			types2.StatisticAverage,
+			types2.StatisticMaximum,
+		},
+		nil,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range cwaMetrics {
+		instanceMetrics[k] = v
+	}
+
+	var volumeIDs []string
+	for _, v := range instance.BlockDeviceMappings {
+		if v.Ebs != nil {
+			volumeIDs = append(volumeIDs, *v.Ebs.VolumeId)
+		}
+	}
+
+	volumeMetrics := map[string]map[string][]types2.Datapoint{}
+	for _, v := range volumeIDs {
+		volumeMetricsMap, err := s.metricProvider.GetDayByDayMetrics(
+			ctx,
+			region,
+			"AWS/EBS",
+			[]string{
+				"VolumeReadBytes",
+				"VolumeWriteBytes",
+			},
+			map[string][]string{
+				"VolumeId": {v},
+			},
+			days,
+			time.Minute,
+			[]types2.Statistic{
+				types2.StatisticSum,
+				types2.StatisticSampleCount,
+			},
+			nil,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for k, val := range volumeMetricsMap {
+			volumeMetricsMap[k] = aws2.GetDatapointsAvgFromSumPeriod(val, int32(time.Minute/time.Second))
+		}
+
+		volumeIops, err := s.metricProvider.GetDayByDayMetrics(
+			ctx,
+			region,
+			"AWS/EBS",
+			[]string{
+				"VolumeReadOps",
+				"VolumeWriteOps",
+			},
+			map[string][]string{
+				"VolumeId": {v},
+			},
+			days,
+			time.Minute,
+			[]types2.Statistic{
+				types2.StatisticSum,
+			},
+			nil,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for k, val := range volumeIops {
+			val = aws2.GetDatapointsAvgFromSumPeriod(val, int32(time.Minute/time.Second))
+			volumeMetricsMap[k] = val
+		}
+
+		volumeMetrics[v] = volumeMetricsMap
+	}
+
+	return instanceMetrics, volumeMetrics, nil
+}
@@ -0,0 +1,118 @@
+package ec2_instance
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/processor/shared"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"strings"
+	"time"
+)
+
+// Records returns the outcome of every optimized instance and its volumes, including the ones without recommendation.
+func (m *Processor) Records() []history.Record {
+	var records []history.Record
+	m.items.Range(func(_ string, i EC2InstanceItem) bool {
+		records = append(records, i.Records()...)
+		return true
+	})
+	commitments := m.coverage()
+	for idx, r := range records {
+		if c, ok := commitments[r.ResourceId]; ok && r.ResourceType == "EC2 Instance" {
+			records[idx].Coverage = &c
+		}
+	}
+	return records
+}
+
+func (i EC2InstanceItem) Records() []history.Record {
+	if i.Skipped || i.OptimizationLoading || i.Wastage == nil || i.Wastage.RightSizing == nil || i.Wastage.RightSizing.Current == nil {
+		return nil
+	}
+
+	var name string
+	for _, t := range i.Instance.Tags {
+		if t.Key != nil && strings.ToLower(*t.Key) == "name" && t.Value != nil {
+			name = *t.Value
+		}
+	}
+	if name == "" {
+		name = *i.Instance.InstanceId
+	}
+
+	tags := shared.EC2Tags(i.Instance.Tags)
+	rightSizing := i.Wastage.RightSizing
+	record := history.Record{
+		ResourceType: "EC2 Instance",
+		ResourceId:   *i.Instance.InstanceId,
+		ResourceName: name,
+		Region:       i.Region,
+		CurrentSpec:  rightSizing.Current.InstanceType,
+		CurrentType:  rightSizing.Current.InstanceType,
+		CurrentCost:  rightSizing.Current.Cost,
+		Description:  rightSizing.Description,
+		Platform:     aws.ToString(i.Instance.PlatformDetails),
+		RuntimeHours: i.Runtime.MonthlyHours(),
+		Tags:         tags,
+		Utilization: map[string]history.Utilization{
+			"cpu":    shared.UsageToUtilization(rightSizing.Vcpu, history.UnitPercent),
+			"memory": shared.UsageToUtilization(rightSizing.Memory, history.UnitPercent),
+		},
+	}
+	if created := instanceCreated(i.Instance); created != nil {
+		record.AgeHours = time.Since(*created).Hours()
+	}
+	if rightSizing.Recommended != nil {
+		record.RecommendedSpec = rightSizing.Recommended.InstanceType
+		record.RecommendedType = rightSizing.Recommended.InstanceType
+		record.RecommendedCost = rightSizing.Recommended.Cost
+		record.Savings = rightSizing.Current.Cost - rightSizing.Recommended.Cost
+	}
+	records := []history.Record{record}
+
+	for _, v := range i.Volumes {
+		vs, ok := i.Wastage.VolumeRightSizing[utils.HashString(*v.VolumeId)]
+		if !ok || vs == nil || vs.Current == nil {
+			continue
+		}
+		volume := history.Record{
+			ResourceType: "EBS Volume",
+			ResourceId:   *v.VolumeId,
+			ResourceName: name,
+			Region:       i.Region,
+			ParentId:     *i.Instance.InstanceId,
+			CurrentSpec:  ebsVolumeSpec(vs.Current),
+			CurrentType:  vs.Current.Tier,
+			CurrentSize:  shared.WrappedToInt32(vs.Current.VolumeSize),
+			CurrentCost:  vs.Current.Cost,
+			Description:  vs.Description,
+			Tags:         shared.InheritTags(shared.EC2Tags(v.Tags), tags),
+			Utilization: map[string]history.Utilization{
+				"iops":       shared.UsageToUtilization(vs.Iops, "io/s"),
+				"throughput": shared.UsageToUtilization(vs.Throughput, "bytes/s"),
+			},
+		}
+		if vs.Recommended != nil {
+			volume.RecommendedSpec = ebsVolumeSpec(vs.Recommended)
+			volume.RecommendedType = vs.Recommended.Tier
+			volume.RecommendedSize = shared.WrappedToInt32(vs.Recommended.VolumeSize)
+			switch vs.Recommended.Tier {
+			case "gp3":
+				volume.RecommendedIops = aws.Int32(getRightsizingEBSVolumeIOPS(vs.Recommended))
+				volume.RecommendedThroughput = aws.Float64(getRightsizingEBSVolumeThroughput(vs.Recommended) / (1024 * 1024))
+			case "io1", "io2":
+				volume.RecommendedIops = aws.Int32(getRightsizingEBSVolumeIOPS(vs.Recommended))
+			}
+			volume.RecommendedCost = vs.Recommended.Cost
+			volume.Savings = vs.Current.Cost - vs.Recommended.Cost
+		}
+		records = append(records, volume)
+	}
+	return records
+}
+
+func ebsVolumeSpec(v *golang2.RightsizingEBSVolume) string {
+	return fmt.Sprintf("%s/%s/%d IOPS", v.Tier, utils.SizeByteToGB(shared.WrappedToInt32(v.VolumeSize)), getRightsizingEBSVolumeIOPS(v))
+}
@@ -0,0 +1,24 @@
+package ec2_instance
+
+import (
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/report"
+)
+
+// ReportItems returns every instance of the run for the HTML report, with the utilization of the instance and
+// its volumes.
+func (m *Processor) ReportItems() []report.Item {
+	var items []report.Item
+	m.items.Range(func(_ string, i EC2InstanceItem) bool {
+		utilization := map[string][]report.Series{
+			*i.Instance.InstanceId: report.NewSeries(i.Metrics, "CPUUtilization", "mem_used_percent", "NetworkIn", "NetworkOut"),
+		}
+		for _, v := range i.Volumes {
+			utilization[*v.VolumeId] = report.NewSeries(i.VolumeMetrics[utils.HashString(*v.VolumeId)],
+				"VolumeReadOps", "VolumeWriteOps", "VolumeReadBytes", "VolumeWriteBytes")
+		}
+		items = append(items, report.Item{Service: "EC2", Optimization: i.ToOptimizationItem(), Utilization: utilization})
+		return true
+	})
+	return items
+}
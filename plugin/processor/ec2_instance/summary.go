@@ -1,6 +0,0 @@
-package ec2_instance
-
-type EC2InstanceSummary struct {
-	CurrentRuntimeCost float64
-	Savings            float64
-}
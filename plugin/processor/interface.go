@@ -1,8 +1,16 @@
 package processor
 
-import "github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
+import (
+	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/report"
+	"github.com/opengovern/plugin-aws/plugin/summary"
+)
 
 type Processor interface {
 	ReEvaluate(id string, items []*golang.PreferenceItem)
 	ExportNonInteractive() *golang.NonInteractiveExport
+	Records() []history.Record
+	SummaryResources() []summary.Resource
+	ReportItems() []report.Item
 }
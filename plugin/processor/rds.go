@@ -5,25 +5,35 @@ import (
 	"github.com/kaytu-io/kaytu/pkg/plugin/sdk"
 	"github.com/kaytu-io/kaytu/pkg/utils"
 	"github.com/opengovern/plugin-aws/plugin/aws"
+	"github.com/opengovern/plugin-aws/plugin/coverage"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
 	"github.com/opengovern/plugin-aws/plugin/kaytu"
-	"github.com/opengovern/plugin-aws/plugin/processor/ec2_instance"
+	"github.com/opengovern/plugin-aws/plugin/policy"
+	preferences2 "github.com/opengovern/plugin-aws/plugin/preferences"
+	"github.com/opengovern/plugin-aws/plugin/pricing"
 	"github.com/opengovern/plugin-aws/plugin/processor/rds_cluster"
 	"github.com/opengovern/plugin-aws/plugin/processor/rds_instance"
 	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"github.com/opengovern/plugin-aws/plugin/report"
+	summary2 "github.com/opengovern/plugin-aws/plugin/summary"
 	"sync/atomic"
 )
 
 type RDSProcessor struct {
 	rdsInstanceProcessor *rds_instance.Processor
 	rdsClusterProcessor  *rds_cluster.Processor
+	commitments          *coverage.Inventory
 }
 
-func NewRDSProcessor(provider *aws.AWS, metricProvider *aws.CloudWatch, identification map[string]string, publishOptimizationItem func(item *golang.ChartOptimizationItem), publishResultSummary func(summary *golang.ResultSummary), kaytuAcccessToken string, jobQueue *sdk.JobQueue, configurations *kaytu.Configuration, observabilityDays int, preferences []*golang.PreferenceItem, client golang2.OptimizationClient) *RDSProcessor {
+func NewRDSProcessor(provider aws.InventoryProvider, metricProvider aws.MetricsProvider, identification map[string]string, publishOptimizationItem func(item *golang.ChartOptimizationItem), publishResultSummary func(summary *golang.ResultSummary), kaytuAcccessToken string, jobQueue *sdk.JobQueue, configurations *kaytu.Configuration, observabilityDays int, preferences []*golang.PreferenceItem, preferenceRules preferences2.Rules, recommendationPolicy *policy.Policy, pricingAdjustments *pricing.Adjustments, displayCurrency *currency.Currency, client golang2.OptimizationClient) *RDSProcessor {
 	lazyloadCounter := atomic.Uint32{}
-	summary := utils.NewConcurrentMap[string, ec2_instance.EC2InstanceSummary]()
+	summary := utils.NewConcurrentMap[string, summary2.Resource]()
+	commitments := coverage.NewInventory(displayCurrency)
 	return &RDSProcessor{
-		rdsInstanceProcessor: rds_instance.NewProcessor(provider, metricProvider, identification, publishOptimizationItem, publishResultSummary, kaytuAcccessToken, jobQueue, configurations, &lazyloadCounter, observabilityDays, &summary, preferences, client),
-		rdsClusterProcessor:  rds_cluster.NewProcessor(provider, metricProvider, identification, publishOptimizationItem, publishResultSummary, kaytuAcccessToken, jobQueue, configurations, &lazyloadCounter, observabilityDays, &summary, preferences, client),
+		rdsInstanceProcessor: rds_instance.NewProcessor(provider, metricProvider, identification, publishOptimizationItem, publishResultSummary, kaytuAcccessToken, jobQueue, configurations, &lazyloadCounter, observabilityDays, &summary, commitments, preferences, preferenceRules, recommendationPolicy, pricingAdjustments, displayCurrency, client),
+		rdsClusterProcessor:  rds_cluster.NewProcessor(provider, metricProvider, identification, publishOptimizationItem, publishResultSummary, kaytuAcccessToken, jobQueue, configurations, &lazyloadCounter, observabilityDays, &summary, commitments, preferences, preferenceRules, recommendationPolicy, pricingAdjustments, displayCurrency, client),
+		commitments:          commitments,
 	}
 }
 
@@ -42,6 +52,33 @@ func (m *RDSProcessor) ExportNonInteractive() *golang.NonInteractiveExport {
 	}
 }
 
+func (m *RDSProcessor) Records() []history.Record {
+	records := append(m.rdsInstanceProcessor.Records(), m.rdsClusterProcessor.Records()...)
+	commitments := m.coverage()
+	for idx, r := range records {
+		if c, ok := commitments[r.ParentId]; ok && r.ResourceType == "RDS Instance Compute" {
+			records[idx].Coverage = &c
+		}
+	}
+	return records
+}
+
+// ReportItems returns the instances and clusters of the run for the HTML report.
+func (m *RDSProcessor) ReportItems() []report.Item {
+	return append(m.rdsInstanceProcessor.ReportItems(), m.rdsClusterProcessor.ReportItems()...)
+}
+
+// SummaryResources returns the instances and clusters of the run for the results summary.
+func (m *RDSProcessor) SummaryResources() []summary2.Resource {
+	return append(m.rdsInstanceProcessor.SummaryResources(), m.rdsClusterProcessor.SummaryResources()...)
+}
+
+// coverage matches the instances of both processors with the reserved DB instances, a reservation can pay
+// for a standalone instance as well as for a cluster member.
+func (m *RDSProcessor) coverage() map[string]history.Coverage {
+	return m.commitments.Allocate(append(m.rdsInstanceProcessor.CoverageResources(), m.rdsClusterProcessor.CoverageResources()...))
+}
+
 func (m *RDSProcessor) exportCsv() []*golang.CSVRow {
 	headers := []string{
 		"AccountID", "Region / AZ", "Resource Type", "Device ID", "Device Name", "Platform / Runtime Engine",
@@ -50,8 +87,9 @@ func (m *RDSProcessor) exportCsv() []*golang.CSVRow {
 	}
 	var rows []*golang.CSVRow
 	rows = append(rows, &golang.CSVRow{Row: headers})
-	rows = append(rows, m.rdsInstanceProcessor.ExportCsv()...)
-	rows = append(rows, m.rdsClusterProcessor.ExportCsv()...)
+	commitments := m.coverage()
+	rows = append(rows, m.rdsInstanceProcessor.ExportCsv(commitments)...)
+	rows = append(rows, m.rdsClusterProcessor.ExportCsv(commitments)...)
 
 	return rows
 }
@@ -0,0 +1,42 @@
+package rds_cluster
+
+import (
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/coverage"
+)
+
+// CoverageResources returns the instances of the optimized clusters to match with the reserved DB instances.
+func (m *Processor) CoverageResources() []coverage.Resource {
+	var resources []coverage.Resource
+	m.items.Range(func(_ string, c RDSClusterItem) bool {
+		if c.Skipped || c.OptimizationLoading || c.Wastage == nil {
+			return true
+		}
+		for _, i := range c.Instances {
+			rightSizing := c.Wastage.RightSizing[utils.HashString(*i.DBInstanceIdentifier)]
+			if rightSizing == nil || rightSizing.Current == nil {
+				continue
+			}
+			resource := coverage.Resource{
+				Kind:         coverage.RDSInstance,
+				Id:           *i.DBInstanceIdentifier,
+				Region:       c.Region,
+				InstanceType: rightSizing.Current.InstanceType,
+				CurrentCost:  rightSizing.Current.ComputeCost,
+			}
+			if i.Engine != nil {
+				resource.Platform = *i.Engine
+			}
+			if i.AvailabilityZone != nil {
+				resource.AvailabilityZone = *i.AvailabilityZone
+			}
+			if rightSizing.Recommended != nil {
+				resource.RecommendedType = rightSizing.Recommended.InstanceType
+				resource.RecommendedCost = rightSizing.Recommended.ComputeCost
+			}
+			resources = append(resources, resource)
+		}
+		return true
+	})
+	return resources
+}
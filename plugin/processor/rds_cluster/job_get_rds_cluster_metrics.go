@@ -246,6 +246,7 @@ func (j *GetRDSClusterMetricsJob) Run(ctx context.Context) error {
 		Region:              j.region,
 		OptimizationLoading: true,
 		Preferences:         j.processor.defaultPreferences,
+		Currency:            j.processor.currency,
 		Skipped:             false,
 		LazyLoadingEnabled:  false,
 		SkipReason:          "",
@@ -52,6 +52,7 @@ func (j *ListRDSClustersInRegionJob) Run(ctx context.Context) error {
 			OptimizationLoading: true,
 			LazyLoadingEnabled:  false,
 			Preferences:         j.processor.defaultPreferences,
+			Currency:            j.processor.currency,
 		}
 		if strings.Contains(strings.ToLower(*cluster.Engine), "docdb") {
 			oi.Skipped = true
@@ -14,6 +14,7 @@ import (
 	"github.com/opengovern/plugin-aws/plugin/version"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/wrapperspb"
+	"time"
 )
 
 type OptimizeRDSClusterJob struct {
@@ -79,8 +80,9 @@ func (j *OptimizeRDSClusterJob) Run(ctx context.Context) error {
 		instances = append(instances, &rdsInstance)
 	}
 
+	itemPreferences, preferenceOverrides := j.processor.preferenceRules.Apply(j.item.Preferences, shared.RDSTags(j.item.Cluster.TagList))
 	preferencesMap := map[string]*wrapperspb.StringValue{}
-	for k, v := range preferences.Export(j.item.Preferences) {
+	for k, v := range preferences.Export(itemPreferences) {
 		preferencesMap[k] = nil
 		if v != nil {
 			preferencesMap[k] = wrapperspb.String(*v)
@@ -139,6 +141,17 @@ func (j *OptimizeRDSClusterJob) Run(ctx context.Context) error {
 	//	j.processor.publishOptimizationItem(j.item.ToOptimizationItem())
 	//	return nil
 	//}
+	// DescribeDBInstances has no start or stop time, the runtime of RDS instances only comes from their metrics
+	runtimes := map[string]shared.Runtime{}
+	for _, i := range j.item.Instances {
+		hashedId := utils.HashString(*i.DBInstanceIdentifier)
+		runtimes[hashedId] = shared.ObserveRuntime(j.item.Metrics[hashedId]["CPUUtilization"], i.InstanceCreateTime, nil, nil, j.processor.observabilityDays, time.Now())
+	}
+	for hashedId, rightSizing := range res.RightSizing {
+		j.processor.pricing.ApplyRDSInstance(rightSizing, j.item.Region)
+		runtimes[hashedId].ScaleRDSInstance(rightSizing)
+		j.processor.policy.ApplyRDSInstance(rightSizing, shared.RDSTags(j.item.Cluster.TagList))
+	}
 
 	j.item = RDSClusterItem{
 		Cluster:             j.item.Cluster,
@@ -146,10 +159,13 @@ func (j *OptimizeRDSClusterJob) Run(ctx context.Context) error {
 		Region:              j.item.Region,
 		OptimizationLoading: false,
 		Preferences:         j.item.Preferences,
+		Currency:            j.processor.currency,
 		Skipped:             false,
 		SkipReason:          "",
 		Metrics:             j.item.Metrics,
 		Wastage:             res,
+		Runtimes:            runtimes,
+		PreferenceOverrides: preferenceOverrides,
 	}
 	j.processor.items.Set(*j.item.Cluster.DBClusterIdentifier, j.item)
 	j.processor.publishOptimizationItem(j.item.ToOptimizationItem())
@@ -7,17 +7,23 @@ import (
 	"github.com/kaytu-io/kaytu/pkg/style"
 	"github.com/kaytu-io/kaytu/pkg/utils"
 	"github.com/opengovern/plugin-aws/plugin/aws"
+	"github.com/opengovern/plugin-aws/plugin/coverage"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
 	"github.com/opengovern/plugin-aws/plugin/kaytu"
-	"github.com/opengovern/plugin-aws/plugin/processor/ec2_instance"
+	"github.com/opengovern/plugin-aws/plugin/policy"
+	preferences2 "github.com/opengovern/plugin-aws/plugin/preferences"
+	"github.com/opengovern/plugin-aws/plugin/pricing"
 	"github.com/opengovern/plugin-aws/plugin/processor/shared"
 	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	summary2 "github.com/opengovern/plugin-aws/plugin/summary"
 	"strings"
 	"sync/atomic"
 )
 
 type Processor struct {
-	provider                *aws.AWS
-	metricProvider          *aws.CloudWatch
+	provider                aws.InventoryProvider
+	metricProvider          aws.MetricsProvider
 	identification          map[string]string
 	items                   utils.ConcurrentMap[string, RDSClusterItem]
 	publishOptimizationItem func(item *golang.ChartOptimizationItem)
@@ -29,11 +35,16 @@ type Processor struct {
 	observabilityDays       int
 	client                  golang2.OptimizationClient
 
-	summary            *utils.ConcurrentMap[string, ec2_instance.EC2InstanceSummary]
+	summary            *utils.ConcurrentMap[string, summary2.Resource]
+	commitments        *coverage.Inventory
 	defaultPreferences []*golang.PreferenceItem
+	preferenceRules    preferences2.Rules
+	policy             *policy.Policy
+	pricing            *pricing.Adjustments
+	currency           *currency.Currency
 }
 
-func NewProcessor(provider *aws.AWS, metricProvider *aws.CloudWatch, identification map[string]string, publishOptimizationItem func(item *golang.ChartOptimizationItem), publishResultSummary func(summary *golang.ResultSummary), kaytuAcccessToken string, jobQueue *sdk.JobQueue, configurations *kaytu.Configuration, lazyloadCounter *atomic.Uint32, observabilityDays int, summary *utils.ConcurrentMap[string, ec2_instance.EC2InstanceSummary], preferences []*golang.PreferenceItem, client golang2.OptimizationClient) *Processor {
+func NewProcessor(provider aws.InventoryProvider, metricProvider aws.MetricsProvider, identification map[string]string, publishOptimizationItem func(item *golang.ChartOptimizationItem), publishResultSummary func(summary *golang.ResultSummary), kaytuAcccessToken string, jobQueue *sdk.JobQueue, configurations *kaytu.Configuration, lazyloadCounter *atomic.Uint32, observabilityDays int, summary *utils.ConcurrentMap[string, summary2.Resource], commitments *coverage.Inventory, preferences []*golang.PreferenceItem, preferenceRules preferences2.Rules, recommendationPolicy *policy.Policy, pricingAdjustments *pricing.Adjustments, displayCurrency *currency.Currency, client golang2.OptimizationClient) *Processor {
 	r := &Processor{
 		provider:                provider,
 		metricProvider:          metricProvider,
@@ -48,7 +59,12 @@ func NewProcessor(provider *aws.AWS, metricProvider *aws.CloudWatch, identificat
 		observabilityDays:       observabilityDays,
 		client:                  client,
 		summary:                 summary,
+		commitments:             commitments,
 		defaultPreferences:      preferences,
+		preferenceRules:         preferenceRules,
+		policy:                  recommendationPolicy,
+		pricing:                 pricingAdjustments,
+		currency:                displayCurrency,
 	}
 
 	jobQueue.Push(NewListAllRegionsJob(r))
@@ -66,10 +82,11 @@ func (m *Processor) ExportNonInteractive() *golang.NonInteractiveExport {
 	return nil
 }
 
-func (m *Processor) ExportCsv() []*golang.CSVRow {
+// ExportCsv returns the rows of the optimized instances, commitments is their coverage keyed by instance id.
+func (m *Processor) ExportCsv(commitments map[string]history.Coverage) []*golang.CSVRow {
 	var rows []*golang.CSVRow
 
-	m.summary.Range(func(id string, _ ec2_instance.EC2InstanceSummary) bool {
+	m.summary.Range(func(id string, _ summary2.Resource) bool {
 		if _, ok := m.items.Get(id); !ok {
 			fmt.Println("Skipping item", id)
 			return true
@@ -81,13 +98,16 @@ func (m *Processor) ExportCsv() []*golang.CSVRow {
 				platform = *i.Engine
 			}
 			hashedId := utils.HashString(*i.DBInstanceIdentifier)
-			rightSizing := cluster.Wastage.RightSizing[hashedId]
+			rightSizing, ok := cluster.Wastage.RightSizing[hashedId]
+			if !ok || rightSizing == nil || rightSizing.Current == nil {
+				continue
+			}
 
 			var computeAdditionalDetails []string
 			var computeRightSizingCost, computeSaving, computeRecSpec string
 			if rightSizing.Recommended != nil {
-				computeRightSizingCost = utils.FormatPriceFloat(rightSizing.Recommended.ComputeCost)
-				computeSaving = utils.FormatPriceFloat(rightSizing.Current.ComputeCost - rightSizing.Recommended.ComputeCost)
+				computeRightSizingCost = m.currency.Format(rightSizing.Recommended.ComputeCost)
+				computeSaving = m.currency.Format(rightSizing.Current.ComputeCost - rightSizing.Recommended.ComputeCost)
 				computeRecSpec = rightSizing.Recommended.InstanceType
 
 				computeAdditionalDetails = append(computeAdditionalDetails,
@@ -104,7 +124,7 @@ func (m *Processor) ExportCsv() []*golang.CSVRow {
 						rightSizing.Recommended.ClusterType))
 				computeAdditionalDetails = append(computeAdditionalDetails,
 					fmt.Sprintf("vCPU:: Current: %d - Avg: %s - Recommended: %d", rightSizing.Current.Vcpu,
-						utils.Percentage(shared.WrappedToFloat64(rightSizing.Vcpu.Avg)), rightSizing.Recommended.Vcpu))
+						utils.Percentage(shared.WrappedToFloat64(rightSizing.GetVcpu().GetAvg())), rightSizing.Recommended.Vcpu))
 				computeAdditionalDetails = append(computeAdditionalDetails,
 					fmt.Sprintf("Processor(s):: Current: %s - Recommended: %s", rightSizing.Current.Processor,
 						rightSizing.Recommended.Processor))
@@ -113,11 +133,15 @@ func (m *Processor) ExportCsv() []*golang.CSVRow {
 						rightSizing.Recommended.Architecture))
 				computeAdditionalDetails = append(computeAdditionalDetails,
 					fmt.Sprintf("Memory:: Current: %d GB - Avg: %s - Recommended: %d GB", rightSizing.Current.MemoryGb,
-						utils.MemoryUsagePercentageByFreeSpace(shared.WrappedToFloat64(rightSizing.FreeMemoryBytes.Avg), float64(rightSizing.Current.MemoryGb)),
+						utils.MemoryUsagePercentageByFreeSpace(shared.WrappedToFloat64(rightSizing.GetFreeMemoryBytes().GetAvg()), float64(rightSizing.Current.MemoryGb)),
 						rightSizing.Recommended.MemoryGb))
 			}
+			computeAdditionalDetails = append(computeAdditionalDetails, shared.PreferenceOverridesDetails(cluster.PreferenceOverrides, "RDSInstance")...)
+			if c, ok := commitments[*i.DBInstanceIdentifier]; ok {
+				computeAdditionalDetails = append(computeAdditionalDetails, shared.CoverageDetails(c, m.currency)...)
+			}
 			computeRow := []string{m.identification["account"], cluster.Region, "RDS Instance Compute", fmt.Sprintf("%s-compute", *i.DBInstanceIdentifier),
-				*i.DBInstanceIdentifier, platform, "730 hours", utils.FormatPriceFloat(rightSizing.Current.ComputeCost),
+				*i.DBInstanceIdentifier, platform, cluster.Runtimes[hashedId].String(), m.currency.Format(rightSizing.Current.ComputeCost),
 				computeRightSizingCost, computeSaving, rightSizing.Current.InstanceType, computeRecSpec, *i.DBInstanceIdentifier,
 				rightSizing.Description, strings.Join(computeAdditionalDetails, "---")}
 			rows = append(rows, &golang.CSVRow{Row: computeRow})
@@ -125,34 +149,33 @@ func (m *Processor) ExportCsv() []*golang.CSVRow {
 			var storageAdditionalDetails []string
 			var storageRightSizingCost, storageSaving, storageRecSpec string
 			if rightSizing.Recommended != nil {
-				storageRightSizingCost = utils.FormatPriceFloat(rightSizing.Recommended.StorageCost)
-				storageSaving = utils.FormatPriceFloat(rightSizing.Current.StorageCost - rightSizing.Recommended.StorageCost)
-				storageRecSpec = fmt.Sprintf("%s/%s/%s IOPS", *shared.WrappedToString(rightSizing.Recommended.StorageType),
-					utils.SizeByteToGB(shared.WrappedToInt32(rightSizing.Recommended.StorageSize)), utils.PInt32ToString(shared.WrappedToInt32(rightSizing.Recommended.StorageIops)))
+				storageRightSizingCost = m.currency.Format(rightSizing.Recommended.StorageCost)
+				storageSaving = m.currency.Format(rightSizing.Current.StorageCost - rightSizing.Recommended.StorageCost)
+				storageRecSpec = shared.RDSStorageSpec(rightSizing.Recommended)
 
 				storageAdditionalDetails = append(storageAdditionalDetails,
 					fmt.Sprintf("Type:: Current: %s - Recommended: %s", utils.PString(shared.WrappedToString(rightSizing.Current.StorageType)),
 						utils.PString(shared.WrappedToString(rightSizing.Recommended.StorageType))))
 				storageAdditionalDetails = append(storageAdditionalDetails,
 					fmt.Sprintf("Size:: Current: %s - Avg : %s - Recommended: %s", utils.SizeByteToGB(shared.WrappedToInt32(rightSizing.Current.StorageSize)),
-						utils.StorageUsagePercentageByFreeSpace(shared.WrappedToFloat64(rightSizing.FreeStorageBytes.Avg), shared.WrappedToInt32(rightSizing.Current.StorageSize)),
-						utils.SizeByteToGB(shared.WrappedToInt32(rightSizing.Current.StorageSize))))
+						utils.StorageUsagePercentageByFreeSpace(shared.WrappedToFloat64(rightSizing.GetFreeStorageBytes().GetAvg()), shared.WrappedToInt32(rightSizing.Current.StorageSize)),
+						utils.SizeByteToGB(shared.WrappedToInt32(rightSizing.Recommended.StorageSize))))
 				storageAdditionalDetails = append(storageAdditionalDetails,
 					fmt.Sprintf("IOPS:: Current: %s - Avg: %s - Recommended: %s", utils.PInt32ToString(shared.WrappedToInt32(rightSizing.Current.StorageIops)),
-						fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(rightSizing.StorageIops.Avg))),
+						fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(rightSizing.GetStorageIops().GetAvg()))),
 						utils.PInt32ToString(shared.WrappedToInt32(rightSizing.Recommended.StorageIops))))
 				storageAdditionalDetails = append(storageAdditionalDetails,
-					fmt.Sprintf("Throughput:: Current: %s - Avg: %s - Recommended: %s", utils.PStorageThroughputMbps(shared.WrappedToFloat64(rightSizing.Current.StorageThroughput)),
-						utils.PStorageThroughputMbps(shared.WrappedToFloat64(rightSizing.StorageThroughput.Avg)), utils.PStorageThroughputMbps(shared.WrappedToFloat64(rightSizing.Recommended.StorageThroughput))))
+					fmt.Sprintf("Throughput:: Current: %s - Avg: %s - Recommended: %s", utils.PStorageThroughputMbps(shared.ThroughputMBpsToBytes(rightSizing.Current.StorageThroughput)),
+						utils.PStorageThroughputMbps(shared.WrappedToFloat64(rightSizing.GetStorageThroughput().GetAvg())), utils.PStorageThroughputMbps(shared.ThroughputMBpsToBytes(rightSizing.Recommended.StorageThroughput))))
 				storageAdditionalDetails = append(storageAdditionalDetails,
 					fmt.Sprintf("VolumeTypeChange:: %v", utils.PString(shared.WrappedToString(rightSizing.Current.StorageType)) != utils.PString(shared.WrappedToString(rightSizing.Recommended.StorageType))))
 				storageAdditionalDetails = append(storageAdditionalDetails,
-					fmt.Sprintf("VolumeSizeChange:: %v", *shared.WrappedToInt32(rightSizing.Current.StorageSize) != *shared.WrappedToInt32(rightSizing.Recommended.StorageSize)))
+					fmt.Sprintf("VolumeSizeChange:: %v", rightSizing.Current.StorageSize.GetValue() != rightSizing.Recommended.StorageSize.GetValue()))
 			}
+			storageAdditionalDetails = append(storageAdditionalDetails, shared.PreferenceOverridesDetails(cluster.PreferenceOverrides, "RDSInstance")...)
 			storageRow := []string{m.identification["account"], cluster.Region, "RDS Instance Storage", fmt.Sprintf("%s-storage", *i.DBInstanceIdentifier),
-				*i.DBInstanceIdentifier, "N/A", "730 hours", utils.FormatPriceFloat(rightSizing.Current.StorageCost),
-				storageRightSizingCost, storageSaving, fmt.Sprintf("%s/%s/%s IOPS", *shared.WrappedToString(rightSizing.Current.StorageType),
-					utils.SizeByteToGB(shared.WrappedToInt32(rightSizing.Current.StorageSize)), utils.PInt32ToString(shared.WrappedToInt32(rightSizing.Current.StorageIops))), storageRecSpec, *i.DBInstanceIdentifier,
+				*i.DBInstanceIdentifier, "N/A", "730 hours", m.currency.Format(rightSizing.Current.StorageCost),
+				storageRightSizingCost, storageSaving, shared.RDSStorageSpec(rightSizing.Current), storageRecSpec, *i.DBInstanceIdentifier,
 				rightSizing.Description, strings.Join(storageAdditionalDetails, "---")}
 			rows = append(rows, &golang.CSVRow{Row: storageRow})
 		}
@@ -170,13 +193,13 @@ func (m *Processor) ResultsSummary() *golang.ResultSummary {
 	summary := &golang.ResultSummary{}
 	var totalCost, savings float64
 
-	m.summary.Range(func(_ string, item ec2_instance.EC2InstanceSummary) bool {
-		totalCost += item.CurrentRuntimeCost
+	m.summary.Range(func(_ string, item summary2.Resource) bool {
+		totalCost += item.CurrentCost
 		savings += item.Savings
 		return true
 	})
 	summary.Message = fmt.Sprintf("Current runtime cost: %s, Savings: %s",
-		style.CostStyle.Render(fmt.Sprintf("%s", utils.FormatPriceFloat(totalCost))), style.SavingStyle.Render(fmt.Sprintf("%s", utils.FormatPriceFloat(savings))))
+		style.CostStyle.Render(fmt.Sprintf("%s", m.currency.Format(totalCost))), style.SavingStyle.Render(fmt.Sprintf("%s", m.currency.Format(savings))))
 	return summary
 }
 
@@ -187,16 +210,70 @@ func (m *Processor) UpdateSummary(itemId string) {
 		totalCurrentCost := 0.0
 
 		for _, instance := range i.Wastage.RightSizing {
-			totalSaving += instance.Current.ComputeCost - instance.Recommended.ComputeCost
+			if instance == nil || instance.Current == nil {
+				continue
+			}
 			totalCurrentCost += instance.Current.ComputeCost
-			totalSaving += instance.Current.StorageCost - instance.Recommended.StorageCost
 			totalCurrentCost += instance.Current.StorageCost
+			if instance.Recommended != nil {
+				totalSaving += instance.Current.ComputeCost - instance.Recommended.ComputeCost
+				totalSaving += instance.Current.StorageCost - instance.Recommended.StorageCost
+			}
 		}
 
-		m.summary.Set(itemId, ec2_instance.EC2InstanceSummary{
-			CurrentRuntimeCost: totalCurrentCost,
-			Savings:            totalSaving,
-		})
+		resource := m.summaryResource(i, summary2.StatusAnalysed)
+		resource.CurrentCost = totalCurrentCost
+		resource.Savings = totalSaving
+		m.summary.Set(itemId, resource)
 	}
 	m.publishResultSummary(m.ResultsSummary())
 }
+
+// SummaryResources returns every cluster of the run for the results summary, the ones still loading
+// failed to get their metrics or optimization.
+func (m *Processor) SummaryResources() []summary2.Resource {
+	var resources []summary2.Resource
+	m.items.Range(func(id string, c RDSClusterItem) bool {
+		if resource, ok := m.summary.Get(id); ok {
+			resources = append(resources, resource)
+			return true
+		}
+		switch {
+		case c.Skipped || c.LazyLoadingEnabled:
+			resource := m.summaryResource(c, summary2.StatusSkipped)
+			resource.SkipReason = c.SkipReason
+			if resource.SkipReason == "" {
+				resource.SkipReason = summary2.ReasonNotLoaded
+			}
+			resources = append(resources, resource)
+		case c.OptimizationLoading:
+			resources = append(resources, m.summaryResource(c, summary2.StatusFailed))
+		default:
+			resources = append(resources, m.summaryResource(c, summary2.StatusAnalysed))
+		}
+		return true
+	})
+	return resources
+}
+
+// summaryResource is the summary of a cluster, of the family of its instances or mixed when they differ.
+func (m *Processor) summaryResource(c RDSClusterItem, status summary2.Status) summary2.Resource {
+	var family string
+	for idx, i := range c.Instances {
+		f := policy.Family(utils.PString(i.DBInstanceClass))
+		if idx > 0 && f != family {
+			family = "mixed"
+			break
+		}
+		family = f
+	}
+	return summary2.Resource{
+		Id:             *c.Cluster.DBClusterIdentifier,
+		Account:        m.identification["account"],
+		Region:         c.Region,
+		ResourceType:   "RDS Cluster",
+		InstanceFamily: family,
+		Tags:           shared.RDSTags(c.Cluster.TagList),
+		Status:         status,
+	}
+}
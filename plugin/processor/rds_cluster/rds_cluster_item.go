@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/rds/types"
 	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
 	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/currency"
 	"github.com/opengovern/plugin-aws/plugin/processor/shared"
 	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
 	"google.golang.org/protobuf/types/known/wrapperspb"
@@ -19,12 +20,18 @@ type RDSClusterItem struct {
 	Region              string
 	OptimizationLoading bool
 	Preferences         []*golang.PreferenceItem
+	// Currency is the currency costs are shown in
+	Currency *currency.Currency
+	// PreferenceOverrides describes the preferences changed by tag rules for the last optimization
+	PreferenceOverrides []string
 	Skipped             bool
 	LazyLoadingEnabled  bool
 	SkipReason          string
 
 	Metrics map[string]map[string][]types2.Datapoint
 	Wastage *golang2.RDSClusterOptimizationResponse
+	// Runtimes are the observed runtimes the compute costs of the instances are projected for, by hashed identifier
+	Runtimes map[string]shared.Runtime
 }
 
 func (c RDSClusterItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*golang.Properties) {
@@ -33,6 +40,9 @@ func (c RDSClusterItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*gol
 
 	for _, i := range c.Instances {
 		hashedId := utils.HashString(*i.DBInstanceIdentifier)
+		if rs, ok := c.Wastage.RightSizing[hashedId]; !ok || rs == nil || rs.Current == nil {
+			continue
+		}
 		computeProps := &golang.Properties{}
 		storageProps := &golang.Properties{}
 
@@ -50,10 +60,10 @@ func (c RDSClusterItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*gol
 			Value: "RDS Instance Compute",
 		}
 		computeRow.Values["runtime"] = &golang.ChartRowItem{
-			Value: "730 hours",
+			Value: c.Runtimes[hashedId].String(),
 		}
 		computeRow.Values["current_cost"] = &golang.ChartRowItem{
-			Value: utils.FormatPriceFloat(c.Wastage.RightSizing[hashedId].Current.ComputeCost),
+			Value: c.Currency.Format(c.Wastage.RightSizing[hashedId].Current.ComputeCost),
 		}
 
 		storageRow := golang.ChartRow{
@@ -73,7 +83,7 @@ func (c RDSClusterItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*gol
 			Value: "730 hours",
 		}
 		storageRow.Values["current_cost"] = &golang.ChartRowItem{
-			Value: utils.FormatPriceFloat(c.Wastage.RightSizing[hashedId].Current.StorageCost),
+			Value: c.Currency.Format(c.Wastage.RightSizing[hashedId].Current.StorageCost),
 		}
 
 		regionProperty := &golang.Property{
@@ -99,8 +109,8 @@ func (c RDSClusterItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*gol
 		vCPUProperty := &golang.Property{
 			Key:     "vCPU",
 			Current: fmt.Sprintf("%d", c.Wastage.RightSizing[hashedId].Current.Vcpu),
-			Average: utils.Percentage(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].Vcpu.Avg)),
-			Max:     utils.Percentage(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].Vcpu.Max)),
+			Average: utils.Percentage(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].GetVcpu().GetAvg())),
+			Max:     utils.Percentage(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].GetVcpu().GetMax())),
 		}
 		processorProperty := &golang.Property{
 			Key:     "Processor(s)",
@@ -113,8 +123,8 @@ func (c RDSClusterItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*gol
 		memoryProperty := &golang.Property{
 			Key:     "Memory",
 			Current: fmt.Sprintf("%d GiB", c.Wastage.RightSizing[hashedId].Current.MemoryGb),
-			Average: utils.MemoryUsagePercentageByFreeSpace(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].FreeMemoryBytes.Avg), float64(c.Wastage.RightSizing[hashedId].Current.MemoryGb)),
-			Max:     utils.MemoryUsagePercentageByFreeSpace(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].FreeMemoryBytes.Min), float64(c.Wastage.RightSizing[hashedId].Current.MemoryGb)),
+			Average: utils.MemoryUsagePercentageByFreeSpace(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].GetFreeMemoryBytes().GetAvg()), float64(c.Wastage.RightSizing[hashedId].Current.MemoryGb)),
+			Max:     utils.MemoryUsagePercentageByFreeSpace(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].GetFreeMemoryBytes().GetMin()), float64(c.Wastage.RightSizing[hashedId].Current.MemoryGb)),
 		}
 		storageTypeProperty := &golang.Property{
 			Key:     "Type",
@@ -123,76 +133,77 @@ func (c RDSClusterItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*gol
 		storageSizeProperty := &golang.Property{
 			Key:     "Size",
 			Current: utils.SizeByteToGB(shared.WrappedToInt32(c.Wastage.RightSizing[hashedId].Current.StorageSize)),
-			Average: utils.StorageUsagePercentageByFreeSpace(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].FreeStorageBytes.Avg), shared.WrappedToInt32(c.Wastage.RightSizing[hashedId].Current.StorageSize)),
-			Max:     utils.StorageUsagePercentageByFreeSpace(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].FreeStorageBytes.Min), shared.WrappedToInt32(c.Wastage.RightSizing[hashedId].Current.StorageSize)),
+			Average: utils.StorageUsagePercentageByFreeSpace(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].GetFreeStorageBytes().GetAvg()), shared.WrappedToInt32(c.Wastage.RightSizing[hashedId].Current.StorageSize)),
+			Max:     utils.StorageUsagePercentageByFreeSpace(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].GetFreeStorageBytes().GetMin()), shared.WrappedToInt32(c.Wastage.RightSizing[hashedId].Current.StorageSize)),
 		}
 
 		computeCostComponentPropertiesMap := make(map[string]*golang.Property)
 		for k, v := range c.Wastage.RightSizing[hashedId].Current.ComputeCostComponents {
 			computeCostComponentPropertiesMap[k] = &golang.Property{
 				Key:     fmt.Sprintf("  %s", k),
-				Current: fmt.Sprintf("$%.2f", v),
+				Current: c.Currency.Format(v),
 			}
 		}
 		storageCostComponentPropertiesMap := make(map[string]*golang.Property)
 		for k, v := range c.Wastage.RightSizing[hashedId].Current.StorageCostComponents {
 			storageCostComponentPropertiesMap[k] = &golang.Property{
 				Key:     fmt.Sprintf("  %s", k),
-				Current: fmt.Sprintf("$%.2f", v),
+				Current: c.Currency.Format(v),
 			}
 		}
 
-		if strings.Contains(strings.ToLower(c.Wastage.RightSizing[hashedId].Current.Engine), "aurora") {
-			avgPercentage := (*shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].VolumeBytesUsed.Avg) / (1024.0 * 1024.0 * 1024.0)) / float64(*shared.WrappedToInt32(c.Wastage.RightSizing[hashedId].Current.StorageSize)) * 100
-			maxPercentage := (*shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].VolumeBytesUsed.Max) / (1024.0 * 1024.0 * 1024.0)) / float64(*shared.WrappedToInt32(c.Wastage.RightSizing[hashedId].Current.StorageSize)) * 100
+		if strings.Contains(strings.ToLower(c.Wastage.RightSizing[hashedId].Current.Engine), "aurora") &&
+			c.Wastage.RightSizing[hashedId].GetVolumeBytesUsed().GetAvg() != nil && c.Wastage.RightSizing[hashedId].GetVolumeBytesUsed().GetMax() != nil &&
+			c.Wastage.RightSizing[hashedId].Current.StorageSize.GetValue() > 0 {
+			avgPercentage := (*shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].GetVolumeBytesUsed().GetAvg()) / (1024.0 * 1024.0 * 1024.0)) / float64(*shared.WrappedToInt32(c.Wastage.RightSizing[hashedId].Current.StorageSize)) * 100
+			maxPercentage := (*shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].GetVolumeBytesUsed().GetMax()) / (1024.0 * 1024.0 * 1024.0)) / float64(*shared.WrappedToInt32(c.Wastage.RightSizing[hashedId].Current.StorageSize)) * 100
 			storageSizeProperty.Average = utils.Percentage(&avgPercentage)
 			storageSizeProperty.Max = utils.Percentage(&maxPercentage)
 		}
 		storageIOPSProperty := &golang.Property{
 			Key:     "IOPS",
 			Current: utils.PInt32ToString(shared.WrappedToInt32(c.Wastage.RightSizing[hashedId].Current.StorageIops)),
-			Average: fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].StorageIops.Avg))),
-			Max:     fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].StorageIops.Max))),
+			Average: fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].GetStorageIops().GetAvg()))),
+			Max:     fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].GetStorageIops().GetMax()))),
 		}
 		if storageIOPSProperty.Current != "" {
 			storageIOPSProperty.Current = fmt.Sprintf("%s io/s", storageIOPSProperty.Current)
 		} else {
 			storageIOPSProperty.Current = ""
 		}
-		if c.Wastage.RightSizing[hashedId].StorageIops.Avg == nil {
+		if c.Wastage.RightSizing[hashedId].GetStorageIops().GetAvg() == nil {
 			storageIOPSProperty.Average = ""
 		}
-		if c.Wastage.RightSizing[hashedId].StorageIops.Max == nil {
+		if c.Wastage.RightSizing[hashedId].GetStorageIops().GetMax() == nil {
 			storageIOPSProperty.Max = ""
 		}
 		// current number is in MB/s, so we need to convert it to bytes/s so matches the other values
+		var currentThroughput *float64
 		if c.Wastage.RightSizing[hashedId].Current.StorageThroughput != nil {
-			v := c.Wastage.RightSizing[hashedId]
-			tmp := *shared.WrappedToFloat64(v.Current.StorageThroughput) * 1024.0 * 1024.0
-			v.Current.StorageThroughput = shared.Float64ToWrapper(&tmp)
-			c.Wastage.RightSizing[hashedId] = v
+			tmp := c.Wastage.RightSizing[hashedId].Current.StorageThroughput.GetValue() * 1024.0 * 1024.0
+			currentThroughput = &tmp
 		}
 		storageThroughputProperty := &golang.Property{
 			Key:     "Throughput",
-			Current: utils.PStorageThroughputMbps(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].Current.StorageThroughput)),
-			Average: utils.PStorageThroughputMbps(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].StorageThroughput.Avg)),
-			Max:     utils.PStorageThroughputMbps(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].StorageThroughput.Max)),
+			Current: utils.PStorageThroughputMbps(currentThroughput),
+			Average: utils.PStorageThroughputMbps(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].GetStorageThroughput().GetAvg())),
+			Max:     utils.PStorageThroughputMbps(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].GetStorageThroughput().GetMax())),
 		}
 
 		if c.Wastage.RightSizing[hashedId].Recommended != nil {
 			processorProperty.Recommended = c.Wastage.RightSizing[hashedId].Recommended.Processor
 			architectureProperty.Recommended = c.Wastage.RightSizing[hashedId].Recommended.Architecture
 			computeRow.Values["right_sized_cost"] = &golang.ChartRowItem{
-				Value: utils.FormatPriceFloat(c.Wastage.RightSizing[hashedId].Recommended.ComputeCost),
+				Value: c.Currency.Format(c.Wastage.RightSizing[hashedId].Recommended.ComputeCost),
 			}
 			computeRow.Values["savings"] = &golang.ChartRowItem{
-				Value: utils.FormatPriceFloat(c.Wastage.RightSizing[hashedId].Current.ComputeCost - c.Wastage.RightSizing[hashedId].Recommended.ComputeCost),
+				Value: c.Currency.Format(c.Wastage.RightSizing[hashedId].Current.ComputeCost - c.Wastage.RightSizing[hashedId].Recommended.ComputeCost),
 			}
 			storageRow.Values["right_sized_cost"] = &golang.ChartRowItem{
-				Value: utils.FormatPriceFloat(c.Wastage.RightSizing[hashedId].Recommended.StorageCost),
+				Value: c.Currency.Format(c.Wastage.RightSizing[hashedId].Recommended.StorageCost),
 			}
 			storageRow.Values["savings"] = &golang.ChartRowItem{
-				Value: utils.FormatPriceFloat(c.Wastage.RightSizing[hashedId].Current.StorageCost - c.Wastage.RightSizing[hashedId].Recommended.StorageCost),
+				Value: c.Currency.Format(c.Wastage.RightSizing[hashedId].Current.StorageCost - c.Wastage.RightSizing[hashedId].Recommended.StorageCost),
 			}
 			regionProperty.Recommended = c.Wastage.RightSizing[hashedId].Recommended.Region
 			instanceSizeProperty.Recommended = c.Wastage.RightSizing[hashedId].Recommended.InstanceType
@@ -210,11 +221,12 @@ func (c RDSClusterItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*gol
 				storageIOPSProperty.Recommended = "N/A"
 			}
 			// Recommended number is in MB/s, so we need to convert it to bytes/s so matches the other values
+			var recommendedThroughput *float64
 			if c.Wastage.RightSizing[hashedId].Recommended.StorageThroughput != nil {
-				v := *shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].Recommended.StorageThroughput) * 1024.0 * 1024.0
-				c.Wastage.RightSizing[hashedId].Recommended.StorageThroughput = shared.Float64ToWrapper(&v)
+				v := c.Wastage.RightSizing[hashedId].Recommended.StorageThroughput.GetValue() * 1024.0 * 1024.0
+				recommendedThroughput = &v
 			}
-			storageThroughputProperty.Recommended = utils.PStorageThroughputMbps(shared.WrappedToFloat64(c.Wastage.RightSizing[hashedId].Recommended.StorageThroughput))
+			storageThroughputProperty.Recommended = utils.PStorageThroughputMbps(recommendedThroughput)
 
 			for k, v := range c.Wastage.RightSizing[hashedId].Recommended.ComputeCostComponents {
 				if _, ok := computeCostComponentPropertiesMap[k]; !ok {
@@ -222,7 +234,7 @@ func (c RDSClusterItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*gol
 						Key: fmt.Sprintf("  %s", k),
 					}
 				}
-				computeCostComponentPropertiesMap[k].Recommended = fmt.Sprintf("$%.2f", v)
+				computeCostComponentPropertiesMap[k].Recommended = c.Currency.Format(v)
 			}
 			for k, v := range c.Wastage.RightSizing[hashedId].Recommended.StorageCostComponents {
 				if _, ok := storageCostComponentPropertiesMap[k]; !ok {
@@ -230,7 +242,7 @@ func (c RDSClusterItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*gol
 						Key: fmt.Sprintf("  %s", k),
 					}
 				}
-				storageCostComponentPropertiesMap[k].Recommended = fmt.Sprintf("$%.2f", v)
+				storageCostComponentPropertiesMap[k].Recommended = c.Currency.Format(v)
 			}
 		}
 		computeProps.Properties = append(computeProps.Properties, regionProperty)
@@ -330,7 +342,7 @@ func (i RDSClusterItem) ToOptimizationItem() *golang.ChartOptimizationItem {
 			totalCurrentCost += rs.Current.ComputeCost
 			totalSaving += rs.Current.StorageCost - rs.Recommended.StorageCost
 			totalCurrentCost += rs.Current.StorageCost
-			status = fmt.Sprintf("%s (%.2f%%)", utils.FormatPriceFloat(totalSaving), (totalSaving/totalCurrentCost)*100)
+			status = fmt.Sprintf("%s (%.2f%%)", i.Currency.Format(totalSaving), (totalSaving/totalCurrentCost)*100)
 		}
 	}
 
@@ -0,0 +1,28 @@
+package rds_cluster
+
+import (
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/processor/shared"
+)
+
+// Records returns the outcome of every instance of the optimized clusters, including the ones without recommendation.
+func (m *Processor) Records() []history.Record {
+	var records []history.Record
+	m.items.Range(func(_ string, c RDSClusterItem) bool {
+		if c.Skipped || c.OptimizationLoading || c.Wastage == nil {
+			return true
+		}
+		clusterTags := shared.RDSTags(c.Cluster.TagList)
+		for _, i := range c.Instances {
+			tags := shared.InheritTags(shared.RDSTags(i.TagList), clusterTags)
+			hashedId := utils.HashString(*i.DBInstanceIdentifier)
+			for _, r := range shared.RDSRecords(c.Region, i, tags, c.Wastage.RightSizing[hashedId], c.Runtimes[hashedId]) {
+				r.ClusterId = *c.Cluster.DBClusterIdentifier
+				records = append(records, r)
+			}
+		}
+		return true
+	})
+	return records
+}
@@ -0,0 +1,24 @@
+package rds_cluster
+
+import (
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/processor/shared"
+	"github.com/opengovern/plugin-aws/plugin/report"
+)
+
+// ReportItems returns every cluster of the run for the HTML report, with the utilization of the compute and
+// storage of its instances.
+func (m *Processor) ReportItems() []report.Item {
+	var items []report.Item
+	m.items.Range(func(_ string, c RDSClusterItem) bool {
+		utilization := map[string][]report.Series{}
+		for _, i := range c.Instances {
+			for rowId, series := range shared.RDSUtilization(*i.DBInstanceIdentifier, c.Metrics[utils.HashString(*i.DBInstanceIdentifier)]) {
+				utilization[rowId] = series
+			}
+		}
+		items = append(items, report.Item{Service: "RDS", Optimization: c.ToOptimizationItem(), Utilization: utilization})
+		return true
+	})
+	return items
+}
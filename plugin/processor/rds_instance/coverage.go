@@ -0,0 +1,36 @@
+package rds_instance
+
+import (
+	"github.com/opengovern/plugin-aws/plugin/coverage"
+)
+
+// CoverageResources returns the optimized instances to match with the reserved DB instances.
+func (m *Processor) CoverageResources() []coverage.Resource {
+	var resources []coverage.Resource
+	m.items.Range(func(_ string, i RDSInstanceItem) bool {
+		if i.Skipped || i.OptimizationLoading || i.Wastage == nil || i.Wastage.RightSizing == nil || i.Wastage.RightSizing.Current == nil {
+			return true
+		}
+		resource := coverage.Resource{
+			Kind:         coverage.RDSInstance,
+			Id:           *i.Instance.DBInstanceIdentifier,
+			Region:       i.Region,
+			InstanceType: i.Wastage.RightSizing.Current.InstanceType,
+			MultiAZ:      i.Instance.MultiAZ != nil && *i.Instance.MultiAZ,
+			CurrentCost:  i.Wastage.RightSizing.Current.ComputeCost,
+		}
+		if i.Instance.Engine != nil {
+			resource.Platform = *i.Instance.Engine
+		}
+		if i.Instance.AvailabilityZone != nil {
+			resource.AvailabilityZone = *i.Instance.AvailabilityZone
+		}
+		if i.Wastage.RightSizing.Recommended != nil {
+			resource.RecommendedType = i.Wastage.RightSizing.Recommended.InstanceType
+			resource.RecommendedCost = i.Wastage.RightSizing.Recommended.ComputeCost
+		}
+		resources = append(resources, resource)
+		return true
+	})
+	return resources
+}
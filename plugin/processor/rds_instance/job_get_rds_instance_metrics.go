@@ -191,6 +191,7 @@ func (j *GetRDSInstanceMetricsJob) Run(ctx context.Context) error {
 		OptimizationLoading: true,
 		LazyLoadingEnabled:  false,
 		Preferences:         j.processor.defaultPreferences,
+		Currency:            j.processor.currency,
 	}
 
 	j.processor.items.Set(*oi.Instance.DBInstanceIdentifier, oi)
@@ -30,6 +30,7 @@ func (j *ListAllRegionsJob) Run(ctx context.Context) error {
 	}
 	for _, region := range regions {
 		j.processor.jobQueue.Push(NewListRDSInstancesInRegionJob(j.processor, region))
+		j.processor.jobQueue.Push(NewListReservedDBInstancesJob(j.processor, region))
 	}
 	return nil
 }
@@ -50,6 +50,7 @@ func (j *ListRDSInstancesInRegionJob) Run(ctx context.Context) error {
 			OptimizationLoading: true,
 			LazyLoadingEnabled:  false,
 			Preferences:         j.processor.defaultPreferences,
+			Currency:            j.processor.currency,
 		}
 
 		if strings.Contains(strings.ToLower(*instance.Engine), "docdb") {
@@ -120,7 +121,7 @@ func (j *ListRDSInstancesInRegionJob) Run(ctx context.Context) error {
 			continue
 		}
 
-		if i, ok := j.processor.items.Get(*instance.DBInstanceIdentifier); ok && i.LazyLoadingEnabled {
+		if i, ok := j.processor.items.Get(*instance.DBInstanceIdentifier); ok && (i.LazyLoadingEnabled || i.Skipped) {
 			continue
 		}
 
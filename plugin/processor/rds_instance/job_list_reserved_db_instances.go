@@ -0,0 +1,36 @@
+package rds_instance
+
+import (
+	"context"
+	"fmt"
+	"github.com/kaytu-io/kaytu/pkg/plugin/sdk"
+)
+
+type ListReservedDBInstancesJob struct {
+	region    string
+	processor *Processor
+}
+
+func NewListReservedDBInstancesJob(processor *Processor, region string) *ListReservedDBInstancesJob {
+	return &ListReservedDBInstancesJob{
+		processor: processor,
+		region:    region,
+	}
+}
+
+func (j *ListReservedDBInstancesJob) Properties() sdk.JobProperties {
+	return sdk.JobProperties{
+		ID:          fmt.Sprintf("list_reserved_db_instances_in_%s", j.region),
+		Description: fmt.Sprintf("Listing all Reserved DB Instances in %s", j.region),
+		MaxRetry:    0,
+	}
+}
+
+func (j *ListReservedDBInstancesJob) Run(ctx context.Context) error {
+	reserved, err := j.processor.provider.ListReservedDBInstances(ctx, j.region)
+	if err != nil {
+		return fmt.Errorf("failed to list reserved DB instances in %s, savings ignore them: %w", j.region, err)
+	}
+	j.processor.commitments.AddReservedDBInstances(j.region, reserved)
+	return nil
+}
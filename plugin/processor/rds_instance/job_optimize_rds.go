@@ -14,6 +14,7 @@ import (
 	"github.com/opengovern/plugin-aws/plugin/version"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/wrapperspb"
+	"time"
 )
 
 type OptimizeRDSInstanceJob struct {
@@ -61,8 +62,9 @@ func (j *OptimizeRDSInstanceJob) Run(ctx context.Context) error {
 		storageThroughput = &floatThroughput
 	}
 
+	itemPreferences, preferenceOverrides := j.processor.preferenceRules.Apply(j.item.Preferences, shared.RDSTags(j.item.Instance.TagList))
 	preferencesMap := map[string]*wrapperspb.StringValue{}
-	for k, v := range preferences.Export(j.item.Preferences) {
+	for k, v := range preferences.Export(itemPreferences) {
 		preferencesMap[k] = nil
 		if v != nil {
 			preferencesMap[k] = wrapperspb.String(*v)
@@ -126,16 +128,24 @@ func (j *OptimizeRDSInstanceJob) Run(ctx context.Context) error {
 		j.processor.UpdateSummary(*j.item.Instance.DBInstanceIdentifier)
 		return nil
 	}
+	j.processor.pricing.ApplyRDSInstance(res.RightSizing, j.item.Region)
+	// DescribeDBInstances has no start or stop time, the runtime of RDS instances only comes from their metrics
+	runtime := shared.ObserveRuntime(j.item.Metrics["CPUUtilization"], j.item.Instance.InstanceCreateTime, nil, nil, j.processor.observabilityDays, time.Now())
+	runtime.ScaleRDSInstance(res.RightSizing)
+	j.processor.policy.ApplyRDSInstance(res.RightSizing, shared.RDSTags(j.item.Instance.TagList))
 
 	j.item = RDSInstanceItem{
 		Instance:            j.item.Instance,
 		Region:              j.item.Region,
 		OptimizationLoading: false,
 		Preferences:         j.item.Preferences,
+		Currency:            j.processor.currency,
 		Skipped:             false,
 		SkipReason:          "",
 		Metrics:             j.item.Metrics,
 		Wastage:             res,
+		Runtime:             runtime,
+		PreferenceOverrides: preferenceOverrides,
 	}
 	j.processor.items.Set(*j.item.Instance.DBInstanceIdentifier, j.item)
 	j.processor.publishOptimizationItem(j.item.ToOptimizationItem())
@@ -7,17 +7,23 @@ import (
 	"github.com/kaytu-io/kaytu/pkg/style"
 	"github.com/kaytu-io/kaytu/pkg/utils"
 	"github.com/opengovern/plugin-aws/plugin/aws"
+	"github.com/opengovern/plugin-aws/plugin/coverage"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
 	"github.com/opengovern/plugin-aws/plugin/kaytu"
-	"github.com/opengovern/plugin-aws/plugin/processor/ec2_instance"
+	"github.com/opengovern/plugin-aws/plugin/policy"
+	preferences2 "github.com/opengovern/plugin-aws/plugin/preferences"
+	"github.com/opengovern/plugin-aws/plugin/pricing"
 	"github.com/opengovern/plugin-aws/plugin/processor/shared"
 	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	summary2 "github.com/opengovern/plugin-aws/plugin/summary"
 	"strings"
 	"sync/atomic"
 )
 
 type Processor struct {
-	provider                *aws.AWS
-	metricProvider          *aws.CloudWatch
+	provider                aws.InventoryProvider
+	metricProvider          aws.MetricsProvider
 	identification          map[string]string
 	items                   utils.ConcurrentMap[string, RDSInstanceItem]
 	publishOptimizationItem func(item *golang.ChartOptimizationItem)
@@ -29,11 +35,16 @@ type Processor struct {
 	observabilityDays       int
 	client                  golang2.OptimizationClient
 
-	summary            *utils.ConcurrentMap[string, ec2_instance.EC2InstanceSummary]
+	summary            *utils.ConcurrentMap[string, summary2.Resource]
+	commitments        *coverage.Inventory
 	defaultPreferences []*golang.PreferenceItem
+	preferenceRules    preferences2.Rules
+	policy             *policy.Policy
+	pricing            *pricing.Adjustments
+	currency           *currency.Currency
 }
 
-func NewProcessor(provider *aws.AWS, metricProvider *aws.CloudWatch, identification map[string]string, publishOptimizationItem func(item *golang.ChartOptimizationItem), publishResultSummary func(summary *golang.ResultSummary), kaytuAcccessToken string, jobQueue *sdk.JobQueue, configurations *kaytu.Configuration, lazyloadCounter *atomic.Uint32, observabilityDays int, summary *utils.ConcurrentMap[string, ec2_instance.EC2InstanceSummary], preferences []*golang.PreferenceItem, client golang2.OptimizationClient) *Processor {
+func NewProcessor(provider aws.InventoryProvider, metricProvider aws.MetricsProvider, identification map[string]string, publishOptimizationItem func(item *golang.ChartOptimizationItem), publishResultSummary func(summary *golang.ResultSummary), kaytuAcccessToken string, jobQueue *sdk.JobQueue, configurations *kaytu.Configuration, lazyloadCounter *atomic.Uint32, observabilityDays int, summary *utils.ConcurrentMap[string, summary2.Resource], commitments *coverage.Inventory, preferences []*golang.PreferenceItem, preferenceRules preferences2.Rules, recommendationPolicy *policy.Policy, pricingAdjustments *pricing.Adjustments, displayCurrency *currency.Currency, client golang2.OptimizationClient) *Processor {
 	r := &Processor{
 		provider:                provider,
 		metricProvider:          metricProvider,
@@ -48,7 +59,12 @@ func NewProcessor(provider *aws.AWS, metricProvider *aws.CloudWatch, identificat
 		observabilityDays:       observabilityDays,
 		client:                  client,
 		summary:                 summary,
+		commitments:             commitments,
 		defaultPreferences:      preferences,
+		preferenceRules:         preferenceRules,
+		policy:                  recommendationPolicy,
+		pricing:                 pricingAdjustments,
+		currency:                displayCurrency,
 	}
 
 	jobQueue.Push(NewListAllRegionsJob(r))
@@ -66,10 +82,11 @@ func (m *Processor) ExportNonInteractive() *golang.NonInteractiveExport {
 	return nil
 }
 
-func (m *Processor) ExportCsv() []*golang.CSVRow {
+// ExportCsv returns the rows of the optimized instances, commitments is their coverage keyed by instance id.
+func (m *Processor) ExportCsv(commitments map[string]history.Coverage) []*golang.CSVRow {
 	var rows []*golang.CSVRow
 
-	m.summary.Range(func(id string, _ ec2_instance.EC2InstanceSummary) bool {
+	m.summary.Range(func(id string, _ summary2.Resource) bool {
 		if _, ok := m.items.Get(id); !ok {
 			fmt.Println("Skipping item", id)
 			return true
@@ -82,8 +99,8 @@ func (m *Processor) ExportCsv() []*golang.CSVRow {
 		var computeAdditionalDetails []string
 		var computeRightSizingCost, computeSaving, computeRecSpec string
 		if i.Wastage.RightSizing.Recommended != nil {
-			computeRightSizingCost = utils.FormatPriceFloat(i.Wastage.RightSizing.Recommended.ComputeCost)
-			computeSaving = utils.FormatPriceFloat(i.Wastage.RightSizing.Current.ComputeCost - i.Wastage.RightSizing.Recommended.ComputeCost)
+			computeRightSizingCost = m.currency.Format(i.Wastage.RightSizing.Recommended.ComputeCost)
+			computeSaving = m.currency.Format(i.Wastage.RightSizing.Current.ComputeCost - i.Wastage.RightSizing.Recommended.ComputeCost)
 			computeRecSpec = i.Wastage.RightSizing.Recommended.InstanceType
 
 			computeAdditionalDetails = append(computeAdditionalDetails,
@@ -100,7 +117,7 @@ func (m *Processor) ExportCsv() []*golang.CSVRow {
 					i.Wastage.RightSizing.Recommended.ClusterType))
 			computeAdditionalDetails = append(computeAdditionalDetails,
 				fmt.Sprintf("vCPU:: Current: %d - Avg: %s - Recommended: %d", i.Wastage.RightSizing.Current.Vcpu,
-					utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.Vcpu.Avg)), i.Wastage.RightSizing.Recommended.Vcpu))
+					utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.GetVcpu().GetAvg())), i.Wastage.RightSizing.Recommended.Vcpu))
 			computeAdditionalDetails = append(computeAdditionalDetails,
 				fmt.Sprintf("Processor(s):: Current: %s - Recommended: %s", i.Wastage.RightSizing.Current.Processor,
 					i.Wastage.RightSizing.Recommended.Processor))
@@ -109,11 +126,15 @@ func (m *Processor) ExportCsv() []*golang.CSVRow {
 					i.Wastage.RightSizing.Recommended.Architecture))
 			computeAdditionalDetails = append(computeAdditionalDetails,
 				fmt.Sprintf("Memory:: Current: %d GB - Avg: %s - Recommended: %d GB", i.Wastage.RightSizing.Current.MemoryGb,
-					utils.MemoryUsagePercentageByFreeSpace(shared.WrappedToFloat64(i.Wastage.RightSizing.FreeMemoryBytes.Avg), float64(i.Wastage.RightSizing.Current.MemoryGb)),
+					utils.MemoryUsagePercentageByFreeSpace(shared.WrappedToFloat64(i.Wastage.RightSizing.GetFreeMemoryBytes().GetAvg()), float64(i.Wastage.RightSizing.Current.MemoryGb)),
 					i.Wastage.RightSizing.Recommended.MemoryGb))
 		}
+		computeAdditionalDetails = append(computeAdditionalDetails, shared.PreferenceOverridesDetails(i.PreferenceOverrides, "RDSInstance")...)
+		if c, ok := commitments[*i.Instance.DBInstanceIdentifier]; ok {
+			computeAdditionalDetails = append(computeAdditionalDetails, shared.CoverageDetails(c, m.currency)...)
+		}
 		computeRow := []string{m.identification["account"], i.Region, "RDS Instance Compute", fmt.Sprintf("%s-compute", *i.Instance.DBInstanceIdentifier),
-			*i.Instance.DBInstanceIdentifier, platform, "730 hours", utils.FormatPriceFloat(i.Wastage.RightSizing.Current.ComputeCost),
+			*i.Instance.DBInstanceIdentifier, platform, i.Runtime.String(), m.currency.Format(i.Wastage.RightSizing.Current.ComputeCost),
 			computeRightSizingCost, computeSaving, i.Wastage.RightSizing.Current.InstanceType, computeRecSpec, *i.Instance.DBInstanceIdentifier,
 			i.Wastage.RightSizing.Description, strings.Join(computeAdditionalDetails, "---")}
 		rows = append(rows, &golang.CSVRow{Row: computeRow})
@@ -121,34 +142,33 @@ func (m *Processor) ExportCsv() []*golang.CSVRow {
 		var storageAdditionalDetails []string
 		var storageRightSizingCost, storageSaving, storageRecSpec string
 		if i.Wastage.RightSizing.Recommended != nil {
-			storageRightSizingCost = utils.FormatPriceFloat(i.Wastage.RightSizing.Recommended.StorageCost)
-			storageSaving = utils.FormatPriceFloat(i.Wastage.RightSizing.Current.StorageCost - i.Wastage.RightSizing.Recommended.StorageCost)
-			storageRecSpec = fmt.Sprintf("%s/%s/%s IOPS", *i.Wastage.RightSizing.Recommended.StorageType,
-				utils.SizeByteToGB(shared.WrappedToInt32(i.Wastage.RightSizing.Recommended.StorageSize)), utils.PInt32ToString(shared.WrappedToInt32(i.Wastage.RightSizing.Recommended.StorageIops)))
+			storageRightSizingCost = m.currency.Format(i.Wastage.RightSizing.Recommended.StorageCost)
+			storageSaving = m.currency.Format(i.Wastage.RightSizing.Current.StorageCost - i.Wastage.RightSizing.Recommended.StorageCost)
+			storageRecSpec = shared.RDSStorageSpec(i.Wastage.RightSizing.Recommended)
 
 			storageAdditionalDetails = append(storageAdditionalDetails,
 				fmt.Sprintf("Type:: Current: %s - Recommended: %s", utils.PString(shared.WrappedToString(i.Wastage.RightSizing.Current.StorageType)),
 					utils.PString(shared.WrappedToString(i.Wastage.RightSizing.Recommended.StorageType))))
 			storageAdditionalDetails = append(storageAdditionalDetails,
 				fmt.Sprintf("Size:: Current: %s - Avg : %s - Recommended: %s", utils.SizeByteToGB(shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)),
-					utils.StorageUsagePercentageByFreeSpace(shared.WrappedToFloat64(i.Wastage.RightSizing.FreeStorageBytes.Avg), shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)),
-					utils.SizeByteToGB(shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize))))
+					utils.StorageUsagePercentageByFreeSpace(shared.WrappedToFloat64(i.Wastage.RightSizing.GetFreeStorageBytes().GetAvg()), shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)),
+					utils.SizeByteToGB(shared.WrappedToInt32(i.Wastage.RightSizing.Recommended.StorageSize))))
 			storageAdditionalDetails = append(storageAdditionalDetails,
 				fmt.Sprintf("IOPS:: Current: %s - Avg: %s - Recommended: %s", utils.PInt32ToString(shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageIops)),
-					fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(i.Wastage.RightSizing.StorageIops.Avg))),
+					fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(i.Wastage.RightSizing.GetStorageIops().GetAvg()))),
 					utils.PInt32ToString(shared.WrappedToInt32(i.Wastage.RightSizing.Recommended.StorageIops))))
 			storageAdditionalDetails = append(storageAdditionalDetails,
-				fmt.Sprintf("Throughput:: Current: %s - Avg: %s - Recommended: %s", utils.PStorageThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.Current.StorageThroughput)),
-					utils.PStorageThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.StorageThroughput.Avg)), utils.PStorageThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.Recommended.StorageThroughput))))
+				fmt.Sprintf("Throughput:: Current: %s - Avg: %s - Recommended: %s", utils.PStorageThroughputMbps(shared.ThroughputMBpsToBytes(i.Wastage.RightSizing.Current.StorageThroughput)),
+					utils.PStorageThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.GetStorageThroughput().GetAvg())), utils.PStorageThroughputMbps(shared.ThroughputMBpsToBytes(i.Wastage.RightSizing.Recommended.StorageThroughput))))
 			storageAdditionalDetails = append(storageAdditionalDetails,
 				fmt.Sprintf("VolumeTypeChange:: %v", utils.PString(shared.WrappedToString(i.Wastage.RightSizing.Current.StorageType)) != utils.PString(shared.WrappedToString(i.Wastage.RightSizing.Recommended.StorageType))))
 			storageAdditionalDetails = append(storageAdditionalDetails,
-				fmt.Sprintf("VolumeSizeChange:: %v", *shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize) != *shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)))
+				fmt.Sprintf("VolumeSizeChange:: %v", i.Wastage.RightSizing.Current.StorageSize.GetValue() != i.Wastage.RightSizing.Recommended.StorageSize.GetValue()))
 		}
+		storageAdditionalDetails = append(storageAdditionalDetails, shared.PreferenceOverridesDetails(i.PreferenceOverrides, "RDSInstance")...)
 		storageRow := []string{m.identification["account"], i.Region, "RDS Instance Storage", fmt.Sprintf("%s-storage", *i.Instance.DBInstanceIdentifier),
-			*i.Instance.DBInstanceIdentifier, "N/A", "730 hours", utils.FormatPriceFloat(i.Wastage.RightSizing.Current.StorageCost),
-			storageRightSizingCost, storageSaving, fmt.Sprintf("%s/%s/%s IOPS", *shared.WrappedToString(i.Wastage.RightSizing.Current.StorageType),
-				utils.SizeByteToGB(shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)), utils.PInt32ToString(shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageIops))), storageRecSpec, *i.Instance.DBInstanceIdentifier,
+			*i.Instance.DBInstanceIdentifier, "N/A", "730 hours", m.currency.Format(i.Wastage.RightSizing.Current.StorageCost),
+			storageRightSizingCost, storageSaving, shared.RDSStorageSpec(i.Wastage.RightSizing.Current), storageRecSpec, *i.Instance.DBInstanceIdentifier,
 			i.Wastage.RightSizing.Description, strings.Join(storageAdditionalDetails, "---")}
 		rows = append(rows, &golang.CSVRow{Row: storageRow})
 
@@ -166,14 +186,14 @@ func (m *Processor) ResultsSummary() *golang.ResultSummary {
 	summary := &golang.ResultSummary{}
 	var totalCost, savings float64
 
-	m.summary.Range(func(_ string, item ec2_instance.EC2InstanceSummary) bool {
-		totalCost += item.CurrentRuntimeCost
+	m.summary.Range(func(_ string, item summary2.Resource) bool {
+		totalCost += item.CurrentCost
 		savings += item.Savings
 		return true
 	})
 
 	summary.Message = fmt.Sprintf("Current runtime cost: %s, Savings: %s",
-		style.CostStyle.Render(fmt.Sprintf("%s", utils.FormatPriceFloat(totalCost))), style.SavingStyle.Render(fmt.Sprintf("%s", utils.FormatPriceFloat(savings))))
+		style.CostStyle.Render(fmt.Sprintf("%s", m.currency.Format(totalCost))), style.SavingStyle.Render(fmt.Sprintf("%s", m.currency.Format(savings))))
 	return summary
 }
 
@@ -188,10 +208,49 @@ func (m *Processor) UpdateSummary(itemId string) {
 		totalSaving += i.Wastage.RightSizing.Current.StorageCost - i.Wastage.RightSizing.Recommended.StorageCost
 		totalCurrentCost += i.Wastage.RightSizing.Current.StorageCost
 
-		m.summary.Set(itemId, ec2_instance.EC2InstanceSummary{
-			CurrentRuntimeCost: totalCurrentCost,
-			Savings:            totalSaving,
-		})
+		resource := m.summaryResource(i, summary2.StatusAnalysed)
+		resource.CurrentCost = totalCurrentCost
+		resource.Savings = totalSaving
+		m.summary.Set(itemId, resource)
 	}
 	m.publishResultSummary(m.ResultsSummary())
 }
+
+// SummaryResources returns every instance of the run for the results summary, the ones still loading
+// failed to get their metrics or optimization.
+func (m *Processor) SummaryResources() []summary2.Resource {
+	var resources []summary2.Resource
+	m.items.Range(func(id string, i RDSInstanceItem) bool {
+		if resource, ok := m.summary.Get(id); ok {
+			resources = append(resources, resource)
+			return true
+		}
+		switch {
+		case i.Skipped || i.LazyLoadingEnabled:
+			resource := m.summaryResource(i, summary2.StatusSkipped)
+			resource.SkipReason = i.SkipReason
+			if resource.SkipReason == "" {
+				resource.SkipReason = summary2.ReasonNotLoaded
+			}
+			resources = append(resources, resource)
+		case i.OptimizationLoading:
+			resources = append(resources, m.summaryResource(i, summary2.StatusFailed))
+		default:
+			resources = append(resources, m.summaryResource(i, summary2.StatusAnalysed))
+		}
+		return true
+	})
+	return resources
+}
+
+func (m *Processor) summaryResource(i RDSInstanceItem, status summary2.Status) summary2.Resource {
+	return summary2.Resource{
+		Id:             *i.Instance.DBInstanceIdentifier,
+		Account:        m.identification["account"],
+		Region:         i.Region,
+		ResourceType:   "RDS Instance",
+		InstanceFamily: policy.Family(utils.PString(i.Instance.DBInstanceClass)),
+		Tags:           shared.RDSTags(i.Instance.TagList),
+		Status:         status,
+	}
+}
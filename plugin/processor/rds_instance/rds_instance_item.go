@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/rds/types"
 	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
 	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/currency"
 	"github.com/opengovern/plugin-aws/plugin/processor/shared"
 	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
 	"google.golang.org/protobuf/types/known/wrapperspb"
@@ -19,12 +20,18 @@ type RDSInstanceItem struct {
 	Region              string
 	OptimizationLoading bool
 	Preferences         []*golang.PreferenceItem
+	// Currency is the currency costs are shown in
+	Currency *currency.Currency
+	// PreferenceOverrides describes the preferences changed by tag rules for the last optimization
+	PreferenceOverrides []string
 	Skipped             bool
 	LazyLoadingEnabled  bool
 	SkipReason          string
 
 	Metrics map[string][]types2.Datapoint
 	Wastage *golang2.RDSInstanceOptimizationResponse
+	// Runtime is the observed runtime the compute costs are projected for
+	Runtime shared.Runtime
 }
 
 func (i RDSInstanceItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*golang.Properties) {
@@ -46,10 +53,10 @@ func (i RDSInstanceItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*go
 		Value: "RDS Instance Compute",
 	}
 	computeRow.Values["runtime"] = &golang.ChartRowItem{
-		Value: "730 hours",
+		Value: i.Runtime.String(),
 	}
 	computeRow.Values["current_cost"] = &golang.ChartRowItem{
-		Value: utils.FormatPriceFloat(i.Wastage.RightSizing.Current.ComputeCost),
+		Value: i.Currency.Format(i.Wastage.RightSizing.Current.ComputeCost),
 	}
 
 	storageRow := golang.ChartRow{
@@ -69,7 +76,7 @@ func (i RDSInstanceItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*go
 		Value: "730 hours",
 	}
 	storageRow.Values["current_cost"] = &golang.ChartRowItem{
-		Value: utils.FormatPriceFloat(i.Wastage.RightSizing.Current.StorageCost),
+		Value: i.Currency.Format(i.Wastage.RightSizing.Current.StorageCost),
 	}
 
 	regionProperty := &golang.Property{
@@ -95,8 +102,8 @@ func (i RDSInstanceItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*go
 	vCPUProperty := &golang.Property{
 		Key:     "vCPU",
 		Current: fmt.Sprintf("%d", i.Wastage.RightSizing.Current.Vcpu),
-		Average: utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.Vcpu.Avg)),
-		Max:     utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.Vcpu.Max)),
+		Average: utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.GetVcpu().GetAvg())),
+		Max:     utils.Percentage(shared.WrappedToFloat64(i.Wastage.RightSizing.GetVcpu().GetMax())),
 	}
 	processorProperty := &golang.Property{
 		Key:     "Processor(s)",
@@ -109,7 +116,7 @@ func (i RDSInstanceItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*go
 	memoryProperty := &golang.Property{
 		Key:     "Memory",
 		Current: fmt.Sprintf("%d GiB", i.Wastage.RightSizing.Current.MemoryGb),
-		Average: utils.MemoryUsagePercentageByFreeSpace(shared.WrappedToFloat64(i.Wastage.RightSizing.FreeMemoryBytes.Avg), float64(i.Wastage.RightSizing.Current.MemoryGb)),
+		Average: utils.MemoryUsagePercentageByFreeSpace(shared.WrappedToFloat64(i.Wastage.RightSizing.GetFreeMemoryBytes().GetAvg()), float64(i.Wastage.RightSizing.Current.MemoryGb)),
 	}
 	storageTypeProperty := &golang.Property{
 		Key:     "Type",
@@ -118,42 +125,45 @@ func (i RDSInstanceItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*go
 	storageSizeProperty := &golang.Property{
 		Key:     "Size",
 		Current: utils.SizeByteToGB(shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)),
-		Average: utils.StorageUsagePercentageByFreeSpace(shared.WrappedToFloat64(i.Wastage.RightSizing.FreeStorageBytes.Avg), shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)),
-		Max:     utils.StorageUsagePercentageByFreeSpace(shared.WrappedToFloat64(i.Wastage.RightSizing.FreeStorageBytes.Min), shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)),
-	}
-	if strings.Contains(strings.ToLower(i.Wastage.RightSizing.Current.Engine), "aurora") {
-		avgPercentage := (*shared.WrappedToFloat64(i.Wastage.RightSizing.VolumeBytesUsed.Avg) / (1024.0 * 1024.0 * 1024.0)) / float64(*shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)) * 100
-		maxPercentage := (*shared.WrappedToFloat64(i.Wastage.RightSizing.VolumeBytesUsed.Max) / (1024.0 * 1024.0 * 1024.0)) / float64(*shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)) * 100
+		Average: utils.StorageUsagePercentageByFreeSpace(shared.WrappedToFloat64(i.Wastage.RightSizing.GetFreeStorageBytes().GetAvg()), shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)),
+		Max:     utils.StorageUsagePercentageByFreeSpace(shared.WrappedToFloat64(i.Wastage.RightSizing.GetFreeStorageBytes().GetMin()), shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)),
+	}
+	if strings.Contains(strings.ToLower(i.Wastage.RightSizing.Current.Engine), "aurora") &&
+		i.Wastage.RightSizing.GetVolumeBytesUsed().GetAvg() != nil && i.Wastage.RightSizing.GetVolumeBytesUsed().GetMax() != nil &&
+		i.Wastage.RightSizing.Current.StorageSize.GetValue() > 0 {
+		avgPercentage := (*shared.WrappedToFloat64(i.Wastage.RightSizing.GetVolumeBytesUsed().GetAvg()) / (1024.0 * 1024.0 * 1024.0)) / float64(*shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)) * 100
+		maxPercentage := (*shared.WrappedToFloat64(i.Wastage.RightSizing.GetVolumeBytesUsed().GetMax()) / (1024.0 * 1024.0 * 1024.0)) / float64(*shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageSize)) * 100
 		storageSizeProperty.Average = utils.Percentage(&avgPercentage)
 		storageSizeProperty.Max = utils.Percentage(&maxPercentage)
 	}
 	storageIOPSProperty := &golang.Property{
 		Key:     "IOPS",
 		Current: utils.PInt32ToString(shared.WrappedToInt32(i.Wastage.RightSizing.Current.StorageIops)),
-		Average: fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(i.Wastage.RightSizing.StorageIops.Avg))),
-		Max:     fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(i.Wastage.RightSizing.StorageIops.Max))),
+		Average: fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(i.Wastage.RightSizing.GetStorageIops().GetAvg()))),
+		Max:     fmt.Sprintf("%s io/s", utils.PFloat64ToString(shared.WrappedToFloat64(i.Wastage.RightSizing.GetStorageIops().GetMax()))),
 	}
 	if storageIOPSProperty.Current != "" {
 		storageIOPSProperty.Current = fmt.Sprintf("%s io/s", storageIOPSProperty.Current)
 	} else {
 		storageIOPSProperty.Current = ""
 	}
-	if i.Wastage.RightSizing.StorageIops.Avg == nil {
+	if i.Wastage.RightSizing.GetStorageIops().GetAvg() == nil {
 		storageIOPSProperty.Average = ""
 	}
-	if i.Wastage.RightSizing.StorageIops.Max == nil {
+	if i.Wastage.RightSizing.GetStorageIops().GetMax() == nil {
 		storageIOPSProperty.Max = ""
 	}
 	// current number is in MB/s, so we need to convert it to bytes/s so matches the other values
+	var currentThroughput *float64
 	if i.Wastage.RightSizing.Current.StorageThroughput != nil {
-		v := *shared.WrappedToFloat64(i.Wastage.RightSizing.Current.StorageThroughput) * 1024.0 * 1024.0
-		i.Wastage.RightSizing.Current.StorageThroughput = shared.Float64ToWrapper(&v)
+		v := i.Wastage.RightSizing.Current.StorageThroughput.GetValue() * 1024.0 * 1024.0
+		currentThroughput = &v
 	}
 	storageThroughputProperty := &golang.Property{
 		Key:     "Throughput",
-		Current: utils.PStorageThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.Current.StorageThroughput)),
-		Average: utils.PStorageThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.StorageThroughput.Avg)),
-		Max:     utils.PStorageThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.StorageThroughput.Max)),
+		Current: utils.PStorageThroughputMbps(currentThroughput),
+		Average: utils.PStorageThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.GetStorageThroughput().GetAvg())),
+		Max:     utils.PStorageThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.GetStorageThroughput().GetMax())),
 	}
 	runtimeProperty := &golang.Property{
 		Key:     "RuntimeHours",
@@ -165,14 +175,14 @@ func (i RDSInstanceItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*go
 	for k, v := range i.Wastage.RightSizing.Current.ComputeCostComponents {
 		computeCostComponentPropertiesMap[k] = &golang.Property{
 			Key:     fmt.Sprintf("  %s", k),
-			Current: fmt.Sprintf("$%.2f", v),
+			Current: i.Currency.Format(v),
 		}
 	}
 	storageCostComponentPropertiesMap := make(map[string]*golang.Property)
 	for k, v := range i.Wastage.RightSizing.Current.StorageCostComponents {
 		storageCostComponentPropertiesMap[k] = &golang.Property{
 			Key:     fmt.Sprintf("  %s", k),
-			Current: fmt.Sprintf("$%.2f", v),
+			Current: i.Currency.Format(v),
 		}
 	}
 
@@ -180,16 +190,16 @@ func (i RDSInstanceItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*go
 		processorProperty.Recommended = i.Wastage.RightSizing.Recommended.Processor
 		architectureProperty.Recommended = i.Wastage.RightSizing.Recommended.Architecture
 		computeRow.Values["right_sized_cost"] = &golang.ChartRowItem{
-			Value: utils.FormatPriceFloat(i.Wastage.RightSizing.Recommended.ComputeCost),
+			Value: i.Currency.Format(i.Wastage.RightSizing.Recommended.ComputeCost),
 		}
 		computeRow.Values["savings"] = &golang.ChartRowItem{
-			Value: utils.FormatPriceFloat(i.Wastage.RightSizing.Current.ComputeCost - i.Wastage.RightSizing.Recommended.ComputeCost),
+			Value: i.Currency.Format(i.Wastage.RightSizing.Current.ComputeCost - i.Wastage.RightSizing.Recommended.ComputeCost),
 		}
 		storageRow.Values["right_sized_cost"] = &golang.ChartRowItem{
-			Value: utils.FormatPriceFloat(i.Wastage.RightSizing.Recommended.StorageCost),
+			Value: i.Currency.Format(i.Wastage.RightSizing.Recommended.StorageCost),
 		}
 		storageRow.Values["savings"] = &golang.ChartRowItem{
-			Value: utils.FormatPriceFloat(i.Wastage.RightSizing.Current.StorageCost - i.Wastage.RightSizing.Recommended.StorageCost),
+			Value: i.Currency.Format(i.Wastage.RightSizing.Current.StorageCost - i.Wastage.RightSizing.Recommended.StorageCost),
 		}
 		regionProperty.Recommended = i.Wastage.RightSizing.Recommended.Region
 		instanceSizeProperty.Recommended = i.Wastage.RightSizing.Recommended.InstanceType
@@ -207,30 +217,31 @@ func (i RDSInstanceItem) RDSInstanceDevice() ([]*golang.ChartRow, map[string]*go
 			storageIOPSProperty.Recommended = "N/A"
 		}
 		// Recommended number is in MB/s, so we need to convert it to bytes/s so matches the other values
+		var recommendedThroughput *float64
 		if i.Wastage.RightSizing.Recommended.StorageThroughput != nil {
-			v := *shared.WrappedToFloat64(i.Wastage.RightSizing.Recommended.StorageThroughput) * 1024.0 * 1024.0
-			i.Wastage.RightSizing.Recommended.StorageThroughput = shared.Float64ToWrapper(&v)
+			v := i.Wastage.RightSizing.Recommended.StorageThroughput.GetValue() * 1024.0 * 1024.0
+			recommendedThroughput = &v
 		}
-		storageThroughputProperty.Recommended = utils.PStorageThroughputMbps(shared.WrappedToFloat64(i.Wastage.RightSizing.Recommended.StorageThroughput))
+		storageThroughputProperty.Recommended = utils.PStorageThroughputMbps(recommendedThroughput)
 
 		for k, v := range i.Wastage.RightSizing.Recommended.ComputeCostComponents {
 			if _, ok := computeCostComponentPropertiesMap[k]; !ok {
 				computeCostComponentPropertiesMap[k] = &golang.Property{
 					Key:         fmt.Sprintf("  %s", k),
-					Recommended: fmt.Sprintf("$%.2f", v),
+					Recommended: i.Currency.Format(v),
 				}
 			} else {
-				computeCostComponentPropertiesMap[k].Recommended = fmt.Sprintf("$%.2f", v)
+				computeCostComponentPropertiesMap[k].Recommended = i.Currency.Format(v)
 			}
 		}
 		for k, v := range i.Wastage.RightSizing.Recommended.StorageCostComponents {
 			if _, ok := storageCostComponentPropertiesMap[k]; !ok {
 				storageCostComponentPropertiesMap[k] = &golang.Property{
 					Key:         fmt.Sprintf("  %s", k),
-					Recommended: fmt.Sprintf("$%.2f", v),
+					Recommended: i.Currency.Format(v),
 				}
 			} else {
-				storageCostComponentPropertiesMap[k].Recommended = fmt.Sprintf("$%.2f", v)
+				storageCostComponentPropertiesMap[k].Recommended = i.Currency.Format(v)
 			}
 		}
 	}
@@ -318,14 +329,14 @@ func (i RDSInstanceItem) ToOptimizationItem() *golang.ChartOptimizationItem {
 		status = "press enter to load"
 	} else if i.OptimizationLoading {
 		status = "loading"
-	} else if i.Wastage.RightSizing.Recommended != nil {
+	} else if i.Wastage != nil && i.Wastage.RightSizing != nil && i.Wastage.RightSizing.Recommended != nil {
 		totalSaving := 0.0
 		totalCurrentCost := 0.0
 		totalSaving += i.Wastage.RightSizing.Current.ComputeCost - i.Wastage.RightSizing.Recommended.ComputeCost
 		totalCurrentCost += i.Wastage.RightSizing.Current.ComputeCost
 		totalSaving += i.Wastage.RightSizing.Current.StorageCost - i.Wastage.RightSizing.Recommended.StorageCost
 		totalCurrentCost += i.Wastage.RightSizing.Current.StorageCost
-		status = fmt.Sprintf("%s (%.2f%%)", utils.FormatPriceFloat(totalSaving), (totalSaving/totalCurrentCost)*100)
+		status = fmt.Sprintf("%s (%.2f%%)", i.Currency.Format(totalSaving), (totalSaving/totalCurrentCost)*100)
 	}
 
 	deviceRows, deviceProps := i.Devices()
@@ -0,0 +1,19 @@
+package rds_instance
+
+import (
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/processor/shared"
+)
+
+// Records returns the outcome of every optimized instance, including the ones without recommendation.
+func (m *Processor) Records() []history.Record {
+	var records []history.Record
+	m.items.Range(func(_ string, i RDSInstanceItem) bool {
+		if i.Skipped || i.OptimizationLoading || i.Wastage == nil {
+			return true
+		}
+		records = append(records, shared.RDSRecords(i.Region, i.Instance, shared.RDSTags(i.Instance.TagList), i.Wastage.RightSizing, i.Runtime)...)
+		return true
+	})
+	return records
+}
@@ -0,0 +1,21 @@
+package rds_instance
+
+import (
+	"github.com/opengovern/plugin-aws/plugin/processor/shared"
+	"github.com/opengovern/plugin-aws/plugin/report"
+)
+
+// ReportItems returns every instance of the run for the HTML report, with the utilization of its compute and
+// storage.
+func (m *Processor) ReportItems() []report.Item {
+	var items []report.Item
+	m.items.Range(func(_ string, i RDSInstanceItem) bool {
+		items = append(items, report.Item{
+			Service:      "RDS",
+			Optimization: i.ToOptimizationItem(),
+			Utilization:  shared.RDSUtilization(*i.Instance.DBInstanceIdentifier, i.Metrics),
+		})
+		return true
+	})
+	return items
+}
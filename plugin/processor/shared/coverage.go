@@ -0,0 +1,18 @@
+package shared
+
+import (
+	"fmt"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"strings"
+)
+
+// CoverageDetails are the additional details of a CSV row of an instance covered by commitments.
+func CoverageDetails(c history.Coverage, displayCurrency *currency.Currency) []string {
+	details := []string{fmt.Sprintf("Commitment Coverage:: %s - Covered: %s - Effective Savings: %s",
+		strings.Join(c.Commitments, ", "), displayCurrency.Format(c.CoveredCost), displayCurrency.Format(c.EffectiveSavings))}
+	for _, w := range c.Warnings {
+		details = append(details, fmt.Sprintf("Commitment Warning:: %s", w))
+	}
+	return details
+}
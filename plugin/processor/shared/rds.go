@@ -0,0 +1,135 @@
+package shared
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"github.com/opengovern/plugin-aws/plugin/remediation"
+	"github.com/opengovern/plugin-aws/plugin/report"
+	"strings"
+	"time"
+)
+
+func RDSStorageSpec(v *golang2.RightsizingAwsRds) string {
+	return fmt.Sprintf("%s/%s/%s IOPS", utils.PString(WrappedToString(v.StorageType)),
+		utils.SizeByteToGB(WrappedToInt32(v.StorageSize)), utils.PInt32ToString(WrappedToInt32(v.StorageIops)))
+}
+
+// RDSRecords splits the recommendation of an RDS instance into its compute and storage records, the same way the CSV export does.
+// Both are tagged with tags, the compute is priced for runtime.
+func RDSRecords(region string, instance types.DBInstance, tags map[string]string, rightSizing *golang2.RDSInstanceRightSizingRecommendation, runtime Runtime) []history.Record {
+	if rightSizing == nil || rightSizing.Current == nil {
+		return nil
+	}
+	instanceId := aws.ToString(instance.DBInstanceIdentifier)
+	compute := history.Record{
+		ResourceType: "RDS Instance Compute",
+		ResourceId:   fmt.Sprintf("%s-compute", instanceId),
+		ResourceName: instanceId,
+		Region:       region,
+		ParentId:     instanceId,
+		CurrentSpec:  rightSizing.Current.InstanceType,
+		CurrentType:  rightSizing.Current.InstanceType,
+		CurrentCost:  rightSizing.Current.ComputeCost,
+		Description:  rightSizing.Description,
+		Platform:     rightSizing.Current.Engine,
+		MultiAZ:      aws.ToBool(instance.MultiAZ),
+		RuntimeHours: runtime.MonthlyHours(),
+		Tags:         tags,
+		Utilization: map[string]history.Utilization{
+			"cpu":    UsageToUtilization(rightSizing.Vcpu, history.UnitPercent),
+			"memory": usedPercentage(rightSizing.FreeMemoryBytes, float64(rightSizing.Current.MemoryGb)),
+		},
+	}
+	if instance.InstanceCreateTime != nil {
+		compute.AgeHours = time.Since(*instance.InstanceCreateTime).Hours()
+	}
+	storage := history.Record{
+		ResourceType: "RDS Instance Storage",
+		ResourceId:   fmt.Sprintf("%s-storage", instanceId),
+		ResourceName: instanceId,
+		Region:       region,
+		ParentId:     instanceId,
+		CurrentSpec:  RDSStorageSpec(rightSizing.Current),
+		CurrentType:  utils.PString(WrappedToString(rightSizing.Current.StorageType)),
+		CurrentCost:  rightSizing.Current.StorageCost,
+		Description:  rightSizing.Description,
+		Tags:         tags,
+		Utilization: map[string]history.Utilization{
+			"storage": usedPercentage(rightSizing.FreeStorageBytes, float64(rightSizing.Current.StorageSize.GetValue())),
+			"iops":    UsageToUtilization(rightSizing.StorageIops, "io/s"),
+		},
+	}
+	if strings.Contains(strings.ToLower(rightSizing.Current.Engine), "aurora") {
+		// aurora storage grows with the data, free storage is not reported
+		delete(storage.Utilization, "storage")
+	} else {
+		storage.CurrentSize = WrappedToInt32(rightSizing.Current.StorageSize)
+	}
+	if rightSizing.Recommended != nil {
+		compute.RecommendedSpec = rightSizing.Recommended.InstanceType
+		compute.RecommendedType = rightSizing.Recommended.InstanceType
+		compute.RecommendedCost = rightSizing.Recommended.ComputeCost
+		compute.Savings = rightSizing.Current.ComputeCost - rightSizing.Recommended.ComputeCost
+		storage.RecommendedSpec = RDSStorageSpec(rightSizing.Recommended)
+		storage.RecommendedType = utils.PString(WrappedToString(rightSizing.Recommended.StorageType))
+		if !strings.Contains(strings.ToLower(rightSizing.Recommended.Engine), "aurora") {
+			storage.RecommendedSize = WrappedToInt32(rightSizing.Recommended.StorageSize)
+		}
+		switch storage.RecommendedType {
+		case "gp3":
+			// below the threshold of the engine gp3 runs at its baseline, setting IOPS or throughput is rejected
+			size := WrappedToInt32(rightSizing.Recommended.StorageSize)
+			if size != nil && *size >= remediation.RDSGp3ProvisionedStorage(rightSizing.Recommended.Engine) {
+				storage.RecommendedIops = WrappedToInt32(rightSizing.Recommended.StorageIops)
+				storage.RecommendedThroughput = WrappedToFloat64(rightSizing.Recommended.StorageThroughput)
+			}
+		case "io1", "io2":
+			storage.RecommendedIops = WrappedToInt32(rightSizing.Recommended.StorageIops)
+		}
+		storage.RecommendedCost = rightSizing.Recommended.StorageCost
+		storage.Savings = rightSizing.Current.StorageCost - rightSizing.Recommended.StorageCost
+	}
+	return []history.Record{compute, storage}
+}
+
+// RDSUtilization splits the metrics of an RDS instance between its compute and storage device rows for the
+// HTML report.
+func RDSUtilization(instanceId string, metrics map[string][]types2.Datapoint) map[string][]report.Series {
+	return map[string][]report.Series{
+		fmt.Sprintf("%s-compute", instanceId): report.NewSeries(metrics, "CPUUtilization", "FreeableMemory",
+			"NetworkReceiveThroughput", "NetworkTransmitThroughput"),
+		fmt.Sprintf("%s-storage", instanceId): report.NewSeries(metrics, "FreeStorageSpace", "VolumeBytesUsed",
+			"ReadIOPS", "WriteIOPS", "ReadThroughput", "WriteThroughput"),
+	}
+}
+
+func UsageToUtilization(usage *golang2.Usage, unit string) history.Utilization {
+	return history.Utilization{
+		Avg:  WrappedToFloat64(usage.GetAvg()),
+		Max:  WrappedToFloat64(usage.GetMax()),
+		Unit: unit,
+	}
+}
+
+// usedPercentage turns free bytes into used percent of a total given in GiB, the peak usage is the minimum free space.
+func usedPercentage(free *golang2.Usage, totalGb float64) history.Utilization {
+	utilization := history.Utilization{Unit: history.UnitPercent}
+	if totalGb <= 0 {
+		return utilization
+	}
+	used := func(v *float64) *float64 {
+		if v == nil {
+			return nil
+		}
+		p := (1 - *v/(totalGb*1024*1024*1024)) * 100
+		return &p
+	}
+	utilization.Avg = used(WrappedToFloat64(free.GetAvg()))
+	utilization.Max = used(WrappedToFloat64(free.GetMin()))
+	return utilization
+}
@@ -0,0 +1,135 @@
+package shared
+
+import (
+	"fmt"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"math"
+	"regexp"
+	"time"
+)
+
+// HoursPerMonth is the runtime the optimization service prices instances for.
+const HoursPerMonth = 730
+
+// Runtime is how many hours an instance ran during the part of the observability window it existed in.
+// The zero value is a runtime that could not be observed, priced as running the whole month.
+type Runtime struct {
+	ObservedHours float64
+	WindowHours   float64
+}
+
+// ObserveRuntime counts the hours of the observability window an instance ran in, the hours with at least
+// one datapoint of a metric only reported while it runs, such as CPUUtilization, and the ones between its
+// last launch and when it stopped, or now when it did not. The window starts when the instance was created
+// if that is later, so instances created during it are not taken for stopped before. Without any datapoint
+// in the window, such as with replayed metrics, the runtime is unknown.
+func ObserveRuntime(datapoints []types2.Datapoint, created, launched, stopped *time.Time, days int, now time.Time) Runtime {
+	start := now.Add(-time.Duration(days*24) * time.Hour)
+	if created != nil && created.After(start) {
+		start = *created
+	}
+	window := now.Sub(start).Hours()
+	if window < 1 {
+		return Runtime{}
+	}
+
+	hours := map[int64]bool{}
+	for _, dp := range datapoints {
+		if dp.Timestamp == nil || dp.Timestamp.Before(start.Truncate(time.Hour)) || dp.Timestamp.After(now) {
+			continue
+		}
+		hours[dp.Timestamp.Truncate(time.Hour).Unix()] = true
+	}
+	if len(hours) == 0 {
+		return Runtime{}
+	}
+	if launched != nil {
+		from, until := *launched, now
+		if from.Before(start) {
+			from = start
+		}
+		if stopped != nil && stopped.Before(until) {
+			until = *stopped
+		}
+		for t := from.Truncate(time.Hour); t.Before(until); t = t.Add(time.Hour) {
+			hours[t.Unix()] = true
+		}
+	}
+	return Runtime{ObservedHours: math.Min(float64(len(hours)), window), WindowHours: window}
+}
+
+// stateTransitionTime matches the time in the state transition reason of EC2 instances, such as
+// "User initiated (2024-03-07 18:00:00 GMT)".
+var stateTransitionTime = regexp.MustCompile(`\((\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) GMT\)`)
+
+// EC2StateTransitionTime returns the time of the last state transition of an EC2 instance from its reason,
+// nil when the reason does not have one.
+func EC2StateTransitionTime(reason string) *time.Time {
+	match := stateTransitionTime.FindStringSubmatch(reason)
+	if match == nil {
+		return nil
+	}
+	t, err := time.Parse("2006-01-02 15:04:05", match[1])
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// MonthlyHours is the runtime projected over a month, the share of the window the instance ran in.
+func (r Runtime) MonthlyHours() float64 {
+	if r.WindowHours <= 0 {
+		return HoursPerMonth
+	}
+	return HoursPerMonth * math.Min(r.ObservedHours/r.WindowHours, 1)
+}
+
+func (r Runtime) String() string {
+	return fmt.Sprintf("%.0f hours", r.MonthlyHours())
+}
+
+func (r Runtime) factor() float64 {
+	return r.MonthlyHours() / HoursPerMonth
+}
+
+// ScaleEC2Instance prices the current and recommended instance of rec for the monthly runtime instead of
+// a whole month.
+func (r Runtime) ScaleEC2Instance(rec *golang2.EC2InstanceRightSizingRecommendation) {
+	factor := r.factor()
+	if rec == nil || factor == 1 {
+		return
+	}
+	for _, instance := range []*golang2.RightsizingEC2Instance{rec.Current, rec.Recommended} {
+		if instance == nil {
+			continue
+		}
+		instance.Cost *= factor
+		instance.LicensePrice *= factor
+		for k := range instance.CostComponents {
+			instance.CostComponents[k] *= factor
+		}
+	}
+}
+
+// ScaleRDSInstance prices the compute of the current and recommended instance of rec for the monthly
+// runtime, storage is paid for whether the instance runs or not.
+func (r Runtime) ScaleRDSInstance(rec *golang2.RDSInstanceRightSizingRecommendation) {
+	factor := r.factor()
+	if rec == nil || factor == 1 {
+		return
+	}
+	for _, instance := range []*golang2.RightsizingAwsRds{rec.Current, rec.Recommended} {
+		if instance == nil {
+			continue
+		}
+		instance.Cost -= instance.ComputeCost * (1 - factor)
+		instance.ComputeCost *= factor
+		for k := range instance.ComputeCostComponents {
+			instance.ComputeCostComponents[k] *= factor
+			if _, ok := instance.CostComponents[k]; ok {
+				instance.CostComponents[k] *= factor
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package shared
+
+import (
+	"fmt"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"strings"
+)
+
+func EC2Tags(tags []ec2types.Tag) map[string]string {
+	result := map[string]string{}
+	for _, t := range tags {
+		if t.Key != nil && t.Value != nil {
+			result[*t.Key] = *t.Value
+		}
+	}
+	return result
+}
+
+func RDSTags(tags []rdstypes.Tag) map[string]string {
+	result := map[string]string{}
+	for _, t := range tags {
+		if t.Key != nil && t.Value != nil {
+			result[*t.Key] = *t.Value
+		}
+	}
+	return result
+}
+
+// InheritTags returns the tags of a resource with the ones of its parent it does not set itself, such as
+// the tags of the instance a volume is attached to.
+func InheritTags(tags, parent map[string]string) map[string]string {
+	result := map[string]string{}
+	for k, v := range parent {
+		result[k] = v
+	}
+	for k, v := range tags {
+		result[k] = v
+	}
+	return result
+}
+
+// PreferenceOverridesDetails is the additional detail of a CSV row listing the preferences of service that
+// tag rules changed, nil when there are none.
+func PreferenceOverridesDetails(overrides []string, service string) []string {
+	var changed []string
+	for _, o := range overrides {
+		if strings.HasPrefix(o, service+".") {
+			changed = append(changed, strings.TrimPrefix(o, service+"."))
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("Preference Overrides:: %s", strings.Join(changed, ", "))}
+}
@@ -44,6 +44,16 @@ func Float64ToWrapper(v *float64) *wrapperspb.DoubleValue {
 	return wrapperspb.Double(*v)
 }
 
+// ThroughputMBpsToBytes converts a storage throughput the optimization service reports in MB/s to bytes/s, the
+// unit of the CloudWatch throughput it is shown next to.
+func ThroughputMBpsToBytes(v *wrapperspb.DoubleValue) *float64 {
+	if v == nil {
+		return nil
+	}
+	tmp := v.GetValue() * 1024.0 * 1024.0
+	return &tmp
+}
+
 func StringToWrapper(v *string) *wrapperspb.StringValue {
 	if v == nil {
 		return nil
@@ -0,0 +1,148 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	step = time.Minute
+	// rate() needs at least two samples, 2m covers scrape intervals up to a minute
+	rateWindow = "2m"
+
+	ignoredNetworkDevices = "lo|veth.*|docker.*|br-.*|cali.*|flannel.*|cni.*|vxlan.*|tunl.*"
+)
+
+// GetInstanceMetrics queries node_exporter metrics of the given instance and converts them into the
+// CloudWatch metric names and datapoint shape produced by the CloudWatch metrics source.
+func (p *Prometheus) GetInstanceMetrics(ctx context.Context, _ string, instance types.Instance, volumes []types.Volume, days int) (map[string][]types2.Datapoint, map[string]map[string][]types2.Datapoint, error) {
+	selector, err := p.instanceSelector(instance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	instanceQueries := []struct {
+		metric string
+		query  string
+		rate   bool
+	}{
+		{
+			metric: "CPUUtilization",
+			query:  fmt.Sprintf(`100 * (1 - avg(rate(node_cpu_seconds_total{mode="idle",%s}[%s])))`, selector, rateWindow),
+		},
+		{
+			metric: "mem_used_percent",
+			query:  fmt.Sprintf(`100 * (1 - sum(node_memory_MemAvailable_bytes{%[1]s}) / sum(node_memory_MemTotal_bytes{%[1]s}))`, selector),
+		},
+		{
+			metric: "NetworkIn",
+			query:  fmt.Sprintf(`sum(rate(node_network_receive_bytes_total{device!~"%s",%s}[%s]))`, ignoredNetworkDevices, selector, rateWindow),
+			rate:   true,
+		},
+		{
+			metric: "NetworkOut",
+			query:  fmt.Sprintf(`sum(rate(node_network_transmit_bytes_total{device!~"%s",%s}[%s]))`, ignoredNetworkDevices, selector, rateWindow),
+			rate:   true,
+		},
+	}
+
+	instanceMetrics := map[string][]types2.Datapoint{}
+	for _, q := range instanceQueries {
+		series, err := p.QueryRangeDayByDay(ctx, q.query, days, step)
+		if err != nil {
+			return nil, nil, err
+		}
+		var datapoints []types2.Datapoint
+		for _, s := range series {
+			datapoints = append(datapoints, toDatapoints(s.Samples, q.rate)...)
+		}
+		instanceMetrics[q.metric] = datapoints
+	}
+
+	attached := map[string]bool{}
+	for _, v := range volumes {
+		if v.VolumeId != nil {
+			attached[*v.VolumeId] = true
+		}
+	}
+
+	volumeQueries := map[string]string{
+		"VolumeReadBytes":  "node_disk_read_bytes_total",
+		"VolumeWriteBytes": "node_disk_written_bytes_total",
+		"VolumeReadOps":    "node_disk_reads_completed_total",
+		"VolumeWriteOps":   "node_disk_writes_completed_total",
+	}
+
+	volumeMetrics := map[string]map[string][]types2.Datapoint{}
+	for metric, counter := range volumeQueries {
+		// EBS volumes show up as NVMe devices whose serial is the volume id without the dash
+		query := fmt.Sprintf(`sum by (serial) (rate(%[1]s{%[2]s}[%[3]s]) * on (device) group_left (serial) node_disk_info{%[2]s})`,
+			counter, selector, rateWindow)
+		series, err := p.QueryRangeDayByDay(ctx, query, days, step)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, s := range series {
+			volumeId := serialToVolumeId(s.Labels["serial"])
+			if !attached[volumeId] {
+				continue
+			}
+			if _, ok := volumeMetrics[volumeId]; !ok {
+				volumeMetrics[volumeId] = map[string][]types2.Datapoint{}
+			}
+			volumeMetrics[volumeId][metric] = append(volumeMetrics[volumeId][metric], toDatapoints(s.Samples, true)...)
+		}
+	}
+
+	return instanceMetrics, volumeMetrics, nil
+}
+
+func (p *Prometheus) instanceSelector(instance types.Instance) (string, error) {
+	switch p.instanceMatch {
+	case InstanceMatchInstanceID:
+		if instance.InstanceId == nil {
+			return "", fmt.Errorf("instance has no id")
+		}
+		return fmt.Sprintf(`%s="%s"`, p.instanceLabel, *instance.InstanceId), nil
+	default:
+		if instance.PrivateIpAddress == nil {
+			return "", fmt.Errorf("instance %s has no private ip to match prometheus targets", aws.ToString(instance.InstanceId))
+		}
+		// node_exporter targets are usually scraped as <ip>:<port>
+		return fmt.Sprintf(`%s=~"%s(:[0-9]+)?"`, p.instanceLabel, strings.ReplaceAll(regexp.QuoteMeta(*instance.PrivateIpAddress), `\`, `\\`)), nil
+	}
+}
+
+func serialToVolumeId(serial string) string {
+	if strings.HasPrefix(serial, "vol") && !strings.HasPrefix(serial, "vol-") {
+		return "vol-" + strings.TrimPrefix(serial, "vol")
+	}
+	return serial
+}
+
+// toDatapoints converts samples into datapoints, rates are per second values so Sum and SampleCount
+// are filled the same way GetDatapointsAvgFromSum expects them for one minute periods.
+func toDatapoints(samples []Sample, rate bool) []types2.Datapoint {
+	var datapoints []types2.Datapoint
+	for _, s := range samples {
+		value := s.Value
+		dp := types2.Datapoint{
+			Timestamp: aws.Time(s.Timestamp),
+			Average:   aws.Float64(value),
+			Maximum:   aws.Float64(value),
+			Minimum:   aws.Float64(value),
+		}
+		if rate {
+			dp.Sum = aws.Float64(value * step.Seconds())
+			dp.SampleCount = aws.Float64(1)
+		}
+		datapoints = append(datapoints, dp)
+	}
+	return datapoints
+}
@@ -0,0 +1,186 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type InstanceMatch string
+
+const (
+	InstanceMatchPrivateIP  InstanceMatch = "private-ip"
+	InstanceMatchInstanceID InstanceMatch = "instance-id"
+)
+
+type Sample struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+type Series struct {
+	Labels  map[string]string
+	Samples []Sample
+}
+
+type Prometheus struct {
+	url           *url.URL
+	instanceLabel string
+	instanceMatch InstanceMatch
+	client        *http.Client
+}
+
+func NewPrometheus(address, instanceLabel string, instanceMatch InstanceMatch) (*Prometheus, error) {
+	if address == "" {
+		return nil, fmt.Errorf("prometheus url is required when using prometheus as metrics source")
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prometheus url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid prometheus url %s: scheme must be http or https", address)
+	}
+	if instanceLabel == "" {
+		instanceLabel = "instance"
+	}
+	switch instanceMatch {
+	case "":
+		instanceMatch = InstanceMatchPrivateIP
+	case InstanceMatchPrivateIP, InstanceMatchInstanceID:
+	default:
+		return nil, fmt.Errorf("invalid prometheus instance match %s, must be %s or %s", instanceMatch, InstanceMatchPrivateIP, InstanceMatchInstanceID)
+	}
+
+	return &Prometheus{
+		url:           u,
+		instanceLabel: instanceLabel,
+		instanceMatch: instanceMatch,
+		client:        &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+type queryRangeResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][2]any          `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// QueryRange runs a range query against the /api/v1/query_range endpoint and returns the resulting matrix.
+func (p *Prometheus) QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]Series, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	u := p.url.JoinPath("api", "v1", "query_range")
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prometheus: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res queryRangeResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("failed to parse prometheus response (status %d): %w", resp.StatusCode, err)
+	}
+	if res.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed: %s: %s", res.ErrorType, res.Error)
+	}
+	if res.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("unexpected prometheus result type %s", res.Data.ResultType)
+	}
+
+	var series []Series
+	for _, r := range res.Data.Result {
+		s := Series{Labels: r.Metric}
+		for _, v := range r.Values {
+			ts, ok := v[0].(float64)
+			if !ok {
+				continue
+			}
+			str, ok := v[1].(string)
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseFloat(str, 64)
+			if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+			sec, frac := math.Modf(ts)
+			s.Samples = append(s.Samples, Sample{
+				Timestamp: time.Unix(int64(sec), int64(frac*float64(time.Second))).UTC(),
+				Value:     value,
+			})
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
+
+// QueryRangeDayByDay splits the observability window into one query per day, keeping every
+// request well below the maximum number of points Prometheus returns per series.
+func (p *Prometheus) QueryRangeDayByDay(ctx context.Context, query string, days int, step time.Duration) ([]Series, error) {
+	seriesByLabels := map[string]*Series{}
+	var keys []string
+	now := time.Now()
+	for i := days; i >= 1; i-- {
+		startTime := now.Add(-time.Duration(24*i) * time.Hour).Truncate(step)
+		endTime := now.Add(-time.Duration(24*(i-1)) * time.Hour).Truncate(step)
+		// query_range bounds are inclusive, stop one step early so consecutive days don't overlap
+		series, err := p.QueryRange(ctx, query, startTime, endTime.Add(-step), step)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range series {
+			key := labelsKey(s.Labels)
+			if _, ok := seriesByLabels[key]; !ok {
+				seriesByLabels[key] = &Series{Labels: s.Labels}
+				keys = append(keys, key)
+			}
+			seriesByLabels[key].Samples = append(seriesByLabels[key].Samples, s.Samples...)
+		}
+	}
+
+	var result []Series
+	for _, k := range keys {
+		result = append(result, *seriesByLabels[k])
+	}
+	return result, nil
+}
+
+func labelsKey(labels map[string]string) string {
+	var parts []string
+	for k, v := range labels {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, v))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,393 @@
+package remediation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"math"
+	"strings"
+	"time"
+)
+
+// Applier changes resources to their recommended settings, recording the previous values in a journal.
+// Every target is checked against its live state first, resources that changed since the analysis or can
+// not be changed safely are skipped.
+type Applier struct {
+	cfg              aws.Config
+	journal          *Journal
+	dryRun           bool
+	applyImmediately bool
+	waitDelay        time.Duration
+	waitTimeout      time.Duration
+}
+
+// NewApplier returns an applier using cfg for the AWS clients, set cfg.BaseEndpoint to run against a local
+// AWS API stand-in. In a dry run the live state is read and the planned changes are journaled without
+// making them. applyImmediately applies RDS changes now instead of in the next maintenance window.
+func NewApplier(cfg aws.Config, journal *Journal, dryRun, applyImmediately bool) *Applier {
+	return &Applier{
+		cfg:              cfg,
+		journal:          journal,
+		dryRun:           dryRun,
+		applyImmediately: applyImmediately,
+		waitDelay:        15 * time.Second,
+		waitTimeout:      15 * time.Minute,
+	}
+}
+
+// SetWaitDelay changes how often the state of an instance is polled while it stops and starts.
+func (a *Applier) SetWaitDelay(delay time.Duration) {
+	a.waitDelay = delay
+}
+
+// Apply changes the targets one by one, saving the journal after each. Failures of a target are recorded
+// in its entry, the returned error is only about the journal.
+func (a *Applier) Apply(ctx context.Context, targets []Target) error {
+	for _, t := range targets {
+		a.journal.Entries = append(a.journal.Entries, a.apply(ctx, t, a.applyImmediately))
+		if err := a.journal.Save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback restores the previous values of the applied entries of the journal, latest first, and returns
+// the changes it made. Unless it is a dry run the entries are marked as rolled back and the journal saved.
+func (a *Applier) Rollback(ctx context.Context) ([]JournalEntry, error) {
+	var result []JournalEntry
+	for i := len(a.journal.Entries) - 1; i >= 0; i-- {
+		entry := &a.journal.Entries[i]
+		if entry.Status != JournalApplied && entry.Status != JournalRollbackFailed {
+			continue
+		}
+		t := Target{
+			Kind:                entry.Kind,
+			Id:                  entry.Id,
+			Region:              entry.Region,
+			CurrentInstanceType: entry.Applied.InstanceType,
+			CurrentVolumeType:   entry.Applied.VolumeType,
+			InstanceType:        entry.Previous.InstanceType,
+			VolumeType:          entry.Previous.VolumeType,
+			Size:                entry.Previous.Size,
+			Iops:                entry.Previous.Iops,
+			Throughput:          entry.Previous.Throughput,
+		}
+		// only restore what was changed and the storage type of the previous values supports
+		if entry.Applied.Iops == nil && entry.Applied.VolumeType == "" || !provisionedIops(t.VolumeType) {
+			t.Iops = nil
+		}
+		if entry.Applied.Throughput == nil && entry.Applied.VolumeType == "" || !provisionedThroughput(t.VolumeType) {
+			t.Throughput = nil
+		}
+		rollback := a.apply(ctx, t, entry.ApplyImmediately)
+		result = append(result, rollback)
+		if a.dryRun {
+			continue
+		}
+		switch rollback.Status {
+		case JournalApplied:
+			entry.Status, entry.Message = JournalRolledBack, rollback.Message
+		case JournalSkipped, JournalFailed:
+			entry.Status, entry.Message = JournalRollbackFailed, rollback.Message
+		}
+		if err := a.journal.Save(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (a *Applier) apply(ctx context.Context, t Target, applyImmediately bool) JournalEntry {
+	entry := JournalEntry{Kind: t.Kind, Id: t.Id, Region: t.Region, Time: time.Now().UTC()}
+	var err error
+	switch t.Kind {
+	case TargetEC2Instance:
+		err = a.applyInstance(ctx, t, &entry)
+	case TargetEBSVolume:
+		err = a.applyVolume(ctx, t, &entry)
+	case TargetRDSInstance:
+		entry.ApplyImmediately = applyImmediately
+		err = a.applyDBInstance(ctx, t, &entry)
+	default:
+		err = fmt.Errorf("unsupported resource kind %s", t.Kind)
+	}
+
+	var skip skipError
+	switch {
+	case errors.As(err, &skip):
+		entry.Status, entry.Message = JournalSkipped, skip.reason
+	case err != nil:
+		entry.Status, entry.Message = JournalFailed, err.Error()
+	case a.dryRun:
+		entry.Status = JournalPlanned
+	default:
+		entry.Status = JournalApplied
+	}
+	return entry
+}
+
+func provisionedIops(volumeType string) bool {
+	return volumeType == "gp3" || volumeType == "io1" || volumeType == "io2"
+}
+
+func provisionedThroughput(volumeType string) bool {
+	return volumeType == "gp3"
+}
+
+// RDSGp3ProvisionedStorage is the allocated storage in GiB from which the IOPS and throughput of RDS gp3
+// storage can be set, below it the baseline applies and AWS rejects both.
+func RDSGp3ProvisionedStorage(engine string) int32 {
+	engine = strings.ToLower(engine)
+	switch {
+	case strings.HasPrefix(engine, "oracle"):
+		return 200
+	case strings.HasPrefix(engine, "sqlserver"):
+		return 20
+	default:
+		return 400
+	}
+}
+
+// skipError is a target that is left alone, before anything was changed.
+type skipError struct {
+	reason string
+}
+
+func (e skipError) Error() string {
+	return e.reason
+}
+
+func skipf(format string, args ...any) error {
+	return skipError{reason: fmt.Sprintf(format, args...)}
+}
+
+func (a *Applier) ec2Client(region string) *ec2.Client {
+	localCfg := a.cfg
+	localCfg.Region = region
+	return ec2.NewFromConfig(localCfg)
+}
+
+func (a *Applier) rdsClient(region string) *rds.Client {
+	localCfg := a.cfg
+	localCfg.Region = region
+	return rds.NewFromConfig(localCfg)
+}
+
+func (a *Applier) applyInstance(ctx context.Context, t Target, entry *JournalEntry) error {
+	client := a.ec2Client(t.Region)
+	out, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{t.Id}})
+	if err != nil {
+		return fmt.Errorf("failed to describe instance: %w", err)
+	}
+	if len(out.Reservations) == 0 || len(out.Reservations[0].Instances) == 0 {
+		return skipf("instance not found")
+	}
+	instance := out.Reservations[0].Instances[0]
+	entry.Previous.InstanceType = string(instance.InstanceType)
+
+	if t.InstanceType == "" || t.InstanceType == entry.Previous.InstanceType {
+		return skipf("instance type is already %s", entry.Previous.InstanceType)
+	}
+	if t.CurrentInstanceType != "" && t.CurrentInstanceType != entry.Previous.InstanceType {
+		return skipf("instance type changed from %s to %s since the analysis", t.CurrentInstanceType, entry.Previous.InstanceType)
+	}
+	for _, tag := range instance.Tags {
+		if aws.ToString(tag.Key) == "aws:autoscaling:groupName" {
+			return skipf("managed by auto scaling group %s, change its launch template instead", aws.ToString(tag.Value))
+		}
+	}
+	if instance.RootDeviceType == ec2types.DeviceTypeInstanceStore {
+		return skipf("instance store backed instances can not be stopped to change their type")
+	}
+	var state ec2types.InstanceStateName
+	if instance.State != nil {
+		state = instance.State.Name
+	}
+	if state != ec2types.InstanceStateNameRunning && state != ec2types.InstanceStateNameStopped {
+		return skipf("instance is %s", state)
+	}
+	entry.Applied.InstanceType = t.InstanceType
+	restart := state == ec2types.InstanceStateNameRunning
+	if restart {
+		entry.Message = "stopped to change the instance type and started again"
+	}
+	if a.dryRun {
+		return nil
+	}
+
+	if restart {
+		if _, err := client.StopInstances(ctx, &ec2.StopInstancesInput{InstanceIds: []string{t.Id}}); err != nil {
+			return fmt.Errorf("failed to stop instance: %w", err)
+		}
+		waiter := ec2.NewInstanceStoppedWaiter(client, func(o *ec2.InstanceStoppedWaiterOptions) {
+			o.MinDelay, o.MaxDelay = a.waitDelay, max(a.waitDelay, o.MaxDelay)
+		})
+		if err := waiter.Wait(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{t.Id}}, a.waitTimeout); err != nil {
+			return fmt.Errorf("instance did not stop: %w", err)
+		}
+	}
+	_, modifyErr := client.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId:   aws.String(t.Id),
+		InstanceType: &ec2types.AttributeValue{Value: aws.String(t.InstanceType)},
+	})
+	if modifyErr != nil {
+		entry.Applied = Settings{}
+		modifyErr = fmt.Errorf("failed to change instance type: %w", modifyErr)
+		if !restart {
+			return modifyErr
+		}
+		// the instance was stopped for nothing, bring it back with its old type
+		entry.Message = "stopped to change the instance type and started again unchanged"
+	}
+	if restart {
+		if _, err := client.StartInstances(ctx, &ec2.StartInstancesInput{InstanceIds: []string{t.Id}}); err != nil {
+			return errors.Join(modifyErr, fmt.Errorf("failed to start instance, it is left stopped: %w", err))
+		}
+		waiter := ec2.NewInstanceRunningWaiter(client, func(o *ec2.InstanceRunningWaiterOptions) {
+			o.MinDelay, o.MaxDelay = a.waitDelay, max(a.waitDelay, o.MaxDelay)
+		})
+		if err := waiter.Wait(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{t.Id}}, a.waitTimeout); err != nil {
+			return errors.Join(modifyErr, fmt.Errorf("instance did not start: %w", err))
+		}
+	}
+	return modifyErr
+}
+
+func (a *Applier) applyVolume(ctx context.Context, t Target, entry *JournalEntry) error {
+	client := a.ec2Client(t.Region)
+	out, err := client.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{VolumeIds: []string{t.Id}})
+	if err != nil {
+		return fmt.Errorf("failed to describe volume: %w", err)
+	}
+	if len(out.Volumes) == 0 {
+		return skipf("volume not found")
+	}
+	volume := out.Volumes[0]
+	entry.Previous = Settings{VolumeType: string(volume.VolumeType), Size: volume.Size, Iops: volume.Iops}
+	if volume.Throughput != nil {
+		entry.Previous.Throughput = aws.Float64(float64(*volume.Throughput))
+	}
+	if t.CurrentVolumeType != "" && t.CurrentVolumeType != entry.Previous.VolumeType {
+		return skipf("volume type changed from %s to %s since the analysis", t.CurrentVolumeType, entry.Previous.VolumeType)
+	}
+	if volume.State != ec2types.VolumeStateAvailable && volume.State != ec2types.VolumeStateInUse {
+		return skipf("volume is %s", volume.State)
+	}
+
+	input := &ec2.ModifyVolumeInput{VolumeId: aws.String(t.Id)}
+	if t.VolumeType != "" && t.VolumeType != entry.Previous.VolumeType {
+		input.VolumeType = ec2types.VolumeType(t.VolumeType)
+		entry.Applied.VolumeType = t.VolumeType
+	}
+	if t.Size != nil && *t.Size > aws.ToInt32(volume.Size) {
+		input.Size = t.Size
+		entry.Applied.Size = t.Size
+	}
+	if t.Iops != nil && *t.Iops != aws.ToInt32(volume.Iops) {
+		input.Iops = t.Iops
+		entry.Applied.Iops = t.Iops
+	}
+	if t.Throughput != nil && int32(math.Round(*t.Throughput)) != aws.ToInt32(volume.Throughput) {
+		input.Throughput = aws.Int32(int32(math.Round(*t.Throughput)))
+		entry.Applied.Throughput = t.Throughput
+	}
+	if entry.Applied == (Settings{}) {
+		return skipf("volume already matches, sizes only grow")
+	}
+	if t.Size != nil && *t.Size < aws.ToInt32(volume.Size) {
+		entry.Message = fmt.Sprintf("size kept at %d GiB, volumes can not shrink", aws.ToInt32(volume.Size))
+	}
+	if a.dryRun {
+		return nil
+	}
+
+	if _, err := client.ModifyVolume(ctx, input); err != nil {
+		entry.Applied = Settings{}
+		return fmt.Errorf("failed to modify volume: %w", err)
+	}
+	return nil
+}
+
+func (a *Applier) applyDBInstance(ctx context.Context, t Target, entry *JournalEntry) error {
+	client := a.rdsClient(t.Region)
+	out, err := client.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{DBInstanceIdentifier: aws.String(t.Id)})
+	if err != nil {
+		return fmt.Errorf("failed to describe db instance: %w", err)
+	}
+	if len(out.DBInstances) == 0 {
+		return skipf("db instance not found")
+	}
+	instance := out.DBInstances[0]
+	entry.Previous = Settings{
+		InstanceType: aws.ToString(instance.DBInstanceClass),
+		VolumeType:   aws.ToString(instance.StorageType),
+		Size:         instance.AllocatedStorage,
+		Iops:         instance.Iops,
+	}
+	if instance.StorageThroughput != nil {
+		entry.Previous.Throughput = aws.Float64(float64(*instance.StorageThroughput))
+	}
+	if t.CurrentInstanceType != "" && t.CurrentInstanceType != entry.Previous.InstanceType {
+		return skipf("instance class changed from %s to %s since the analysis", t.CurrentInstanceType, entry.Previous.InstanceType)
+	}
+	if status := aws.ToString(instance.DBInstanceStatus); status != "available" {
+		return skipf("db instance is %s", status)
+	}
+
+	input := &rds.ModifyDBInstanceInput{
+		DBInstanceIdentifier: aws.String(t.Id),
+		ApplyImmediately:     aws.Bool(entry.ApplyImmediately),
+	}
+	if t.InstanceType != "" && t.InstanceType != entry.Previous.InstanceType {
+		input.DBInstanceClass = aws.String(t.InstanceType)
+		entry.Applied.InstanceType = t.InstanceType
+	}
+	// the storage of aurora instances belongs to the cluster
+	if instance.DBClusterIdentifier == nil {
+		if t.VolumeType != "" && t.VolumeType != entry.Previous.VolumeType {
+			input.StorageType = aws.String(t.VolumeType)
+			entry.Applied.VolumeType = t.VolumeType
+		}
+		if t.Size != nil && *t.Size > aws.ToInt32(instance.AllocatedStorage) {
+			input.AllocatedStorage = t.Size
+			entry.Applied.Size = t.Size
+		}
+		// gp3 below the threshold of the engine runs at its baseline, AWS rejects setting IOPS or throughput
+		storageType, allocated := aws.ToString(instance.StorageType), aws.ToInt32(instance.AllocatedStorage)
+		if input.StorageType != nil {
+			storageType = *input.StorageType
+		}
+		if input.AllocatedStorage != nil {
+			allocated = *input.AllocatedStorage
+		}
+		baseline := storageType == "gp3" && allocated < RDSGp3ProvisionedStorage(aws.ToString(instance.Engine))
+		if !baseline && t.Iops != nil && *t.Iops != aws.ToInt32(instance.Iops) {
+			input.Iops = t.Iops
+			entry.Applied.Iops = t.Iops
+		}
+		if !baseline && t.Throughput != nil && int32(math.Round(*t.Throughput)) != aws.ToInt32(instance.StorageThroughput) {
+			input.StorageThroughput = aws.Int32(int32(math.Round(*t.Throughput)))
+			entry.Applied.Throughput = t.Throughput
+		}
+	}
+	if entry.Applied == (Settings{}) {
+		return skipf("db instance already matches, storage only grows")
+	}
+	if !entry.ApplyImmediately {
+		entry.Message = "pending until the next maintenance window"
+	}
+	if a.dryRun {
+		return nil
+	}
+
+	if _, err := client.ModifyDBInstance(ctx, input); err != nil {
+		entry.Applied = Settings{}
+		return fmt.Errorf("failed to modify db instance: %w", err)
+	}
+	return nil
+}
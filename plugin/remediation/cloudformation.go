@@ -0,0 +1,468 @@
+package remediation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cloudFormationProperty is a property of a resource to change, an empty value removes the property.
+// Numbers are written as numbers unless the template has them as strings, or numeric is false.
+type cloudFormationProperty struct {
+	name     string
+	value    string
+	numeric  bool
+	required bool
+	growOnly bool
+}
+
+func cloudFormationResourceType(kind TargetKind) string {
+	switch kind {
+	case TargetEC2Instance:
+		return "AWS::EC2::Instance"
+	case TargetEBSVolume:
+		return "AWS::EC2::Volume"
+	case TargetRDSInstance:
+		return "AWS::RDS::DBInstance"
+	}
+	return ""
+}
+
+func cloudFormationProperties(t Target) []cloudFormationProperty {
+	var properties []cloudFormationProperty
+	set := func(p cloudFormationProperty) {
+		properties = append(properties, p)
+	}
+	storage := func(typeName, sizeName, iopsName, throughputName string, sizeNumeric bool) {
+		if t.VolumeType != "" {
+			set(cloudFormationProperty{name: typeName, value: t.VolumeType})
+		}
+		if t.Size != nil {
+			set(cloudFormationProperty{name: sizeName, value: strconv.Itoa(int(*t.Size)), numeric: sizeNumeric, required: true, growOnly: true})
+		}
+		if t.Iops != nil {
+			set(cloudFormationProperty{name: iopsName, value: strconv.Itoa(int(*t.Iops)), numeric: true})
+		} else if t.VolumeType != "" {
+			set(cloudFormationProperty{name: iopsName})
+		}
+		if t.Throughput != nil {
+			set(cloudFormationProperty{name: throughputName, value: strconv.Itoa(int(math.Round(*t.Throughput))), numeric: true})
+		} else if t.VolumeType != "" {
+			set(cloudFormationProperty{name: throughputName})
+		}
+	}
+
+	switch t.Kind {
+	case TargetEC2Instance:
+		if t.InstanceType != "" {
+			set(cloudFormationProperty{name: "InstanceType", value: t.InstanceType, required: true})
+		}
+	case TargetEBSVolume:
+		storage("VolumeType", "Size", "Iops", "Throughput", true)
+	case TargetRDSInstance:
+		if t.InstanceType != "" {
+			set(cloudFormationProperty{name: "DBInstanceClass", value: t.InstanceType, required: true})
+		}
+		if t.ClusterId != "" {
+			// the storage of cluster members is set on the AWS::RDS::DBCluster
+			break
+		}
+		// AllocatedStorage is a string in the resource specification
+		storage("StorageType", "AllocatedStorage", "Iops", "StorageThroughput", false)
+	}
+	return properties
+}
+
+type CloudFormationChange struct {
+	LogicalId  string
+	ResourceId string
+	Property   string
+	// Parameter is set when the property references a template parameter and the change is a parameter override
+	Parameter string
+	From      string
+	To        string
+}
+
+// CloudFormationParameter is a parameter override in the format of aws cloudformation create-change-set --parameters.
+type CloudFormationParameter struct {
+	ParameterKey   string `json:"ParameterKey"`
+	ParameterValue string `json:"ParameterValue"`
+}
+
+type CloudFormationPatch struct {
+	Changes    []CloudFormationChange
+	Skipped    []Skipped
+	Parameters []CloudFormationParameter
+	// Template is the modified template, in the format of the original
+	Template []byte
+}
+
+type cloudFormationOverride struct {
+	value   string
+	changes []CloudFormationChange
+	targets []Target
+}
+
+// NewCloudFormationPatch locates the resources of the targets in a saved template, JSON or YAML, through
+// the physical ids of the stack resources and changes their properties to the recommended values. Properties
+// referencing a parameter become parameter overrides as long as nothing else in the template uses the
+// parameter. Templates synthesized by the CDK work the same way, the CDK code itself is not changed.
+func NewCloudFormationPatch(templatePath, resourcesPath string, targets []Target) (*CloudFormationPatch, error) {
+	content, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cloudformation template: %w", err)
+	}
+	isJSON := strings.HasPrefix(strings.TrimSpace(string(content)), "{")
+	source := content
+	if isJSON {
+		// tabs can only be whitespace in JSON, but YAML does not allow them for indentation
+		source = bytes.ReplaceAll(content, []byte("\t"), []byte(" "))
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(source, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse cloudformation template %s: %w", templatePath, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse cloudformation template %s: not a mapping", templatePath)
+	}
+	root := doc.Content[0]
+	resources := yamlMappingValue(root, "Resources")
+	if resources == nil || resources.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("cloudformation template %s has no resources", templatePath)
+	}
+	physicalIds, err := readCloudFormationResources(resourcesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := &CloudFormationPatch{}
+	overrides := map[string]*cloudFormationOverride{}
+	for _, t := range targets {
+		skip := func(resource, reason string, args ...any) {
+			patch.Skipped = append(patch.Skipped, Skipped{Kind: t.Kind, ResourceId: t.Id, Resource: resource, Reason: fmt.Sprintf(reason, args...)})
+		}
+
+		logicalId, ok := physicalIds[t.Id]
+		if !ok {
+			continue
+		}
+		resource := yamlMappingValue(resources, logicalId)
+		if resource == nil || resource.Kind != yaml.MappingNode {
+			skip(logicalId, "resource not found in %s", templatePath)
+			continue
+		}
+		if resourceType := yamlMappingValue(resource, "Type"); resourceType == nil || resourceType.Value != cloudFormationResourceType(t.Kind) {
+			skip(logicalId, "resource is not of type %s", cloudFormationResourceType(t.Kind))
+			continue
+		}
+		properties := yamlMappingValue(resource, "Properties")
+		add := func(property cloudFormationProperty) {
+			if properties == nil {
+				properties = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				resource.Content = append(resource.Content, yamlString("Properties"), properties)
+			}
+			properties.Content = append(properties.Content, yamlString(property.name), cloudFormationValue(property))
+		}
+
+		for _, property := range cloudFormationProperties(t) {
+			change := CloudFormationChange{LogicalId: logicalId, ResourceId: t.Id, Property: property.name, To: property.value}
+			existing := yamlMappingValue(properties, property.name)
+			if existing == nil {
+				if property.value == "" {
+					continue
+				}
+				if property.required {
+					skip(logicalId, "%s is not set in the template, change it to %s where it comes from", property.name, property.value)
+					continue
+				}
+				add(property)
+				patch.Changes = append(patch.Changes, change)
+				continue
+			}
+
+			if parameter, ok := cloudFormationRef(existing); ok {
+				change.Parameter = parameter
+				change.From = cloudFormationParameterDefault(root, parameter)
+				if property.value == "" {
+					skip(logicalId, "%s references parameter %s, remove it since %s does not support it", property.name, parameter, t.VolumeType)
+					continue
+				}
+				if property.growOnly && change.From == "" {
+					skip(logicalId, "%s references parameter %s without default, set it to %s unless that shrinks it", property.name, parameter, property.value)
+					continue
+				} else if property.growOnly && cloudFormationShrinks(change.From, property.value) {
+					skip(logicalId, "%s can not shrink from %s to %s without replacing the resource", property.name, change.From, property.value)
+					continue
+				}
+				if override, ok := overrides[parameter]; ok && override.value != property.value {
+					skip(logicalId, "%s references parameter %s which is already overridden with %s", property.name, parameter, override.value)
+					continue
+				}
+				if overrides[parameter] == nil {
+					overrides[parameter] = &cloudFormationOverride{value: property.value}
+				}
+				overrides[parameter].changes = append(overrides[parameter].changes, change)
+				overrides[parameter].targets = append(overrides[parameter].targets, t)
+				continue
+			}
+			if existing.Kind != yaml.ScalarNode || (strings.HasPrefix(existing.Tag, "!") && !strings.HasPrefix(existing.Tag, "!!")) {
+				skip(logicalId, "%s is set by an intrinsic function, change it to %s where it comes from", property.name, property.value)
+				continue
+			}
+
+			change.From = existing.Value
+			if property.value == "" {
+				yamlMappingDelete(properties, property.name)
+				patch.Changes = append(patch.Changes, change)
+				continue
+			}
+			if existing.Value == property.value {
+				continue
+			}
+			if property.growOnly && cloudFormationShrinks(existing.Value, property.value) {
+				skip(logicalId, "%s can not shrink from %s to %s without replacing the resource", property.name, existing.Value, property.value)
+				continue
+			}
+			existing.Value = property.value
+			if !property.numeric {
+				existing.Tag = "!!str"
+			} else if existing.Tag != "!!str" {
+				existing.Tag = "!!int"
+			}
+			patch.Changes = append(patch.Changes, change)
+		}
+	}
+
+	var parameters []string
+	for parameter := range overrides {
+		parameters = append(parameters, parameter)
+	}
+	sort.Strings(parameters)
+	for _, parameter := range parameters {
+		override := overrides[parameter]
+		if uses := cloudFormationRefCount(root, parameter); uses != len(override.changes) {
+			for i, c := range override.changes {
+				patch.Skipped = append(patch.Skipped, Skipped{
+					Kind: override.targets[i].Kind, ResourceId: c.ResourceId, Resource: c.LogicalId,
+					Reason: fmt.Sprintf("%s references parameter %s which is also used elsewhere in the template, set it to %s", c.Property, parameter, override.value),
+				})
+			}
+			continue
+		}
+		patch.Parameters = append(patch.Parameters, CloudFormationParameter{ParameterKey: parameter, ParameterValue: override.value})
+		patch.Changes = append(patch.Changes, override.changes...)
+	}
+
+	if isJSON {
+		var out bytes.Buffer
+		if err := writeJSONNode(&out, root); err != nil {
+			return nil, err
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, out.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		indented.WriteString("\n")
+		patch.Template = indented.Bytes()
+	} else {
+		var out bytes.Buffer
+		encoder := yaml.NewEncoder(&out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to write cloudformation template: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write cloudformation template: %w", err)
+		}
+		patch.Template = out.Bytes()
+	}
+	return patch, nil
+}
+
+func (p *CloudFormationPatch) Summary() string {
+	resources := map[string]bool{}
+	for _, c := range p.Changes {
+		resources[c.LogicalId] = true
+	}
+	return fmt.Sprintf("CloudFormation: %d changes to %d resources, %d parameter overrides, %d skipped",
+		len(p.Changes), len(resources), len(p.Parameters), len(p.Skipped))
+}
+
+// Write saves the modified template, and the parameter overrides when there are any.
+func (p *CloudFormationPatch) Write(templatePath, parametersPath string) error {
+	if err := os.WriteFile(templatePath, p.Template, 0o644); err != nil {
+		return fmt.Errorf("failed to write cloudformation template: %w", err)
+	}
+	if len(p.Parameters) == 0 {
+		return nil
+	}
+	content, err := json.MarshalIndent(p.Parameters, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(parametersPath, append(content, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write cloudformation parameters: %w", err)
+	}
+	return nil
+}
+
+func cloudFormationValue(property cloudFormationProperty) *yaml.Node {
+	node := yamlString(property.value)
+	if property.numeric {
+		node.Tag = "!!int"
+	}
+	return node
+}
+
+func cloudFormationShrinks(current, wanted string) bool {
+	currentSize, _ := strconv.ParseFloat(current, 64)
+	wantedSize, _ := strconv.ParseFloat(wanted, 64)
+	return wantedSize < currentSize
+}
+
+// cloudFormationRef returns the parameter a value references with Ref, in its short or long form.
+func cloudFormationRef(node *yaml.Node) (string, bool) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!Ref" {
+		return node.Value, true
+	}
+	if node.Kind == yaml.MappingNode && len(node.Content) == 2 && node.Content[0].Value == "Ref" && node.Content[1].Kind == yaml.ScalarNode {
+		return node.Content[1].Value, true
+	}
+	return "", false
+}
+
+func cloudFormationRefCount(node *yaml.Node, parameter string) int {
+	if ref, ok := cloudFormationRef(node); ok && ref == parameter {
+		return 1
+	}
+	count := 0
+	for _, child := range node.Content {
+		count += cloudFormationRefCount(child, parameter)
+	}
+	// parameters substituted into strings with Fn::Sub
+	if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "${"+parameter+"}") {
+		count++
+	}
+	return count
+}
+
+func cloudFormationParameterDefault(root *yaml.Node, parameter string) string {
+	if value := yamlMappingValue(yamlMappingValue(yamlMappingValue(root, "Parameters"), parameter), "Default"); value != nil {
+		return value.Value
+	}
+	return ""
+}
+
+type cloudFormationStackResource struct {
+	LogicalResourceId  string `json:"LogicalResourceId"`
+	PhysicalResourceId string `json:"PhysicalResourceId"`
+}
+
+// readCloudFormationResources maps physical ids to logical ids from the output of aws cloudformation
+// describe-stack-resources or list-stack-resources, or a plain object of logical to physical ids.
+func readCloudFormationResources(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cloudformation resources: %w", err)
+	}
+	var stack struct {
+		StackResources         []cloudFormationStackResource `json:"StackResources"`
+		StackResourceSummaries []cloudFormationStackResource `json:"StackResourceSummaries"`
+	}
+	if err := json.Unmarshal(content, &stack); err != nil {
+		return nil, fmt.Errorf("failed to parse cloudformation resources %s: %w", path, err)
+	}
+	physicalIds := map[string]string{}
+	for _, r := range append(stack.StackResources, stack.StackResourceSummaries...) {
+		if r.PhysicalResourceId != "" {
+			physicalIds[r.PhysicalResourceId] = r.LogicalResourceId
+		}
+	}
+	if len(stack.StackResources) > 0 || len(stack.StackResourceSummaries) > 0 {
+		return physicalIds, nil
+	}
+
+	var logicalIds map[string]string
+	if err := json.Unmarshal(content, &logicalIds); err != nil {
+		return nil, fmt.Errorf("unsupported cloudformation resources %s, expected describe-stack-resources output or an object of logical to physical ids", path)
+	}
+	for logicalId, physicalId := range logicalIds {
+		physicalIds[physicalId] = logicalId
+	}
+	return physicalIds, nil
+}
+
+func yamlString(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func yamlMappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func yamlMappingDelete(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// writeJSONNode writes a node parsed from a JSON template back as JSON, keeping the order of the keys.
+func writeJSONNode(out *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		out.WriteString("{")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			key, _ := json.Marshal(node.Content[i].Value)
+			out.Write(key)
+			out.WriteString(":")
+			if err := writeJSONNode(out, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		out.WriteString("}")
+	case yaml.SequenceNode:
+		out.WriteString("[")
+		for i, child := range node.Content {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			if err := writeJSONNode(out, child); err != nil {
+				return err
+			}
+		}
+		out.WriteString("]")
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!int", "!!float", "!!bool":
+			out.WriteString(node.Value)
+		case "!!null":
+			out.WriteString("null")
+		default:
+			value, _ := json.Marshal(node.Value)
+			out.Write(value)
+		}
+	default:
+		return fmt.Errorf("unexpected node in cloudformation template at line %d", node.Line)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package remediation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const journalVersion = 1
+
+// Settings are the values of a resource an apply changes. Only the settings a change touches are set,
+// with the same units as Target.
+type Settings struct {
+	InstanceType string   `json:"instanceType,omitempty"`
+	VolumeType   string   `json:"volumeType,omitempty"`
+	Size         *int32   `json:"size,omitempty"`
+	Iops         *int32   `json:"iops,omitempty"`
+	Throughput   *float64 `json:"throughput,omitempty"`
+}
+
+type JournalStatus string
+
+const (
+	JournalPlanned        JournalStatus = "planned"
+	JournalApplied        JournalStatus = "applied"
+	JournalSkipped        JournalStatus = "skipped"
+	JournalFailed         JournalStatus = "failed"
+	JournalRolledBack     JournalStatus = "rolled back"
+	JournalRollbackFailed JournalStatus = "rollback failed"
+)
+
+// JournalEntry is a change made to one resource. Previous holds the values read from AWS right before the
+// change, Applied the values that were requested.
+type JournalEntry struct {
+	Kind             TargetKind    `json:"kind"`
+	Id               string        `json:"id"`
+	Region           string        `json:"region"`
+	Previous         Settings      `json:"previous"`
+	Applied          Settings      `json:"applied"`
+	ApplyImmediately bool          `json:"applyImmediately,omitempty"`
+	Status           JournalStatus `json:"status"`
+	Message          string        `json:"message,omitempty"`
+	Time             time.Time     `json:"time"`
+}
+
+// Journal records the changes of an apply so they can be rolled back. It is saved after every change, a
+// run that is interrupted leaves the changes made so far in it.
+type Journal struct {
+	Version   int            `json:"version"`
+	Account   string         `json:"account"`
+	DryRun    bool           `json:"dryRun,omitempty"`
+	StartedAt time.Time      `json:"startedAt"`
+	Entries   []JournalEntry `json:"entries"`
+
+	path string
+}
+
+// NewJournal creates the journal of an apply at path, an existing journal is never overwritten since it
+// may be the only record of earlier changes.
+func NewJournal(path, account string, dryRun bool) (*Journal, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("apply journal %s already exists", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to check apply journal: %w", err)
+	}
+	return &Journal{Version: journalVersion, Account: account, DryRun: dryRun, StartedAt: time.Now().UTC(), path: path}, nil
+}
+
+func ReadJournal(path string) (*Journal, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apply journal: %w", err)
+	}
+	var journal Journal
+	if err := json.Unmarshal(content, &journal); err != nil {
+		return nil, fmt.Errorf("failed to parse apply journal: %w", err)
+	}
+	if journal.Version != journalVersion {
+		return nil, fmt.Errorf("unsupported apply journal version %d", journal.Version)
+	}
+	if journal.DryRun {
+		return nil, fmt.Errorf("apply journal %s is from a dry run, there is nothing to roll back", path)
+	}
+	journal.path = path
+	return &journal, nil
+}
+
+func (j *Journal) Path() string {
+	return j.path
+}
+
+func (j *Journal) Save() error {
+	content, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create apply journal directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), ".journal-*")
+	if err != nil {
+		return fmt.Errorf("failed to write apply journal: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write apply journal: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write apply journal: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return fmt.Errorf("failed to write apply journal: %w", err)
+	}
+	return nil
+}
+
+// Summary counts the entries by status, in the order of the statuses.
+func (j *Journal) Summary() string {
+	counts := map[JournalStatus]int{}
+	for _, e := range j.Entries {
+		counts[e.Status]++
+	}
+	message := ""
+	for _, status := range []JournalStatus{JournalPlanned, JournalApplied, JournalRolledBack, JournalSkipped, JournalFailed, JournalRollbackFailed} {
+		if counts[status] == 0 {
+			continue
+		}
+		if message != "" {
+			message += ", "
+		}
+		message += fmt.Sprintf("%d %s", counts[status], status)
+	}
+	if message == "" {
+		message = "nothing to change"
+	}
+	return message
+}
@@ -0,0 +1,110 @@
+package remediation
+
+import (
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"sort"
+)
+
+type TargetKind string
+
+const (
+	TargetEC2Instance TargetKind = "EC2 Instance"
+	TargetEBSVolume   TargetKind = "EBS Volume"
+	TargetRDSInstance TargetKind = "RDS Instance"
+)
+
+// Target is a resource to change following its recommendation. Only the settings that are set change,
+// InstanceType is the instance type of EC2 instances and the instance class of RDS instances, VolumeType
+// the tier of EBS volumes and the storage type of RDS instances. Size is in GiB and Throughput in MiB/s.
+// CurrentInstanceType, CurrentVolumeType and CurrentSize are what the analysis saw, to detect changes made
+// since and to keep storage from shrinking. ClusterId is the cluster of RDS instances that are members of
+// one, their storage belongs to the cluster and is not changed with the instance.
+type Target struct {
+	Kind                TargetKind
+	Id                  string
+	Name                string
+	Region              string
+	ClusterId           string
+	CurrentInstanceType string
+	CurrentVolumeType   string
+	CurrentSize         *int32
+	InstanceType        string
+	VolumeType          string
+	Size                *int32
+	Iops                *int32
+	Throughput          *float64
+	Savings             float64
+	Description         string
+}
+
+// Targets turns the records of a run into the resources to change, those with a recommendation that saves
+// money. The compute and storage records of an RDS instance are merged into one target.
+func Targets(records []history.Record) []Target {
+	targets := map[string]*Target{}
+	target := func(kind TargetKind, id string, r history.Record) *Target {
+		key := string(kind) + "/" + id
+		if t, ok := targets[key]; ok {
+			return t
+		}
+		targets[key] = &Target{Kind: kind, Id: id, Name: r.ResourceName, Region: r.Region, ClusterId: r.ClusterId, Description: r.Description}
+		return targets[key]
+	}
+
+	for _, r := range records {
+		if !r.HasWaste() {
+			continue
+		}
+		switch r.ResourceType {
+		case "EC2 Instance":
+			t := target(TargetEC2Instance, r.ResourceId, r)
+			t.CurrentInstanceType = r.CurrentType
+			if r.RecommendedType != r.CurrentType {
+				t.InstanceType = r.RecommendedType
+			}
+			t.Savings += r.Savings
+		case "EBS Volume":
+			t := target(TargetEBSVolume, r.ResourceId, r)
+			t.CurrentVolumeType, t.CurrentSize = r.CurrentType, r.CurrentSize
+			if r.RecommendedType != r.CurrentType {
+				t.VolumeType = r.RecommendedType
+			}
+			t.Size, t.Iops, t.Throughput = r.RecommendedSize, r.RecommendedIops, r.RecommendedThroughput
+			t.Savings += r.Savings
+		case "RDS Instance Compute":
+			t := target(TargetRDSInstance, r.ParentId, r)
+			t.CurrentInstanceType = r.CurrentType
+			if r.RecommendedType != r.CurrentType {
+				t.InstanceType = r.RecommendedType
+			}
+			t.Savings += r.Savings
+		case "RDS Instance Storage":
+			t := target(TargetRDSInstance, r.ParentId, r)
+			t.CurrentVolumeType, t.CurrentSize = r.CurrentType, r.CurrentSize
+			if r.RecommendedType != r.CurrentType {
+				t.VolumeType = r.RecommendedType
+			}
+			t.Size, t.Iops, t.Throughput = r.RecommendedSize, r.RecommendedIops, r.RecommendedThroughput
+			t.Savings += r.Savings
+		}
+	}
+
+	var result []Target
+	for _, t := range targets {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].Id < result[j].Id
+	})
+	return result
+}
+
+// Skipped is a change that could not be made, with the reason to do it by hand.
+type Skipped struct {
+	Kind       TargetKind
+	ResourceId string
+	Resource   string
+	Reason     string
+}
@@ -0,0 +1,169 @@
+package remediation
+
+import (
+	"fmt"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Script is a shell script of AWS CLI commands changing resources to their recommended settings, meant to
+// be reviewed and run by hand as a lighter alternative to the infrastructure as code patches.
+type Script struct {
+	Resources int
+	Regions   int
+	Savings   float64
+	Content   []byte
+}
+
+// NewScript writes the commands for the targets of an account, grouped by region. Every resource is
+// preceded by the justification of its recommendation. Instances are only stopped and started again when
+// they are running, and storage sizes only grow. Savings in the comments are converted to the currency.
+func NewScript(account string, targets []Target, c *currency.Currency) *Script {
+	regions := map[string][]Target{}
+	for _, t := range targets {
+		if t.Savings > 0 {
+			regions[t.Region] = append(regions[t.Region], t)
+		}
+	}
+	var names []string
+	for region := range regions {
+		names = append(names, region)
+	}
+	sort.Strings(names)
+
+	script := &Script{Regions: len(names)}
+	var body strings.Builder
+	for _, region := range names {
+		body.WriteString(fmt.Sprintf("\n# ==== account %s, region %s ====\n", account, region))
+		for _, t := range regions[region] {
+			body.WriteString("\n")
+			writeScriptTarget(&body, t, c)
+			script.Resources++
+			script.Savings += t.Savings
+		}
+	}
+
+	var content strings.Builder
+	content.WriteString("#!/usr/bin/env bash\n")
+	content.WriteString(fmt.Sprintf("# Changes %d resources of account %s to their recommended settings, saving %s/month.\n",
+		script.Resources, account, c.Format(script.Savings)))
+	content.WriteString("# Review it before running, running instances are stopped while their type changes.\n")
+	content.WriteString("set -euo pipefail\n\n")
+	content.WriteString("# RDS changes wait for the next maintenance window, set to --apply-immediately to make them now\n")
+	content.WriteString("RDS_APPLY=--no-apply-immediately\n")
+	content.WriteString(body.String())
+	script.Content = []byte(content.String())
+	return script
+}
+
+func writeScriptTarget(w *strings.Builder, t Target, c *currency.Currency) {
+	name := t.Id
+	if t.Name != "" && t.Name != t.Id {
+		name = fmt.Sprintf("%s (%s)", t.Id, scriptComment(t.Name))
+	}
+	w.WriteString(fmt.Sprintf("# %s %s, saves %s/month\n", t.Kind, name, c.Format(t.Savings)))
+	for _, line := range strings.Split(strings.TrimSpace(t.Description), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			w.WriteString("# " + line + "\n")
+		}
+	}
+
+	region := "--region " + shellQuote(t.Region)
+	id := shellQuote(t.Id)
+	switch t.Kind {
+	case TargetEC2Instance:
+		if t.InstanceType == "" {
+			w.WriteString("# nothing to change\n")
+			return
+		}
+		w.WriteString(fmt.Sprintf("state=$(aws ec2 describe-instances %s --instance-ids %s --query 'Reservations[0].Instances[0].State.Name' --output text)\n", region, id))
+		w.WriteString("if [ \"$state\" = running ]; then\n")
+		w.WriteString(fmt.Sprintf("  aws ec2 stop-instances %s --instance-ids %s > /dev/null\n", region, id))
+		w.WriteString(fmt.Sprintf("  aws ec2 wait instance-stopped %s --instance-ids %s\n", region, id))
+		w.WriteString("fi\n")
+		w.WriteString(fmt.Sprintf("aws ec2 modify-instance-attribute %s --instance-id %s --instance-type Value=%s\n", region, id, shellQuote(t.InstanceType)))
+		w.WriteString("if [ \"$state\" = running ]; then\n")
+		w.WriteString(fmt.Sprintf("  aws ec2 start-instances %s --instance-ids %s > /dev/null\n", region, id))
+		w.WriteString(fmt.Sprintf("  aws ec2 wait instance-running %s --instance-ids %s\n", region, id))
+		w.WriteString("fi\n")
+	case TargetEBSVolume:
+		args := scriptStorageArgs(w, t, "--volume-type", "--size", "--iops", "--throughput")
+		if len(args) == 0 {
+			w.WriteString("# nothing to change\n")
+			return
+		}
+		w.WriteString(fmt.Sprintf("aws ec2 modify-volume %s --volume-id %s %s > /dev/null\n", region, id, strings.Join(args, " ")))
+	case TargetRDSInstance:
+		var args []string
+		if t.InstanceType != "" {
+			args = append(args, "--db-instance-class", shellQuote(t.InstanceType))
+		}
+		if t.ClusterId != "" {
+			// modify-db-instance rejects storage options for the members of a cluster
+			w.WriteString(fmt.Sprintf("# storage belongs to cluster %s and is left as is\n", scriptComment(t.ClusterId)))
+		} else {
+			args = append(args, scriptStorageArgs(w, t, "--storage-type", "--allocated-storage", "--iops", "--storage-throughput")...)
+		}
+		if len(args) == 0 {
+			w.WriteString("# nothing to change\n")
+			return
+		}
+		w.WriteString(fmt.Sprintf("aws rds modify-db-instance %s --db-instance-identifier %s %s \"$RDS_APPLY\" > /dev/null\n", region, id, strings.Join(args, " ")))
+		w.WriteString(fmt.Sprintf("aws rds wait db-instance-available %s --db-instance-identifier %s\n", region, id))
+	}
+}
+
+// scriptStorageArgs returns the options changing the storage of t, noting the sizes that can not shrink.
+func scriptStorageArgs(w *strings.Builder, t Target, typeOption, sizeOption, iopsOption, throughputOption string) []string {
+	var args []string
+	if t.VolumeType != "" {
+		args = append(args, typeOption, shellQuote(t.VolumeType))
+	}
+	if t.Size != nil {
+		switch {
+		case t.CurrentSize == nil:
+			w.WriteString(fmt.Sprintf("# the current size is unknown, grow it to %d GiB if it is smaller\n", *t.Size))
+		case *t.Size > *t.CurrentSize:
+			args = append(args, sizeOption, fmt.Sprintf("%d", *t.Size))
+		case *t.Size < *t.CurrentSize:
+			w.WriteString(fmt.Sprintf("# size stays at %d GiB, storage can not shrink to %d GiB without replacing it\n", *t.CurrentSize, *t.Size))
+		}
+	}
+	if t.Iops != nil {
+		args = append(args, iopsOption, fmt.Sprintf("%d", *t.Iops))
+	}
+	if t.Throughput != nil {
+		args = append(args, throughputOption, fmt.Sprintf("%d", int64(math.Round(*t.Throughput))))
+	}
+	return args
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9._:/=@+-]+$`)
+
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func scriptComment(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Summary describes the script, the savings converted to the currency.
+func (s *Script) Summary(c *currency.Currency) string {
+	return fmt.Sprintf("AWS CLI script: %d resources in %d regions, saving %s/month", s.Resources, s.Regions, c.Format(s.Savings))
+}
+
+// Write saves the script to path as an executable file.
+func (s *Script) Write(path string) error {
+	if err := os.WriteFile(path, s.Content, 0o755); err != nil {
+		return fmt.Errorf("failed to write aws cli script: %w", err)
+	}
+	return nil
+}
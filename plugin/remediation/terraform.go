@@ -0,0 +1,321 @@
+package remediation
+
+import (
+	"fmt"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// terraformAttribute is a setting of a resource to change, a null value removes the attribute.
+type terraformAttribute struct {
+	name  string
+	value cty.Value
+	// required attributes are only changed when the configuration sets them, otherwise the value comes
+	// from somewhere else like a launch template or snapshot.
+	required bool
+	// growOnly attributes are storage sizes, shrinking them replaces the resource.
+	growOnly bool
+}
+
+func terraformResourceTypes(kind TargetKind) []string {
+	switch kind {
+	case TargetEC2Instance:
+		return []string{"aws_instance"}
+	case TargetEBSVolume:
+		return []string{"aws_ebs_volume"}
+	case TargetRDSInstance:
+		return []string{"aws_db_instance", "aws_rds_cluster_instance"}
+	}
+	return nil
+}
+
+func terraformAttributes(resourceType string, t Target) []terraformAttribute {
+	var attributes []terraformAttribute
+	set := func(name string, value cty.Value, required, growOnly bool) {
+		attributes = append(attributes, terraformAttribute{name: name, value: value, required: required, growOnly: growOnly})
+	}
+	storage := func(typeName, sizeName, iopsName, throughputName string) {
+		if t.VolumeType != "" {
+			set(typeName, cty.StringVal(t.VolumeType), false, false)
+		}
+		if t.Size != nil {
+			set(sizeName, cty.NumberIntVal(int64(*t.Size)), true, true)
+		}
+		if t.Iops != nil {
+			set(iopsName, cty.NumberIntVal(int64(*t.Iops)), false, false)
+		} else if t.VolumeType != "" {
+			set(iopsName, cty.NullVal(cty.Number), false, false)
+		}
+		if t.Throughput != nil {
+			set(throughputName, cty.NumberIntVal(int64(math.Round(*t.Throughput))), false, false)
+		} else if t.VolumeType != "" {
+			set(throughputName, cty.NullVal(cty.Number), false, false)
+		}
+	}
+
+	switch resourceType {
+	case "aws_instance":
+		if t.InstanceType != "" {
+			set("instance_type", cty.StringVal(t.InstanceType), true, false)
+		}
+	case "aws_rds_cluster_instance":
+		if t.InstanceType != "" {
+			set("instance_class", cty.StringVal(t.InstanceType), true, false)
+		}
+	case "aws_db_instance":
+		if t.InstanceType != "" {
+			set("instance_class", cty.StringVal(t.InstanceType), true, false)
+		}
+		storage("storage_type", "allocated_storage", "iops", "storage_throughput")
+	case "aws_ebs_volume":
+		storage("type", "size", "iops", "throughput")
+	}
+	return attributes
+}
+
+type TerraformChange struct {
+	Address    string
+	File       string
+	ResourceId string
+	Attribute  string
+	// From and To are HCL expressions, From is empty when the attribute is added and To when it is removed
+	From string
+	To   string
+}
+
+type TerraformPatch struct {
+	Changes []TerraformChange
+	Skipped []Skipped
+	// Diff is a unified diff relative to the configuration directory, to be applied with git apply or patch -p1
+	Diff string
+}
+
+type terraformFile struct {
+	path     string
+	src      []byte
+	file     *hclwrite.File
+	modified bool
+}
+
+// NewTerraformPatch finds the resources of the targets in the state and rewrites their settings in the .tf files
+// of the root module in dir. Resources created in other modules or with count and for_each, and settings
+// that are not literals in the configuration are reported as skipped instead. The attributes of a changed
+// block are realigned the way terraform fmt does.
+func NewTerraformPatch(dir, statePath string, targets []Target) (*TerraformPatch, error) {
+	state, err := readTerraformState(statePath)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no terraform files found in %s", dir)
+	}
+	sort.Strings(paths)
+	var files []*terraformFile
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read terraform file: %w", err)
+		}
+		file, diags := hclwrite.ParseConfig(content, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse terraform file: %w", diags)
+		}
+		files = append(files, &terraformFile{path: path, src: content, file: file})
+	}
+
+	patch := &TerraformPatch{}
+	for _, t := range targets {
+		skip := func(resource, reason string, args ...any) {
+			patch.Skipped = append(patch.Skipped, Skipped{Kind: t.Kind, ResourceId: t.Id, Resource: resource, Reason: fmt.Sprintf(reason, args...)})
+		}
+
+		resource, ok := state.find(terraformResourceTypes(t.Kind), t.Id)
+		if !ok {
+			continue
+		}
+		if resource.Module != "" {
+			skip(resource.Address, "defined in %s, change the module inputs", resource.Module)
+			continue
+		}
+		if resource.Indexed {
+			skip(resource.Address, "created with count or for_each, change the values it iterates over")
+			continue
+		}
+		file, block := findTerraformBlock(files, resource.Type, resource.Name)
+		if block == nil {
+			skip(resource.Address, "resource block not found in %s", dir)
+			continue
+		}
+		if !strings.Contains(string(block.Body().BuildTokens(nil).Bytes()), "\n") {
+			skip(resource.Address, "resource block is on a single line")
+			continue
+		}
+
+		body := block.Body()
+		for _, attribute := range terraformAttributes(resource.Type, t) {
+			change := TerraformChange{Address: resource.Address, File: file.path, ResourceId: t.Id, Attribute: attribute.name}
+			if !attribute.value.IsNull() {
+				change.To = strings.TrimSpace(string(hclwrite.TokensForValue(attribute.value).Bytes()))
+			}
+
+			existing := body.GetAttribute(attribute.name)
+			if existing == nil {
+				if attribute.value.IsNull() {
+					continue
+				}
+				if attribute.required {
+					skip(resource.Address, "%s is not set in the configuration, change it to %s where it comes from", attribute.name, change.To)
+					continue
+				}
+				body.SetAttributeValue(attribute.name, attribute.value)
+				file.modified = true
+				patch.Changes = append(patch.Changes, change)
+				continue
+			}
+
+			change.From = strings.TrimSpace(string(existing.Expr().BuildTokens(nil).Bytes()))
+			current, literal := terraformLiteral(existing)
+			if !literal && attribute.value.IsNull() {
+				skip(resource.Address, "%s is set to %s, remove it since %s does not support it", attribute.name, change.From, t.VolumeType)
+				continue
+			} else if !literal {
+				skip(resource.Address, "%s is set to %s, change it to %s where it comes from", attribute.name, change.From, change.To)
+				continue
+			}
+			if attribute.value.IsNull() {
+				body.RemoveAttribute(attribute.name)
+				file.modified = true
+				patch.Changes = append(patch.Changes, change)
+				continue
+			}
+			wanted := ctyString(attribute.value)
+			if current == wanted {
+				continue
+			}
+			if attribute.growOnly {
+				currentSize, _ := strconv.ParseFloat(current, 64)
+				wantedSize, _ := strconv.ParseFloat(wanted, 64)
+				if wantedSize < currentSize {
+					skip(resource.Address, "%s can not shrink from %s to %s without replacing the resource", attribute.name, current, wanted)
+					continue
+				}
+			}
+			body.SetAttributeValue(attribute.name, attribute.value)
+			file.modified = true
+			patch.Changes = append(patch.Changes, change)
+		}
+	}
+
+	for _, file := range files {
+		if !file.modified {
+			continue
+		}
+		diff, err := file.diff(dir)
+		if err != nil {
+			return nil, err
+		}
+		patch.Diff += diff
+	}
+	return patch, nil
+}
+
+func findTerraformBlock(files []*terraformFile, resourceType, name string) (*terraformFile, *hclwrite.Block) {
+	for _, f := range files {
+		for _, block := range f.file.Body().Blocks() {
+			labels := block.Labels()
+			if block.Type() == "resource" && len(labels) == 2 && labels[0] == resourceType && labels[1] == name {
+				return f, block
+			}
+		}
+	}
+	return nil, nil
+}
+
+// terraformLiteral returns the value of an attribute when it is a string or number that does not depend
+// on variables, locals or other resources.
+func terraformLiteral(attribute *hclwrite.Attribute) (string, bool) {
+	expr, diags := hclsyntax.ParseExpression(attribute.Expr().BuildTokens(nil).Bytes(), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", false
+	}
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsKnown() || value.IsNull() {
+		return "", false
+	}
+	if value.Type() != cty.String && value.Type() != cty.Number {
+		return "", false
+	}
+	return ctyString(value), true
+}
+
+func ctyString(value cty.Value) string {
+	value, err := convert.Convert(value, cty.String)
+	if err != nil {
+		return ""
+	}
+	return value.AsString()
+}
+
+func (f *terraformFile) diff(dir string) (string, error) {
+	name, err := filepath.Rel(dir, f.path)
+	if err != nil {
+		return "", err
+	}
+	name = filepath.ToSlash(name)
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        diffLines(string(f.src)),
+		B:        diffLines(string(f.file.Bytes())),
+		FromFile: "a/" + name,
+		ToFile:   "b/" + name,
+		Context:  3,
+	})
+}
+
+// diffLines splits s into lines keeping their line breaks, difflib.SplitLines adds an empty line when
+// the text ends with a line break.
+func diffLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
+
+func (p *TerraformPatch) Summary() string {
+	files := map[string]bool{}
+	resources := map[string]bool{}
+	for _, c := range p.Changes {
+		files[c.File] = true
+		resources[c.Address] = true
+	}
+	return fmt.Sprintf("Terraform patch: %d changes to %d resources in %d files, %d skipped",
+		len(p.Changes), len(resources), len(files), len(p.Skipped))
+}
+
+// Write saves the diff to path, preceded by the skipped changes as comments which git apply and patch ignore.
+func (p *TerraformPatch) Write(path string) error {
+	var content strings.Builder
+	for _, s := range p.Skipped {
+		content.WriteString(fmt.Sprintf("# skipped %s (%s %s): %s\n", s.Resource, s.Kind, s.ResourceId, s.Reason))
+	}
+	content.WriteString(p.Diff)
+	if err := os.WriteFile(path, []byte(content.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write terraform patch: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package remediation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// terraformResource is a managed resource of the state, addressed the way terraform plan shows it.
+type terraformResource struct {
+	Address string
+	Module  string
+	Type    string
+	Name    string
+	Indexed bool
+}
+
+// terraformIdAttributes are the attributes a scanned resource id can be found in, the identifier of RDS
+// instances is their name while id is the DbiResourceId on recent provider versions.
+var terraformIdAttributes = []string{"id", "identifier"}
+
+type terraformStateFile struct {
+	// state files written by terraform
+	Version   int `json:"version"`
+	Resources []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Instances []struct {
+			IndexKey   any            `json:"index_key"`
+			Attributes map[string]any `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+
+	// output of terraform show -json
+	FormatVersion string `json:"format_version"`
+	Values        *struct {
+		RootModule terraformShowModule `json:"root_module"`
+	} `json:"values"`
+}
+
+type terraformShowModule struct {
+	Address   string `json:"address"`
+	Resources []struct {
+		Address string         `json:"address"`
+		Mode    string         `json:"mode"`
+		Type    string         `json:"type"`
+		Name    string         `json:"name"`
+		Index   any            `json:"index"`
+		Values  map[string]any `json:"values"`
+	} `json:"resources"`
+	ChildModules []terraformShowModule `json:"child_modules"`
+}
+
+// terraformState maps the ids of the managed resources to their address, by resource type.
+type terraformState map[string]map[string]terraformResource
+
+// readTerraformState reads either a state file or the output of terraform show -json.
+func readTerraformState(path string) (terraformState, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terraform state: %w", err)
+	}
+	var file terraformStateFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse terraform state %s: %w", path, err)
+	}
+
+	state := terraformState{}
+	switch {
+	case file.Values != nil:
+		state.addShowModule(file.Values.RootModule)
+	case file.Version == 4:
+		for _, r := range file.Resources {
+			if r.Mode != "managed" {
+				continue
+			}
+			for _, instance := range r.Instances {
+				resource := terraformResource{
+					Address: terraformAddress(r.Module, r.Type, r.Name, instance.IndexKey),
+					Module:  r.Module,
+					Type:    r.Type,
+					Name:    r.Name,
+					Indexed: instance.IndexKey != nil,
+				}
+				state.add(resource, instance.Attributes)
+			}
+		}
+	case file.FormatVersion != "":
+		// show -json of an empty state has no values
+	default:
+		return nil, fmt.Errorf("unsupported terraform state %s, expected a version 4 state or terraform show -json output", path)
+	}
+	return state, nil
+}
+
+func (s terraformState) addShowModule(module terraformShowModule) {
+	for _, r := range module.Resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		s.add(terraformResource{
+			Address: r.Address,
+			Module:  module.Address,
+			Type:    r.Type,
+			Name:    r.Name,
+			Indexed: r.Index != nil,
+		}, r.Values)
+	}
+	for _, child := range module.ChildModules {
+		s.addShowModule(child)
+	}
+}
+
+func (s terraformState) add(resource terraformResource, attributes map[string]any) {
+	for _, name := range terraformIdAttributes {
+		id, ok := attributes[name].(string)
+		if !ok || id == "" {
+			continue
+		}
+		if s[resource.Type] == nil {
+			s[resource.Type] = map[string]terraformResource{}
+		}
+		s[resource.Type][id] = resource
+	}
+}
+
+func (s terraformState) find(resourceTypes []string, id string) (terraformResource, bool) {
+	for _, t := range resourceTypes {
+		if r, ok := s[t][id]; ok {
+			return r, true
+		}
+	}
+	return terraformResource{}, false
+}
+
+func terraformAddress(module, resourceType, name string, index any) string {
+	address := resourceType + "." + name
+	if module != "" {
+		address = module + "." + address
+	}
+	switch v := index.(type) {
+	case string:
+		address += fmt.Sprintf("[%q]", v)
+	case float64:
+		address += fmt.Sprintf("[%d]", int(v))
+	}
+	return address
+}
@@ -0,0 +1,145 @@
+package report
+
+import (
+	"fmt"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/summary"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Markdown is a concise report of a run to paste into pull request comments, wikis or chat: the totals per
+// service, the devices saving the most, the rows of the CSV export, and why resources were skipped.
+type Markdown struct {
+	Account string
+	// Limit is the number of rows of each table, all of them when zero
+	Limit int
+	// MinSavings is the monthly savings, in the currency the report is rendered in, a device needs to be listed
+	MinSavings float64
+	records    []history.Record
+	resources  []summary.Resource
+}
+
+// NewMarkdown returns the markdown report of the records and the resources of the results summary.
+func NewMarkdown(account string, records []history.Record, resources []summary.Resource, limit int, minSavings float64) *Markdown {
+	return &Markdown{Account: account, Limit: limit, MinSavings: minSavings, records: records, resources: resources}
+}
+
+// Render renders the report, costs converted to the currency.
+func (m *Markdown) Render(c *currency.Currency) string {
+	var b strings.Builder
+	totals, total := Totals(m.resources, false)
+	var skipped, failed int
+	reasons := map[string]int{}
+	for _, r := range m.resources {
+		switch r.Status {
+		case summary.StatusSkipped:
+			skipped++
+			reasons[r.SkipReason]++
+		case summary.StatusFailed:
+			failed++
+		}
+	}
+
+	b.WriteString("### AWS optimization summary\n\n")
+	fmt.Fprintf(&b, "Account %s: analysed %d resources, skipped %d, failed %d. Current cost **%s**, potential savings **%s (%.1f%%)** per month.\n",
+		m.Account, total.Resources, skipped, failed, c.Format(total.CurrentCost), c.Format(total.Savings), total.SavingsPercent())
+
+	if len(totals) > 0 {
+		b.WriteString("\n| Service | Resources | Current Cost | Savings | Savings % |\n| --- | ---: | ---: | ---: | ---: |\n")
+		for _, t := range totals {
+			fmt.Fprintf(&b, "| %s | %d | %s | %s | %.1f%% |\n", cell(t.Service), t.Resources, c.Format(t.CurrentCost),
+				c.Format(t.Savings), t.SavingsPercent())
+		}
+	}
+
+	// the threshold in USD, the currency of the records
+	minSavings := m.MinSavings / c.Convert(1)
+	var top []history.Record
+	for _, r := range m.records {
+		if r.Savings > 0 && r.Savings >= minSavings {
+			top = append(top, r)
+		}
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Savings != top[j].Savings {
+			return top[i].Savings > top[j].Savings
+		}
+		return top[i].Key() < top[j].Key()
+	})
+	b.WriteString("\n#### Top savings\n\n")
+	if len(top) == 0 {
+		fmt.Fprintf(&b, "No resource saves %s.\n", threshold(c, minSavings))
+	} else {
+		b.WriteString("| Resource | Type | Region | Current | Suggested | Current Cost | Savings |\n| --- | --- | --- | --- | --- | ---: | ---: |\n")
+		for _, r := range limit(top, m.Limit) {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n", cell(resourceName(r)), cell(r.ResourceType), cell(r.Region),
+				cell(r.CurrentSpec), cell(r.RecommendedSpec), c.Format(r.CurrentCost), c.Format(r.Savings))
+		}
+		if hidden := top[len(limit(top, m.Limit)):]; len(hidden) > 0 {
+			var savings float64
+			for _, r := range hidden {
+				savings += r.Savings
+			}
+			fmt.Fprintf(&b, "\n_%d more resources save %s in total._\n", len(hidden), c.Format(savings))
+		}
+	}
+
+	if len(reasons) > 0 {
+		var keys []string
+		for reason := range reasons {
+			keys = append(keys, reason)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if reasons[keys[i]] != reasons[keys[j]] {
+				return reasons[keys[i]] > reasons[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		b.WriteString("\n#### Skipped resources\n\n| Reason | Resources |\n| --- | ---: |\n")
+		for _, reason := range limit(keys, m.Limit) {
+			fmt.Fprintf(&b, "| %s | %d |\n", cell(reason), reasons[reason])
+		}
+		if hidden := len(keys) - len(limit(keys, m.Limit)); hidden > 0 {
+			fmt.Fprintf(&b, "\n_%d more reasons._\n", hidden)
+		}
+	}
+	return b.String()
+}
+
+// Write writes the report to a markdown file, costs converted to the currency.
+func (m *Markdown) Write(path string, c *currency.Currency) error {
+	if err := os.WriteFile(path, []byte(m.Render(c)), 0644); err != nil {
+		return fmt.Errorf("failed to write markdown report: %w", err)
+	}
+	return nil
+}
+
+func threshold(c *currency.Currency, minSavings float64) string {
+	if minSavings > 0 {
+		return fmt.Sprintf("at least %s", c.Format(minSavings))
+	}
+	return "money"
+}
+
+// limit returns the first n rows, all of them when n is zero.
+func limit[T any](rows []T, n int) []T {
+	if n > 0 && len(rows) > n {
+		return rows[:n]
+	}
+	return rows
+}
+
+func resourceName(r history.Record) string {
+	if r.ResourceName == "" || r.ResourceName == r.ResourceId {
+		return r.ResourceId
+	}
+	return fmt.Sprintf("%s (%s)", r.ResourceName, r.ResourceId)
+}
+
+// cell escapes the characters of a value breaking a markdown table.
+func cell(value string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(value)
+}
@@ -0,0 +1,196 @@
+package report
+
+import (
+	"fmt"
+	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/summary"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Item is a resource of the report, the overview row, device rows and device properties its processor shows in
+// the terminal and the utilization series of its devices by device row id.
+type Item struct {
+	Service      string
+	Optimization *golang.ChartOptimizationItem
+	Utilization  map[string][]Series
+}
+
+// Total is the monthly current cost and savings of the analysed resources of a service, in a region when
+// broken down by region.
+type Total struct {
+	Service     string
+	Region      string
+	Resources   int
+	CurrentCost float64
+	Savings     float64
+}
+
+// Report is a self-contained HTML report of a run: the savings by service and region, sortable tables with
+// the columns of the overview and devices charts and a detail pane per resource with the properties and
+// utilization sparklines of its devices. Costs are in USD until written.
+type Report struct {
+	Account     string
+	GeneratedAt time.Time
+	Overview    []*golang.ChartColumnItem
+	Devices     []*golang.ChartColumnItem
+	Items       []Item
+	Totals      []Total
+	Total       Total
+}
+
+// New builds the report of the items, with the columns of the overview and devices charts and the totals of
+// the analysed resources of the results summary.
+func New(account string, overview, devices *golang.ChartDefinition, items []Item, resources []summary.Resource, now time.Time) *Report {
+	r := &Report{
+		Account:     account,
+		GeneratedAt: now,
+		Overview:    columns(overview),
+		Devices:     columns(devices),
+	}
+	for _, i := range items {
+		if i.Optimization != nil && i.Optimization.OverviewChartRow != nil {
+			r.Items = append(r.Items, i)
+		}
+	}
+	sort.Slice(r.Items, func(i, j int) bool {
+		return r.Items[i].Optimization.OverviewChartRow.RowId < r.Items[j].Optimization.OverviewChartRow.RowId
+	})
+
+	r.Totals, r.Total = Totals(resources, true)
+	return r
+}
+
+// Totals sums the analysed resources by service and, when byRegion, region, the ones saving the most first,
+// and all together.
+func Totals(resources []summary.Resource, byRegion bool) ([]Total, Total) {
+	total := Total{Service: "Total"}
+	totals := map[string]*Total{}
+	for _, res := range resources {
+		if res.Status != summary.StatusAnalysed {
+			continue
+		}
+		key := Total{Service: Service(res.ResourceType)}
+		if byRegion {
+			key.Region = res.Region
+		}
+		t, ok := totals[key.Service+"/"+key.Region]
+		if !ok {
+			t = &key
+			totals[key.Service+"/"+key.Region] = t
+		}
+		for _, t := range []*Total{t, &total} {
+			t.Resources++
+			t.CurrentCost += res.CurrentCost
+			t.Savings += res.Savings
+		}
+	}
+	var result []Total
+	for _, t := range totals {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Savings != result[j].Savings {
+			return result[i].Savings > result[j].Savings
+		}
+		if result[i].Service != result[j].Service {
+			return result[i].Service < result[j].Service
+		}
+		return result[i].Region < result[j].Region
+	})
+	return result, total
+}
+
+// Service is the AWS service of a resource type of the results summary, EC2 for EC2 Instance.
+func Service(resourceType string) string {
+	if fields := strings.Fields(resourceType); len(fields) > 0 {
+		return fields[0]
+	}
+	return resourceType
+}
+
+// columns are the columns of a chart with a name, the ones only drawing terminal decorations left out.
+func columns(chart *golang.ChartDefinition) []*golang.ChartColumnItem {
+	if chart == nil {
+		return nil
+	}
+	var result []*golang.ChartColumnItem
+	for _, c := range chart.Columns {
+		if c.Name != "" {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// SavingsPercent is the part of the current cost the resources save.
+func (t Total) SavingsPercent() float64 {
+	if t.CurrentCost <= 0 {
+		return 0
+	}
+	return t.Savings / t.CurrentCost * 100
+}
+
+// Write writes the report to an HTML file, costs converted to the currency.
+func (r *Report) Write(path string, c *currency.Currency) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	defer f.Close()
+	if err := r.template(c).Execute(f, r); err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	return f.Close()
+}
+
+// Summary describes the resources and savings of the report.
+func (r *Report) Summary(c *currency.Currency) string {
+	return fmt.Sprintf("HTML report of %d resources saving %s (%.1f%%) monthly", len(r.Items), c.Format(r.Total.Savings), r.Total.SavingsPercent())
+}
+
+func (r *Report) template(c *currency.Currency) *template.Template {
+	code := currency.USD
+	if c != nil {
+		code = c.Code
+	}
+	return template.Must(template.New("report").Funcs(template.FuncMap{
+		"cost":     c.Format,
+		"currency": func() string { return code },
+		"cell": func(row *golang.ChartRow, column *golang.ChartColumnItem) string {
+			if row == nil || row.Values[column.Id] == nil {
+				return ""
+			}
+			return row.Values[column.Id].Value
+		},
+		"properties": func(i Item, rowId string) []*golang.Property {
+			props := i.Optimization.DevicesProperties[rowId]
+			if props == nil {
+				return nil
+			}
+			var visible []*golang.Property
+			for _, p := range props.Properties {
+				if !p.Hidden {
+					visible = append(visible, p)
+				}
+			}
+			return visible
+		},
+		"utilization": func(i Item, rowId string) []Series {
+			return i.Utilization[rowId]
+		},
+		"anchor": func(rowId string) string {
+			return "resource-" + rowId
+		},
+		"skipReason": func(i Item) string {
+			if i.Optimization.SkipReason == nil {
+				return ""
+			}
+			return i.Optimization.SkipReason.Value
+		},
+	}).Parse(htmlTemplate))
+}
@@ -0,0 +1,140 @@
+package report
+
+import (
+	"fmt"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"sort"
+	"strings"
+)
+
+const (
+	sparklineWidth  = 120
+	sparklineHeight = 24
+)
+
+// Series is a utilization metric of a device, its CloudWatch datapoints drawn as a sparkline.
+type Series struct {
+	Metric     string
+	Datapoints []types2.Datapoint
+}
+
+// NewSeries returns the series of the metrics collected for a device, in the order of names, leaving out the
+// ones without datapoints.
+func NewSeries(metrics map[string][]types2.Datapoint, names ...string) []Series {
+	var series []Series
+	for _, name := range names {
+		if len(metrics[name]) > 0 {
+			series = append(series, Series{Metric: name, Datapoints: metrics[name]})
+		}
+	}
+	return series
+}
+
+// Values are the values of the datapoints in time order, their average or, when not collected, their maximum
+// or sum.
+func (s Series) Values() []float64 {
+	datapoints := make([]types2.Datapoint, 0, len(s.Datapoints))
+	for _, dp := range s.Datapoints {
+		if dp.Timestamp != nil && (dp.Average != nil || dp.Maximum != nil || dp.Sum != nil) {
+			datapoints = append(datapoints, dp)
+		}
+	}
+	sort.Slice(datapoints, func(i, j int) bool { return datapoints[i].Timestamp.Before(*datapoints[j].Timestamp) })
+	values := make([]float64, 0, len(datapoints))
+	for _, dp := range datapoints {
+		switch {
+		case dp.Average != nil:
+			values = append(values, *dp.Average)
+		case dp.Maximum != nil:
+			values = append(values, *dp.Maximum)
+		default:
+			values = append(values, *dp.Sum)
+		}
+	}
+	return values
+}
+
+// Points are the points of the SVG polyline of the sparkline, the lowest value at the bottom and the highest
+// at the top. A single value or values that never change are drawn as a flat line in the middle. Series with
+// more values than the sparkline is wide are drawn with the highest value of each slice of its width, so
+// spikes still show and the report doesn't grow with the observability period.
+func (s Series) Points() string {
+	values := downsample(s.Values(), sparklineWidth)
+	if len(values) == 0 {
+		return ""
+	}
+	if len(values) == 1 {
+		values = append(values, values[0])
+	}
+	low, high := values[0], values[0]
+	for _, v := range values {
+		if v < low {
+			low = v
+		}
+		if v > high {
+			high = v
+		}
+	}
+	points := make([]string, 0, len(values))
+	for idx, v := range values {
+		y := float64(sparklineHeight) / 2
+		if high > low {
+			y = float64(sparklineHeight) - (v-low)/(high-low)*float64(sparklineHeight)
+		}
+		x := float64(idx) * float64(sparklineWidth) / float64(len(values)-1)
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	return strings.Join(points, " ")
+}
+
+// downsample splits values into buckets of consecutive values and keeps the highest of each, values are
+// returned as is when there are no more of them than buckets.
+func downsample(values []float64, buckets int) []float64 {
+	if len(values) <= buckets {
+		return values
+	}
+	sampled := make([]float64, 0, buckets)
+	for b := 0; b < buckets; b++ {
+		bucket := values[b*len(values)/buckets : (b+1)*len(values)/buckets]
+		high := bucket[0]
+		for _, v := range bucket {
+			if v > high {
+				high = v
+			}
+		}
+		sampled = append(sampled, high)
+	}
+	return sampled
+}
+
+// Range describes the lowest, average and highest value of the series.
+func (s Series) Range() string {
+	values := s.Values()
+	if len(values) == 0 {
+		return "no datapoints"
+	}
+	low, high, sum := values[0], values[0], 0.0
+	for _, v := range values {
+		if v < low {
+			low = v
+		}
+		if v > high {
+			high = v
+		}
+		sum += v
+	}
+	return fmt.Sprintf("min %s, avg %s, max %s", formatValue(low), formatValue(sum/float64(len(values))), formatValue(high))
+}
+
+func formatValue(v float64) string {
+	switch {
+	case v >= 1e9:
+		return fmt.Sprintf("%.1fG", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.1fk", v/1e3)
+	default:
+		return fmt.Sprintf("%.1f", v)
+	}
+}
@@ -0,0 +1,133 @@
+package report
+
+// htmlTemplate is the report page, its styles and the script sorting its tables inlined so the file can be
+// shared on its own.
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>AWS optimization report - {{.Account}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+h1 { margin-bottom: 0; }
+.meta { color: #59636e; margin-bottom: 2em; }
+.cards { display: flex; gap: 1em; margin-bottom: 2em; }
+.card { border: 1px solid #d1d9e0; border-radius: 6px; padding: 1em 1.5em; }
+.card .value { font-size: 1.6em; font-weight: 600; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border-bottom: 1px solid #d1d9e0; padding: 0.4em 0.8em; text-align: left; vertical-align: top; }
+table.sortable th { cursor: pointer; user-select: none; }
+table.sortable th[aria-sort="ascending"]::after { content: " \25B2"; }
+table.sortable th[aria-sort="descending"]::after { content: " \25BC"; }
+details { border: 1px solid #d1d9e0; border-radius: 6px; padding: 0.5em 1em; margin-bottom: 1em; }
+details:target { border-color: #0969da; }
+summary { cursor: pointer; font-weight: 600; }
+.description { white-space: pre-wrap; color: #59636e; }
+.sparkline polyline { fill: none; stroke: #0969da; stroke-width: 1.5; }
+.range { color: #59636e; font-size: 0.85em; }
+</style>
+</head>
+<body>
+<h1>AWS optimization report</h1>
+<div class="meta">Account {{.Account}}, generated {{.GeneratedAt.UTC.Format "2006-01-02 15:04 UTC"}}, monthly costs in {{currency}}</div>
+
+<h2>Overview</h2>
+<div class="cards">
+<div class="card"><div>Resources analysed</div><div class="value">{{.Total.Resources}}</div></div>
+<div class="card"><div>Current cost</div><div class="value">{{cost .Total.CurrentCost}}</div></div>
+<div class="card"><div>Potential savings</div><div class="value">{{cost .Total.Savings}} ({{printf "%.1f" .Total.SavingsPercent}}%)</div></div>
+</div>
+<table class="sortable">
+<thead><tr><th>Service</th><th>Region</th><th>Resources</th><th>Current Cost</th><th>Savings</th><th>Savings %</th></tr></thead>
+<tbody>
+{{- range .Totals}}
+<tr><td>{{.Service}}</td><td>{{.Region}}</td><td>{{.Resources}}</td><td>{{cost .CurrentCost}}</td><td>{{cost .Savings}}</td><td>{{printf "%.1f" .SavingsPercent}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+
+<h2>Resources</h2>
+<table class="sortable">
+<thead><tr><th>Service</th>{{range .Overview}}<th>{{.Name}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range $item := .Items}}
+<tr>{{$row := $item.Optimization.OverviewChartRow}}<td>{{$item.Service}}</td>{{range $i, $column := $.Overview}}<td>{{if eq $i 0}}<a href="#{{anchor $row.RowId}}">{{cell $row $column}}</a>{{else}}{{cell $row $column}}{{end}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+
+<h2>Details</h2>
+{{- range $item := .Items}}
+{{- $row := $item.Optimization.OverviewChartRow}}
+<details id="{{anchor $row.RowId}}">
+<summary>{{$item.Service}} {{cell $row (index $.Overview 0)}}{{with cell $row (index $.Overview 1)}} ({{.}}){{end}}</summary>
+{{- with skipReason $item}}
+<p>Skipped: {{.}}</p>
+{{- end}}
+{{- with $item.Optimization.Description}}
+<p class="description">{{.}}</p>
+{{- end}}
+{{- if $item.Optimization.DevicesChartRows}}
+<table class="sortable">
+<thead><tr>{{range $.Devices}}<th>{{.Name}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range $device := $item.Optimization.DevicesChartRows}}
+<tr>{{range $.Devices}}<td>{{cell $device .}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- range $device := $item.Optimization.DevicesChartRows}}
+<h4>{{$device.RowId}}</h4>
+{{- with properties $item $device.RowId}}
+<table>
+<thead><tr><th></th><th>Current</th><th>Average</th><th>Max</th><th>Recommended</th></tr></thead>
+<tbody>
+{{- range .}}
+<tr><td>{{.Key}}</td><td>{{.Current}}</td><td>{{.Average}}</td><td>{{.Max}}</td><td>{{.Recommended}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- with utilization $item $device.RowId}}
+<table>
+<thead><tr><th>Metric</th><th>Utilization</th><th></th></tr></thead>
+<tbody>
+{{- range .}}
+<tr><td>{{.Metric}}</td><td><svg class="sparkline" width="120" height="24" viewBox="-1 -1 122 26"><polyline points="{{.Points}}"/></svg></td><td class="range">{{.Range}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- end}}
+</details>
+{{- end}}
+
+<script>
+(function () {
+  var number = /^(?:[A-Z]{3} |[A-Z]{0,2}[^\w\s]{1,2})?(-?[\d,]+(?:\.\d+)?)/;
+  function key(cell) {
+    var text = cell.textContent.trim(), m = text.match(number);
+    return m ? parseFloat(m[1].replace(/,/g, "")) : text.toLowerCase();
+  }
+  document.querySelectorAll("table.sortable").forEach(function (table) {
+    table.querySelectorAll("th").forEach(function (th, column) {
+      th.addEventListener("click", function () {
+        var ascending = th.getAttribute("aria-sort") !== "ascending";
+        table.querySelectorAll("th").forEach(function (other) { other.removeAttribute("aria-sort"); });
+        th.setAttribute("aria-sort", ascending ? "ascending" : "descending");
+        var body = table.tBodies[0], rows = Array.prototype.slice.call(body.rows);
+        rows.sort(function (a, b) {
+          var x = key(a.cells[column]), y = key(b.cells[column]);
+          if (typeof x !== typeof y) { x = String(x); y = String(y); }
+          return (x < y ? -1 : x > y ? 1 : 0) * (ascending ? 1 : -1);
+        });
+        rows.forEach(function (row) { body.appendChild(row); });
+      });
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`
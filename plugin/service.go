@@ -7,17 +7,31 @@ import (
 	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
 	"github.com/kaytu-io/kaytu/pkg/plugin/sdk"
 	awsConfig "github.com/opengovern/plugin-aws/plugin/aws"
+	"github.com/opengovern/plugin-aws/plugin/chargeback"
+	"github.com/opengovern/plugin-aws/plugin/commitment"
+	"github.com/opengovern/plugin-aws/plugin/coverage"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
 	"github.com/opengovern/plugin-aws/plugin/kaytu"
+	"github.com/opengovern/plugin-aws/plugin/openmetrics"
+	"github.com/opengovern/plugin-aws/plugin/parquet"
+	"github.com/opengovern/plugin-aws/plugin/policy"
 	"github.com/opengovern/plugin-aws/plugin/preferences"
+	"github.com/opengovern/plugin-aws/plugin/pricing"
 	processor2 "github.com/opengovern/plugin-aws/plugin/processor"
 	"github.com/opengovern/plugin-aws/plugin/processor/ec2_instance"
+	"github.com/opengovern/plugin-aws/plugin/prometheus"
 	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"github.com/opengovern/plugin-aws/plugin/remediation"
+	"github.com/opengovern/plugin-aws/plugin/report"
+	"github.com/opengovern/plugin-aws/plugin/summary"
 	"github.com/opengovern/plugin-aws/plugin/version"
 	"golang.org/x/oauth2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/oauth"
 	"math"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -42,26 +56,45 @@ func (p *AWSPlugin) GetConfig(_ context.Context) golang.RegisterConfig {
 			{
 				Name:        "ec2-instance",
 				Description: "Get optimization suggestions for your AWS EC2 Instances",
-				Flags: []*golang.Flag{
-					{
-						Name:        "profile",
+				Flags: append(commonFlags(),
+					&golang.Flag{
+						Name:        "metrics-source",
+						Default:     "cloudwatch",
+						Description: "Source of the utilization metrics (cloudwatch or prometheus)",
+						Required:    false,
+					},
+					&golang.Flag{
+						Name:        "prometheus-url",
 						Default:     "",
-						Description: "AWS profile for authentication",
+						Description: "Prometheus server URL, used when metrics-source is prometheus",
 						Required:    false,
 					},
-					{
-						Name:        "observabilityDays",
-						Default:     "5",
-						Description: "Observability Days",
+					&golang.Flag{
+						Name:        "prometheus-instance-label",
+						Default:     "instance",
+						Description: "Prometheus label identifying the node_exporter target of an instance",
 						Required:    false,
 					},
-				},
+					&golang.Flag{
+						Name:        "prometheus-instance-match",
+						Default:     "private-ip",
+						Description: "How the instance label is matched to EC2 instances (private-ip or instance-id)",
+						Required:    false,
+					},
+				),
 				DefaultPreferences: preferences.DefaultEC2Preferences,
 				LoginRequired:      true,
 			},
 			{
-				Name:        "rds-instance",
-				Description: "Get optimization suggestions for your AWS RDS Instances",
+				Name:               "rds-instance",
+				Description:        "Get optimization suggestions for your AWS RDS Instances",
+				Flags:              commonFlags(),
+				DefaultPreferences: preferences.DefaultRDSPreferences,
+				LoginRequired:      true,
+			},
+			{
+				Name:        "rollback",
+				Description: "Restore the previous settings of the resources changed by an apply",
 				Flags: []*golang.Flag{
 					{
 						Name:        "profile",
@@ -70,122 +103,569 @@ func (p *AWSPlugin) GetConfig(_ context.Context) golang.RegisterConfig {
 						Required:    false,
 					},
 					{
-						Name:        "observabilityDays",
-						Default:     "5",
-						Description: "Observability Days",
+						Name:        "apply-journal",
+						Default:     "",
+						Description: "Journal written by the apply to roll back",
+						Required:    true,
+					},
+					{
+						Name:        "dry-run",
+						Default:     "false",
+						Description: "Show what would be restored without changing anything",
+						Required:    false,
+					},
+					{
+						Name:        "aws-endpoint",
+						Default:     "",
+						Description: "Send all AWS API calls to this endpoint, e.g. a local AWS API stand-in",
 						Required:    false,
 					},
 				},
-				DefaultPreferences: preferences.DefaultRDSPreferences,
-				LoginRequired:      true,
+				LoginRequired: false,
 			},
 		},
-		OverviewChart: &golang.ChartDefinition{
-			Columns: []*golang.ChartColumnItem{
-				{
-					Id:    "resource_id",
-					Name:  "Resource ID",
-					Width: 23,
-				},
-				{
-					Id:    "resource_name",
-					Name:  "Resource Name",
-					Width: 23,
-				},
-				{
-					Id:    "resource_type",
-					Name:  "Resource Type",
-					Width: 15,
-				},
-				{
-					Id:    "region",
-					Name:  "Region",
-					Width: 15,
-				},
-				{
-					Id:    "platform",
-					Name:  "Platform",
-					Width: 15,
-				},
-				{
-					Id:    "total_saving",
-					Name:  "Total Saving (Monthly)",
-					Width: 40,
-				},
-				{
-					Id:    "x_kaytu_right_arrow",
-					Name:  "",
-					Width: 1,
-				},
+		OverviewChart: overviewChart(),
+		DevicesChart:  devicesChart(),
+	}
+}
+
+// overviewChart is the chart of the resources, one row per instance or cluster, also mirrored by the HTML report.
+func overviewChart() *golang.ChartDefinition {
+	return &golang.ChartDefinition{
+		Columns: []*golang.ChartColumnItem{
+			{
+				Id:    "resource_id",
+				Name:  "Resource ID",
+				Width: 23,
+			},
+			{
+				Id:    "resource_name",
+				Name:  "Resource Name",
+				Width: 23,
+			},
+			{
+				Id:    "resource_type",
+				Name:  "Resource Type",
+				Width: 15,
+			},
+			{
+				Id:    "region",
+				Name:  "Region",
+				Width: 15,
+			},
+			{
+				Id:    "platform",
+				Name:  "Platform",
+				Width: 15,
+			},
+			{
+				Id:    "total_saving",
+				Name:  "Total Saving (Monthly)",
+				Width: 40,
+			},
+			{
+				Id:    "x_kaytu_right_arrow",
+				Name:  "",
+				Width: 1,
 			},
 		},
-		DevicesChart: &golang.ChartDefinition{
-			Columns: []*golang.ChartColumnItem{
-				{
-					Id:    "resource_id",
-					Name:  "Resource ID",
-					Width: 23,
-				},
-				{
-					Id:    "resource_name",
-					Name:  "Resource Name",
-					Width: 23,
-				},
-				{
-					Id:    "resource_type",
-					Name:  "ResourceType",
-					Width: 15,
-				},
-				{
-					Id:    "runtime",
-					Name:  "Runtime",
-					Width: 10,
-				},
-				{
-					Id:    "current_cost",
-					Name:  "Current Cost",
-					Width: 20,
-				},
-				{
-					Id:    "right_sized_cost",
-					Name:  "Right sized Cost",
-					Width: 20,
-				},
-				{
-					Id:    "savings",
-					Name:  "Savings",
-					Width: 20,
-				},
+	}
+}
+
+// devicesChart is the chart of the devices of a resource, also mirrored by the HTML report.
+func devicesChart() *golang.ChartDefinition {
+	return &golang.ChartDefinition{
+		Columns: []*golang.ChartColumnItem{
+			{
+				Id:    "resource_id",
+				Name:  "Resource ID",
+				Width: 23,
+			},
+			{
+				Id:    "resource_name",
+				Name:  "Resource Name",
+				Width: 23,
+			},
+			{
+				Id:    "resource_type",
+				Name:  "ResourceType",
+				Width: 15,
+			},
+			{
+				Id:    "runtime",
+				Name:  "Runtime",
+				Width: 10,
+			},
+			{
+				Id:    "current_cost",
+				Name:  "Current Cost",
+				Width: 20,
+			},
+			{
+				Id:    "right_sized_cost",
+				Name:  "Right sized Cost",
+				Width: 20,
+			},
+			{
+				Id:    "savings",
+				Name:  "Savings",
+				Width: 20,
 			},
 		},
 	}
 }
 
+func commonFlags() []*golang.Flag {
+	return []*golang.Flag{
+		{
+			Name:        "profile",
+			Default:     "",
+			Description: "AWS profile for authentication",
+			Required:    false,
+		},
+		{
+			Name:        "observabilityDays",
+			Default:     "5",
+			Description: "Observability Days",
+			Required:    false,
+		},
+		{
+			Name:        "preferences-file",
+			Default:     "",
+			Description: "YAML file of named preference profiles and tag rules applied before optimizing",
+			Required:    false,
+		},
+		{
+			Name:        "preferences-profile",
+			Default:     "",
+			Description: "Profile of preferences-file to use, defaults to the profile named default or the only one",
+			Required:    false,
+		},
+		{
+			Name:        "policy-file",
+			Default:     "",
+			Description: "YAML file of policy rules rejecting or annotating recommendations",
+			Required:    false,
+		},
+		{
+			Name:        "pricing-file",
+			Default:     "",
+			Description: "YAML file of discounts and private prices applied to the list prices, listed in a row after the resources of the export",
+			Required:    false,
+		},
+		{
+			Name:        "currency",
+			Default:     "USD",
+			Description: "Currency costs are shown in, converted with the rates of exchange-rates-file, records and plans stay in USD",
+			Required:    false,
+		},
+		{
+			Name:        "exchange-rates-file",
+			Default:     "",
+			Description: "YAML file of exchange rates per USD the costs are converted with",
+			Required:    false,
+		},
+		{
+			Name:        "group-by-tag",
+			Default:     "",
+			Description: "Cost allocation tag, such as team, the results summary is also broken down by and the current cost and savings are charged back by in a CSV and JSON report",
+			Required:    false,
+		},
+		{
+			Name:        "chargeback-file",
+			Default:     "",
+			Description: "File the chargeback report is written to, with .csv and .json extensions, defaults to kaytu-chargeback",
+			Required:    false,
+		},
+		{
+			Name:        "html-report-file",
+			Default:     "",
+			Description: "Write a self-contained HTML report of the savings, resources and their utilization to this file",
+			Required:    false,
+		},
+		{
+			Name:        "markdown-file",
+			Default:     "",
+			Description: "Write the markdown report, also shown with output markdown, to this file",
+			Required:    false,
+		},
+		{
+			Name:        "markdown-limit",
+			Default:     "10",
+			Description: "Rows of each table of the markdown report, 0 for all",
+			Required:    false,
+		},
+		{
+			Name:        "markdown-min-savings",
+			Default:     "0",
+			Description: "Monthly savings, in the display currency, a resource needs to be listed in the markdown report",
+			Required:    false,
+		},
+		{
+			Name:        "openmetrics-file",
+			Default:     "",
+			Description: "Write the cost, savings and utilization gauges of the run to this OpenMetrics file, e.g. in the node_exporter textfile collector directory",
+			Required:    false,
+		},
+		{
+			Name:        "parquet-dir",
+			Default:     "",
+			Description: "Write the recommendation records of the run as Parquet files partitioned by account, region and date under this directory",
+			Required:    false,
+		},
+		{
+			Name:        "parquet-utilization",
+			Default:     "false",
+			Description: "Also write the utilization datapoints of the devices to parquet-dir",
+			Required:    false,
+		},
+		{
+			Name:        "metrics-record-file",
+			Default:     "",
+			Description: "Record all retrieved CloudWatch datapoints to this fixture file",
+			Required:    false,
+		},
+		{
+			Name:        "metrics-replay-file",
+			Default:     "",
+			Description: "Serve CloudWatch datapoints from a fixture file recorded with metrics-record-file",
+			Required:    false,
+		},
+		{
+			Name:        "no-cache",
+			Default:     "false",
+			Description: "Do not use the local CloudWatch metrics cache",
+			Required:    false,
+		},
+		{
+			Name:        "cache-dir",
+			Default:     "",
+			Description: "Directory of the local CloudWatch metrics cache, defaults to the user cache directory",
+			Required:    false,
+		},
+		{
+			Name:        "cache-ttl",
+			Default:     "1h",
+			Description: "How long datapoints of the current, incomplete day are reused from the cache",
+			Required:    false,
+		},
+		{
+			Name:        "no-history",
+			Default:     "false",
+			Description: "Do not store the results of this run in the local run history",
+			Required:    false,
+		},
+		{
+			Name:        "history-dir",
+			Default:     "",
+			Description: "Directory of the local run history, defaults to the user cache directory",
+			Required:    false,
+		},
+		{
+			Name:        "history-max-runs",
+			Default:     strconv.Itoa(history.DefaultMaxRuns),
+			Description: "Number of runs of a command kept in the local run history, older ones are deleted, 0 keeps all of them",
+			Required:    false,
+		},
+		{
+			Name:        "diff",
+			Default:     "false",
+			Description: "Report new waste, resolved and changed recommendations and realized savings since the previous run",
+			Required:    false,
+		},
+		{
+			Name:        "diff-file",
+			Default:     "",
+			Description: "File the diff with the previous run is written to as CSV, defaults to kaytu-diff.csv",
+			Required:    false,
+		},
+		{
+			Name:        "realized-savings",
+			Default:     "false",
+			Description: "Report the recommendations of earlier runs that were applied, their realized savings and post-change utilization",
+			Required:    false,
+		},
+		{
+			Name:        "realized-savings-file",
+			Default:     "",
+			Description: "File the realized savings are written to as CSV, defaults to kaytu-realized-savings.csv",
+			Required:    false,
+		},
+		{
+			Name:        "terraform-dir",
+			Default:     "",
+			Description: "Directory of the Terraform configuration to write a patch with the recommended values for",
+			Required:    false,
+		},
+		{
+			Name:        "terraform-state",
+			Default:     "",
+			Description: "Terraform state file or terraform show -json output mapping the resources of terraform-dir to their ids",
+			Required:    false,
+		},
+		{
+			Name:        "terraform-patch-file",
+			Default:     "kaytu-terraform.patch",
+			Description: "File the Terraform patch is written to",
+			Required:    false,
+		},
+		{
+			Name:        "cloudformation-template",
+			Default:     "",
+			Description: "Saved CloudFormation template (JSON or YAML) to write a copy with the recommended properties for",
+			Required:    false,
+		},
+		{
+			Name:        "cloudformation-resources",
+			Default:     "",
+			Description: "aws cloudformation describe-stack-resources output mapping the resources of cloudformation-template to their physical ids",
+			Required:    false,
+		},
+		{
+			Name:        "cloudformation-output",
+			Default:     "",
+			Description: "File the modified CloudFormation template is written to, defaults to the template name prefixed with kaytu-",
+			Required:    false,
+		},
+		{
+			Name:        "cloudformation-parameters-file",
+			Default:     "kaytu-cloudformation-parameters.json",
+			Description: "File the change-set parameter overrides are written to",
+			Required:    false,
+		},
+		{
+			Name:        "aws-cli-script-file",
+			Default:     "",
+			Description: "Write a shell script of AWS CLI commands making the recommended changes to this file",
+			Required:    false,
+		},
+		{
+			Name:        "commitment-catalog",
+			Default:     "",
+			Description: "YAML price catalog of reserved instances and savings plans, plans commitment purchases for the rightsized fleet",
+			Required:    false,
+		},
+		{
+			Name:        "commitment-plan-file",
+			Default:     "",
+			Description: "File the commitment plan is written to as CSV, defaults to kaytu-commitment-plan.csv",
+			Required:    false,
+		},
+		{
+			Name:        "apply",
+			Default:     "",
+			Description: "Comma separated ids of the resources to change to their recommended settings, nothing is changed without it",
+			Required:    false,
+		},
+		{
+			Name:        "dry-run",
+			Default:     "false",
+			Description: "Journal the changes apply would make without making them",
+			Required:    false,
+		},
+		{
+			Name:        "apply-immediately",
+			Default:     "false",
+			Description: "Apply RDS changes immediately instead of in the next maintenance window",
+			Required:    false,
+		},
+		{
+			Name:        "apply-journal",
+			Default:     "",
+			Description: "File the rollback journal of apply is written to, defaults to kaytu-apply-<time>.json",
+			Required:    false,
+		},
+		{
+			Name:        "aws-endpoint",
+			Default:     "",
+			Description: "Send all AWS API calls to this endpoint, e.g. a local AWS API stand-in",
+			Required:    false,
+		},
+	}
+}
+
 func (p *AWSPlugin) SetStream(_ context.Context, stream *sdk.StreamController) {
 	p.stream = stream
 }
 
 func (p *AWSPlugin) StartProcess(ctx context.Context, command string, flags map[string]string, kaytuAccessToken string, preferences []*golang.PreferenceItem, jobQueue *sdk.JobQueue) error {
+	startedAt := time.Now()
 	profile := flags["profile"]
 	cfg, err := awsConfig.GetConfig(ctx, "", "", "", "", &profile, nil)
 	if err != nil {
 		return err
 	}
+	if flags["aws-endpoint"] != "" {
+		cfg.BaseEndpoint = aws.String(flags["aws-endpoint"])
+	}
+	dryRun, _ := strconv.ParseBool(strings.TrimSpace(flags["dry-run"]))
+	if command == "rollback" {
+		return p.rollback(ctx, cfg, flags["apply-journal"], dryRun)
+	}
 
 	awsPrv, err := awsConfig.NewAWS(cfg)
 	if err != nil {
 		return err
 	}
 
-	cloudWatch, err := awsConfig.NewCloudWatch(cfg)
+	identification, err := awsPrv.Identify(ctx)
 	if err != nil {
 		return err
 	}
 
-	identification, err := awsPrv.Identify(ctx)
+	var metricProvider awsConfig.MetricsProvider
+	if flags["metrics-replay-file"] != "" {
+		metricProvider, err = awsConfig.NewMetricsReplay(flags["metrics-replay-file"])
+		if err != nil {
+			return err
+		}
+	} else {
+		var metricsCache *awsConfig.MetricsCache
+		noCache, _ := strconv.ParseBool(strings.TrimSpace(flags["no-cache"]))
+		if !noCache {
+			cacheTTL := time.Hour
+			if flags["cache-ttl"] != "" {
+				cacheTTL, err = time.ParseDuration(strings.TrimSpace(flags["cache-ttl"]))
+				if err != nil {
+					return fmt.Errorf("invalid cache-ttl: %w", err)
+				}
+			}
+			metricsCache, err = awsConfig.NewMetricsCache(flags["cache-dir"], identification["account"], cacheTTL)
+			if err != nil {
+				return err
+			}
+		}
+		metricProvider, err = awsConfig.NewCloudWatch(cfg, metricsCache)
+		if err != nil {
+			return err
+		}
+	}
+	var metricsRecorder *awsConfig.MetricsRecorder
+	if flags["metrics-record-file"] != "" {
+		metricsRecorder = awsConfig.NewMetricsRecorder(metricProvider)
+		metricProvider = metricsRecorder
+	}
+
+	var historyStore *history.Store
+	noHistory, _ := strconv.ParseBool(strings.TrimSpace(flags["no-history"]))
+	diff, _ := strconv.ParseBool(strings.TrimSpace(flags["diff"]))
+	realizedSavings, _ := strconv.ParseBool(strings.TrimSpace(flags["realized-savings"]))
+	if diff && noHistory {
+		return fmt.Errorf("diff needs the run history, it cannot be combined with no-history")
+	}
+	if realizedSavings && noHistory {
+		return fmt.Errorf("realized-savings needs the run history, it cannot be combined with no-history")
+	}
+	if flags["terraform-dir"] != "" && flags["terraform-state"] == "" {
+		return fmt.Errorf("terraform-dir needs terraform-state to match resources to their ids")
+	}
+	terraformPatchFile := flags["terraform-patch-file"]
+	if terraformPatchFile == "" {
+		terraformPatchFile = "kaytu-terraform.patch"
+	}
+	if flags["cloudformation-template"] != "" && flags["cloudformation-resources"] == "" {
+		return fmt.Errorf("cloudformation-template needs cloudformation-resources to match resources to their physical ids")
+	}
+	cloudFormationOutput := flags["cloudformation-output"]
+	if cloudFormationOutput == "" && flags["cloudformation-template"] != "" {
+		cloudFormationOutput = "kaytu-" + filepath.Base(flags["cloudformation-template"])
+	}
+	cloudFormationParametersFile := flags["cloudformation-parameters-file"]
+	if cloudFormationParametersFile == "" {
+		cloudFormationParametersFile = "kaytu-cloudformation-parameters.json"
+	}
+	preferences, preferenceRules, err := loadPreferencesFile(preferences, flags["preferences-file"], flags["preferences-profile"])
 	if err != nil {
 		return err
 	}
+	var recommendationPolicy *policy.Policy
+	if flags["policy-file"] != "" {
+		recommendationPolicy, err = policy.Load(flags["policy-file"])
+		if err != nil {
+			return err
+		}
+	}
+	var pricingAdjustments *pricing.Adjustments
+	if flags["pricing-file"] != "" {
+		pricingAdjustments, err = pricing.Load(flags["pricing-file"])
+		if err != nil {
+			return err
+		}
+	}
+	displayCurrency, err := currency.Load(flags["currency"], flags["exchange-rates-file"])
+	if err != nil {
+		return err
+	}
+	var commitmentCatalog *commitment.Catalog
+	if flags["commitment-catalog"] != "" {
+		commitmentCatalog, err = commitment.LoadCatalog(flags["commitment-catalog"])
+		if err != nil {
+			return err
+		}
+	}
+	commitmentPlanFile := flags["commitment-plan-file"]
+	if commitmentPlanFile == "" {
+		commitmentPlanFile = "kaytu-commitment-plan.csv"
+	}
+	diffFile := flags["diff-file"]
+	if diffFile == "" {
+		diffFile = "kaytu-diff.csv"
+	}
+	realizedSavingsFile := flags["realized-savings-file"]
+	if realizedSavingsFile == "" {
+		realizedSavingsFile = "kaytu-realized-savings.csv"
+	}
+	chargebackFile := strings.TrimSuffix(strings.TrimSuffix(flags["chargeback-file"], ".csv"), ".json")
+	if chargebackFile == "" {
+		chargebackFile = "kaytu-chargeback"
+	}
+	markdownOutput := strings.TrimSpace(flags["output"]) == "markdown"
+	var markdownLimit int
+	var markdownMinSavings float64
+	if markdownOutput || flags["markdown-file"] != "" {
+		markdownLimit, err = strconv.Atoi(strings.TrimSpace(flags["markdown-limit"]))
+		if err != nil || markdownLimit < 0 {
+			return fmt.Errorf("invalid markdown-limit %s, expected a number of rows", flags["markdown-limit"])
+		}
+		markdownMinSavings, err = strconv.ParseFloat(strings.TrimSpace(flags["markdown-min-savings"]), 64)
+		if err != nil || markdownMinSavings < 0 {
+			return fmt.Errorf("invalid markdown-min-savings %s, expected a monthly amount", flags["markdown-min-savings"])
+		}
+	}
+	var applySelection map[string]bool
+	var applyJournal *remediation.Journal
+	applyImmediately, _ := strconv.ParseBool(strings.TrimSpace(flags["apply-immediately"]))
+	if strings.TrimSpace(flags["apply"]) != "" {
+		applySelection = map[string]bool{}
+		for _, id := range strings.Split(flags["apply"], ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				applySelection[id] = true
+			}
+		}
+		journalPath := flags["apply-journal"]
+		if journalPath == "" {
+			journalPath = fmt.Sprintf("kaytu-apply-%s.json", startedAt.UTC().Format("20060102T150405"))
+		}
+		applyJournal, err = remediation.NewJournal(journalPath, identification["account"], dryRun)
+		if err != nil {
+			return err
+		}
+	} else if dryRun {
+		return fmt.Errorf("dry-run needs apply to select the resources to change")
+	}
+	if !noHistory {
+		maxRuns := history.DefaultMaxRuns
+		if flags["history-max-runs"] != "" {
+			maxRuns, err = strconv.Atoi(strings.TrimSpace(flags["history-max-runs"]))
+			if err != nil || maxRuns < 0 {
+				return fmt.Errorf("invalid history-max-runs %s, expected a number of runs", flags["history-max-runs"])
+			}
+		}
+		historyStore, err = history.NewStore(flags["history-dir"], maxRuns)
+		if err != nil {
+			return err
+		}
+	}
 
 	configurations, err := kaytu.ConfigurationRequest(ctx)
 	if err != nil {
@@ -248,9 +728,23 @@ func (p *AWSPlugin) StartProcess(ctx context.Context, command string, flags map[
 	client := golang2.NewOptimizationClient(conn)
 
 	if command == "ec2-instance" {
+		var metricsSource ec2_instance.MetricsSource
+		switch flags["metrics-source"] {
+		case "", "cloudwatch":
+			metricsSource = ec2_instance.NewCloudWatchMetricsSource(metricProvider)
+		case "prometheus":
+			metricsSource, err = prometheus.NewPrometheus(flags["prometheus-url"], flags["prometheus-instance-label"], prometheus.InstanceMatch(flags["prometheus-instance-match"]))
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid metrics source: %s", flags["metrics-source"])
+		}
+
 		p.processor = ec2_instance.NewProcessor(
 			awsPrv,
-			cloudWatch,
+			metricProvider,
+			metricsSource,
 			identification,
 			publishOptimizationItem,
 			publishResultSummary,
@@ -259,12 +753,16 @@ func (p *AWSPlugin) StartProcess(ctx context.Context, command string, flags map[
 			configurations,
 			observabilityDays,
 			preferences,
+			preferenceRules,
+			recommendationPolicy,
+			pricingAdjustments,
+			displayCurrency,
 			client,
 		)
 	} else if command == "rds-instance" {
 		p.processor = processor2.NewRDSProcessor(
 			awsPrv,
-			cloudWatch,
+			metricProvider,
 			identification,
 			publishOptimizationItem,
 			publishResultSummary,
@@ -273,12 +771,30 @@ func (p *AWSPlugin) StartProcess(ctx context.Context, command string, flags map[
 			configurations,
 			observabilityDays,
 			preferences,
+			preferenceRules,
+			recommendationPolicy,
+			pricingAdjustments,
+			displayCurrency,
 			client,
 		)
 	} else {
 		return fmt.Errorf("invalid command: %s", command)
 	}
 	jobQueue.SetOnFinish(func(ctx context.Context) {
+		// a result summary replaces the previous one, so the lines and failures of the outputs written once
+		// the jobs are done are collected and published together in one message at the end.
+		var lines, failures []string
+		note := func(line string) {
+			lines = append(lines, line)
+		}
+		fail := func(err error) {
+			failures = append(failures, err.Error())
+		}
+		if metricsRecorder != nil {
+			if err := metricsRecorder.Save(flags["metrics-record-file"]); err != nil {
+				fail(err)
+			}
+		}
 		publishNonInteractiveExport := func(ex *golang.NonInteractiveExport) {
 			p.stream.Send(&golang.PluginMessage{
 				PluginMessage: &golang.PluginMessage_NonInteractive{
@@ -286,13 +802,259 @@ func (p *AWSPlugin) StartProcess(ctx context.Context, command string, flags map[
 				},
 			})
 		}
-		publishNonInteractiveExport(p.processor.ExportNonInteractive())
+		export := p.processor.ExportNonInteractive()
+		// the rows about the whole run come after the resources and an empty row, so that the first row of
+		// the export stays its column headers
+		var trailer [][]string
+		if displayCurrency != nil {
+			trailer = append(trailer, displayCurrency.ExportRow())
+		}
+		summaryResources := p.processor.SummaryResources()
+		resultsSummary := summary.New(summaryResources, flags["group-by-tag"])
+		lines = append(lines, resultsSummary.Lines(displayCurrency)...)
+		if flags["html-report-file"] != "" {
+			htmlReport := report.New(identification["account"], overviewChart(), devicesChart(), p.processor.ReportItems(), summaryResources, time.Now())
+			if err := htmlReport.Write(flags["html-report-file"], displayCurrency); err != nil {
+				fail(err)
+			} else {
+				note(fmt.Sprintf("%s, written to %s", htmlReport.Summary(displayCurrency), flags["html-report-file"]))
+			}
+		}
+		records := p.processor.Records()
+		var markdownReport string
+		if markdownOutput || flags["markdown-file"] != "" {
+			markdown := report.NewMarkdown(identification["account"], records, summaryResources, markdownLimit, markdownMinSavings)
+			if markdownOutput {
+				markdownReport = markdown.Render(displayCurrency)
+			}
+			if flags["markdown-file"] != "" {
+				if err := markdown.Write(flags["markdown-file"], displayCurrency); err != nil {
+					fail(err)
+				} else if !markdownOutput {
+					note(fmt.Sprintf("Markdown report written to %s", flags["markdown-file"]))
+				}
+			}
+		}
+		if flags["openmetrics-file"] != "" {
+			exposition := openmetrics.New(identification["account"], records, p.processor.ReportItems())
+			if err := exposition.Write(flags["openmetrics-file"]); err != nil {
+				fail(err)
+			} else {
+				note(fmt.Sprintf("%d OpenMetrics samples written to %s", exposition.Samples(), flags["openmetrics-file"]))
+			}
+		}
+		if flags["parquet-dir"] != "" {
+			utilization, _ := strconv.ParseBool(strings.TrimSpace(flags["parquet-utilization"]))
+			var items []report.Item
+			if utilization {
+				items = p.processor.ReportItems()
+			}
+			if dataLake, err := parquet.New(identification["account"], records, items, utilization, time.Now()); err != nil {
+				fail(err)
+			} else if err := dataLake.Write(flags["parquet-dir"]); err != nil {
+				fail(err)
+			} else {
+				note(fmt.Sprintf("%s written to %s", dataLake.Summary(), flags["parquet-dir"]))
+			}
+		}
+		if summary := coverage.Summary(records, displayCurrency); summary != "" {
+			note(summary)
+		}
+		if flags["terraform-dir"] != "" {
+			patch, err := remediation.NewTerraformPatch(flags["terraform-dir"], flags["terraform-state"], remediation.Targets(records))
+			if err != nil {
+				fail(err)
+			} else if err := patch.Write(terraformPatchFile); err != nil {
+				fail(err)
+			} else {
+				note(fmt.Sprintf("%s, written to %s", patch.Summary(), terraformPatchFile))
+			}
+		}
+		if flags["cloudformation-template"] != "" {
+			patch, err := remediation.NewCloudFormationPatch(flags["cloudformation-template"], flags["cloudformation-resources"], remediation.Targets(records))
+			if err != nil {
+				fail(err)
+			} else if err := patch.Write(cloudFormationOutput, cloudFormationParametersFile); err != nil {
+				fail(err)
+			} else {
+				note(fmt.Sprintf("%s, written to %s", patch.Summary(), cloudFormationOutput))
+			}
+		}
+		if flags["aws-cli-script-file"] != "" {
+			script := remediation.NewScript(identification["account"], remediation.Targets(records), displayCurrency)
+			if err := script.Write(flags["aws-cli-script-file"]); err != nil {
+				fail(err)
+			} else {
+				note(fmt.Sprintf("%s, written to %s", script.Summary(displayCurrency), flags["aws-cli-script-file"]))
+			}
+		}
+		if commitmentCatalog != nil {
+			plan := commitment.NewPlan(identification["account"], records, commitmentCatalog)
+			if err := plan.Write(commitmentPlanFile, displayCurrency); err != nil {
+				fail(err)
+			} else {
+				note(fmt.Sprintf("%s, written to %s", plan.Summary(displayCurrency), commitmentPlanFile))
+			}
+		}
+		if flags["group-by-tag"] != "" {
+			chargebackReport := chargeback.NewReport(identification["account"], flags["group-by-tag"], records)
+			if err := chargebackReport.WriteCSV(chargebackFile+".csv", displayCurrency); err != nil {
+				fail(err)
+			} else if err := chargebackReport.WriteJSON(chargebackFile+".json", displayCurrency); err != nil {
+				fail(err)
+			} else {
+				note(fmt.Sprintf("%s, written to %s.csv and %s.json", chargebackReport.Summary(displayCurrency), chargebackFile, chargebackFile))
+			}
+		}
+		if applyJournal != nil {
+			var targets []remediation.Target
+			for _, t := range remediation.Targets(records) {
+				if applySelection[t.Id] {
+					targets = append(targets, t)
+					delete(applySelection, t.Id)
+				}
+			}
+			for id := range applySelection {
+				applyJournal.Entries = append(applyJournal.Entries, remediation.JournalEntry{
+					Id: id, Status: remediation.JournalSkipped, Message: "no recommendation with savings for this resource", Time: time.Now().UTC(),
+				})
+			}
+			applier := remediation.NewApplier(cfg, applyJournal, dryRun, applyImmediately)
+			if err := applier.Apply(ctx, targets); err != nil {
+				fail(err)
+			} else if err := applyJournal.Save(); err != nil {
+				fail(err)
+			} else {
+				note(fmt.Sprintf("%s: %s, journal written to %s", applyTitle(dryRun), applyJournal.Summary(), applyJournal.Path()))
+			}
+		}
+		if historyStore != nil {
+			run := history.Run{
+				Account:    identification["account"],
+				Command:    command,
+				StartedAt:  startedAt,
+				FinishedAt: time.Now(),
+				Records:    records,
+			}
+			for _, r := range summaryResources {
+				if r.Status != summary.StatusAnalysed {
+					run.Unanalysed = append(run.Unanalysed, r.Id)
+				}
+			}
+			if diff {
+				previous, err := historyStore.Latest(run.Account, run.Command, run.StartedAt)
+				if err != nil {
+					fail(err)
+				}
+				runDiff := history.Compare(previous, run)
+				if err := runDiff.Write(diffFile, displayCurrency); err != nil {
+					fail(err)
+				} else {
+					note(fmt.Sprintf("%s, written to %s", runDiff.Summary(displayCurrency), diffFile))
+				}
+			}
+			if realizedSavings {
+				runs, err := historyStore.Runs(run.Account, run.Command)
+				if err != nil {
+					fail(err)
+				}
+				savings := history.RealizedSavings(runs, run)
+				if err := history.WriteRealizedSavings(realizedSavingsFile, run.Account, savings, displayCurrency); err != nil {
+					fail(err)
+				} else {
+					note(fmt.Sprintf("%s, written to %s", history.RealizedSavingsSummary(savings, displayCurrency), realizedSavingsFile))
+				}
+			}
+			if err := historyStore.Save(run); err != nil {
+				fail(err)
+			}
+		}
+		if pricingAdjustments != nil {
+			trailer = append(trailer, pricingAdjustments.ExportRow(displayCurrency))
+		}
+		trailer = append(trailer, resultsSummary.Rows(displayCurrency)...)
+		if len(trailer) > 0 {
+			export.Csv = append(export.Csv, &golang.CSVRow{Row: []string{}})
+			for _, row := range trailer {
+				export.Csv = append(export.Csv, &golang.CSVRow{Row: row})
+			}
+		}
+		publishNonInteractiveExport(export)
+		if markdownOutput {
+			// the markdown report is the whole result summary so it can be posted as is, only failures follow it
+			lines = []string{markdownReport}
+		}
+		for _, failure := range failures {
+			lines = append(lines, "Error: "+failure)
+		}
+		if len(lines) > 0 {
+			publishResultSummary(&golang.ResultSummary{Message: strings.Join(lines, "\n")})
+		}
 		publishResultsReady(true)
 	})
 
 	return nil
 }
 
+// loadPreferencesFile overrides items with the settings of a profile of a preferences file and returns the
+// tag rules of the file, applied per resource by the processors. Without a file items are returned as is.
+func loadPreferencesFile(items []*golang.PreferenceItem, path, profileName string) ([]*golang.PreferenceItem, preferences.Rules, error) {
+	if path == "" {
+		if profileName != "" {
+			return nil, nil, fmt.Errorf("preferences-profile needs preferences-file")
+		}
+		return items, nil, nil
+	}
+	file, err := preferences.LoadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	profile, err := file.Profile(profileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return profile.Apply(items), file.Rules, nil
+}
+
+// rollback restores the previous settings recorded in an apply journal, it does not analyze any resources.
+func (p *AWSPlugin) rollback(ctx context.Context, cfg aws.Config, journalPath string, dryRun bool) error {
+	if journalPath == "" {
+		return fmt.Errorf("rollback needs apply-journal")
+	}
+	journal, err := remediation.ReadJournal(journalPath)
+	if err != nil {
+		return err
+	}
+	restored, err := remediation.NewApplier(cfg, journal, dryRun, false).Rollback(ctx)
+	if err != nil {
+		return err
+	}
+	title := "Rollback"
+	if dryRun {
+		title = "Rollback dry run"
+	}
+	p.stream.Send(&golang.PluginMessage{
+		PluginMessage: &golang.PluginMessage_Summary{
+			Summary: &golang.ResultSummary{Message: fmt.Sprintf("%s: %s, journal %s", title, (&remediation.Journal{Entries: restored}).Summary(), journalPath)},
+		},
+	})
+	p.stream.Send(&golang.PluginMessage{
+		PluginMessage: &golang.PluginMessage_Ready{
+			Ready: &golang.ResultsReady{
+				Ready: true,
+			},
+		},
+	})
+	return nil
+}
+
+func applyTitle(dryRun bool) string {
+	if dryRun {
+		return "Apply dry run"
+	}
+	return "Apply"
+}
+
 func (p *AWSPlugin) ReEvaluate(_ context.Context, evaluate *golang.ReEvaluate) {
 	p.processor.ReEvaluate(evaluate.Id, evaluate.Preferences)
 }
@@ -0,0 +1,189 @@
+package summary
+
+import (
+	"fmt"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"sort"
+	"strings"
+)
+
+type Status string
+
+const (
+	StatusAnalysed Status = "analysed"
+	StatusSkipped  Status = "skipped"
+	StatusFailed   Status = "failed"
+)
+
+const (
+	DimensionAccount        = "Account"
+	DimensionRegion         = "Region"
+	DimensionResourceType   = "Resource Type"
+	DimensionInstanceFamily = "Instance Family"
+)
+
+// ReasonNotLoaded is the skip reason of the resources left to load on demand that were not.
+const ReasonNotLoaded = "not loaded"
+
+// Untagged is the group of the resources without the cost allocation tag.
+const Untagged = "(untagged)"
+
+// Resource is a resource of the run. The monthly current cost and savings of all its devices, its volumes
+// or storage included, are only known once analysed; resources skipped by the processors, with the reason
+// why, or still waiting for their metrics or optimization when the run finished, failed, have none.
+type Resource struct {
+	Id             string            `json:"id"`
+	Account        string            `json:"account"`
+	Region         string            `json:"region"`
+	ResourceType   string            `json:"resourceType"`
+	InstanceFamily string            `json:"instanceFamily"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	Status         Status            `json:"status"`
+	SkipReason     string            `json:"skipReason,omitempty"`
+	CurrentCost    float64           `json:"currentCost"`
+	Savings        float64           `json:"savings"`
+}
+
+// Group is the total of the resources sharing the value of a dimension.
+type Group struct {
+	Key         string  `json:"key"`
+	Analysed    int     `json:"analysed"`
+	Skipped     int     `json:"skipped"`
+	Failed      int     `json:"failed"`
+	CurrentCost float64 `json:"currentCost"`
+	Savings     float64 `json:"savings"`
+}
+
+// Breakdown is the groups of a dimension, the ones saving the most first.
+type Breakdown struct {
+	Dimension string  `json:"dimension"`
+	Groups    []Group `json:"groups"`
+}
+
+// Summary is the total of the run and its breakdowns by account, region, resource type, instance family
+// and, when set, a cost allocation tag. Costs are in USD.
+type Summary struct {
+	Total      Group       `json:"total"`
+	Breakdowns []Breakdown `json:"breakdowns"`
+	Tag        string      `json:"tag,omitempty"`
+}
+
+// New summarizes resources, broken down by the value of the tag key too when not empty.
+func New(resources []Resource, tagKey string) *Summary {
+	s := &Summary{Tag: tagKey}
+	dimensions := []string{DimensionAccount, DimensionRegion, DimensionResourceType, DimensionInstanceFamily}
+	if tagKey != "" {
+		dimensions = append(dimensions, TagDimension(tagKey))
+	}
+	groups := map[string]map[string]*Group{}
+	for _, d := range dimensions {
+		groups[d] = map[string]*Group{}
+	}
+	for _, r := range resources {
+		keys := map[string]string{
+			DimensionAccount:        r.Account,
+			DimensionRegion:         r.Region,
+			DimensionResourceType:   r.ResourceType,
+			DimensionInstanceFamily: r.InstanceFamily,
+		}
+		if tagKey != "" {
+			keys[TagDimension(tagKey)] = TagValue(r.Tags, tagKey)
+		}
+		s.Total.add(r)
+		for _, d := range dimensions {
+			g, ok := groups[d][keys[d]]
+			if !ok {
+				g = &Group{Key: keys[d]}
+				groups[d][keys[d]] = g
+			}
+			g.add(r)
+		}
+	}
+	for _, d := range dimensions {
+		breakdown := Breakdown{Dimension: d}
+		for _, g := range groups[d] {
+			breakdown.Groups = append(breakdown.Groups, *g)
+		}
+		sort.Slice(breakdown.Groups, func(i, j int) bool {
+			if breakdown.Groups[i].Savings != breakdown.Groups[j].Savings {
+				return breakdown.Groups[i].Savings > breakdown.Groups[j].Savings
+			}
+			return breakdown.Groups[i].Key < breakdown.Groups[j].Key
+		})
+		s.Breakdowns = append(s.Breakdowns, breakdown)
+	}
+	return s
+}
+
+// TagValue is the value of the cost allocation tag a resource is grouped by, Untagged when it has none.
+func TagValue(tags map[string]string, tagKey string) string {
+	if value := tags[tagKey]; value != "" {
+		return value
+	}
+	return Untagged
+}
+
+// TagDimension is the dimension of the breakdown by a cost allocation tag.
+func TagDimension(tagKey string) string {
+	return "Tag " + tagKey
+}
+
+func (g *Group) add(r Resource) {
+	switch r.Status {
+	case StatusSkipped:
+		g.Skipped++
+	case StatusFailed:
+		g.Failed++
+	default:
+		g.Analysed++
+	}
+	g.CurrentCost += r.CurrentCost
+	g.Savings += r.Savings
+}
+
+// SavingsPercent is the part of the current cost the group saves.
+func (g Group) SavingsPercent() float64 {
+	if g.CurrentCost <= 0 {
+		return 0
+	}
+	return g.Savings / g.CurrentCost * 100
+}
+
+func (g Group) costs(c *currency.Currency) string {
+	return fmt.Sprintf("%s saving %s (%.1f%%)", c.Format(g.CurrentCost), c.Format(g.Savings), g.SavingsPercent())
+}
+
+// Lines are the summary lines of the run, the total with the resource counts then a line per breakdown.
+func (s *Summary) Lines(c *currency.Currency) []string {
+	lines := []string{fmt.Sprintf("Analysed %d resources, skipped %d, failed %d: current cost %s",
+		s.Total.Analysed, s.Total.Skipped, s.Total.Failed, s.Total.costs(c))}
+	for _, b := range s.Breakdowns {
+		var groups []string
+		for _, g := range b.Groups {
+			groups = append(groups, fmt.Sprintf("%s %s", g.Key, g.costs(c)))
+		}
+		if len(groups) > 0 {
+			lines = append(lines, fmt.Sprintf("By %s: %s", b.Dimension, strings.Join(groups, ", ")))
+		}
+	}
+	return lines
+}
+
+// Rows are the summary rows of the CSV export, headers first, labeled Summary in their first column.
+func (s *Summary) Rows(c *currency.Currency) [][]string {
+	rows := [][]string{
+		{"Summary", "Dimension", "Key", "Analysed", "Skipped", "Failed", "Current Cost", "Savings", "Savings %"},
+		s.Total.row("Total", c),
+	}
+	for _, b := range s.Breakdowns {
+		for _, g := range b.Groups {
+			rows = append(rows, g.row(b.Dimension, c))
+		}
+	}
+	return rows
+}
+
+func (g Group) row(dimension string, c *currency.Currency) []string {
+	return []string{"Summary", dimension, g.Key, fmt.Sprintf("%d", g.Analysed), fmt.Sprintf("%d", g.Skipped),
+		fmt.Sprintf("%d", g.Failed), c.Format(g.CurrentCost), c.Format(g.Savings), fmt.Sprintf("%.1f", g.SavingsPercent())}
+}
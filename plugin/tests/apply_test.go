@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/opengovern/plugin-aws/plugin/remediation"
+	"github.com/stretchr/testify/suite"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type ApplyTestSuite struct {
+	suite.Suite
+
+	endpoint *FakeEndpoint
+	cfg      aws.Config
+	path     string
+}
+
+func TestApply(t *testing.T) {
+	suite.Run(t, &ApplyTestSuite{})
+}
+
+func (ts *ApplyTestSuite) SetupTest() {
+	ts.endpoint = NewFakeEndpoint()
+	ts.cfg = aws.Config{
+		Region:       "us-east-1",
+		Credentials:  aws.AnonymousCredentials{},
+		BaseEndpoint: aws.String(ts.endpoint.URL()),
+	}
+	ts.path = filepath.Join(ts.T().TempDir(), "journal.json")
+
+	ts.endpoint.Instances["i-web"] = &FakeInstance{Type: "m5.xlarge", State: "running"}
+	ts.endpoint.Volumes["vol-data"] = &FakeVolume{Type: "gp2", Size: 100, Iops: 300}
+	// from 20GiB sql server takes the IOPS and throughput of gp3
+	ts.endpoint.DBInstances["db-1"] = &FakeDBInstance{Class: "db.m5.large", Engine: "sqlserver-se", Status: "available", Storage: "gp2", Allocated: 50, Iops: 150}
+}
+
+func (ts *ApplyTestSuite) TearDownTest() {
+	ts.endpoint.Close()
+}
+
+func (ts *ApplyTestSuite) applier(journal *remediation.Journal, dryRun, applyImmediately bool) *remediation.Applier {
+	applier := remediation.NewApplier(ts.cfg, journal, dryRun, applyImmediately)
+	applier.SetWaitDelay(time.Millisecond)
+	return applier
+}
+
+func applyTargets() []remediation.Target {
+	return []remediation.Target{
+		{Kind: remediation.TargetEC2Instance, Id: "i-web", Region: "us-east-1", CurrentInstanceType: "m5.xlarge", InstanceType: "m5.large"},
+		{Kind: remediation.TargetEBSVolume, Id: "vol-data", Region: "us-east-1", CurrentVolumeType: "gp2", VolumeType: "gp3",
+			Size: aws.Int32(80), Iops: aws.Int32(3000), Throughput: aws.Float64(125)},
+		{Kind: remediation.TargetRDSInstance, Id: "db-1", Region: "us-east-1", CurrentInstanceType: "db.m5.large", CurrentVolumeType: "gp2",
+			InstanceType: "db.t3.large", VolumeType: "gp3", Size: aws.Int32(60), Iops: aws.Int32(3000), Throughput: aws.Float64(125)},
+	}
+}
+
+func (ts *ApplyTestSuite) TestApplyAndRollback() {
+	journal, err := remediation.NewJournal(ts.path, "123456789012", false)
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.applier(journal, false, true).Apply(context.Background(), applyTargets()))
+
+	ts.Equal("m5.large", ts.endpoint.Instances["i-web"].Type)
+	ts.Equal("running", ts.endpoint.Instances["i-web"].State)
+	ts.Equal(FakeVolume{Type: "gp3", Size: 100, Iops: 3000, Throughput: 125}, *ts.endpoint.Volumes["vol-data"])
+	db := ts.endpoint.DBInstances["db-1"]
+	ts.Equal([]any{"db.t3.large", "gp3", int32(60), int32(3000), int32(125)}, []any{db.Class, db.Storage, db.Allocated, db.Iops, db.Throughput})
+
+	actions := ts.endpoint.Actions()
+	ts.Equal("StopInstances(InstanceId.1=i-web)", actions[1])
+	ts.Contains(actions, "ModifyInstanceAttribute(InstanceId=i-web, InstanceType.Value=m5.large)")
+	ts.Contains(actions, "StartInstances(InstanceId.1=i-web)")
+	ts.Contains(actions, "ModifyVolume(Iops=3000, Throughput=125, VolumeId=vol-data, VolumeType=gp3)")
+	ts.Contains(actions, "ModifyDBInstance(AllocatedStorage=60, ApplyImmediately=true, DBInstanceClass=db.t3.large, DBInstanceIdentifier=db-1, Iops=3000, StorageThroughput=125, StorageType=gp3)")
+
+	saved, err := remediation.ReadJournal(ts.path)
+	ts.Require().NoError(err)
+	ts.Require().Len(saved.Entries, 3)
+	ts.Equal("3 applied", saved.Summary())
+	ts.Equal(remediation.Settings{VolumeType: "gp2", Size: aws.Int32(100), Iops: aws.Int32(300)}, saved.Entries[1].Previous)
+	ts.Equal("size kept at 100 GiB, volumes can not shrink", saved.Entries[1].Message)
+
+	restored, err := ts.applier(saved, false, false).Rollback(context.Background())
+	ts.Require().NoError(err)
+	ts.Require().Len(restored, 3)
+	ts.Equal(remediation.TargetRDSInstance, restored[0].Kind)
+	ts.Equal("m5.xlarge", ts.endpoint.Instances["i-web"].Type)
+	ts.Equal("gp2", ts.endpoint.Volumes["vol-data"].Type)
+	ts.Equal([]any{"db.m5.large", "gp2", int32(60)}, []any{db.Class, db.Storage, db.Allocated})
+	ts.Contains(ts.endpoint.Actions(), "ModifyVolume(VolumeId=vol-data, VolumeType=gp2)")
+
+	saved, err = remediation.ReadJournal(ts.path)
+	ts.Require().NoError(err)
+	ts.Equal("3 rolled back", saved.Summary())
+	restored, err = ts.applier(saved, false, false).Rollback(context.Background())
+	ts.Require().NoError(err)
+	ts.Empty(restored)
+}
+
+func (ts *ApplyTestSuite) TestDryRun() {
+	journal, err := remediation.NewJournal(ts.path, "123456789012", true)
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.applier(journal, true, false).Apply(context.Background(), applyTargets()))
+
+	ts.Equal([]string{
+		"DescribeInstances(InstanceId.1=i-web)",
+		"DescribeVolumes(VolumeId.1=vol-data)",
+		"DescribeDBInstances(DBInstanceIdentifier=db-1)",
+	}, ts.endpoint.Actions())
+	ts.Equal("3 planned", journal.Summary())
+	ts.Equal("pending until the next maintenance window", journal.Entries[2].Message)
+
+	_, err = remediation.ReadJournal(ts.path)
+	ts.ErrorContains(err, "is from a dry run")
+	_, err = remediation.NewJournal(ts.path, "123456789012", false)
+	ts.ErrorContains(err, "already exists")
+}
+
+func (ts *ApplyTestSuite) TestRDSGp3Baseline() {
+	ts.endpoint.DBInstances["db-small"] = &FakeDBInstance{Class: "db.m5.large", Engine: "mysql", Status: "available", Storage: "gp2", Allocated: 50, Iops: 150}
+	ts.endpoint.DBInstances["db-large"] = &FakeDBInstance{Class: "db.m5.large", Engine: "mysql", Status: "available", Storage: "gp2", Allocated: 400, Iops: 1200}
+	rds := func(id string, size int32) remediation.Target {
+		return remediation.Target{Kind: remediation.TargetRDSInstance, Id: id, Region: "us-east-1", CurrentVolumeType: "gp2", VolumeType: "gp3",
+			Size: aws.Int32(size), Iops: aws.Int32(12000), Throughput: aws.Float64(500)}
+	}
+	journal, err := remediation.NewJournal(ts.path, "123456789012", false)
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.applier(journal, false, true).Apply(context.Background(), []remediation.Target{rds("db-small", 60), rds("db-large", 400)}))
+
+	// below 400GiB mysql gp3 runs at its baseline, AWS rejects IOPS and throughput
+	actions := ts.endpoint.Actions()
+	ts.Contains(actions, "ModifyDBInstance(AllocatedStorage=60, ApplyImmediately=true, DBInstanceIdentifier=db-small, StorageType=gp3)")
+	ts.Contains(actions, "ModifyDBInstance(ApplyImmediately=true, DBInstanceIdentifier=db-large, Iops=12000, StorageThroughput=500, StorageType=gp3)")
+	ts.Equal(remediation.Settings{VolumeType: "gp3", Size: aws.Int32(60)}, journal.Entries[0].Applied)
+	ts.Equal("2 applied", journal.Summary())
+}
+
+func (ts *ApplyTestSuite) TestGuards() {
+	ts.endpoint.Instances["i-asg"] = &FakeInstance{Type: "m5.xlarge", State: "running", Tags: map[string]string{"aws:autoscaling:groupName": "web-asg"}}
+	ts.endpoint.Instances["i-drift"] = &FakeInstance{Type: "c5.xlarge", State: "running"}
+	ts.endpoint.Instances["i-store"] = &FakeInstance{Type: "m5.xlarge", State: "running", InstanceStore: true}
+	ts.endpoint.Instances["i-pending"] = &FakeInstance{Type: "m5.xlarge", State: "shutting-down"}
+	ts.endpoint.Instances["i-stopped"] = &FakeInstance{Type: "m5.xlarge", State: "stopped"}
+	ts.endpoint.DBInstances["db-aurora"] = &FakeDBInstance{Class: "db.r5.large", Status: "available", Cluster: "cluster-1", Storage: "aurora"}
+	ts.endpoint.DBInstances["db-busy"] = &FakeDBInstance{Class: "db.m5.large", Status: "backing-up", Storage: "gp2"}
+	ts.endpoint.Errors["ModifyVolume"] = "VolumeModificationRateExceeded"
+
+	instance := func(id string) remediation.Target {
+		return remediation.Target{Kind: remediation.TargetEC2Instance, Id: id, Region: "us-east-1", CurrentInstanceType: "m5.xlarge", InstanceType: "m5.large"}
+	}
+	targets := []remediation.Target{
+		instance("i-asg"), instance("i-drift"), instance("i-store"), instance("i-pending"), instance("i-stopped"), instance("i-missing"),
+		applyTargets()[1],
+		{Kind: remediation.TargetRDSInstance, Id: "db-aurora", Region: "us-east-1", InstanceType: "db.r5.medium", VolumeType: "gp3", Size: aws.Int32(20)},
+		{Kind: remediation.TargetRDSInstance, Id: "db-busy", Region: "us-east-1", InstanceType: "db.t3.large"},
+	}
+	journal, err := remediation.NewJournal(ts.path, "123456789012", false)
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.applier(journal, false, false).Apply(context.Background(), targets))
+
+	messages := map[string]string{}
+	for _, e := range journal.Entries {
+		messages[e.Id] = string(e.Status) + ": " + e.Message
+	}
+	ts.Equal(map[string]string{
+		"i-asg":     "skipped: managed by auto scaling group web-asg, change its launch template instead",
+		"i-drift":   "skipped: instance type changed from m5.xlarge to c5.xlarge since the analysis",
+		"i-store":   "skipped: instance store backed instances can not be stopped to change their type",
+		"i-pending": "skipped: instance is shutting-down",
+		"i-stopped": "applied: ",
+		"i-missing": "skipped: instance not found",
+		"vol-data":  "failed: failed to modify volume: operation error EC2: ModifyVolume, https response error StatusCode: 400, RequestID: 1, api error VolumeModificationRateExceeded: injected",
+		"db-aurora": "applied: pending until the next maintenance window",
+		"db-busy":   "skipped: db instance is backing-up",
+	}, messages)
+	ts.Equal("stopped", ts.endpoint.Instances["i-stopped"].State)
+	ts.Equal(map[string]string{"DBInstanceClass": "db.r5.medium"}, ts.endpoint.DBInstances["db-aurora"].Pending)
+	ts.Equal("2 applied, 6 skipped, 1 failed", journal.Summary())
+}
@@ -1,51 +1,603 @@
 package tests
 
 import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	rdstype "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
+	"github.com/kaytu-io/kaytu/pkg/plugin/sdk"
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/kaytu"
+	"github.com/opengovern/plugin-aws/plugin/policy"
+	"github.com/opengovern/plugin-aws/plugin/preferences"
+	"github.com/opengovern/plugin-aws/plugin/pricing"
+	"github.com/opengovern/plugin-aws/plugin/processor"
+	"github.com/opengovern/plugin-aws/plugin/processor/ec2_instance"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"github.com/opengovern/plugin-aws/plugin/summary"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
+// fakeRegisterClient stands in for the CLI end of the plugin stream, only Send is used by the job queue.
+type fakeRegisterClient struct {
+	golang.Plugin_RegisterClient
+
+	lock     sync.Mutex
+	messages []*golang.PluginMessage
+}
+
+func (c *fakeRegisterClient) Send(msg *golang.PluginMessage) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.messages = append(c.messages, msg)
+	return nil
+}
+
+// wastageTransport answers the loading requests processors send to the kaytu API while listing resources.
+type wastageTransport struct {
+	lock     sync.Mutex
+	requests []string
+}
+
+func (t *wastageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.lock.Lock()
+	t.requests = append(t.requests, req.URL.Path)
+	t.lock.Unlock()
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+func (t *wastageTransport) Requests() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]string{}, t.requests...)
+}
+
 type AWSTestSuite struct {
 	suite.Suite
+
+	aws              *FakeAWS
+	client           *FakeOptimizationClient
+	metrics          *fakeMetricsProvider
+	configuration    *kaytu.Configuration
+	rules            preferences.Rules
+	policy           *policy.Policy
+	pricing          *pricing.Adjustments
+	currency         *currency.Currency
+	wastage          *wastageTransport
+	defaultTransport http.RoundTripper
+
+	lock  sync.Mutex
+	items map[string]*golang.ChartOptimizationItem
 }
 
 func TestAWS(t *testing.T) {
 	suite.Run(t, &AWSTestSuite{})
 }
 
-func (ts *AWSTestSuite) SetupSuite() {
+func (ts *AWSTestSuite) SetupTest() {
+	ts.aws = NewFakeAWS()
+	ts.aws.Regions = []string{"us-east-1", "eu-west-1"}
+	ts.client = NewFakeOptimizationClient()
+	ts.metrics = &fakeMetricsProvider{}
+	ts.configuration = &kaytu.Configuration{EC2LazyLoad: 10, RDSLazyLoad: 10}
+	ts.rules = nil
+	ts.policy = nil
+	ts.pricing = nil
+	ts.currency = nil
+	ts.items = map[string]*golang.ChartOptimizationItem{}
+
+	ts.wastage = &wastageTransport{}
+	ts.defaultTransport = http.DefaultTransport
+	http.DefaultTransport = ts.wastage
+}
+
+func (ts *AWSTestSuite) TearDownTest() {
+	http.DefaultTransport = ts.defaultTransport
+}
 
-	return
+func (ts *AWSTestSuite) publishOptimizationItem(item *golang.ChartOptimizationItem) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ts.items[item.GetOverviewChartRow().GetRowId()] = item
 }
 
-type AWSAPI interface {
-	Identify() (map[string]string, error)
-	ListAllRegions() ([]string, error)
-	ListInstances(region string) ([]types.Instance, error)
-	ListAttachedVolumes(region string, instance types.Instance) ([]types.Volume, error)
-	ListRDSInstance(region string) ([]rdstype.DBInstance, error)
+func (ts *AWSTestSuite) item(id string) *golang.ChartOptimizationItem {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	return ts.items[id]
 }
 
-type MockAWS struct{}
+// run creates the processor on a real job queue and waits until every job it pushed is done.
+func (ts *AWSTestSuite) run(newProcessor func(jobQueue *sdk.JobQueue) processor.Processor) processor.Processor {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	jobQueue := sdk.NewJobQueue(4, sdk.NewStreamController(&fakeRegisterClient{}))
+	finished := make(chan struct{})
+	var once sync.Once
+	jobQueue.SetOnFinish(func(ctx context.Context) {
+		once.Do(func() { close(finished) })
+	})
 
-func (m *MockAWS) Identify() (map[string]string, error) {
-	return nil, nil
+	prc := newProcessor(jobQueue)
+	go jobQueue.Start(ctx)
+
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		ts.FailNow("job queue did not finish")
+	}
+	return prc
 }
 
-func (m *MockAWS) ListAllRegions() ([]string, error) {
-	return nil, nil
+func (ts *AWSTestSuite) runEC2() processor.Processor {
+	return ts.run(func(jobQueue *sdk.JobQueue) processor.Processor {
+		return ec2_instance.NewProcessor(
+			ts.aws,
+			ts.metrics,
+			ec2_instance.NewCloudWatchMetricsSource(ts.metrics),
+			ts.aws.Identification,
+			ts.publishOptimizationItem,
+			func(summary *golang.ResultSummary) {},
+			"",
+			jobQueue,
+			ts.configuration,
+			1,
+			preferences.DefaultEC2Preferences,
+			ts.rules,
+			ts.policy,
+			ts.pricing,
+			ts.currency,
+			ts.client,
+		)
+	})
 }
 
-func (m *MockAWS) ListInstances(region string) ([]types.Instance, error) {
-	return nil, nil
+func (ts *AWSTestSuite) runRDS() processor.Processor {
+	return ts.run(func(jobQueue *sdk.JobQueue) processor.Processor {
+		return processor.NewRDSProcessor(
+			ts.aws,
+			ts.metrics,
+			ts.aws.Identification,
+			ts.publishOptimizationItem,
+			func(summary *golang.ResultSummary) {},
+			"",
+			jobQueue,
+			ts.configuration,
+			1,
+			preferences.DefaultRDSPreferences,
+			ts.rules,
+			ts.policy,
+			ts.pricing,
+			ts.currency,
+			ts.client,
+		)
+	})
 }
 
-func (m *MockAWS) ListAttachedVolumes(region string, instance types.Instance) ([]types.Volume, error) {
-	return nil, nil
+func ec2Instance(id string, state types.InstanceStateName, tags ...types.Tag) types.Instance {
+	return types.Instance{
+		InstanceId:      aws.String(id),
+		InstanceType:    types.InstanceTypeM5Xlarge,
+		ImageId:         aws.String("ami-1"),
+		State:           &types.InstanceState{Name: state},
+		CpuOptions:      &types.CpuOptions{CoreCount: aws.Int32(2), ThreadsPerCore: aws.Int32(2)},
+		EbsOptimized:    aws.Bool(true),
+		UsageOperation:  aws.String("RunInstances"),
+		PlatformDetails: aws.String("Linux/UNIX"),
+		Placement:       &types.Placement{AvailabilityZone: aws.String("us-east-1a"), Tenancy: types.TenancyDefault},
+		Tags:            tags,
+	}
 }
 
-func (m *MockAWS) ListRDSInstance(region string) ([]rdstype.DBInstance, error) {
-	return nil, nil
+func rdsInstance(id, engine string, clusterId *string) rdstype.DBInstance {
+	return rdstype.DBInstance{
+		DBInstanceIdentifier:       aws.String(id),
+		DBClusterIdentifier:        clusterId,
+		DBInstanceClass:            aws.String("db.m5.large"),
+		Engine:                     aws.String(engine),
+		EngineVersion:              aws.String("8.0.35"),
+		LicenseModel:               aws.String("general-public-license"),
+		AvailabilityZone:           aws.String("us-east-1a"),
+		PerformanceInsightsEnabled: aws.Bool(false),
+		StorageType:                aws.String("gp2"),
+		AllocatedStorage:           aws.Int32(100),
+		InstanceCreateTime:         aws.Time(time.Now().Add(-90 * 24 * time.Hour)),
+	}
+}
+
+func rdsRecommendation(current, recommended string) *golang2.RDSInstanceRightSizingRecommendation {
+	rec := emptyRDSRecommendation()
+	rec.Current = &golang2.RightsizingAwsRds{
+		InstanceType: current, Engine: "mysql", Vcpu: 2, MemoryGb: 8, ComputeCost: 124.1, StorageCost: 11.5,
+		StorageType: wrapperspb.String("gp2"), StorageSize: wrapperspb.Int32(100),
+	}
+	rec.Recommended = &golang2.RightsizingAwsRds{
+		InstanceType: recommended, Engine: "mysql", Vcpu: 2, MemoryGb: 4, ComputeCost: 62.05, StorageCost: 9.2,
+		StorageType: wrapperspb.String("gp3"), StorageSize: wrapperspb.Int32(100),
+	}
+	rec.Description = "memory usage is low"
+	return rec
+}
+
+func csvRowsOf(export *golang.NonInteractiveExport, resourceType string) [][]string {
+	var rows [][]string
+	for _, row := range export.Csv[1:] {
+		if row.Row[2] == resourceType {
+			rows = append(rows, row.Row)
+		}
+	}
+	return rows
+}
+
+func (ts *AWSTestSuite) TestEC2Instances() {
+	web := ec2Instance("i-web", types.InstanceStateNameRunning, types.Tag{Key: aws.String("Name"), Value: aws.String("web")})
+	web.BlockDeviceMappings = []types.InstanceBlockDeviceMapping{
+		{DeviceName: aws.String("/dev/xvda"), Ebs: &types.EbsInstanceBlockDevice{VolumeId: aws.String("vol-1")}},
+	}
+	spot := ec2Instance("i-spot", types.InstanceStateNameRunning)
+	spot.InstanceLifecycle = types.InstanceLifecycleTypeSpot
+	ts.aws.Instances["us-east-1"] = []types.Instance{web, spot, ec2Instance("i-stopped", types.InstanceStateNameStopped)}
+	ts.aws.Images["ami-1"] = types.Image{ImageId: aws.String("ami-1"), EnaSupport: aws.Bool(true)}
+	ts.aws.Volumes["vol-1"] = types.Volume{VolumeId: aws.String("vol-1"), VolumeType: types.VolumeTypeGp2, Size: aws.Int32(100), Iops: aws.Int32(300)}
+
+	usage := &golang2.Usage{Avg: wrapperspb.Double(12.5), Max: wrapperspb.Double(40)}
+	ts.client.EC2InstanceResponses[utils.HashString("i-web")] = &golang2.EC2InstanceOptimizationResponse{
+		RightSizing: &golang2.EC2InstanceRightSizingRecommendation{
+			Current:           &golang2.RightsizingEC2Instance{InstanceType: "m5.xlarge", Region: "us-east-1", Cost: 140.16, Vcpu: 4, Memory: 16},
+			Recommended:       &golang2.RightsizingEC2Instance{InstanceType: "m5.large", Region: "us-east-1", Cost: 70.08, Vcpu: 2, Memory: 8},
+			Vcpu:              usage,
+			Memory:            usage,
+			EbsBandwidth:      usage,
+			EbsIops:           usage,
+			NetworkThroughput: usage,
+			Description:       "cpu usage is low",
+		},
+		VolumeRightSizing: map[string]*golang2.EBSVolumeRecommendation{
+			utils.HashString("vol-1"): {
+				Current:     &golang2.RightsizingEBSVolume{Tier: "gp2", VolumeSize: wrapperspb.Int32(100), BaselineIops: 300, Cost: 10},
+				Recommended: &golang2.RightsizingEBSVolume{Tier: "gp3", VolumeSize: wrapperspb.Int32(100), BaselineIops: 3000, BaselineThroughput: 125, Cost: 8},
+				Iops:        usage,
+				Throughput:  usage,
+			},
+		},
+	}
+
+	export := ts.runEC2().ExportNonInteractive()
+
+	ts.Contains(ts.aws.Calls(), "ListInstances(eu-west-1)")
+	ts.Contains(ts.aws.Calls(), "ListAttachedVolumes(us-east-1, i-web)")
+	ts.NotContains(ts.aws.Calls(), "ListAttachedVolumes(us-east-1, i-spot)")
+
+	ts.Require().Len(ts.client.EC2InstanceRequests(), 1)
+	req := ts.client.EC2InstanceRequests()[0]
+	ts.Equal(utils.HashString("i-web"), req.Instance.HashedInstanceId)
+	ts.Equal("us-east-1", req.Region)
+	ts.Len(req.Volumes, 1)
+	ts.NotEmpty(req.Metrics["CPUUtilization"].GetMetric())
+	ts.NotEmpty(req.VolumeMetrics[utils.HashString("vol-1")].GetMetrics()["VolumeReadOps"].GetMetric())
+	ts.Equal([]string{"/kaytu/wastage/api/v1/wastage/ec2-instance"}, ts.wastage.Requests())
+
+	ts.Require().NotNil(ts.item("i-web"))
+	ts.False(ts.item("i-web").Loading)
+	ts.False(ts.item("i-web").Skipped)
+	ts.Require().NotNil(ts.item("i-spot"))
+	ts.True(ts.item("i-spot").Skipped)
+	ts.Equal("spot instance", ts.item("i-spot").GetSkipReason().GetValue())
+	ts.Nil(ts.item("i-stopped"))
+
+	ts.Equal("AccountID", export.Csv[0].Row[0])
+	instances := csvRowsOf(export, "EC2 Instance")
+	ts.Require().Len(instances, 1)
+	ts.Equal([]string{"123456789012", "us-east-1", "EC2 Instance", "i-web", "web"}, instances[0][:5])
+	ts.Equal("m5.xlarge", instances[0][10])
+	ts.Equal("m5.large", instances[0][11])
+	volumes := csvRowsOf(export, "EBS Volume")
+	ts.Require().Len(volumes, 1)
+	ts.Equal("vol-1", volumes[0][3])
+	ts.Equal("i-web", volumes[0][12])
+}
+
+func (ts *AWSTestSuite) TestEC2LazyLoad() {
+	ts.configuration.EC2LazyLoad = 1
+	ts.aws.Instances["us-east-1"] = []types.Instance{
+		ec2Instance("i-1", types.InstanceStateNameRunning),
+		ec2Instance("i-2", types.InstanceStateNameRunning),
+	}
+
+	ts.runEC2()
+
+	ts.Len(ts.client.EC2InstanceRequests(), 1)
+	ts.Require().NotNil(ts.item("i-2"))
+	ts.True(ts.item("i-2").LazyLoadingEnabled)
+}
+
+func (ts *AWSTestSuite) TestListFailure() {
+	ts.aws.Errors["ListInstances"] = context.DeadlineExceeded
+	ts.aws.Instances["us-east-1"] = []types.Instance{ec2Instance("i-1", types.InstanceStateNameRunning)}
+
+	export := ts.runEC2().ExportNonInteractive()
+
+	ts.Empty(ts.client.EC2InstanceRequests())
+	ts.Empty(ts.items)
+	ts.Len(export.Csv, 1)
+}
+
+func (ts *AWSTestSuite) TestRDS() {
+	ts.aws.RDSInstances["us-east-1"] = []rdstype.DBInstance{
+		rdsInstance("db-1", "mysql", nil),
+		rdsInstance("docdb-1", "docdb", nil),
+		rdsInstance("aurora-1-a", "aurora-mysql", aws.String("aurora-1")),
+	}
+	ts.aws.RDSClusters["us-east-1"] = []rdstype.DBCluster{
+		{DBClusterIdentifier: aws.String("aurora-1"), Engine: aws.String("aurora-mysql")},
+	}
+	ts.client.RDSInstanceResponses[utils.HashString("db-1")] = &golang2.RDSInstanceOptimizationResponse{
+		RightSizing: rdsRecommendation("db.m5.large", "db.t3.large"),
+	}
+	ts.client.RDSClusterResponses[utils.HashString("aurora-1")] = &golang2.RDSClusterOptimizationResponse{
+		RightSizing: map[string]*golang2.RDSInstanceRightSizingRecommendation{
+			utils.HashString("aurora-1-a"): rdsRecommendation("db.r5.large", "db.t4g.large"),
+		},
+	}
+
+	export := ts.runRDS().ExportNonInteractive()
+
+	ts.Contains(ts.aws.Calls(), "ListRDSInstanceByCluster(us-east-1, aurora-1)")
+
+	ts.Require().Len(ts.client.RDSInstanceRequests(), 1)
+	ts.Equal(utils.HashString("db-1"), ts.client.RDSInstanceRequests()[0].Instance.HashedInstanceId)
+	ts.Require().Len(ts.client.RDSClusterRequests(), 1)
+	clusterReq := ts.client.RDSClusterRequests()[0]
+	ts.Require().Len(clusterReq.Instances, 1)
+	ts.Equal(utils.HashString("aurora-1-a"), clusterReq.Instances[0].HashedInstanceId)
+
+	ts.Require().NotNil(ts.item("db-1"))
+	ts.False(ts.item("db-1").Loading)
+	ts.Require().NotNil(ts.item("docdb-1"))
+	ts.True(ts.item("docdb-1").Skipped)
+	ts.Nil(ts.item("aurora-1-a"))
+	ts.Require().NotNil(ts.item("aurora-1"))
+	ts.False(ts.item("aurora-1").Loading)
+
+	compute := csvRowsOf(export, "RDS Instance Compute")
+	ts.Require().Len(compute, 2)
+	var suggested []string
+	for _, row := range compute {
+		suggested = append(suggested, row[4]+":"+row[11])
+	}
+	ts.ElementsMatch([]string{"db-1:db.t3.large", "aurora-1-a:db.t4g.large"}, suggested)
+	ts.Len(csvRowsOf(export, "RDS Instance Storage"), 2)
+}
+
+func (ts *AWSTestSuite) TestPreferenceRules() {
+	thirty := "30"
+	ts.rules = preferences.Rules{{
+		Name:        "prod",
+		Tags:        map[string]string{"env": "prod"},
+		Preferences: preferences.Profile{"RDSInstance": {"CpuBreathingRoom": {Value: &thirty}}},
+	}}
+	prod := rdsInstance("db-prod", "mysql", nil)
+	prod.TagList = []rdstype.Tag{{Key: aws.String("env"), Value: aws.String("prod")}}
+	dev := rdsInstance("db-dev", "mysql", nil)
+	dev.TagList = []rdstype.Tag{{Key: aws.String("env"), Value: aws.String("dev")}}
+	ts.aws.RDSInstances["us-east-1"] = []rdstype.DBInstance{prod, dev}
+	ts.client.RDSInstanceResponses[utils.HashString("db-prod")] = &golang2.RDSInstanceOptimizationResponse{
+		RightSizing: rdsRecommendation("db.m5.large", "db.m5.large"),
+	}
+	ts.client.RDSInstanceResponses[utils.HashString("db-dev")] = &golang2.RDSInstanceOptimizationResponse{
+		RightSizing: rdsRecommendation("db.m5.large", "db.t3.large"),
+	}
+
+	export := ts.runRDS().ExportNonInteractive()
+
+	breathingRoom := map[string]string{}
+	for _, req := range ts.client.RDSInstanceRequests() {
+		breathingRoom[req.Instance.HashedInstanceId] = req.Preferences["CpuBreathingRoom"].GetValue()
+	}
+	ts.Equal(map[string]string{utils.HashString("db-prod"): "30", utils.HashString("db-dev"): "10"}, breathingRoom)
+
+	details := map[string]string{}
+	for _, row := range csvRowsOf(export, "RDS Instance Compute") {
+		details[row[4]] = row[len(row)-1]
+	}
+	ts.Contains(details["db-prod"], "Preference Overrides:: CpuBreathingRoom=30 (prod)")
+	ts.NotContains(details["db-dev"], "Preference Overrides")
+}
+
+func (ts *AWSTestSuite) TestPolicy() {
+	path := filepath.Join(ts.T().TempDir(), "policy.yaml")
+	ts.Require().NoError(os.WriteFile(path, []byte(`version: 1
+rules:
+  - name: no burstable
+    resource: RDSInstance
+    when: family(recommended.instance_type).startsWith("t")
+    action: reject
+`), 0o600))
+	var err error
+	ts.policy, err = policy.Load(path)
+	ts.Require().NoError(err)
+	ts.aws.RDSInstances["us-east-1"] = []rdstype.DBInstance{
+		rdsInstance("db-1", "mysql", nil),
+		rdsInstance("aurora-1-a", "aurora-mysql", aws.String("aurora-1")),
+	}
+	ts.aws.RDSClusters["us-east-1"] = []rdstype.DBCluster{
+		{DBClusterIdentifier: aws.String("aurora-1"), Engine: aws.String("aurora-mysql")},
+	}
+	ts.client.RDSInstanceResponses[utils.HashString("db-1")] = &golang2.RDSInstanceOptimizationResponse{
+		RightSizing: rdsRecommendation("db.m5.large", "db.t3.large"),
+	}
+	ts.client.RDSClusterResponses[utils.HashString("aurora-1")] = &golang2.RDSClusterOptimizationResponse{
+		RightSizing: map[string]*golang2.RDSInstanceRightSizingRecommendation{
+			utils.HashString("aurora-1-a"): rdsRecommendation("db.r5.large", "db.t4g.large"),
+		},
+	}
+
+	export := ts.runRDS().ExportNonInteractive()
+
+	compute := csvRowsOf(export, "RDS Instance Compute")
+	ts.Require().Len(compute, 2)
+	for _, row := range compute {
+		ts.Equal(row[10], row[11], row[4])
+		ts.Equal("$0.00", row[9], row[4])
+		ts.True(strings.HasPrefix(row[13], "Rejected by policy rule no burstable: "), row[13])
+	}
+}
+
+func (ts *AWSTestSuite) TestCommitments() {
+	ts.aws.Instances["us-east-1"] = []types.Instance{ec2Instance("i-1", types.InstanceStateNameRunning)}
+	ts.aws.ReservedInstances["us-east-1"] = []types.ReservedInstances{reservedInstance("ri-1", types.InstanceTypeM5Xlarge, 1, "")}
+	ts.client.EC2InstanceResponses[utils.HashString("i-1")] = &golang2.EC2InstanceOptimizationResponse{
+		RightSizing: ec2Rightsizing("m5.large", "Intel Xeon", 8),
+	}
+
+	prc := ts.runEC2()
+	export := prc.ExportNonInteractive()
+
+	ts.Contains(ts.aws.Calls(), "ListReservedInstances(us-east-1)")
+	ts.Contains(ts.aws.Calls(), "ListSavingsPlans()")
+	instances := csvRowsOf(export, "EC2 Instance")
+	ts.Require().Len(instances, 1)
+	ts.Contains(instances[0][14], "Commitment Coverage:: ri-1 - Covered: $140.16 - Effective Savings: $0.00")
+	ts.Contains(instances[0][14], "Commitment Warning:: m5.large leaves 4 of the 8 normalized units of reserved instance ri-1 given to it unused")
+
+	records := prc.Records()
+	ts.Require().Len(records, 1)
+	ts.Require().NotNil(records[0].Coverage)
+	ts.Equal([]string{"ri-1"}, records[0].Coverage.Commitments)
+	ts.Equal(70.08, records[0].Savings)
+}
+
+func (ts *AWSTestSuite) TestPricingAdjustments() {
+	path := filepath.Join(ts.T().TempDir(), "pricing.yaml")
+	ts.Require().NoError(os.WriteFile(path, []byte("version: 1\nadjustments:\n  - name: EDP\n    discount: 50\n"), 0o600))
+	var err error
+	ts.pricing, err = pricing.Load(path)
+	ts.Require().NoError(err)
+	ts.aws.Instances["us-east-1"] = []types.Instance{ec2Instance("i-web", types.InstanceStateNameRunning)}
+	ts.client.EC2InstanceResponses[utils.HashString("i-web")] = &golang2.EC2InstanceOptimizationResponse{
+		RightSizing: goldenEC2Recommendation(),
+	}
+
+	prc := ts.runEC2()
+	instances := csvRowsOf(prc.ExportNonInteractive(), "EC2 Instance")
+
+	ts.Require().Len(instances, 1)
+	ts.Equal([]string{"$70.08", "$35.04", "$35.04"}, instances[0][7:10])
+	ts.Require().Len(prc.Records(), 1)
+	ts.InDelta(35.04, prc.Records()[0].Savings, 0.001)
+	ts.Equal([]string{"Pricing Adjustments", "EDP: 50% discount on all usage types of all services in all regions"}, ts.pricing.ExportRow(nil))
+	ts.Equal(140.16, ts.client.EC2InstanceResponses[utils.HashString("i-web")].RightSizing.Current.Cost, "responses are copies")
+}
+
+func (ts *AWSTestSuite) TestObservedRuntime() {
+	// a single hour with datapoints in the one day window, like an instance stopped the rest of the day
+	ts.metrics.Start = time.Now().Add(-20 * time.Hour)
+	ts.aws.Instances["us-east-1"] = []types.Instance{ec2Instance("i-web", types.InstanceStateNameRunning)}
+	ts.client.EC2InstanceResponses[utils.HashString("i-web")] = &golang2.EC2InstanceOptimizationResponse{
+		RightSizing: goldenEC2Recommendation(),
+	}
+	ts.aws.RDSInstances["us-east-1"] = []rdstype.DBInstance{
+		rdsInstance("db-1", "mysql", nil),
+		rdsInstance("aurora-1-a", "aurora-mysql", aws.String("aurora-1")),
+	}
+	ts.aws.RDSClusters["us-east-1"] = []rdstype.DBCluster{
+		{DBClusterIdentifier: aws.String("aurora-1"), Engine: aws.String("aurora-mysql")},
+	}
+	ts.client.RDSInstanceResponses[utils.HashString("db-1")] = &golang2.RDSInstanceOptimizationResponse{
+		RightSizing: rdsRecommendation("db.m5.large", "db.t3.large"),
+	}
+	ts.client.RDSClusterResponses[utils.HashString("aurora-1")] = &golang2.RDSClusterOptimizationResponse{
+		RightSizing: map[string]*golang2.RDSInstanceRightSizingRecommendation{
+			utils.HashString("aurora-1-a"): rdsRecommendation("db.r5.large", "db.t4g.large"),
+		},
+	}
+
+	instances := csvRowsOf(ts.runEC2().ExportNonInteractive(), "EC2 Instance")
+	ts.Require().Len(instances, 1)
+	ts.Equal([]string{"30 hours", "$5.84", "$2.92", "$2.92"}, instances[0][6:10])
+	ts.Equal("30 hours", ts.item("i-web").DevicesChartRows[0].Values["runtime"].Value)
+
+	export := ts.runRDS().ExportNonInteractive()
+	compute := csvRowsOf(export, "RDS Instance Compute")
+	ts.Require().Len(compute, 2)
+	for _, row := range compute {
+		ts.Equal([]string{"30 hours", "$5.17", "$2.59"}, row[6:9], row[3])
+	}
+	storage := csvRowsOf(export, "RDS Instance Storage")
+	ts.Require().Len(storage, 2)
+	for _, row := range storage {
+		ts.Equal([]string{"730 hours", "$11.50"}, row[6:8], row[3])
+	}
+}
+
+func (ts *AWSTestSuite) TestCurrency() {
+	ts.currency = &currency.Currency{Code: "EUR", Symbol: "€", Rate: 0.5}
+	ts.aws.Instances["us-east-1"] = []types.Instance{ec2Instance("i-web", types.InstanceStateNameRunning)}
+	ts.client.EC2InstanceResponses[utils.HashString("i-web")] = &golang2.EC2InstanceOptimizationResponse{
+		RightSizing: goldenEC2Recommendation(),
+	}
+
+	prc := ts.runEC2()
+	instances := csvRowsOf(prc.ExportNonInteractive(), "EC2 Instance")
+
+	ts.Require().Len(instances, 1)
+	ts.Equal([]string{"€70.08", "€35.04", "€35.04"}, instances[0][7:10])
+	ts.Contains(instances[0][14], "License Cost:: Current: €0.00")
+	ts.Equal("€70.08", ts.item("i-web").DevicesChartRows[0].Values["current_cost"].Value)
+	ts.Equal("€35.04 (50.00%)", ts.item("i-web").OverviewChartRow.Values["total_saving"].Value)
+	ts.Require().Len(prc.Records(), 1)
+	ts.Equal(140.16, prc.Records()[0].CurrentCost, "records stay in USD")
+}
+
+func (ts *AWSTestSuite) TestSummaryResources() {
+	team := types.Tag{Key: aws.String("team"), Value: aws.String("web")}
+	spot := ec2Instance("i-spot", types.InstanceStateNameRunning)
+	spot.InstanceLifecycle = types.InstanceLifecycleTypeSpot
+	ts.aws.Instances["us-east-1"] = []types.Instance{ec2Instance("i-web", types.InstanceStateNameRunning, team), spot}
+	ts.aws.Instances["eu-west-1"] = []types.Instance{ec2Instance("i-broken", types.InstanceStateNameRunning, team)}
+	ts.client.EC2InstanceResponses[utils.HashString("i-web")] = &golang2.EC2InstanceOptimizationResponse{
+		RightSizing: goldenEC2Recommendation(),
+	}
+	ts.client.EC2InstanceErrs[utils.HashString("i-broken")] = errors.New("unavailable")
+
+	resources := ts.runEC2().SummaryResources()
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Id < resources[j].Id })
+
+	ts.Equal([]summary.Resource{
+		{Id: "i-broken", Account: "123456789012", Region: "eu-west-1", ResourceType: "EC2 Instance", InstanceFamily: "m5",
+			Tags: map[string]string{"team": "web"}, Status: summary.StatusFailed},
+		{Id: "i-spot", Account: "123456789012", Region: "us-east-1", ResourceType: "EC2 Instance", InstanceFamily: "m5",
+			Tags: map[string]string{}, Status: summary.StatusSkipped, SkipReason: "spot instance"},
+		{Id: "i-web", Account: "123456789012", Region: "us-east-1", ResourceType: "EC2 Instance", InstanceFamily: "m5",
+			Tags: map[string]string{"team": "web"}, Status: summary.StatusAnalysed, CurrentCost: 140.16, Savings: 70.08},
+	}, resources)
+
+	s := summary.New(resources, "team")
+	ts.Equal([]string{
+		"Analysed 1 resources, skipped 1, failed 1: current cost $140.16 saving $70.08 (50.0%)",
+		"By Account: 123456789012 $140.16 saving $70.08 (50.0%)",
+		"By Region: us-east-1 $140.16 saving $70.08 (50.0%), eu-west-1 $0.00 saving $0.00 (0.0%)",
+		"By Resource Type: EC2 Instance $140.16 saving $70.08 (50.0%)",
+		"By Instance Family: m5 $140.16 saving $70.08 (50.0%)",
+		"By Tag team: web $140.16 saving $70.08 (50.0%), (untagged) $0.00 saving $0.00 (0.0%)",
+	}, s.Lines(nil))
 }
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	rdstype "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/opengovern/plugin-aws/plugin/chargeback"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/summary"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type ChargebackTestSuite struct {
+	suite.Suite
+}
+
+func TestChargeback(t *testing.T) {
+	suite.Run(t, &ChargebackTestSuite{})
+}
+
+func taggedRecord(resourceType, id string, cost, savings float64, tags map[string]string) history.Record {
+	return history.Record{ResourceType: resourceType, ResourceId: id, ResourceName: id, Region: "us-east-1",
+		CurrentSpec: "current", RecommendedSpec: "recommended", CurrentCost: cost, Savings: savings, Tags: tags}
+}
+
+func (ts *ChargebackTestSuite) TestGroups() {
+	web := map[string]string{"team": "web"}
+	volume := taggedRecord("EBS Volume", "vol-web", 10, 2, web)
+	volume.ParentId = "i-web"
+	compute := taggedRecord("RDS Instance Compute", "db-data-compute", 200, 100, map[string]string{"team": "data"})
+	storage := taggedRecord("RDS Instance Storage", "db-data-storage", 20, 0, map[string]string{"team": "data"})
+	compute.ParentId, storage.ParentId = "db-data", "db-data"
+	report := chargeback.NewReport("123456789012", "team", []history.Record{
+		taggedRecord("EC2 Instance", "i-web", 140, 70, web),
+		volume,
+		compute,
+		storage,
+		taggedRecord("EC2 Instance", "i-orphan", 50, 5, map[string]string{"Name": "orphan"}),
+		taggedRecord("EC2 Instance", "i-blank", 30, 0, map[string]string{"team": ""}),
+	})
+
+	ts.Require().Len(report.Groups, 3)
+	ts.Equal([]string{"data", "web", summary.Untagged},
+		[]string{report.Groups[0].Value, report.Groups[1].Value, report.Groups[2].Value})
+
+	// an instance counts once with its volumes or storage
+	data := report.Groups[0]
+	ts.Equal(1, data.Resources)
+	ts.InDelta(220.0, data.CurrentCost, 0.001)
+	ts.InDelta(100.0, data.Savings, 0.001)
+	ts.Require().Len(data.TopOffenders, 1)
+	ts.Equal("db-data-compute", data.TopOffenders[0].ResourceId)
+
+	web2 := report.Groups[1]
+	ts.Equal(1, web2.Resources)
+	ts.Equal([]string{"i-web", "vol-web"}, []string{web2.TopOffenders[0].ResourceId, web2.TopOffenders[1].ResourceId})
+
+	untagged := report.Groups[2]
+	ts.Equal(2, untagged.Resources)
+	ts.InDelta(80.0, untagged.CurrentCost, 0.001)
+
+	ts.Equal(4, report.Total.Resources)
+	ts.InDelta(450.0, report.Total.CurrentCost, 0.001)
+	ts.InDelta(177.0, report.Total.Savings, 0.001)
+	ts.Equal("Chargeback by team: data saves $100.00 of $220.00 (45.5%), web saves $72.00 of $150.00 (48.0%), "+
+		"(untagged) saves $5.00 of $80.00 (6.2%)", report.Summary(nil))
+}
+
+func (ts *ChargebackTestSuite) TestTopOffenders() {
+	var records []history.Record
+	for i := 1; i <= 7; i++ {
+		records = append(records, taggedRecord("EC2 Instance", fmt.Sprintf("i-%d", i), 100, float64(i*10), map[string]string{"team": "web"}))
+	}
+
+	report := chargeback.NewReport("123456789012", "team", records)
+
+	ts.Require().Len(report.Groups, 1)
+	ts.Equal(7, report.Groups[0].Resources)
+	var ids []string
+	for _, o := range report.Groups[0].TopOffenders {
+		ids = append(ids, o.ResourceId)
+	}
+	ts.Equal([]string{"i-7", "i-6", "i-5", "i-4", "i-3"}, ids)
+}
+
+func (ts *ChargebackTestSuite) TestRows() {
+	report := chargeback.NewReport("123456789012", "team", []history.Record{
+		taggedRecord("EC2 Instance", "i-web", 140, 70, map[string]string{"team": "web"}),
+	})
+
+	rows := report.Rows(&currency.Currency{Code: "EUR", Symbol: "€", Rate: 0.5})
+
+	ts.Equal([][]string{
+		{"AccountID", "Tag", "Tag Value", "Resources", "Current Cost", "Savings", "Savings %", "Rank", "Resource Type",
+			"Resource ID", "Resource Name", "Region", "Current Spec", "Suggested Spec", "Resource Current Cost", "Resource Savings"},
+		{"123456789012", "team", "web", "1", "€70.00", "€35.00", "50.0", "", "", "", "", "", "", "", "", ""},
+		{"123456789012", "team", "web", "", "", "", "", "1", "EC2 Instance", "i-web", "i-web", "us-east-1", "current",
+			"recommended", "€70.00", "€35.00"},
+		{"123456789012", "team", "Total", "1", "€70.00", "€35.00", "50.0", "", "", "", "", "", "", "", "", ""},
+	}, rows)
+}
+
+func (ts *ChargebackTestSuite) TestWriteJSON() {
+	report := chargeback.NewReport("123456789012", "team", []history.Record{
+		taggedRecord("EC2 Instance", "i-web", 140, 70, map[string]string{"team": "web"}),
+	})
+	path := filepath.Join(ts.T().TempDir(), "kaytu-chargeback.json")
+
+	ts.Require().NoError(report.WriteJSON(path, nil))
+
+	content, err := os.ReadFile(path)
+	ts.Require().NoError(err)
+	var written chargeback.Report
+	ts.Require().NoError(json.Unmarshal(content, &written))
+	ts.Equal(*report, written)
+	ts.Equal("USD", written.Currency)
+
+	// converted like the CSV
+	ts.Require().NoError(report.WriteJSON(path, &currency.Currency{Code: "EUR", Symbol: "€", Rate: 0.5}))
+	content, err = os.ReadFile(path)
+	ts.Require().NoError(err)
+	written = chargeback.Report{}
+	ts.Require().NoError(json.Unmarshal(content, &written))
+	ts.Equal("EUR", written.Currency)
+	ts.Equal([]float64{70, 35}, []float64{written.Total.CurrentCost, written.Total.Savings})
+	ts.Equal([]float64{70, 35}, []float64{written.Groups[0].TopOffenders[0].CurrentCost, written.Groups[0].TopOffenders[0].Savings})
+	ts.Equal(140.0, report.Total.CurrentCost, "the report stays in USD")
+}
+
+func (ts *AWSTestSuite) TestChargebackInheritsTags() {
+	ts.goldenRDS()
+	ts.aws.RDSClusters["us-east-1"][0].TagList = []rdstype.Tag{{Key: aws.String("team"), Value: aws.String("data")}}
+
+	report := chargeback.NewReport("123456789012", "team", ts.runRDS().Records())
+
+	ts.Require().Len(report.Groups, 2)
+	var data chargeback.Group
+	for _, g := range report.Groups {
+		if g.Value == "data" {
+			data = g
+		}
+	}
+	// the cluster, counted once for the storage and compute of aurora-1-a, its instance with a recommendation
+	ts.Equal(1, data.Resources)
+	for _, o := range data.TopOffenders {
+		ts.Contains([]string{"aurora-1-a-compute", "aurora-1-a-storage"}, o.ResourceId)
+	}
+}
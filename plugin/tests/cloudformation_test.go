@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/opengovern/plugin-aws/plugin/remediation"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type CloudFormationTestSuite struct {
+	suite.Suite
+
+	dir string
+}
+
+func TestCloudFormation(t *testing.T) {
+	suite.Run(t, &CloudFormationTestSuite{})
+}
+
+func (ts *CloudFormationTestSuite) SetupTest() {
+	ts.dir = ts.T().TempDir()
+}
+
+func (ts *CloudFormationTestSuite) write(name, content string) string {
+	path := filepath.Join(ts.dir, name)
+	ts.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+const cloudFormationYaml = `AWSTemplateFormatVersion: "2010-09-09"
+Parameters:
+  ApiInstanceType:
+    Type: String
+    Default: m5.xlarge
+  SharedInstanceType:
+    Type: String
+    Default: m5.xlarge
+Conditions:
+  IsProd: !Equals [!Ref "AWS::StackName", prod]
+Resources:
+  # serves the website
+  Web:
+    Type: AWS::EC2::Instance
+    Properties:
+      ImageId: ami-1
+      InstanceType: m5.xlarge # sized for launch
+  Api:
+    Type: AWS::EC2::Instance
+    Properties:
+      ImageId: ami-1
+      InstanceType: !Ref ApiInstanceType
+  Worker:
+    Type: AWS::EC2::Instance
+    Properties:
+      ImageId: ami-1
+      InstanceType: !Ref SharedInstanceType
+  Batch:
+    Type: AWS::EC2::Instance
+    Properties:
+      ImageId: ami-1
+      InstanceType: !Ref SharedInstanceType
+  Cache:
+    Type: AWS::EC2::Instance
+    Properties:
+      ImageId: ami-1
+      InstanceType: !If [IsProd, m5.xlarge, t3.large]
+  Data:
+    Type: AWS::EC2::Volume
+    Properties:
+      AvailabilityZone: us-east-1a
+      Size: 100
+      VolumeType: gp2
+`
+
+func (ts *CloudFormationTestSuite) TestYamlTemplate() {
+	template := ts.write("template.yaml", cloudFormationYaml)
+	resources := ts.write("resources.json", `{"StackResources": [
+  {"LogicalResourceId": "Web", "PhysicalResourceId": "i-web", "ResourceType": "AWS::EC2::Instance"},
+  {"LogicalResourceId": "Api", "PhysicalResourceId": "i-api", "ResourceType": "AWS::EC2::Instance"},
+  {"LogicalResourceId": "Worker", "PhysicalResourceId": "i-worker", "ResourceType": "AWS::EC2::Instance"},
+  {"LogicalResourceId": "Cache", "PhysicalResourceId": "i-cache", "ResourceType": "AWS::EC2::Instance"},
+  {"LogicalResourceId": "Data", "PhysicalResourceId": "vol-data", "ResourceType": "AWS::EC2::Volume"}
+]}`)
+	records := terraformRecords()
+	records = append(records, instanceRecord("i-api", "m5.xlarge", "m5.large", 140, 70))
+	for i := range records {
+		if records[i].ResourceType == "EC2 Instance" {
+			records[i].CurrentType, records[i].RecommendedType = "m5.xlarge", records[i].RecommendedSpec
+		}
+	}
+
+	patch, err := remediation.NewCloudFormationPatch(template, resources, remediation.Targets(records))
+	ts.Require().NoError(err)
+
+	assertGolden(&ts.Suite, "cloudformation_template.yaml", patch.Template)
+	ts.Equal([]remediation.CloudFormationParameter{{ParameterKey: "ApiInstanceType", ParameterValue: "m5.large"}}, patch.Parameters)
+	reasons := map[string]string{}
+	for _, s := range patch.Skipped {
+		reasons[s.ResourceId] += s.Reason
+	}
+	ts.Equal(map[string]string{
+		"i-worker": "InstanceType references parameter SharedInstanceType which is also used elsewhere in the template, set it to m5.large",
+		"i-cache":  "InstanceType is set by an intrinsic function, change it to m5.large where it comes from",
+		"vol-data": "Size can not shrink from 100 to 80 without replacing the resource",
+	}, reasons)
+	ts.Equal("CloudFormation: 5 changes to 3 resources, 1 parameter overrides, 3 skipped", patch.Summary())
+
+	output := filepath.Join(ts.dir, "kaytu-template.yaml")
+	parameters := filepath.Join(ts.dir, "parameters.json")
+	ts.Require().NoError(patch.Write(output, parameters))
+	content, err := os.ReadFile(parameters)
+	ts.Require().NoError(err)
+	var written []remediation.CloudFormationParameter
+	ts.Require().NoError(json.Unmarshal(content, &written))
+	ts.Equal(patch.Parameters, written)
+}
+
+func (ts *CloudFormationTestSuite) TestJsonTemplate() {
+	template := ts.write("template.json", "{\n\t\"Resources\": {\n"+
+		"\t\t\"Db\": {\"Type\": \"AWS::RDS::DBInstance\", \"Properties\": {\"DBInstanceIdentifier\": \"db-1\", \"DBInstanceClass\": {\"Ref\": \"DbClass\"}, \"AllocatedStorage\": \"50\", \"StorageType\": \"gp2\", \"Engine\": \"mysql\", \"MultiAZ\": false}},\n"+
+		"\t\t\"Web\": {\"Type\": \"AWS::EC2::Instance\", \"Properties\": {\"LaunchTemplate\": {\"LaunchTemplateId\": \"lt-1\", \"Version\": 1}}}\n"+
+		"\t},\n\t\"Parameters\": {\"DbClass\": {\"Type\": \"String\", \"Default\": \"db.m5.large\"}}\n}\n")
+	resources := ts.write("resources.json", `{"Db": "db-1", "Web": "i-web"}`)
+
+	patch, err := remediation.NewCloudFormationPatch(template, resources, remediation.Targets(terraformRecords()))
+	ts.Require().NoError(err)
+
+	assertGolden(&ts.Suite, "cloudformation_template.json", patch.Template)
+	ts.Equal([]remediation.CloudFormationParameter{{ParameterKey: "DbClass", ParameterValue: "db.t3.large"}}, patch.Parameters)
+	ts.Require().Len(patch.Skipped, 1)
+	ts.Equal("InstanceType is not set in the template, change it to m5.large where it comes from", patch.Skipped[0].Reason)
+}
+
+func (ts *CloudFormationTestSuite) TestClusterMemberKeepsStorage() {
+	template := ts.write("template.yaml", `Resources:
+  Member:
+    Type: AWS::RDS::DBInstance
+    Properties:
+      DBClusterIdentifier: cluster-1
+      DBInstanceClass: db.m5.large
+      Engine: aurora-mysql
+`)
+	resources := ts.write("resources.json", `{"Member": "db-1"}`)
+	records := terraformRecords()
+	for i := range records {
+		if records[i].ParentId == "db-1" {
+			records[i].ClusterId = "cluster-1"
+		}
+	}
+
+	patch, err := remediation.NewCloudFormationPatch(template, resources, remediation.Targets(records))
+	ts.Require().NoError(err)
+
+	ts.Equal([]remediation.CloudFormationChange{{
+		LogicalId: "Member", ResourceId: "db-1", Property: "DBInstanceClass", From: "db.m5.large", To: "db.t3.large",
+	}}, patch.Changes)
+	ts.NotContains(string(patch.Template), "StorageType")
+	ts.NotContains(string(patch.Template), "AllocatedStorage")
+}
+
+func (ts *CloudFormationTestSuite) TestInvalidInput() {
+	resources := ts.write("resources.json", `{"Web": "i-web"}`)
+	_, err := remediation.NewCloudFormationPatch(ts.write("template.yaml", "Outputs: {}\n"), resources, nil)
+	ts.ErrorContains(err, "has no resources")
+
+	_, err = remediation.NewCloudFormationPatch(ts.write("template.json", `{"Resources": {}}`), ts.write("list.json", `[1]`), nil)
+	ts.ErrorContains(err, "failed to parse cloudformation resources")
+}
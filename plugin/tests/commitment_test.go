@@ -0,0 +1,240 @@
+package tests
+
+import (
+	"github.com/opengovern/plugin-aws/plugin/commitment"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type CommitmentTestSuite struct {
+	suite.Suite
+}
+
+func TestCommitment(t *testing.T) {
+	suite.Run(t, &CommitmentTestSuite{})
+}
+
+func (ts *CommitmentTestSuite) load(content string) (*commitment.Catalog, error) {
+	path := filepath.Join(ts.T().TempDir(), "catalog.yaml")
+	ts.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+	return commitment.LoadCatalog(path)
+}
+
+const catalogFile = `version: 1
+prices:
+  - resource: EC2Instance
+    region: us-east-1
+    instanceType: m5.large
+    platform: Linux/UNIX
+    onDemand: 0.096
+    offers:
+      - type: reserved-instance
+        term: 1
+        payment: no-upfront
+        hourly: 0.06
+      - type: reserved-instance
+        term: 1
+        payment: all-upfront
+        upfront: 482
+      - type: compute-savings-plan
+        term: 3
+        payment: no-upfront
+        hourly: 0.066
+      - type: ec2-instance-savings-plan
+        term: 3
+        payment: partial-upfront
+        upfront: 600
+        hourly: 0.03
+  - resource: RDSInstance
+    region: us-east-1
+    instanceType: db.t3.large
+    platform: mysql
+    onDemand: 0.136
+    offers:
+      - type: reserved-instance
+        term: 1
+        payment: no-upfront
+        hourly: 0.15
+      - type: reserved-instance
+        term: 3
+        payment: all-upfront
+        upfront: 1500
+`
+
+func plannedRecord(resourceType, id, currentType, recommendedType string, cost, ageHours float64) history.Record {
+	r := history.Record{ResourceType: resourceType, ResourceId: id, Region: "us-east-1", Platform: "Linux/UNIX",
+		CurrentSpec: currentType, CurrentType: currentType, CurrentCost: cost, AgeHours: ageHours}
+	if recommendedType != "" {
+		r.RecommendedSpec, r.RecommendedType, r.RecommendedCost = recommendedType, recommendedType, cost
+		r.CurrentCost = cost * 2
+	}
+	return r
+}
+
+func (ts *CommitmentTestSuite) TestPlan() {
+	catalog, err := ts.load(catalogFile)
+	ts.Require().NoError(err)
+
+	noPlatform := plannedRecord("EC2 Instance", "i-2", "m5.large", "", 70.08, 2000)
+	noPlatform.Platform = ""
+	covered := plannedRecord("EC2 Instance", "i-4", "m5.large", "", 70.08, 5000)
+	covered.Coverage = &history.Coverage{Commitments: []string{"ri-1"}}
+	database := plannedRecord("RDS Instance Compute", "db-1-compute", "db.m5.large", "db.t3.large", 99.28, 2000)
+	database.Platform = "mysql"
+	plan := commitment.NewPlan("123456789012", []history.Record{
+		plannedRecord("EC2 Instance", "i-1", "m5.xlarge", "m5.large", 70.08, 1000),
+		noPlatform,
+		plannedRecord("EC2 Instance", "i-3", "m5.large", "", 70.08, 100),
+		covered,
+		plannedRecord("EC2 Instance", "i-5", "c5.large", "", 62.05, 5000),
+		plannedRecord("EBS Volume", "vol-1", "gp2", "gp3", 8, 5000),
+		database,
+		plannedRecord("RDS Instance Storage", "db-1-storage", "gp2", "", 11.5, 0),
+	}, catalog)
+
+	ts.Equal(3, plan.Steady)
+	ts.Equal(1, plan.Transient)
+	ts.Equal(1, plan.Covered)
+	ts.Equal(1, plan.Unpriced)
+	ts.InDelta(441.65, plan.Cost, 0.001)
+	ts.Require().Len(plan.Purchases, 3)
+
+	// the all upfront reservation saves more than the no upfront one, the RDS one year offer costs more than on demand
+	ri := plan.Purchases[0]
+	ts.Equal("m5.large", ri.InstanceType)
+	ts.Equal(2, ri.Instances)
+	ts.Equal(commitment.PaymentAllUpfront, ri.Offer.Payment)
+	ts.InDelta(964, ri.Upfront(), 0.001)
+	ts.InDelta(140.16, ri.OnDemandCost, 0.001)
+	ts.InDelta(59.8267, ri.Savings, 0.001)
+	ts.InDelta(6.878, ri.BreakEvenMonths, 0.001)
+	ts.InDelta(31.735, ri.Coverage, 0.001)
+
+	sp := plan.Purchases[1]
+	ts.Equal(commitment.OfferEC2InstanceSavingsPlan, sp.Offer.Type)
+	ts.InDelta(63.0267, sp.Savings, 0.001)
+	ts.InDelta(19.812, sp.BreakEvenMonths, 0.001)
+	ts.InDelta(0.10566, sp.HourlyCommitment(), 0.00001)
+
+	rds := plan.Purchases[2]
+	ts.Equal(commitment.ResourceRDSInstance, rds.Resource)
+	ts.Equal(3, rds.Offer.Term)
+	ts.InDelta(57.6133, rds.Savings, 0.001)
+	ts.InDelta(22.479, rds.Coverage, 0.001)
+
+	rows := plan.Rows(nil)
+	ts.Require().Len(rows, 4)
+	ts.Equal([]string{"123456789012", "us-east-1", "EC2 Instance", "m5.large", "Linux/UNIX", "2", "EC2 Instance Savings Plan", "3 year",
+		"partial-upfront", "$1,200.00", "$0.1057", "$140.16", "$77.13", "$63.03", "45.0", "19.8", "31.7"}, rows[2])
+	ts.Equal("RDS Instance", rows[3][2])
+	eur := plan.Rows(&currency.Currency{Code: "EUR", Symbol: "€", Rate: 0.5})
+	ts.Equal([]string{"€600.00", "€0.0528", "€70.08", "€38.57", "€31.51"}, eur[2][9:14])
+
+	ts.Equal("Commitment plan for 3 steady instances: 1-year purchases save $59.83/month covering 31.7% of the rightsized instance cost, "+
+		"3-year purchases save $120.64/month covering 54.2% of the rightsized instance cost; left out: 1 created less than 720 hours ago, "+
+		"1 already covered by commitments, 1 without a price in the catalog", plan.Summary(nil))
+
+	path := filepath.Join(ts.T().TempDir(), "plan.csv")
+	ts.Require().NoError(plan.Write(path, nil))
+	content, err := os.ReadFile(path)
+	ts.Require().NoError(err)
+	ts.Contains(string(content), "AccountID,Region,Resource Type,Instance Type")
+}
+
+func (ts *CommitmentTestSuite) TestPartTimeInstances() {
+	catalog, err := ts.load(catalogFile)
+	ts.Require().NoError(err)
+	partTime := plannedRecord("EC2 Instance", "i-2", "m5.large", "", 35.04, 2000)
+	partTime.RuntimeHours = 365
+	stopped := plannedRecord("RDS Instance Compute", "db-1-compute", "db.m5.large", "db.t3.large", 19.856, 2000)
+	stopped.Platform = "mysql"
+	stopped.RuntimeHours = 146
+
+	plan := commitment.NewPlan("123456789012", []history.Record{
+		plannedRecord("EC2 Instance", "i-1", "m5.large", "", 70.08, 2000),
+		partTime,
+		stopped,
+	}, catalog)
+
+	ts.Require().Len(plan.Purchases, 2)
+	// the reservation is paid for both instances, on demand only for the hours they run
+	ri := plan.Purchases[0]
+	ts.Equal(2, ri.Instances)
+	ts.InDelta(105.12, ri.OnDemandCost, 0.001)
+	ts.InDelta(80.3333, ri.CommitmentCost, 0.001)
+	ts.InDelta(24.7867, ri.Savings, 0.001)
+	ts.InDelta(9.170, ri.BreakEvenMonths, 0.001)
+	// the database is mostly stopped, none of its commitments saves money
+	ts.Equal(commitment.OfferEC2InstanceSavingsPlan, plan.Purchases[1].Offer.Type)
+	ts.InDelta(27.9867, plan.Purchases[1].Savings, 0.001)
+}
+
+func (ts *CommitmentTestSuite) TestNothingToBuy() {
+	catalog, err := ts.load(catalogFile + "minRuntimeHours: 10000\n")
+	ts.Require().NoError(err)
+	plan := commitment.NewPlan("123456789012", []history.Record{
+		plannedRecord("EC2 Instance", "i-1", "m5.large", "", 70.08, 5000),
+	}, catalog)
+	ts.Empty(plan.Purchases)
+	ts.Equal("Commitment plan: no purchase saves money for the 0 steady instances; left out: 1 created less than 10000 hours ago", plan.Summary(nil))
+	ts.Len(plan.Rows(nil), 1)
+}
+
+func (ts *CommitmentTestSuite) TestValidation() {
+	_, err := ts.load(`version: 1
+prices:
+  - resource: EC2Instance
+    region: us-east-1
+    instanceType: m5.large
+    onDemand: 0.096
+    offers:
+      - type: reserved-instance
+        term: 2
+        payment: no-upfront
+      - type: reserved-instance
+        term: 1
+        payment: no-upfront
+        upfront: 10
+  - resource: RDSInstance
+    region: us-east-1
+    instanceType: db.m5.large
+    offers:
+      - type: compute-savings-plan
+        term: 1
+        payment: later
+  - resource: Lambda
+`)
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "price 1 (m5.large us-east-1): offer 1: term must be 1 or 3 years, got 2")
+	ts.Contains(err.Error(), "price 1 (m5.large us-east-1): offer 2: no-upfront offers have no upfront fee")
+	ts.Contains(err.Error(), "price 2 (db.m5.large us-east-1): platform, the engine, is required for RDSInstance")
+	ts.Contains(err.Error(), "price 2 (db.m5.large us-east-1): onDemand must be positive")
+	ts.Contains(err.Error(), "price 2 (db.m5.large us-east-1): offer 1: compute-savings-plan only applies to EC2Instance")
+	ts.Contains(err.Error(), "price 3 ( ): unknown resource \"Lambda\", expected EC2Instance or RDSInstance")
+	ts.Contains(err.Error(), "price 3 ( ): has no offers")
+
+	_, err = ts.load(`version: 1
+prices:
+  - resource: EC2Instance
+    region: us-east-1
+    instanceType: m5.large
+    onDemand: 0.096
+    offers: [{type: reserved-instance, term: 1, payment: no-upfront, hourly: 0.06}]
+  - resource: EC2Instance
+    region: us-east-1
+    instanceType: m5.large
+    platform: linux/unix
+    onDemand: 0.096
+    offers: [{type: reserved-instance, term: 1, payment: all-upfront, upfront: 482}]
+`)
+	ts.ErrorContains(err, "price 2 (m5.large us-east-1): the instance type is priced more than once")
+
+	_, err = ts.load("version: 2\nprices: []\n")
+	ts.ErrorContains(err, "unsupported version 2")
+	_, err = ts.load("version: 1\nprices: []\n")
+	ts.ErrorContains(err, "has no prices")
+}
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	rdstype "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	sptypes "github.com/aws/aws-sdk-go-v2/service/savingsplans/types"
+	aws2 "github.com/opengovern/plugin-aws/plugin/aws"
+	"github.com/opengovern/plugin-aws/plugin/coverage"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type CoverageTestSuite struct {
+	suite.Suite
+}
+
+func TestCoverage(t *testing.T) {
+	suite.Run(t, &CoverageTestSuite{})
+}
+
+func reservedInstance(id string, instanceType types.InstanceType, count int32, zone string) types.ReservedInstances {
+	ri := types.ReservedInstances{
+		ReservedInstancesId: aws.String(id),
+		InstanceType:        instanceType,
+		InstanceCount:       aws.Int32(count),
+		ProductDescription:  types.RIProductDescription("Linux/UNIX (Amazon VPC)"),
+		InstanceTenancy:     types.TenancyDefault,
+		Scope:               types.ScopeRegional,
+	}
+	if zone != "" {
+		ri.Scope = types.ScopeAvailabilityZone
+		ri.AvailabilityZone = aws.String(zone)
+	}
+	return ri
+}
+
+func ec2Resource(id, current, recommended string, currentCost, recommendedCost float64) coverage.Resource {
+	return coverage.Resource{
+		Kind: coverage.EC2Instance, Id: id, Region: "us-east-1", AvailabilityZone: "us-east-1a", Platform: "Linux/UNIX", Tenancy: "default",
+		InstanceType: current, RecommendedType: recommended, CurrentCost: currentCost, RecommendedCost: recommendedCost,
+	}
+}
+
+func (ts *CoverageTestSuite) TestReservedInstances() {
+	inventory := coverage.NewInventory(nil)
+	inventory.AddReservedInstances("us-east-1", []types.ReservedInstances{
+		reservedInstance("ri-flexible", types.InstanceTypeM5Xlarge, 2, ""),
+		reservedInstance("ri-zonal", types.InstanceTypeC5Xlarge, 1, "us-east-1a"),
+	})
+
+	windows := ec2Resource("i-windows", "m5.xlarge", "m5.large", 140.16, 70.08)
+	windows.Platform = "Windows"
+	result := inventory.Allocate([]coverage.Resource{
+		ec2Resource("i-3", "m5.2xlarge", "m5.xlarge", 280.32, 140.16),
+		ec2Resource("i-1", "m5.xlarge", "m5.large", 140.16, 70.08),
+		ec2Resource("i-2", "c5.xlarge", "c6i.large", 124.1, 62.05),
+		windows,
+		ec2Resource("i-4", "r5.large", "", 91.98, 0),
+	})
+
+	// i-1 comes first and takes 8 of the 16 units, the flexible reservation stays with the smaller size
+	ts.Equal(history.Coverage{
+		Commitments:      []string{"ri-flexible"},
+		CoveredCost:      140.16,
+		EffectiveSavings: 0,
+		Warnings:         []string{"m5.large leaves 4 of the 8 normalized units of reserved instance ri-flexible given to it unused"},
+	}, result["i-1"])
+	// i-3 gets the 8 units left, half of its size, and keeps them on m5.xlarge
+	ts.Equal([]string{"ri-flexible"}, result["i-3"].Commitments)
+	ts.InDelta(140.16, result["i-3"].CoveredCost, 0.001)
+	ts.InDelta(140.16, result["i-3"].EffectiveSavings, 0.001)
+	ts.Empty(result["i-3"].Warnings)
+	// the zonal reservation is lost when the family changes, the change costs money
+	ts.Equal([]string{"ri-zonal"}, result["i-2"].Commitments)
+	ts.InDelta(-62.05, result["i-2"].EffectiveSavings, 0.001)
+	ts.Equal([]string{"c6i.large leaves reserved instance ri-zonal (c5.xlarge) unused"}, result["i-2"].Warnings)
+
+	ts.NotContains(result, "i-windows")
+	ts.NotContains(result, "i-4")
+}
+
+func (ts *CoverageTestSuite) TestSavingsPlans() {
+	inventory := coverage.NewInventory(&currency.Currency{Code: "EUR", Symbol: "€", Rate: 0.5})
+	inventory.AddSavingsPlans([]sptypes.SavingsPlan{
+		{SavingsPlanId: aws.String("sp-compute"), SavingsPlanType: sptypes.SavingsPlanTypeCompute, Commitment: aws.String("0.05")},
+		{SavingsPlanId: aws.String("sp-r5"), SavingsPlanType: sptypes.SavingsPlanTypeEc2Instance, Ec2InstanceFamily: aws.String("r5"), Region: aws.String("us-east-1"), Commitment: aws.String("0.1")},
+		{SavingsPlanId: aws.String("sp-sagemaker"), SavingsPlanType: sptypes.SavingsPlanTypeSagemaker, Commitment: aws.String("10")},
+	})
+
+	result := inventory.Allocate([]coverage.Resource{
+		ec2Resource("i-1", "m6i.xlarge", "m6i.large", 140.16, 70.08),
+		ec2Resource("i-2", "r5.large", "r6g.large", 91.98, 73.58),
+	})
+
+	// i-1 comes first and takes the whole 36.5 of the compute commitment
+	ts.Equal([]string{"sp-compute"}, result["i-1"].Commitments)
+	ts.InDelta(36.5, result["i-1"].CoveredCost, 0.001)
+	ts.InDelta(70.08, result["i-1"].EffectiveSavings, 0.001)
+	// the EC2 instance savings plan pays 73 of i-2's cost, r6g is not in its family
+	ts.Equal([]string{"sp-r5"}, result["i-2"].Commitments)
+	ts.InDelta(73, result["i-2"].CoveredCost, 0.001)
+	ts.InDelta(-54.6, result["i-2"].EffectiveSavings, 0.001)
+	ts.Equal([]string{"r6g.large frees €36.50/month of savings plan sp-r5 commitment"}, result["i-2"].Warnings)
+	ts.Empty(result["i-1"].Warnings)
+}
+
+func (ts *CoverageTestSuite) TestListSavingsPlans() {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input map[string]any
+		ts.Require().NoError(json.NewDecoder(r.Body).Decode(&input))
+		requests = append(requests, input)
+		w.Header().Set("Content-Type", "application/json")
+		if input["nextToken"] == nil {
+			w.Write([]byte(`{"savingsPlans": [{"savingsPlanId": "sp-1", "savingsPlanType": "Compute", "commitment": "0.05"}], "nextToken": "page-2"}`))
+		} else {
+			w.Write([]byte(`{"savingsPlans": [{"savingsPlanId": "sp-2", "savingsPlanType": "EC2Instance", "ec2InstanceFamily": "r5", "region": "us-east-1", "commitment": "0.1"}]}`))
+		}
+	}))
+	defer server.Close()
+	provider, err := aws2.NewAWS(aws.Config{Region: "eu-west-1", Credentials: aws.AnonymousCredentials{}, BaseEndpoint: aws.String(server.URL)})
+	ts.Require().NoError(err)
+
+	plans, err := provider.ListSavingsPlans(context.Background())
+
+	ts.Require().NoError(err)
+	ts.Require().Len(plans, 2)
+	ts.Equal("sp-1", *plans[0].SavingsPlanId)
+	ts.Equal(sptypes.SavingsPlanTypeEc2Instance, plans[1].SavingsPlanType)
+	ts.Equal("r5", *plans[1].Ec2InstanceFamily)
+	ts.Require().Len(requests, 2)
+	ts.Equal([]any{"active"}, requests[0]["states"])
+	ts.Equal("page-2", requests[1]["nextToken"])
+}
+
+func (ts *CoverageTestSuite) TestReservedDBInstances() {
+	inventory := coverage.NewInventory(nil)
+	inventory.AddReservedDBInstances("us-east-1", []rdstype.ReservedDBInstance{
+		{ReservedDBInstanceId: aws.String("rdb-mysql"), DBInstanceClass: aws.String("db.m5.xlarge"), DBInstanceCount: aws.Int32(1), ProductDescription: aws.String("mysql"), MultiAZ: aws.Bool(false)},
+		{ReservedDBInstanceId: aws.String("rdb-sqlserver"), DBInstanceClass: aws.String("db.m5.xlarge"), DBInstanceCount: aws.Int32(1), ProductDescription: aws.String("sqlserver-se(li)"), MultiAZ: aws.Bool(false)},
+		{ReservedDBInstanceId: aws.String("rdb-postgres"), DBInstanceClass: aws.String("db.r5.large"), DBInstanceCount: aws.Int32(1), ProductDescription: aws.String("postgresql"), MultiAZ: aws.Bool(false)},
+	})
+	rds := func(id, engine, current, recommended string) coverage.Resource {
+		return coverage.Resource{Kind: coverage.RDSInstance, Id: id, Region: "us-east-1", Platform: engine,
+			InstanceType: current, RecommendedType: recommended, CurrentCost: 124.1, RecommendedCost: 62.05}
+	}
+
+	multiAZ := rds("db-multi-az", "mysql", "db.m5.large", "db.m5.large")
+	multiAZ.MultiAZ = true
+	result := inventory.Allocate([]coverage.Resource{
+		rds("db-1", "mysql", "db.m5.large", "db.t3.large"),
+		rds("db-2", "mysql", "db.m5.large", "db.m5.large"),
+		rds("db-3", "sqlserver-se", "db.m5.large", "db.m5.large"),
+		rds("db-postgres", "postgres", "db.r5.large", "db.r5.large"),
+		multiAZ,
+	})
+
+	ts.Equal([]string{"rdb-mysql"}, result["db-1"].Commitments)
+	ts.Equal([]string{"db.t3.large leaves 4 of the 4 normalized units of reserved instance rdb-mysql given to it unused"}, result["db-1"].Warnings)
+	ts.InDelta(-62.05, result["db-1"].EffectiveSavings, 0.001)
+	ts.Equal([]string{"rdb-mysql"}, result["db-2"].Commitments)
+	ts.Empty(result["db-2"].Warnings)
+	// license included reservations only cover their own class
+	ts.NotContains(result, "db-3")
+	ts.NotContains(result, "db-multi-az")
+	// the postgresql product description covers the postgres engine
+	ts.Equal([]string{"rdb-postgres"}, result["db-postgres"].Commitments)
+	ts.InDelta(124.1, result["db-postgres"].CoveredCost, 0.001)
+}
+
+func (ts *CoverageTestSuite) TestSummary() {
+	ts.Empty(coverage.Summary([]history.Record{{ResourceType: "EC2 Instance", Savings: 10}}, nil))
+	ts.Equal("Commitments: 2 resources covered by reserved instances or savings plans, effective savings €2.50/month instead of €15.00/month on demand, 1 warnings about commitments left unused",
+		coverage.Summary([]history.Record{
+			{RecommendedSpec: "m5.large", Savings: 10, Coverage: &history.Coverage{EffectiveSavings: 5}},
+			{RecommendedSpec: "m5.large", Savings: 20, Coverage: &history.Coverage{Warnings: []string{"unused"}}},
+			{RecommendedSpec: "m5.large", Savings: 40},
+		}, &currency.Currency{Code: "EUR", Symbol: "€", Rate: 0.5}))
+	var none *coverage.Inventory
+	ts.Nil(none.Allocate([]coverage.Resource{ec2Resource("i-1", "m5.xlarge", "m5.large", 140.16, 70.08)}))
+}
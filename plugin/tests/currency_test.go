@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type CurrencyTestSuite struct {
+	suite.Suite
+}
+
+func TestCurrency(t *testing.T) {
+	suite.Run(t, &CurrencyTestSuite{})
+}
+
+func (ts *CurrencyTestSuite) write(content string) string {
+	path := filepath.Join(ts.T().TempDir(), "rates.yaml")
+	ts.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func (ts *CurrencyTestSuite) TestLoad() {
+	path := ts.write("version: 1\nrates:\n  EUR: 0.92\n  JPY: 151.4\n  CHF: 0.9\n")
+
+	eur, err := currency.Load("eur", path)
+	ts.Require().NoError(err)
+	ts.Equal("€92.00", eur.Format(100))
+	ts.Equal("€-4.60", eur.Format(-5))
+	ts.Equal("€920,000.00", eur.Format(1_000_000))
+	ts.Equal("€-4,600.00", eur.Format(-5000))
+	ts.Equal("€1,380.0000", eur.FormatHourly(1500))
+	ts.Equal([]string{"Currency", "EUR", "1 USD = 0.92 EUR"}, eur.ExportRow())
+
+	jpy, err := currency.Load("JPY", path)
+	ts.Require().NoError(err)
+	ts.Equal("¥10,610", jpy.Format(70.08))
+	ts.Equal("¥151", jpy.Format(1))
+
+	chf, err := currency.Load("CHF", path)
+	ts.Require().NoError(err)
+	ts.Equal("CHF 63.07", chf.Format(70.08))
+
+	usd, err := currency.Load("USD", "")
+	ts.Require().NoError(err)
+	ts.Nil(usd)
+	ts.Equal("$70.08", usd.Format(70.08))
+	ts.Equal("$12,345.67", usd.Format(12345.67))
+	ts.Equal(70.08, usd.Convert(70.08))
+
+	_, err = currency.Load("GBP", path)
+	ts.ErrorContains(err, "has no rate for GBP")
+	_, err = currency.Load("EUR", "")
+	ts.ErrorContains(err, "currency EUR needs exchange-rates-file")
+}
+
+func (ts *CurrencyTestSuite) TestValidation() {
+	_, err := currency.LoadRates(ts.write("version: 1\nrates:\n  euro: 0.92\n  JPY: -1\n"))
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "rate JPY: must be positive, got -1")
+	ts.Contains(err.Error(), "rate euro: not an ISO 4217 currency code")
+
+	_, err = currency.LoadRates(ts.write("version: 2\nrates:\n  EUR: 0.92\n"))
+	ts.ErrorContains(err, "unsupported version 2")
+	_, err = currency.LoadRates(ts.write("version: 1\nrates: {}\n"))
+	ts.ErrorContains(err, "has no rates")
+	_, err = currency.LoadRates(ts.write("version: 1\nrate:\n  EUR: 0.92\n"))
+	ts.ErrorContains(err, "field rate not found")
+}
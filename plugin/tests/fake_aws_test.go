@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	rdstype "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	sptypes "github.com/aws/aws-sdk-go-v2/service/savingsplans/types"
+	"strings"
+	"sync"
+)
+
+// FakeAWS is an in-memory aws.InventoryProvider. Tests script it by filling the resource maps,
+// failures are injected per method name through Errors.
+type FakeAWS struct {
+	Identification map[string]string
+	Regions        []string
+	// Instances, RDSInstances, RDSClusters and the reservations are keyed by region
+	Instances           map[string][]types.Instance
+	RDSInstances        map[string][]rdstype.DBInstance
+	RDSClusters         map[string][]rdstype.DBCluster
+	ReservedInstances   map[string][]types.ReservedInstances
+	ReservedDBInstances map[string][]rdstype.ReservedDBInstance
+	SavingsPlans        []sptypes.SavingsPlan
+	// Images are keyed by image id, Volumes by volume id
+	Images  map[string]types.Image
+	Volumes map[string]types.Volume
+	Errors  map[string]error
+
+	lock  sync.Mutex
+	calls []string
+}
+
+func NewFakeAWS() *FakeAWS {
+	return &FakeAWS{
+		Identification:      map[string]string{"account": "123456789012"},
+		Instances:           map[string][]types.Instance{},
+		RDSInstances:        map[string][]rdstype.DBInstance{},
+		RDSClusters:         map[string][]rdstype.DBCluster{},
+		ReservedInstances:   map[string][]types.ReservedInstances{},
+		ReservedDBInstances: map[string][]rdstype.ReservedDBInstance{},
+		Images:              map[string]types.Image{},
+		Volumes:             map[string]types.Volume{},
+		Errors:              map[string]error{},
+	}
+}
+
+func (f *FakeAWS) call(method string, args ...string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf("%s(%s)", method, strings.Join(args, ", ")))
+	return f.Errors[method]
+}
+
+// Calls returns every call made so far formatted as Method(arg, ...).
+func (f *FakeAWS) Calls() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.calls...)
+}
+
+func (f *FakeAWS) Identify(_ context.Context) (map[string]string, error) {
+	if err := f.call("Identify"); err != nil {
+		return nil, err
+	}
+	return f.Identification, nil
+}
+
+func (f *FakeAWS) ListAllRegions(_ context.Context) ([]string, error) {
+	if err := f.call("ListAllRegions"); err != nil {
+		return nil, err
+	}
+	return f.Regions, nil
+}
+
+func (f *FakeAWS) ListInstances(_ context.Context, region string) ([]types.Instance, error) {
+	if err := f.call("ListInstances", region); err != nil {
+		return nil, err
+	}
+	return f.Instances[region], nil
+}
+
+func (f *FakeAWS) GetImage(_ context.Context, region, imageId string) (*types.Image, error) {
+	if err := f.call("GetImage", region, imageId); err != nil {
+		return nil, err
+	}
+	image, ok := f.Images[imageId]
+	if !ok {
+		return nil, nil
+	}
+	return &image, nil
+}
+
+func (f *FakeAWS) ListAttachedVolumes(_ context.Context, region string, instance types.Instance) ([]types.Volume, error) {
+	if err := f.call("ListAttachedVolumes", region, *instance.InstanceId); err != nil {
+		return nil, err
+	}
+	var volumes []types.Volume
+	for _, bd := range instance.BlockDeviceMappings {
+		if bd.Ebs == nil || bd.Ebs.VolumeId == nil {
+			continue
+		}
+		if v, ok := f.Volumes[*bd.Ebs.VolumeId]; ok {
+			volumes = append(volumes, v)
+		}
+	}
+	return volumes, nil
+}
+
+func (f *FakeAWS) ListRDSInstance(_ context.Context, region string) ([]rdstype.DBInstance, error) {
+	if err := f.call("ListRDSInstance", region); err != nil {
+		return nil, err
+	}
+	return f.RDSInstances[region], nil
+}
+
+func (f *FakeAWS) ListRDSInstanceByCluster(_ context.Context, region, clusterId string) ([]rdstype.DBInstance, error) {
+	if err := f.call("ListRDSInstanceByCluster", region, clusterId); err != nil {
+		return nil, err
+	}
+	var instances []rdstype.DBInstance
+	for _, i := range f.RDSInstances[region] {
+		if i.DBClusterIdentifier != nil && *i.DBClusterIdentifier == clusterId {
+			instances = append(instances, i)
+		}
+	}
+	return instances, nil
+}
+
+func (f *FakeAWS) ListRDSClusters(_ context.Context, region string) ([]rdstype.DBCluster, error) {
+	if err := f.call("ListRDSClusters", region); err != nil {
+		return nil, err
+	}
+	return f.RDSClusters[region], nil
+}
+
+func (f *FakeAWS) ListReservedInstances(_ context.Context, region string) ([]types.ReservedInstances, error) {
+	if err := f.call("ListReservedInstances", region); err != nil {
+		return nil, err
+	}
+	return f.ReservedInstances[region], nil
+}
+
+func (f *FakeAWS) ListReservedDBInstances(_ context.Context, region string) ([]rdstype.ReservedDBInstance, error) {
+	if err := f.call("ListReservedDBInstances", region); err != nil {
+		return nil, err
+	}
+	return f.ReservedDBInstances[region], nil
+}
+
+func (f *FakeAWS) ListSavingsPlans(_ context.Context) ([]sptypes.SavingsPlan, error) {
+	if err := f.call("ListSavingsPlans"); err != nil {
+		return nil, err
+	}
+	return f.SavingsPlans, nil
+}
@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type FakeInstance struct {
+	Type          string
+	State         string
+	Tags          map[string]string
+	InstanceStore bool
+}
+
+type FakeVolume struct {
+	Type       string
+	Size       int32
+	Iops       int32
+	Throughput int32
+}
+
+type FakeDBInstance struct {
+	Class      string
+	Engine     string
+	Status     string
+	Cluster    string
+	Storage    string
+	Allocated  int32
+	Iops       int32
+	Throughput int32
+	// Pending holds the modifications waiting for the maintenance window
+	Pending map[string]string
+}
+
+// FakeEndpoint is a local stand-in for the EC2 and RDS query APIs used by apply, serving the resources in
+// its maps. Stopping and starting an instance completes on the next describe. Failures are injected per
+// action through Errors.
+type FakeEndpoint struct {
+	Instances   map[string]*FakeInstance
+	Volumes     map[string]*FakeVolume
+	DBInstances map[string]*FakeDBInstance
+	Errors      map[string]string
+
+	server  *httptest.Server
+	lock    sync.Mutex
+	actions []string
+}
+
+func NewFakeEndpoint() *FakeEndpoint {
+	f := &FakeEndpoint{
+		Instances:   map[string]*FakeInstance{},
+		Volumes:     map[string]*FakeVolume{},
+		DBInstances: map[string]*FakeDBInstance{},
+		Errors:      map[string]string{},
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+func (f *FakeEndpoint) URL() string {
+	return f.server.URL
+}
+
+func (f *FakeEndpoint) Close() {
+	f.server.Close()
+}
+
+// Actions returns every call made so far formatted as Action(param=value, ...), parameters sorted by name.
+func (f *FakeEndpoint) Actions() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.actions...)
+}
+
+func (f *FakeEndpoint) handle(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	action := r.Form.Get("Action")
+	var params []string
+	for key := range r.Form {
+		if key != "Action" && key != "Version" {
+			params = append(params, key+"="+r.Form.Get(key))
+		}
+	}
+	sort.Strings(params)
+	f.actions = append(f.actions, fmt.Sprintf("%s(%s)", action, strings.Join(params, ", ")))
+
+	if code, ok := f.Errors[action]; ok {
+		w.WriteHeader(http.StatusBadRequest)
+		if strings.Contains(action, "DB") {
+			fmt.Fprintf(w, "<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>injected</Message></Error><RequestId>1</RequestId></ErrorResponse>", code)
+		} else {
+			fmt.Fprintf(w, "<Response><Errors><Error><Code>%s</Code><Message>injected</Message></Error></Errors><RequestID>1</RequestID></Response>", code)
+		}
+		return
+	}
+
+	var body string
+	switch action {
+	case "DescribeInstances":
+		body = f.describeInstances(r.Form.Get("InstanceId.1"))
+	case "StopInstances":
+		f.Instances[r.Form.Get("InstanceId.1")].State = "stopping"
+		body = "<StopInstancesResponse><instancesSet/></StopInstancesResponse>"
+	case "StartInstances":
+		f.Instances[r.Form.Get("InstanceId.1")].State = "pending"
+		body = "<StartInstancesResponse><instancesSet/></StartInstancesResponse>"
+	case "ModifyInstanceAttribute":
+		f.Instances[r.Form.Get("InstanceId")].Type = r.Form.Get("InstanceType.Value")
+		body = "<ModifyInstanceAttributeResponse><return>true</return></ModifyInstanceAttributeResponse>"
+	case "DescribeVolumes":
+		body = "<DescribeVolumesResponse><volumeSet>"
+		if v, ok := f.Volumes[r.Form.Get("VolumeId.1")]; ok {
+			body += fmt.Sprintf("<item><volumeId>%s</volumeId><volumeType>%s</volumeType><size>%d</size><iops>%d</iops>",
+				r.Form.Get("VolumeId.1"), v.Type, v.Size, v.Iops)
+			if v.Throughput != 0 {
+				body += fmt.Sprintf("<throughput>%d</throughput>", v.Throughput)
+			}
+			body += "<status>in-use</status></item>"
+		}
+		body += "</volumeSet></DescribeVolumesResponse>"
+	case "ModifyVolume":
+		v := f.Volumes[r.Form.Get("VolumeId")]
+		if value := r.Form.Get("VolumeType"); value != "" {
+			v.Type, v.Iops, v.Throughput = value, 0, 0
+		}
+		setInt32(r.Form.Get("Size"), &v.Size)
+		setInt32(r.Form.Get("Iops"), &v.Iops)
+		setInt32(r.Form.Get("Throughput"), &v.Throughput)
+		body = "<ModifyVolumeResponse><volumeModification><modificationState>modifying</modificationState></volumeModification></ModifyVolumeResponse>"
+	case "DescribeDBInstances":
+		body = "<DescribeDBInstancesResponse><DescribeDBInstancesResult><DBInstances>"
+		if db, ok := f.DBInstances[r.Form.Get("DBInstanceIdentifier")]; ok {
+			body += fmt.Sprintf("<DBInstance><DBInstanceIdentifier>%s</DBInstanceIdentifier><DBInstanceClass>%s</DBInstanceClass>"+
+				"<DBInstanceStatus>%s</DBInstanceStatus><StorageType>%s</StorageType><AllocatedStorage>%d</AllocatedStorage><Iops>%d</Iops>",
+				r.Form.Get("DBInstanceIdentifier"), db.Class, db.Status, db.Storage, db.Allocated, db.Iops)
+			if db.Throughput != 0 {
+				body += fmt.Sprintf("<StorageThroughput>%d</StorageThroughput>", db.Throughput)
+			}
+			if db.Engine != "" {
+				body += fmt.Sprintf("<Engine>%s</Engine>", db.Engine)
+			}
+			if db.Cluster != "" {
+				body += fmt.Sprintf("<DBClusterIdentifier>%s</DBClusterIdentifier>", db.Cluster)
+			}
+			body += "</DBInstance>"
+		}
+		body += "</DBInstances></DescribeDBInstancesResult></DescribeDBInstancesResponse>"
+	case "ModifyDBInstance":
+		db := f.DBInstances[r.Form.Get("DBInstanceIdentifier")]
+		changes := map[string]string{}
+		for _, key := range []string{"DBInstanceClass", "StorageType", "AllocatedStorage", "Iops", "StorageThroughput"} {
+			if value := r.Form.Get(key); value != "" {
+				changes[key] = value
+			}
+		}
+		if r.Form.Get("ApplyImmediately") == "true" {
+			db.apply(changes)
+		} else {
+			db.Pending = changes
+		}
+		body = "<ModifyDBInstanceResponse><ModifyDBInstanceResult><DBInstance/></ModifyDBInstanceResult></ModifyDBInstanceResponse>"
+	default:
+		http.Error(w, "unsupported action "+action, http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = w.Write([]byte(xml.Header + body))
+}
+
+// describeInstances completes a pending stop or start, so waiters see the final state on their next poll.
+func (f *FakeEndpoint) describeInstances(id string) string {
+	body := "<DescribeInstancesResponse><reservationSet>"
+	if i, ok := f.Instances[id]; ok {
+		state := i.State
+		switch i.State {
+		case "stopping":
+			i.State = "stopped"
+		case "pending":
+			i.State = "running"
+		}
+		rootDevice := "ebs"
+		if i.InstanceStore {
+			rootDevice = "instance-store"
+		}
+		body += fmt.Sprintf("<item><instancesSet><item><instanceId>%s</instanceId><instanceType>%s</instanceType>"+
+			"<instanceState><name>%s</name></instanceState><rootDeviceType>%s</rootDeviceType><tagSet>", id, i.Type, state, rootDevice)
+		for key, value := range i.Tags {
+			body += fmt.Sprintf("<item><key>%s</key><value>%s</value></item>", key, value)
+		}
+		body += "</tagSet></item></instancesSet></item>"
+	}
+	return body + "</reservationSet></DescribeInstancesResponse>"
+}
+
+func (db *FakeDBInstance) apply(changes map[string]string) {
+	for key, value := range changes {
+		switch key {
+		case "DBInstanceClass":
+			db.Class = value
+		case "StorageType":
+			db.Storage = value
+		case "AllocatedStorage":
+			setInt32(value, &db.Allocated)
+		case "Iops":
+			setInt32(value, &db.Iops)
+		case "StorageThroughput":
+			setInt32(value, &db.Throughput)
+		}
+	}
+}
+
+func setInt32(value string, target *int32) {
+	if value == "" {
+		return
+	}
+	n, _ := strconv.ParseInt(value, 10, 32)
+	*target = int32(n)
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"sync"
+)
+
+// FakeOptimizationClient answers optimization requests from responses keyed by the hashed resource id
+// and keeps every request it received. Unknown resources get a recommendation without any change, known
+// ones a copy of their response, like the fresh message of a real client. Err fails every request,
+// EC2InstanceErrs the ones of an instance.
+type FakeOptimizationClient struct {
+	EC2InstanceResponses map[string]*golang2.EC2InstanceOptimizationResponse
+	RDSInstanceResponses map[string]*golang2.RDSInstanceOptimizationResponse
+	RDSClusterResponses  map[string]*golang2.RDSClusterOptimizationResponse
+	EC2InstanceErrs      map[string]error
+	Err                  error
+
+	lock                sync.Mutex
+	ec2InstanceRequests []*golang2.EC2InstanceOptimizationRequest
+	rdsInstanceRequests []*golang2.RDSInstanceOptimizationRequest
+	rdsClusterRequests  []*golang2.RDSClusterOptimizationRequest
+}
+
+func NewFakeOptimizationClient() *FakeOptimizationClient {
+	return &FakeOptimizationClient{
+		EC2InstanceResponses: map[string]*golang2.EC2InstanceOptimizationResponse{},
+		RDSInstanceResponses: map[string]*golang2.RDSInstanceOptimizationResponse{},
+		RDSClusterResponses:  map[string]*golang2.RDSClusterOptimizationResponse{},
+		EC2InstanceErrs:      map[string]error{},
+	}
+}
+
+func (c *FakeOptimizationClient) EC2InstanceOptimization(_ context.Context, in *golang2.EC2InstanceOptimizationRequest, _ ...grpc.CallOption) (*golang2.EC2InstanceOptimizationResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.ec2InstanceRequests = append(c.ec2InstanceRequests, in)
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	if err, ok := c.EC2InstanceErrs[in.Instance.HashedInstanceId]; ok {
+		return nil, err
+	}
+	if res, ok := c.EC2InstanceResponses[in.Instance.HashedInstanceId]; ok {
+		return proto.Clone(res).(*golang2.EC2InstanceOptimizationResponse), nil
+	}
+	return &golang2.EC2InstanceOptimizationResponse{
+		RightSizing: &golang2.EC2InstanceRightSizingRecommendation{Current: &golang2.RightsizingEC2Instance{}},
+	}, nil
+}
+
+func (c *FakeOptimizationClient) RDSInstanceOptimization(_ context.Context, in *golang2.RDSInstanceOptimizationRequest, _ ...grpc.CallOption) (*golang2.RDSInstanceOptimizationResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.rdsInstanceRequests = append(c.rdsInstanceRequests, in)
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	if res, ok := c.RDSInstanceResponses[in.Instance.HashedInstanceId]; ok {
+		return proto.Clone(res).(*golang2.RDSInstanceOptimizationResponse), nil
+	}
+	return &golang2.RDSInstanceOptimizationResponse{RightSizing: emptyRDSRecommendation()}, nil
+}
+
+func (c *FakeOptimizationClient) RDSClusterOptimization(_ context.Context, in *golang2.RDSClusterOptimizationRequest, _ ...grpc.CallOption) (*golang2.RDSClusterOptimizationResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.rdsClusterRequests = append(c.rdsClusterRequests, in)
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	if res, ok := c.RDSClusterResponses[in.Cluster.HashedClusterId]; ok {
+		return proto.Clone(res).(*golang2.RDSClusterOptimizationResponse), nil
+	}
+	res := &golang2.RDSClusterOptimizationResponse{RightSizing: map[string]*golang2.RDSInstanceRightSizingRecommendation{}}
+	for _, i := range in.Instances {
+		res.RightSizing[i.HashedInstanceId] = emptyRDSRecommendation()
+	}
+	return res, nil
+}
+
+func emptyRDSRecommendation() *golang2.RDSInstanceRightSizingRecommendation {
+	return &golang2.RDSInstanceRightSizingRecommendation{
+		Current:                &golang2.RightsizingAwsRds{StorageType: wrapperspb.String(""), StorageSize: wrapperspb.Int32(0)},
+		Recommended:            &golang2.RightsizingAwsRds{StorageType: wrapperspb.String(""), StorageSize: wrapperspb.Int32(0)},
+		Vcpu:                   &golang2.Usage{},
+		FreeMemoryBytes:        &golang2.Usage{},
+		FreeStorageBytes:       &golang2.Usage{},
+		NetworkThroughputBytes: &golang2.Usage{},
+		StorageIops:            &golang2.Usage{},
+		StorageThroughput:      &golang2.Usage{},
+		VolumeBytesUsed:        &golang2.Usage{},
+	}
+}
+
+func (c *FakeOptimizationClient) EC2InstanceRequests() []*golang2.EC2InstanceOptimizationRequest {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*golang2.EC2InstanceOptimizationRequest{}, c.ec2InstanceRequests...)
+}
+
+func (c *FakeOptimizationClient) RDSInstanceRequests() []*golang2.RDSInstanceOptimizationRequest {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*golang2.RDSInstanceOptimizationRequest{}, c.rdsInstanceRequests...)
+}
+
+func (c *FakeOptimizationClient) RDSClusterRequests() []*golang2.RDSClusterOptimizationRequest {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*golang2.RDSClusterOptimizationRequest{}, c.rdsClusterRequests...)
+}
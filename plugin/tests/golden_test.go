@@ -0,0 +1,346 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	rdstype "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/processor/ec2_instance"
+	"github.com/opengovern/plugin-aws/plugin/processor/rds_instance"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// update rewrites the golden files instead of comparing against them: go test ./plugin/tests -update
+var update = flag.Bool("update", false, "rewrite the golden files under testdata/golden")
+
+func (ts *AWSTestSuite) assertGolden(name string, got []byte) {
+	assertGolden(&ts.Suite, name, got)
+}
+
+func assertGolden(s *suite.Suite, name string, got []byte) {
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		s.Require().NoError(os.MkdirAll(filepath.Dir(path), 0755))
+		s.Require().NoError(os.WriteFile(path, got, 0644))
+		return
+	}
+	want, err := os.ReadFile(path)
+	s.Require().NoError(err, "missing golden file, run the tests with -update")
+	s.Equal(string(want), string(got), "%s is out of date, run the tests with -update", path)
+}
+
+// goldenCsv renders the export as CSV, data rows are sorted since items are kept in an unordered map.
+func (ts *AWSTestSuite) goldenCsv(export *golang.NonInteractiveExport) []byte {
+	var rows [][]string
+	for _, row := range export.Csv[1:] {
+		rows = append(rows, row.Row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		for k := range rows[i] {
+			if rows[i][k] != rows[j][k] {
+				return rows[i][k] < rows[j][k]
+			}
+		}
+		return false
+	})
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	ts.Require().NoError(w.Write(export.Csv[0].Row))
+	ts.Require().NoError(w.WriteAll(rows))
+	return buf.Bytes()
+}
+
+type goldenProperty struct {
+	Key         string
+	Current     string `json:",omitempty"`
+	Average     string `json:",omitempty"`
+	Max         string `json:",omitempty"`
+	Recommended string `json:",omitempty"`
+}
+
+type goldenDevice struct {
+	RowId      string
+	Values     map[string]string
+	Properties []goldenProperty
+}
+
+// goldenDevices renders chart rows with their properties as JSON.
+func (ts *AWSTestSuite) goldenDevices(rows []*golang.ChartRow, props map[string]*golang.Properties) []byte {
+	var devices []goldenDevice
+	for _, row := range rows {
+		device := goldenDevice{RowId: row.RowId, Values: map[string]string{}}
+		for k, v := range row.Values {
+			device.Values[k] = v.Value
+		}
+		if p, ok := props[row.RowId]; ok {
+			for _, property := range p.Properties {
+				device.Properties = append(device.Properties, goldenProperty{
+					Key:         property.Key,
+					Current:     property.Current,
+					Average:     property.Average,
+					Max:         property.Max,
+					Recommended: property.Recommended,
+				})
+			}
+		}
+		devices = append(devices, device)
+	}
+	out, err := json.MarshalIndent(devices, "", "  ")
+	ts.Require().NoError(err)
+	return append(out, '\n')
+}
+
+func goldenUsage(avg, max float64) *golang2.Usage {
+	return &golang2.Usage{Avg: wrapperspb.Double(avg), Min: wrapperspb.Double(avg / 2), Max: wrapperspb.Double(max)}
+}
+
+func goldenEC2Recommendation() *golang2.EC2InstanceRightSizingRecommendation {
+	return &golang2.EC2InstanceRightSizingRecommendation{
+		Current: &golang2.RightsizingEC2Instance{
+			InstanceType: "m5.xlarge", Region: "us-east-1", Cost: 140.16, Processor: "Intel Xeon", Architecture: "x86_64",
+			LicensePrice: 0, Vcpu: 4, Memory: 16, EbsBandwidth: "4750 Mbps", EbsIops: "18750", NetworkThroughput: "10 Gbps",
+			EnaSupported: "required", CostComponents: map[string]float64{"Compute": 140.16},
+		},
+		Recommended: &golang2.RightsizingEC2Instance{
+			InstanceType: "m5.large", Region: "us-east-1", Cost: 70.08, Processor: "Intel Xeon", Architecture: "x86_64",
+			LicensePrice: 0, Vcpu: 2, Memory: 8, EbsBandwidth: "4750 Mbps", EbsIops: "18750", NetworkThroughput: "10 Gbps",
+			EnaSupported: "required", CostComponents: map[string]float64{"Compute": 70.08},
+		},
+		Vcpu:              goldenUsage(12.5, 40),
+		Memory:            goldenUsage(30, 55),
+		EbsBandwidth:      goldenUsage(2*1024*1024, 8*1024*1024),
+		EbsIops:           goldenUsage(120, 900),
+		NetworkThroughput: goldenUsage(1024*1024, 4*1024*1024),
+		Description:       "cpu usage is low",
+	}
+}
+
+func goldenEBSRecommendation() *golang2.EBSVolumeRecommendation {
+	return &golang2.EBSVolumeRecommendation{
+		Current: &golang2.RightsizingEBSVolume{
+			Tier: "gp2", VolumeSize: wrapperspb.Int32(100), BaselineIops: 300, BaselineThroughput: 128 * 1024 * 1024, Cost: 10,
+			CostComponents: map[string]float64{"Storage": 10},
+		},
+		Recommended: &golang2.RightsizingEBSVolume{
+			Tier: "gp3", VolumeSize: wrapperspb.Int32(80), BaselineIops: 3000, BaselineThroughput: 125 * 1024 * 1024, Cost: 6.4,
+			CostComponents: map[string]float64{"Storage": 6.4},
+		},
+		Iops:        goldenUsage(85, 310),
+		Throughput:  goldenUsage(512*1024, 3*1024*1024),
+		Description: "volume is oversized",
+	}
+}
+
+func goldenVolume(id string, size int32) types.Volume {
+	return types.Volume{VolumeId: aws.String(id), VolumeType: types.VolumeTypeGp2, Size: aws.Int32(size), Iops: aws.Int32(300)}
+}
+
+func withVolumes(instance types.Instance, volumeIds ...string) types.Instance {
+	for _, id := range volumeIds {
+		instance.BlockDeviceMappings = append(instance.BlockDeviceMappings, types.InstanceBlockDeviceMapping{
+			DeviceName: aws.String("/dev/" + id), Ebs: &types.EbsInstanceBlockDevice{VolumeId: aws.String(id)},
+		})
+	}
+	return instance
+}
+
+func goldenRDSRecommendation(engine string) *golang2.RDSInstanceRightSizingRecommendation {
+	return &golang2.RDSInstanceRightSizingRecommendation{
+		Current: &golang2.RightsizingAwsRds{
+			Region: "us-east-1", InstanceType: "db.m5.large", Engine: engine, EngineVersion: "8.0.35", ClusterType: "Single-AZ",
+			Processor: "Intel Xeon", Architecture: "x86_64", Vcpu: 2, MemoryGb: 8,
+			StorageType: wrapperspb.String("gp2"), StorageSize: wrapperspb.Int32(100), StorageIops: wrapperspb.Int32(300),
+			StorageThroughput: wrapperspb.Double(128), ComputeCost: 124.1, StorageCost: 11.5,
+			ComputeCostComponents: map[string]float64{"Instance": 124.1}, StorageCostComponents: map[string]float64{"Storage": 11.5},
+		},
+		Recommended: &golang2.RightsizingAwsRds{
+			Region: "us-east-1", InstanceType: "db.t3.large", Engine: engine, EngineVersion: "8.0.35", ClusterType: "Single-AZ",
+			Processor: "Intel Xeon", Architecture: "x86_64", Vcpu: 2, MemoryGb: 4,
+			StorageType: wrapperspb.String("gp3"), StorageSize: wrapperspb.Int32(60), StorageIops: wrapperspb.Int32(3000),
+			StorageThroughput: wrapperspb.Double(125), ComputeCost: 62.05, StorageCost: 9.2,
+			ComputeCostComponents: map[string]float64{"Instance": 62.05}, StorageCostComponents: map[string]float64{"Storage": 9.2},
+		},
+		Vcpu:                   goldenUsage(18, 64),
+		FreeMemoryBytes:        goldenUsage(5*1024*1024*1024, 6*1024*1024*1024),
+		FreeStorageBytes:       goldenUsage(70*1024*1024*1024, 75*1024*1024*1024),
+		NetworkThroughputBytes: goldenUsage(1024*1024, 2*1024*1024),
+		StorageIops:            goldenUsage(95, 420),
+		StorageThroughput:      goldenUsage(3*1024*1024, 9*1024*1024),
+		VolumeBytesUsed:        goldenUsage(30*1024*1024*1024, 32*1024*1024*1024),
+		Description:            "memory usage is low",
+	}
+}
+
+// goldenEC2 feeds a representative instance and the edge cases through the processor:
+// an instance without recommendation, one without usage, a volume without recommendation
+// and a volume missing from the response.
+func (ts *AWSTestSuite) goldenEC2() {
+	ts.aws.Images["ami-1"] = types.Image{ImageId: aws.String("ami-1"), EnaSupport: aws.Bool(true)}
+	for _, id := range []string{"vol-web", "vol-norec", "vol-missing", "vol-nousage"} {
+		ts.aws.Volumes[id] = goldenVolume(id, 100)
+	}
+	ts.aws.Instances["us-east-1"] = []types.Instance{
+		withVolumes(ec2Instance("i-web", types.InstanceStateNameRunning, types.Tag{Key: aws.String("Name"), Value: aws.String("web")}),
+			"vol-web", "vol-norec", "vol-missing"),
+		ec2Instance("i-norec", types.InstanceStateNameRunning),
+		withVolumes(ec2Instance("i-nousage", types.InstanceStateNameRunning), "vol-nousage"),
+	}
+
+	web := &golang2.EC2InstanceOptimizationResponse{
+		RightSizing: goldenEC2Recommendation(),
+		VolumeRightSizing: map[string]*golang2.EBSVolumeRecommendation{
+			utils.HashString("vol-web"):   goldenEBSRecommendation(),
+			utils.HashString("vol-norec"): {Current: goldenEBSRecommendation().Current, Iops: goldenUsage(10, 20)},
+		},
+	}
+	ts.client.EC2InstanceResponses[utils.HashString("i-web")] = web
+	ts.client.EC2InstanceResponses[utils.HashString("i-norec")] = &golang2.EC2InstanceOptimizationResponse{
+		RightSizing: &golang2.EC2InstanceRightSizingRecommendation{Current: goldenEC2Recommendation().Current},
+	}
+	nousage := goldenEC2Recommendation()
+	nousage.Vcpu, nousage.Memory, nousage.EbsBandwidth, nousage.EbsIops, nousage.NetworkThroughput = nil, nil, nil, nil, nil
+	nousageVolume := goldenEBSRecommendation()
+	nousageVolume.Iops, nousageVolume.Throughput = nil, nil
+	ts.client.EC2InstanceResponses[utils.HashString("i-nousage")] = &golang2.EC2InstanceOptimizationResponse{
+		RightSizing:       nousage,
+		VolumeRightSizing: map[string]*golang2.EBSVolumeRecommendation{utils.HashString("vol-nousage"): nousageVolume},
+	}
+}
+
+func (ts *AWSTestSuite) TestGoldenEC2Csv() {
+	ts.goldenEC2()
+
+	export := ts.runEC2().ExportNonInteractive()
+
+	ts.assertGolden("ec2_instance.csv", ts.goldenCsv(export))
+}
+
+func (ts *AWSTestSuite) TestGoldenEC2Devices() {
+	cases := map[string]ec2_instance.EC2InstanceItem{
+		"representative": {
+			Instance: withVolumes(ec2Instance("i-web", types.InstanceStateNameRunning, types.Tag{Key: aws.String("Name"), Value: aws.String("web")}),
+				"vol-web", "vol-missing"),
+			Image:   &types.Image{ImageId: aws.String("ami-1"), EnaSupport: aws.Bool(true)},
+			Region:  "us-east-1",
+			Volumes: []types.Volume{goldenVolume("vol-web", 100), goldenVolume("vol-missing", 20)},
+			Wastage: &golang2.EC2InstanceOptimizationResponse{
+				RightSizing:       goldenEC2Recommendation(),
+				VolumeRightSizing: map[string]*golang2.EBSVolumeRecommendation{utils.HashString("vol-web"): goldenEBSRecommendation()},
+			},
+		},
+		"nil_recommended": {
+			Instance: withVolumes(ec2Instance("i-norec", types.InstanceStateNameRunning), "vol-norec"),
+			Region:   "us-east-1",
+			Volumes:  []types.Volume{goldenVolume("vol-norec", 100)},
+			Wastage: &golang2.EC2InstanceOptimizationResponse{
+				RightSizing: &golang2.EC2InstanceRightSizingRecommendation{Current: goldenEC2Recommendation().Current},
+				VolumeRightSizing: map[string]*golang2.EBSVolumeRecommendation{
+					utils.HashString("vol-norec"): {Current: &golang2.RightsizingEBSVolume{Tier: "gp2", Cost: 10}},
+				},
+			},
+		},
+		"nil_usage": {
+			Instance: ec2Instance("i-nousage", types.InstanceStateNameRunning),
+			Region:   "us-east-1",
+			Wastage: &golang2.EC2InstanceOptimizationResponse{
+				RightSizing: &golang2.EC2InstanceRightSizingRecommendation{
+					Current:     goldenEC2Recommendation().Current,
+					Recommended: goldenEC2Recommendation().Recommended,
+				},
+			},
+		},
+	}
+
+	for name, item := range cases {
+		rows, props := item.Devices()
+		rendered := ts.goldenDevices(rows, props)
+		ts.assertGolden("ec2_instance_"+name+".json", rendered)
+
+		again, againProps := item.Devices()
+		ts.Equal(string(rendered), string(ts.goldenDevices(again, againProps)), "%s: rendering is not idempotent", name)
+	}
+}
+
+// goldenRDS covers a representative instance, missing storage type, a missing recommendation
+// and an aurora cluster where one of the instances is absent from the response.
+func (ts *AWSTestSuite) goldenRDS() {
+	ts.aws.RDSInstances["us-east-1"] = []rdstype.DBInstance{
+		rdsInstance("db-1", "mysql", nil),
+		rdsInstance("db-nostorage", "mysql", nil),
+		rdsInstance("db-norec", "postgres", nil),
+		rdsInstance("aurora-1-a", "aurora-mysql", aws.String("aurora-1")),
+		rdsInstance("aurora-1-b", "aurora-mysql", aws.String("aurora-1")),
+	}
+	ts.aws.RDSClusters["us-east-1"] = []rdstype.DBCluster{
+		{DBClusterIdentifier: aws.String("aurora-1"), Engine: aws.String("aurora-mysql")},
+	}
+
+	ts.client.RDSInstanceResponses[utils.HashString("db-1")] = &golang2.RDSInstanceOptimizationResponse{
+		RightSizing: goldenRDSRecommendation("mysql"),
+	}
+	nostorage := goldenRDSRecommendation("mysql")
+	nostorage.Current.StorageType, nostorage.Recommended.StorageType = nil, nil
+	nostorage.Current.StorageThroughput, nostorage.Recommended.StorageThroughput = nil, nil
+	ts.client.RDSInstanceResponses[utils.HashString("db-nostorage")] = &golang2.RDSInstanceOptimizationResponse{RightSizing: nostorage}
+	norec := goldenRDSRecommendation("postgres")
+	norec.Recommended = nil
+	ts.client.RDSInstanceResponses[utils.HashString("db-norec")] = &golang2.RDSInstanceOptimizationResponse{RightSizing: norec}
+
+	aurora := goldenRDSRecommendation("aurora-mysql")
+	aurora.Current.StorageType, aurora.Recommended.StorageType = wrapperspb.String("aurora"), wrapperspb.String("aurora-iopt1")
+	ts.client.RDSClusterResponses[utils.HashString("aurora-1")] = &golang2.RDSClusterOptimizationResponse{
+		RightSizing: map[string]*golang2.RDSInstanceRightSizingRecommendation{utils.HashString("aurora-1-a"): aurora},
+	}
+}
+
+func (ts *AWSTestSuite) TestGoldenRDSCsv() {
+	ts.goldenRDS()
+
+	export := ts.runRDS().ExportNonInteractive()
+
+	ts.assertGolden("rds.csv", ts.goldenCsv(export))
+}
+
+func (ts *AWSTestSuite) TestGoldenRDSDevices() {
+	nostorage := goldenRDSRecommendation("mysql")
+	nostorage.Current.StorageType, nostorage.Recommended.StorageType = nil, nil
+	norec := goldenRDSRecommendation("postgres")
+	norec.Recommended = nil
+	aurora := goldenRDSRecommendation("aurora-mysql")
+	aurora.Current.StorageType, aurora.Recommended.StorageType = wrapperspb.String("aurora"), wrapperspb.String("aurora-iopt1")
+	auroraNoVolume := goldenRDSRecommendation("aurora-mysql")
+	auroraNoVolume.VolumeBytesUsed = nil
+
+	cases := map[string]*golang2.RDSInstanceRightSizingRecommendation{
+		"representative":          goldenRDSRecommendation("mysql"),
+		"nil_storage_type":        nostorage,
+		"nil_recommended":         norec,
+		"aurora":                  aurora,
+		"aurora_nil_volume_usage": auroraNoVolume,
+	}
+
+	for name, rec := range cases {
+		item := rds_instance.RDSInstanceItem{
+			Instance: rdsInstance("db-"+name, rec.Current.Engine, nil),
+			Region:   "us-east-1",
+			Wastage:  &golang2.RDSInstanceOptimizationResponse{RightSizing: rec},
+		}
+		rows, props := item.Devices()
+		rendered := ts.goldenDevices(rows, props)
+		ts.assertGolden("rds_instance_"+name+".json", rendered)
+
+		again, againProps := item.Devices()
+		ts.Equal(string(rendered), string(ts.goldenDevices(again, againProps)), "%s: rendering is not idempotent", name)
+	}
+}
@@ -0,0 +1,446 @@
+package tests
+
+import (
+	"encoding/csv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/processor/shared"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type HistoryTestSuite struct {
+	suite.Suite
+
+	store *history.Store
+}
+
+func TestHistory(t *testing.T) {
+	suite.Run(t, &HistoryTestSuite{})
+}
+
+func (ts *HistoryTestSuite) SetupTest() {
+	store, err := history.NewStore(ts.T().TempDir(), 0)
+	ts.Require().NoError(err)
+	ts.store = store
+}
+
+func instanceRecord(id, spec, recommended string, cost, recommendedCost float64) history.Record {
+	r := history.Record{ResourceType: "EC2 Instance", ResourceId: id, ResourceName: id, Region: "us-east-1", CurrentSpec: spec, CurrentCost: cost}
+	if recommended != "" {
+		r.RecommendedSpec = recommended
+		r.RecommendedCost = recommendedCost
+		r.Savings = cost - recommendedCost
+	}
+	return r
+}
+
+func (ts *HistoryTestSuite) TestStoreKeepsRunsPerAccountAndCommand() {
+	first := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i, account := range []string{"111", "111", "222"} {
+		ts.Require().NoError(ts.store.Save(history.Run{
+			Account:   account,
+			Command:   "ec2-instance",
+			StartedAt: first.Add(time.Duration(i) * time.Hour),
+			Records:   []history.Record{instanceRecord("i-1", "m5.xlarge", "", 140, 0)},
+		}))
+	}
+	ts.Require().NoError(ts.store.Save(history.Run{Account: "111", Command: "rds-instance", StartedAt: first}))
+
+	runs, err := ts.store.Runs("111", "ec2-instance")
+	ts.Require().NoError(err)
+	ts.Require().Len(runs, 2)
+	ts.True(runs[0].StartedAt.Before(runs[1].StartedAt))
+	ts.Equal("m5.xlarge", runs[1].Records[0].CurrentSpec)
+
+	latest, err := ts.store.Latest("111", "ec2-instance", first.Add(90*time.Minute))
+	ts.Require().NoError(err)
+	ts.Require().NotNil(latest)
+	ts.True(latest.StartedAt.Equal(first.Add(time.Hour)))
+
+	latest, err = ts.store.Latest("111", "ec2-instance", first)
+	ts.Require().NoError(err)
+	ts.Nil(latest)
+
+	runs, err = ts.store.Runs("333", "ec2-instance")
+	ts.NoError(err)
+	ts.Empty(runs)
+}
+
+func (ts *HistoryTestSuite) TestStoreKeepsMaxRuns() {
+	store, err := history.NewStore(ts.T().TempDir(), 2)
+	ts.Require().NoError(err)
+	first := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		ts.Require().NoError(store.Save(history.Run{Account: "111", Command: "ec2-instance", StartedAt: first.Add(time.Duration(i) * time.Hour)}))
+	}
+	ts.Require().NoError(store.Save(history.Run{Account: "111", Command: "rds-instance", StartedAt: first}))
+
+	runs, err := store.Runs("111", "ec2-instance")
+	ts.Require().NoError(err)
+	ts.Require().Len(runs, 2)
+	ts.True(runs[0].StartedAt.Equal(first.Add(2 * time.Hour)))
+	ts.True(runs[1].StartedAt.Equal(first.Add(3 * time.Hour)))
+
+	runs, err = store.Runs("111", "rds-instance")
+	ts.Require().NoError(err)
+	ts.Len(runs, 1)
+}
+
+func (ts *HistoryTestSuite) TestCorruptedRunFails() {
+	dir := ts.T().TempDir()
+	store, err := history.NewStore(dir, 0)
+	ts.Require().NoError(err)
+	ts.Require().NoError(store.Save(history.Run{Account: "111", Command: "ec2-instance", StartedAt: time.Now()}))
+	files, err := filepath.Glob(filepath.Join(dir, "111", "ec2-instance", "*.json"))
+	ts.Require().NoError(err)
+	ts.Require().Len(files, 1)
+	ts.Require().NoError(os.WriteFile(files[0], []byte("{"), 0o600))
+
+	_, err = store.Runs("111", "ec2-instance")
+	ts.Error(err)
+}
+
+func (ts *HistoryTestSuite) TestCompare() {
+	previous := history.Run{
+		Account:   "111",
+		StartedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		Records: []history.Record{
+			instanceRecord("i-resized", "m5.xlarge", "m5.large", 140, 70),
+			instanceRecord("i-changed", "m5.xlarge", "m5.large", 140, 70),
+			instanceRecord("i-same", "m5.xlarge", "m5.large", 140, 70),
+			instanceRecord("i-idle", "m5.large", "", 70, 0),
+			instanceRecord("i-deleted", "m5.large", "m5.medium", 70, 35),
+		},
+	}
+	current := history.Run{
+		Account:   "111",
+		StartedAt: previous.StartedAt.Add(24 * time.Hour),
+		Records: []history.Record{
+			instanceRecord("i-resized", "m5.large", "", 70, 0),
+			instanceRecord("i-changed", "m5.xlarge", "t3.large", 140, 60),
+			instanceRecord("i-same", "m5.xlarge", "m5.large", 140, 70),
+			instanceRecord("i-idle", "m5.large", "m5.medium", 70, 35),
+			instanceRecord("i-new", "c5.xlarge", "c5.large", 124, 62),
+		},
+	}
+
+	diff := history.Compare(&previous, current)
+
+	var changes []string
+	for _, c := range diff.Changes {
+		if c.Current != nil {
+			changes = append(changes, string(c.Kind)+":"+c.Current.ResourceId)
+		} else {
+			changes = append(changes, string(c.Kind)+":"+c.Previous.ResourceId)
+		}
+	}
+	ts.Equal([]string{
+		"New Waste:i-idle",
+		"New Waste:i-new",
+		"Changed:i-changed",
+		"Resolved:i-resized",
+		"Removed:i-deleted",
+	}, changes)
+	ts.Equal(70.0, diff.RealizedSavings)
+	ts.Equal(70.0, diff.Changes[3].RealizedSavings)
+
+	rows := diff.ExportCsv(nil)
+	ts.Len(rows, 6)
+	ts.Equal([]string{"Resolved", "111", "us-east-1", "EC2 Instance", "i-resized", "i-resized", "m5.xlarge", "m5.large",
+		"m5.large", "", "$70.00", "$0.00", "$70.00"}, rows[4].Row)
+	ts.Contains(diff.Summary(nil), "2 new waste, 1 changed, 1 resolved, 1 removed, 0 not analysed")
+	ts.Contains(diff.Summary(&currency.Currency{Code: "EUR", Symbol: "€", Rate: 0.5}), "realized savings: €35.00")
+}
+
+func (ts *HistoryTestSuite) TestCompareRealizedSavingsAtSameRuntime() {
+	// the previous run was stored before the runtime was observed, for the whole month
+	previous := history.Run{
+		Account:   "111",
+		StartedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		Records: []history.Record{
+			instanceRecord("i-resized", "m5.xlarge", "m5.large", 140, 70),
+			instanceRecord("i-rejected", "m5.xlarge", "m5.large", 140, 70),
+		},
+	}
+	resized := instanceRecord("i-resized", "m5.large", "", 35, 0)
+	resized.RuntimeHours = 365
+	rejected := instanceRecord("i-rejected", "m5.xlarge", "", 70, 0)
+	rejected.RuntimeHours = 365
+	current := history.Run{
+		Account:   "111",
+		StartedAt: previous.StartedAt.Add(24 * time.Hour),
+		Records:   []history.Record{resized, rejected},
+	}
+
+	diff := history.Compare(&previous, current)
+
+	ts.Require().Len(diff.Changes, 2)
+	// priced for the 365 hours of the current run m5.xlarge is 70, the drop of i-rejected only comes from the runtime
+	ts.Equal("i-rejected", diff.Changes[0].Current.ResourceId)
+	ts.Zero(diff.Changes[0].RealizedSavings)
+	ts.Equal("i-resized", diff.Changes[1].Current.ResourceId)
+	ts.Equal(35.0, diff.Changes[1].RealizedSavings)
+	ts.Equal(35.0, diff.RealizedSavings)
+}
+
+func (ts *HistoryTestSuite) TestCompareUnanalysedIsNotRemoved() {
+	volume := instanceRecord("vol-1", "gp2/100", "gp3/100", 10, 8)
+	volume.ResourceType, volume.ParentId = "EBS Volume", "i-skipped"
+	member := instanceRecord("db-1-compute", "db.r5.large", "db.r5.large", 200, 150)
+	member.ResourceType, member.ParentId, member.ClusterId = "RDS Instance Compute", "db-1", "cluster-1"
+	previous := history.Run{
+		Account:   "111",
+		StartedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		Records: []history.Record{
+			instanceRecord("i-skipped", "m5.xlarge", "m5.large", 140, 70),
+			volume,
+			member,
+			instanceRecord("i-deleted", "m5.large", "m5.medium", 70, 35),
+		},
+	}
+	current := history.Run{
+		Account:    "111",
+		StartedAt:  previous.StartedAt.Add(24 * time.Hour),
+		Unanalysed: []string{"i-skipped", "cluster-1"},
+	}
+
+	diff := history.Compare(&previous, current)
+
+	kinds := map[string]history.ChangeKind{}
+	for _, c := range diff.Changes {
+		kinds[c.Previous.ResourceId] = c.Kind
+	}
+	ts.Equal(map[string]history.ChangeKind{
+		"i-skipped":    history.ChangeNotAnalysed,
+		"vol-1":        history.ChangeNotAnalysed,
+		"db-1-compute": history.ChangeNotAnalysed,
+		"i-deleted":    history.ChangeRemoved,
+	}, kinds)
+	ts.Contains(diff.Summary(nil), "1 removed, 3 not analysed")
+}
+
+func (ts *HistoryTestSuite) TestDiffWrite() {
+	diff := history.Compare(nil, history.Run{Account: "111", Records: []history.Record{
+		instanceRecord("i-1", "m5.xlarge", "m5.large", 140, 70),
+	}})
+	path := filepath.Join(ts.T().TempDir(), "diff.csv")
+	ts.Require().NoError(diff.Write(path, nil))
+
+	f, err := os.Open(path)
+	ts.Require().NoError(err)
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	ts.Require().NoError(err)
+	ts.Require().Len(rows, 2)
+	ts.Equal("Change", rows[0][0])
+	ts.Equal([]string{"New Waste", "111", "us-east-1", "EC2 Instance", "i-1"}, rows[1][:5])
+}
+
+func (ts *HistoryTestSuite) TestCompareWithoutPreviousRun() {
+	diff := history.Compare(nil, history.Run{Records: []history.Record{
+		instanceRecord("i-1", "m5.xlarge", "m5.large", 140, 70),
+		instanceRecord("i-2", "m5.large", "", 70, 0),
+	}})
+
+	ts.Require().Len(diff.Changes, 1)
+	ts.Equal(history.ChangeNewWaste, diff.Changes[0].Kind)
+	ts.Zero(diff.RealizedSavings)
+	ts.Contains(diff.Summary(nil), "No previous run")
+}
+
+func (ts *AWSTestSuite) TestEC2Records() {
+	web := withVolumes(ec2Instance("i-web", types.InstanceStateNameRunning, types.Tag{Key: aws.String("Name"), Value: aws.String("web")}), "vol-web")
+	ts.aws.Instances["us-east-1"] = []types.Instance{web, ec2Instance("i-norec", types.InstanceStateNameRunning)}
+	ts.aws.Volumes["vol-web"] = goldenVolume("vol-web", 100)
+	ts.client.EC2InstanceResponses[utils.HashString("i-web")] = &golang2.EC2InstanceOptimizationResponse{
+		RightSizing:       goldenEC2Recommendation(),
+		VolumeRightSizing: map[string]*golang2.EBSVolumeRecommendation{utils.HashString("vol-web"): goldenEBSRecommendation()},
+	}
+	ts.client.EC2InstanceResponses[utils.HashString("i-norec")] = &golang2.EC2InstanceOptimizationResponse{
+		RightSizing: &golang2.EC2InstanceRightSizingRecommendation{Current: goldenEC2Recommendation().Current},
+	}
+
+	records := ts.runEC2().Records()
+
+	byId := map[string]history.Record{}
+	for _, r := range records {
+		byId[r.Key()] = r
+	}
+	ts.Len(byId, 3)
+	ts.Equal(history.Record{
+		ResourceType: "EC2 Instance", ResourceId: "i-web", ResourceName: "web", Region: "us-east-1", Platform: "Linux/UNIX",
+		RuntimeHours: 730, CurrentSpec: "m5.xlarge", RecommendedSpec: "m5.large", CurrentType: "m5.xlarge", RecommendedType: "m5.large",
+		CurrentCost: 140.16, RecommendedCost: 70.08, Savings: 70.08, Description: "cpu usage is low",
+		Utilization: map[string]history.Utilization{
+			"cpu":    {Avg: aws.Float64(12.5), Max: aws.Float64(40), Unit: history.UnitPercent},
+			"memory": {Avg: aws.Float64(30), Max: aws.Float64(55), Unit: history.UnitPercent},
+		},
+		Tags: map[string]string{"Name": "web"},
+	}, byId["EC2 Instance/i-web"])
+	ts.Equal(map[string]string{"Name": "web"}, byId["EBS Volume/vol-web"].Tags)
+	ts.Equal("i-web", byId["EBS Volume/vol-web"].ParentId)
+	ts.Equal("gp3/80 GB/3000 IOPS", byId["EBS Volume/vol-web"].RecommendedSpec)
+	ts.Equal(aws.Int32(80), byId["EBS Volume/vol-web"].RecommendedSize)
+	ts.Equal(aws.Int32(3000), byId["EBS Volume/vol-web"].RecommendedIops)
+	ts.Equal(aws.Float64(125), byId["EBS Volume/vol-web"].RecommendedThroughput)
+	ts.False(byId["EC2 Instance/i-norec"].HasWaste())
+}
+
+func (ts *AWSTestSuite) TestRDSRecords() {
+	ts.goldenRDS()
+
+	records := ts.runRDS().Records()
+
+	byId := map[string]history.Record{}
+	for _, r := range records {
+		byId[r.Key()] = r
+	}
+	// aurora-1-b is missing from the cluster response
+	ts.Len(byId, 8)
+	ts.Equal("db.t3.large", byId["RDS Instance Compute/db-1-compute"].RecommendedSpec)
+	ts.Equal("gp2/100 GB/300 IOPS", byId["RDS Instance Storage/db-1-storage"].CurrentSpec)
+	ts.False(byId["RDS Instance Compute/db-norec-compute"].HasWaste())
+	ts.Equal("aurora-1-a", byId["RDS Instance Storage/aurora-1-a-storage"].ParentId)
+	ts.NotContains(byId["RDS Instance Storage/aurora-1-a-storage"].Utilization, "storage")
+	ts.Equal("mysql", byId["RDS Instance Compute/db-1-compute"].Platform)
+	ts.InDelta(90*24, byId["RDS Instance Compute/db-1-compute"].AgeHours, 1)
+	ts.Equal(730.0, byId["RDS Instance Compute/db-1-compute"].RuntimeHours)
+	ts.Zero(byId["RDS Instance Storage/db-1-storage"].AgeHours)
+	ts.Zero(byId["RDS Instance Storage/db-1-storage"].RuntimeHours)
+
+	// 70GiB of 100GiB storage free on average and 35GiB at the lowest, 5GiB of 8GiB memory free on average
+	storage := byId["RDS Instance Storage/db-1-storage"]
+	ts.Equal("gp3", storage.RecommendedType)
+	ts.Equal(aws.Int32(60), storage.RecommendedSize)
+	// 60GiB of gp3 is below the 400GiB from which mysql takes IOPS and throughput
+	ts.Nil(storage.RecommendedIops)
+	ts.Nil(storage.RecommendedThroughput)
+	ts.Nil(byId["RDS Instance Storage/aurora-1-a-storage"].RecommendedSize)
+	ts.InDelta(30.0, *storage.Utilization["storage"].Avg, 0.01)
+	ts.InDelta(65.0, *storage.Utilization["storage"].Max, 0.01)
+	ts.InDelta(37.5, *byId["RDS Instance Compute/db-1-compute"].Utilization["memory"].Avg, 0.01)
+}
+
+func (ts *AWSTestSuite) TestRDSRecordsGp3Threshold() {
+	cases := []struct {
+		engine string
+		size   int32
+		iops   bool
+	}{
+		{"mysql", 399, false},
+		{"mysql", 400, true},
+		{"oracle-ee", 199, false},
+		{"oracle-ee", 200, true},
+		{"sqlserver-se", 19, false},
+		{"sqlserver-se", 20, true},
+	}
+	for _, c := range cases {
+		rec := goldenRDSRecommendation(c.engine)
+		rec.Recommended.StorageSize = wrapperspb.Int32(c.size)
+
+		records := shared.RDSRecords("us-east-1", rdsInstance("db-1", c.engine, nil), nil, rec, shared.Runtime{})
+
+		storage := records[1]
+		ts.Equal(aws.Int32(c.size), storage.RecommendedSize)
+		if c.iops {
+			ts.Equal(aws.Int32(3000), storage.RecommendedIops, "%s at %dGiB", c.engine, c.size)
+			ts.Equal(aws.Float64(125), storage.RecommendedThroughput, "%s at %dGiB", c.engine, c.size)
+		} else {
+			ts.Nil(storage.RecommendedIops, "%s at %dGiB", c.engine, c.size)
+			ts.Nil(storage.RecommendedThroughput, "%s at %dGiB", c.engine, c.size)
+		}
+	}
+}
+
+func typedRecord(id, currentType, recommendedType string, cost, recommendedCost float64, cpuMax float64) history.Record {
+	r := instanceRecord(id, currentType, recommendedType, cost, recommendedCost)
+	r.CurrentType, r.RecommendedType = currentType, recommendedType
+	r.Utilization = map[string]history.Utilization{
+		"cpu": {Avg: aws.Float64(cpuMax / 2), Max: aws.Float64(cpuMax), Unit: history.UnitPercent},
+	}
+	return r
+}
+
+func (ts *HistoryTestSuite) TestRealizedSavings() {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 10, 0, 0, 0, time.UTC) }
+	runs := []history.Run{
+		{StartedAt: day(1), Records: []history.Record{
+			typedRecord("i-applied", "m5.xlarge", "m5.large", 140, 70, 20),
+			typedRecord("i-hot", "m5.xlarge", "m5.large", 140, 70, 30),
+			typedRecord("i-ignored", "m5.xlarge", "m5.large", 140, 70, 20),
+			typedRecord("i-other", "m5.xlarge", "m5.large", 140, 70, 20),
+		}},
+		{StartedAt: day(2), Records: []history.Record{
+			typedRecord("i-applied", "m5.large", "", 70, 0, 40),
+			typedRecord("i-hot", "m5.xlarge", "m5.large", 140, 70, 30),
+			typedRecord("i-ignored", "m5.xlarge", "m5.large", 140, 70, 20),
+			typedRecord("i-other", "m5.xlarge", "m5.large", 140, 70, 20),
+		}},
+	}
+	current := history.Run{StartedAt: day(3), Records: []history.Record{
+		typedRecord("i-applied", "m5.large", "", 70, 0, 40),
+		typedRecord("i-hot", "m5.large", "", 70, 0, 97),
+		typedRecord("i-ignored", "m5.xlarge", "m5.large", 140, 70, 20),
+		typedRecord("i-other", "t3.large", "", 60, 0, 20),
+		typedRecord("i-new", "m5.large", "", 70, 0, 20),
+	}}
+
+	savings := history.RealizedSavings(append(runs, current), current)
+
+	ts.Require().Len(savings, 2)
+	ts.Equal("i-applied", savings[0].Current.ResourceId)
+	ts.Equal(day(1), savings[0].RecommendedAt)
+	ts.Equal(day(2), savings[0].AppliedBy)
+	ts.Equal(70.0, savings[0].RealizedSavings)
+	ts.Empty(savings[0].Regressions)
+
+	ts.Equal("i-hot", savings[1].Current.ResourceId)
+	ts.Equal(day(2), savings[1].RecommendedAt)
+	ts.Equal(day(3), savings[1].AppliedBy)
+	ts.Equal(70.0, savings[1].ExpectedSavings)
+	ts.Equal([]string{"cpu"}, savings[1].Regressions)
+
+	rows := history.RealizedSavingsCsv("111", savings, nil)
+	ts.Len(rows, 3)
+	ts.Equal("cpu:: Avg: 48.50% - Max: 97.00%", rows[2].Row[13])
+	ts.Equal("cpu", rows[2].Row[14])
+	ts.Equal("2 applied recommendations, realized savings: $140.00 (monthly), 1 possibly under-provisioned", history.RealizedSavingsSummary(savings, nil))
+	ts.Equal("2 applied recommendations, realized savings: €70.00 (monthly), 1 possibly under-provisioned",
+		history.RealizedSavingsSummary(savings, &currency.Currency{Code: "EUR", Symbol: "€", Rate: 0.5}))
+
+	path := filepath.Join(ts.T().TempDir(), "realized.csv")
+	ts.Require().NoError(history.WriteRealizedSavings(path, "111", savings, nil))
+	f, err := os.Open(path)
+	ts.Require().NoError(err)
+	defer f.Close()
+	written, err := csv.NewReader(f).ReadAll()
+	ts.Require().NoError(err)
+	ts.Require().Len(written, 3)
+	ts.Equal(rows[2].Row, written[2])
+}
+
+func (ts *HistoryTestSuite) TestRealizedSavingsAtSameRuntime() {
+	// stored before the runtime was observed, for the whole month
+	baseline := history.Run{StartedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), Records: []history.Record{
+		typedRecord("i-applied", "m5.xlarge", "m5.large", 140, 70, 20),
+	}}
+	applied := typedRecord("i-applied", "m5.large", "", 35, 0, 40)
+	applied.RuntimeHours = 365
+	current := history.Run{StartedAt: baseline.StartedAt.Add(24 * time.Hour), Records: []history.Record{applied}}
+
+	savings := history.RealizedSavings([]history.Run{baseline, current}, current)
+
+	// m5.xlarge is 70 for the 365 hours the instance now runs, the other 70 come from the runtime
+	ts.Require().Len(savings, 1)
+	ts.Equal(35.0, savings[0].RealizedSavings)
+	ts.Equal(70.0, savings[0].ExpectedSavings)
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/report"
+	"github.com/opengovern/plugin-aws/plugin/summary"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type MarkdownTestSuite struct {
+	suite.Suite
+}
+
+func TestMarkdown(t *testing.T) {
+	suite.Run(t, &MarkdownTestSuite{})
+}
+
+func markdownRecords() []history.Record {
+	web := taggedRecord("EC2 Instance", "i-web", 140.16, 70.08, nil)
+	web.ResourceName, web.CurrentSpec, web.RecommendedSpec = "web", "m5.xlarge", "m5.large"
+	volume := taggedRecord("EBS Volume", "vol-web", 10, 3.6, nil)
+	volume.ResourceName, volume.CurrentSpec, volume.RecommendedSpec = "web", "gp2/100 GB/300 IOPS", "gp3/80 GB/3000 IOPS"
+	db := taggedRecord("RDS Instance Compute", "db-1-compute", 100, 50, nil)
+	db.ResourceName, db.CurrentSpec, db.RecommendedSpec = "db-1", "db.m5.large", "db.t3.large"
+	return []history.Record{volume, web, db, taggedRecord("EC2 Instance", "i-fine", 70.08, 0, nil)}
+}
+
+func markdownResources() []summary.Resource {
+	return []summary.Resource{
+		{Id: "i-web", ResourceType: "EC2 Instance", Status: summary.StatusAnalysed, CurrentCost: 150.16, Savings: 73.68},
+		{Id: "i-fine", ResourceType: "EC2 Instance", Status: summary.StatusAnalysed, CurrentCost: 70.08},
+		{Id: "db-1", ResourceType: "RDS Instance", Status: summary.StatusAnalysed, CurrentCost: 100, Savings: 50},
+		{Id: "i-spot", ResourceType: "EC2 Instance", Status: summary.StatusSkipped, SkipReason: "spot instance"},
+		{Id: "i-spot-2", ResourceType: "EC2 Instance", Status: summary.StatusSkipped, SkipReason: "spot instance"},
+		{Id: "i-asg", ResourceType: "EC2 Instance", Status: summary.StatusSkipped, SkipReason: "auto-scaling group instance"},
+		{Id: "i-broken", ResourceType: "EC2 Instance", Status: summary.StatusFailed},
+	}
+}
+
+func (ts *MarkdownTestSuite) TestRender() {
+	markdown := report.NewMarkdown("123456789012", markdownRecords(), markdownResources(), 2, 0)
+
+	ts.Equal(`### AWS optimization summary
+
+Account 123456789012: analysed 3 resources, skipped 3, failed 1. Current cost **$320.24**, potential savings **$123.68 (38.6%)** per month.
+
+| Service | Resources | Current Cost | Savings | Savings % |
+| --- | ---: | ---: | ---: | ---: |
+| EC2 | 2 | $220.24 | $73.68 | 33.5% |
+| RDS | 1 | $100.00 | $50.00 | 50.0% |
+
+#### Top savings
+
+| Resource | Type | Region | Current | Suggested | Current Cost | Savings |
+| --- | --- | --- | --- | --- | ---: | ---: |
+| web (i-web) | EC2 Instance | us-east-1 | m5.xlarge | m5.large | $140.16 | $70.08 |
+| db-1 (db-1-compute) | RDS Instance Compute | us-east-1 | db.m5.large | db.t3.large | $100.00 | $50.00 |
+
+_1 more resources save $3.60 in total._
+
+#### Skipped resources
+
+| Reason | Resources |
+| --- | ---: |
+| spot instance | 2 |
+| auto-scaling group instance | 1 |
+`, markdown.Render(nil))
+}
+
+func (ts *MarkdownTestSuite) TestMinSavings() {
+	eur := &currency.Currency{Code: "EUR", Symbol: "€", Rate: 0.5}
+
+	markdown := report.NewMarkdown("123456789012", markdownRecords(), nil, 0, 30)
+	rendered := markdown.Render(eur)
+	ts.Contains(rendered, "| web (i-web) | EC2 Instance | us-east-1 | m5.xlarge | m5.large | €70.08 | €35.04 |")
+	ts.NotContains(rendered, "db-1")
+	ts.NotContains(rendered, "more resources")
+	ts.NotContains(rendered, "Skipped resources")
+
+	markdown.MinSavings = 100
+	ts.Contains(markdown.Render(eur), "No resource saves at least €100.00.")
+}
+
+func (ts *MarkdownTestSuite) TestEscapesCells() {
+	record := taggedRecord("EC2 Instance", "i-pipe", 100, 50, nil)
+	record.ResourceName = "a|b"
+
+	rendered := report.NewMarkdown("123456789012", []history.Record{record}, nil, 0, 0).Render(nil)
+
+	ts.Contains(rendered, "| a\\|b (i-pipe) |")
+}
+
+func (ts *MarkdownTestSuite) TestWrite() {
+	markdown := report.NewMarkdown("123456789012", markdownRecords(), markdownResources(), 10, 0)
+	path := filepath.Join(ts.T().TempDir(), "kaytu.md")
+
+	ts.Require().NoError(markdown.Write(path, nil))
+
+	content, err := os.ReadFile(path)
+	ts.Require().NoError(err)
+	ts.Equal(markdown.Render(nil), string(content))
+}
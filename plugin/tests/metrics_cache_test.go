@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	aws2 "github.com/opengovern/plugin-aws/plugin/aws"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type metricsWindow struct {
+	MetricNames []string
+	Start, End  time.Time
+}
+
+// windowMetricsProvider returns one datapoint per hour of the requested window and remembers the windows.
+type windowMetricsProvider struct {
+	windows []metricsWindow
+}
+
+func (p *windowMetricsProvider) GetMetrics(_ context.Context, _ string, _ string, metricNames []string, _ map[string][]string, startTime, endTime time.Time, _ time.Duration, _ []types2.Statistic, _ []string) (map[string][]types2.Datapoint, error) {
+	p.windows = append(p.windows, metricsWindow{MetricNames: metricNames, Start: startTime, End: endTime})
+	res := map[string][]types2.Datapoint{}
+	for _, m := range metricNames {
+		res[m] = []types2.Datapoint{}
+		for ts := startTime; ts.Before(endTime); ts = ts.Add(time.Hour) {
+			res[m] = append(res[m], types2.Datapoint{Timestamp: aws.Time(ts), Average: aws.Float64(1)})
+		}
+	}
+	return res, nil
+}
+
+func (p *windowMetricsProvider) GetDayByDayMetrics(_ context.Context, _ string, _ string, _ []string, _ map[string][]string, _ int, _ time.Duration, _ []types2.Statistic, _ []string) (map[string][]types2.Datapoint, error) {
+	panic("cache should only request metrics by window")
+}
+
+type MetricsCacheTestSuite struct {
+	suite.Suite
+
+	dir      string
+	now      time.Time
+	provider *windowMetricsProvider
+}
+
+func TestMetricsCache(t *testing.T) {
+	suite.Run(t, &MetricsCacheTestSuite{})
+}
+
+func (ts *MetricsCacheTestSuite) SetupTest() {
+	ts.dir = ts.T().TempDir()
+	ts.now = time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+	ts.provider = &windowMetricsProvider{}
+}
+
+func (ts *MetricsCacheTestSuite) cache(account string, ttl time.Duration) *aws2.MetricsCache {
+	cache, err := aws2.NewMetricsCache(ts.dir, account, ttl)
+	ts.Require().NoError(err)
+	cache.SetClock(func() time.Time { return ts.now })
+	return cache
+}
+
+func (ts *MetricsCacheTestSuite) get(cache *aws2.MetricsCache, metricNames []string, days int) map[string][]types2.Datapoint {
+	metrics, err := cache.GetDayByDayMetrics(context.Background(), ts.provider, "us-east-1", "AWS/EC2", metricNames,
+		map[string][]string{"InstanceId": {"i-1"}}, days, time.Hour, []types2.Statistic{types2.StatisticAverage}, nil)
+	ts.Require().NoError(err)
+	return metrics
+}
+
+func (ts *MetricsCacheTestSuite) TestFirstRunCoversWindow() {
+	metrics := ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 3)
+
+	// today, two complete UTC days and the day the window starts in
+	ts.Len(ts.provider.windows, 4)
+	ts.Equal(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), ts.provider.windows[0].Start)
+	ts.Equal(ts.now, ts.provider.windows[0].End)
+	ts.Len(metrics["CPUUtilization"], 72)
+	for _, dp := range metrics["CPUUtilization"] {
+		ts.False(dp.Timestamp.Before(ts.now.Add(-72 * time.Hour)))
+	}
+}
+
+func (ts *MetricsCacheTestSuite) TestPartialDayRespectsTTL() {
+	ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 3)
+	ts.provider.windows = nil
+
+	ts.now = ts.now.Add(30 * time.Minute)
+	ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 3)
+	ts.Empty(ts.provider.windows)
+
+	ts.now = ts.now.Add(time.Hour)
+	metrics := ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 3)
+	ts.Require().Len(ts.provider.windows, 1)
+	ts.Equal(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), ts.provider.windows[0].Start)
+	ts.Equal(ts.now, ts.provider.windows[0].End)
+	ts.Len(metrics["CPUUtilization"], 72)
+}
+
+func (ts *MetricsCacheTestSuite) TestCompleteDaysArePermanent() {
+	ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 3)
+	ts.provider.windows = nil
+
+	// two days later with a longer window only the days not stored as complete are fetched
+	ts.now = ts.now.Add(2 * 24 * time.Hour)
+	ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 6)
+
+	var starts []time.Time
+	for _, w := range ts.provider.windows {
+		starts = append(starts, w.Start)
+	}
+	ts.ElementsMatch([]time.Time{
+		time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+		// the day before yesterday was only fetched partially during the first run
+		time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC),
+	}, starts)
+}
+
+func (ts *MetricsCacheTestSuite) TestOnlyMissingMetricsAreFetched() {
+	ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 1)
+	ts.provider.windows = nil
+
+	metrics := ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization", "NetworkIn"}, 1)
+
+	ts.Require().Len(ts.provider.windows, 2)
+	for _, w := range ts.provider.windows {
+		ts.Equal([]string{"NetworkIn"}, w.MetricNames)
+	}
+	ts.Len(metrics["CPUUtilization"], 24)
+	ts.Len(metrics["NetworkIn"], 24)
+}
+
+func (ts *MetricsCacheTestSuite) TestAccountsAreSeparated() {
+	ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 1)
+	ts.provider.windows = nil
+
+	ts.get(ts.cache("222", time.Hour), []string{"CPUUtilization"}, 1)
+	ts.Len(ts.provider.windows, 2)
+}
+
+func (ts *MetricsCacheTestSuite) TestCorruptedEntryIsRefetched() {
+	ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 1)
+	ts.provider.windows = nil
+
+	files, err := filepath.Glob(filepath.Join(ts.dir, "111", "*.json"))
+	ts.Require().NoError(err)
+	ts.Require().Len(files, 1)
+	ts.Require().NoError(os.WriteFile(files[0], []byte("{"), 0o600))
+
+	metrics := ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 1)
+	ts.Len(ts.provider.windows, 2)
+	ts.Len(metrics["CPUUtilization"], 24)
+}
+
+func (ts *MetricsCacheTestSuite) TestDaysOutsideWindowAreDropped() {
+	ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 3)
+	files, err := filepath.Glob(filepath.Join(ts.dir, "111", "*.json"))
+	ts.Require().NoError(err)
+	ts.Require().Len(files, 1)
+	before, err := os.ReadFile(files[0])
+	ts.Require().NoError(err)
+	ts.Contains(string(before), `"2024-03-07"`)
+
+	ts.now = ts.now.Add(2 * 24 * time.Hour)
+	ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 3)
+
+	after, err := os.ReadFile(files[0])
+	ts.Require().NoError(err)
+	ts.NotContains(string(after), `"2024-03-07"`)
+	ts.NotContains(string(after), `"2024-03-08"`)
+	ts.Contains(string(after), `"2024-03-09"`)
+	ts.Contains(string(after), `"2024-03-12"`)
+}
+
+func (ts *MetricsCacheTestSuite) TestStaleFilesAreDeleted() {
+	ts.get(ts.cache("111", time.Hour), []string{"NetworkIn"}, 3)
+	ts.get(ts.cache("222", time.Hour), []string{"NetworkIn"}, 3)
+	stale, err := filepath.Glob(filepath.Join(ts.dir, "*", "*.json"))
+	ts.Require().NoError(err)
+	ts.Require().Len(stale, 2)
+	for _, path := range stale {
+		ts.Require().NoError(os.Chtimes(path, ts.now.Add(-4*24*time.Hour), ts.now.Add(-4*24*time.Hour)))
+	}
+	leftover := filepath.Join(ts.dir, "111", ".metrics-123")
+	ts.Require().NoError(os.WriteFile(leftover, []byte("{"), 0o600))
+	ts.Require().NoError(os.Chtimes(leftover, ts.now.Add(-4*24*time.Hour), ts.now.Add(-4*24*time.Hour)))
+
+	ts.get(ts.cache("111", time.Hour), []string{"CPUUtilization"}, 3)
+
+	files, err := filepath.Glob(filepath.Join(ts.dir, "111", "*.json"))
+	ts.Require().NoError(err)
+	ts.Len(files, 1, "only the file of the metric of this run is left")
+	ts.NoFileExists(leftover)
+	ts.FileExists(filepath.Join(ts.dir, "222", filepath.Base(stale[1])), "other accounts are left alone")
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	aws2 "github.com/opengovern/plugin-aws/plugin/aws"
+	"github.com/stretchr/testify/suite"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeMetricsProvider returns a datapoint a minute per metric and requested day, from Start or the
+// beginning of 2024, outside of any observability window.
+type fakeMetricsProvider struct {
+	Start time.Time
+
+	calls int
+}
+
+func (f *fakeMetricsProvider) GetMetrics(_ context.Context, _ string, _ string, metricNames []string, _ map[string][]string, _, _ time.Time, _ time.Duration, _ []types2.Statistic, _ []string) (map[string][]types2.Datapoint, error) {
+	f.calls++
+	return fakeDatapoints(metricNames, 1, f.Start), nil
+}
+
+func (f *fakeMetricsProvider) GetDayByDayMetrics(_ context.Context, _ string, _ string, metricNames []string, _ map[string][]string, days int, _ time.Duration, _ []types2.Statistic, _ []string) (map[string][]types2.Datapoint, error) {
+	f.calls++
+	return fakeDatapoints(metricNames, days, f.Start), nil
+}
+
+func fakeDatapoints(metricNames []string, count int, start time.Time) map[string][]types2.Datapoint {
+	res := map[string][]types2.Datapoint{}
+	ts := start
+	if ts.IsZero() {
+		ts = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	for _, m := range metricNames {
+		for i := 0; i < count; i++ {
+			res[m] = append(res[m], types2.Datapoint{
+				Timestamp:   aws.Time(ts.Add(time.Duration(i) * time.Minute)),
+				Average:     aws.Float64(float64(10 * (i + 1))),
+				Maximum:     aws.Float64(float64(20 * (i + 1))),
+				Minimum:     aws.Float64(0),
+				Sum:         aws.Float64(float64(600 * (i + 1))),
+				SampleCount: aws.Float64(60),
+				Unit:        types2.StandardUnitPercent,
+			})
+		}
+	}
+	return res
+}
+
+type MetricsReplayTestSuite struct {
+	suite.Suite
+
+	provider *fakeMetricsProvider
+	recorder *aws2.MetricsRecorder
+}
+
+func TestMetricsReplay(t *testing.T) {
+	suite.Run(t, &MetricsReplayTestSuite{})
+}
+
+func (ts *MetricsReplayTestSuite) SetupTest() {
+	ts.provider = &fakeMetricsProvider{}
+	ts.recorder = aws2.NewMetricsRecorder(ts.provider)
+}
+
+func (ts *MetricsReplayTestSuite) replay() *aws2.MetricsReplay {
+	path := filepath.Join(ts.T().TempDir(), "metrics.json")
+	ts.Require().NoError(ts.recorder.Save(path))
+	replay, err := aws2.NewMetricsReplay(path)
+	ts.Require().NoError(err)
+	return replay
+}
+
+func (ts *MetricsReplayTestSuite) TestRecordAndReplay() {
+	ctx := context.Background()
+	filters := map[string][]string{"InstanceId": {"i-1"}}
+
+	recorded, err := ts.recorder.GetDayByDayMetrics(ctx, "us-east-1", "AWS/EC2", []string{"CPUUtilization"}, filters, 3, time.Minute, nil, []string{"tm99"})
+	ts.Require().NoError(err)
+	start := time.Now().Add(-time.Hour)
+	_, err = ts.recorder.GetMetrics(ctx, "us-east-1", "AWS/RDS", []string{"FreeableMemory"}, filters, start, start.Add(time.Hour), time.Minute, []types2.Statistic{types2.StatisticAverage}, nil)
+	ts.Require().NoError(err)
+
+	replay := ts.replay()
+
+	replayed, err := replay.GetDayByDayMetrics(ctx, "us-east-1", "AWS/EC2", []string{"CPUUtilization"}, filters, 3, time.Minute, nil, []string{"tm99"})
+	ts.Require().NoError(err)
+	ts.Equal(recorded, replayed)
+
+	// time ranges are matched by window length, not absolute time
+	later := time.Now()
+	_, err = replay.GetMetrics(ctx, "us-east-1", "AWS/RDS", []string{"FreeableMemory"}, filters, later, later.Add(time.Hour), time.Minute, []types2.Statistic{types2.StatisticAverage}, nil)
+	ts.NoError(err)
+	ts.Equal(2, ts.provider.calls)
+}
+
+func (ts *MetricsReplayTestSuite) TestUnknownRequest() {
+	ctx := context.Background()
+	_, err := ts.recorder.GetDayByDayMetrics(ctx, "us-east-1", "AWS/EC2", []string{"CPUUtilization"}, map[string][]string{"InstanceId": {"i-1"}}, 3, time.Minute, nil, nil)
+	ts.Require().NoError(err)
+
+	replay := ts.replay()
+	_, err = replay.GetDayByDayMetrics(ctx, "us-east-1", "AWS/EC2", []string{"CPUUtilization"}, map[string][]string{"InstanceId": {"i-2"}}, 3, time.Minute, nil, nil)
+	ts.Error(err)
+	_, err = replay.GetDayByDayMetrics(ctx, "eu-west-1", "AWS/EC2", []string{"CPUUtilization"}, map[string][]string{"InstanceId": {"i-1"}}, 3, time.Minute, nil, nil)
+	ts.Error(err)
+}
+
+func (ts *MetricsReplayTestSuite) TestRecordingIsNotMutatedByCaller() {
+	ctx := context.Background()
+	filters := map[string][]string{"VolumeId": {"vol-1"}}
+
+	metrics, err := ts.recorder.GetDayByDayMetrics(ctx, "us-east-1", "AWS/EBS", []string{"VolumeReadOps"}, filters, 2, time.Minute, nil, nil)
+	ts.Require().NoError(err)
+	// processors rewrite the returned datapoints in place
+	metrics["VolumeReadOps"][0] = types2.Datapoint{Average: aws.Float64(-1)}
+
+	replayed, err := ts.replay().GetDayByDayMetrics(ctx, "us-east-1", "AWS/EBS", []string{"VolumeReadOps"}, filters, 2, time.Minute, nil, nil)
+	ts.Require().NoError(err)
+	ts.Equal(10.0, *replayed["VolumeReadOps"][0].Average)
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/openmetrics"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"github.com/opengovern/plugin-aws/plugin/report"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type OpenMetricsTestSuite struct {
+	suite.Suite
+}
+
+func TestOpenMetrics(t *testing.T) {
+	suite.Run(t, &OpenMetricsTestSuite{})
+}
+
+func (ts *OpenMetricsTestSuite) TestPercentile() {
+	var values []float64
+	for i := 100; i >= 1; i-- {
+		values = append(values, float64(i))
+	}
+
+	ts.Equal(99.0, openmetrics.Percentile(values, 99))
+	ts.Equal(50.0, openmetrics.Percentile(values, 50))
+	ts.Equal(7.0, openmetrics.Percentile([]float64{7}, 99))
+	ts.Equal(3.0, openmetrics.Percentile([]float64{1, 3}, 99))
+	ts.Zero(openmetrics.Percentile(nil, 99))
+	ts.Equal(100.0, values[0], "the values are not sorted in place")
+}
+
+func (ts *OpenMetricsTestSuite) TestString() {
+	web := history.Record{ResourceType: "EC2 Instance", ResourceId: "i-web", Region: "us-east-1", CurrentType: "m5.xlarge",
+		CurrentCost: 140.16, Savings: 70.08}
+	volume := history.Record{ResourceType: "EBS Volume", ResourceId: "vol-web", Region: "us-east-1", ParentId: "i-web",
+		CurrentType: "gp2", CurrentCost: 10}
+	compute := history.Record{ResourceType: "RDS Instance Compute", ResourceId: "db-1-compute", Region: "eu-west-1",
+		ParentId: "db-1", CurrentType: "db.m5.large", CurrentCost: 100, Savings: 50}
+	storage := history.Record{ResourceType: "RDS Instance Storage", ResourceId: "db-1-storage", Region: "eu-west-1",
+		ParentId: "db-1", CurrentType: "gp2", CurrentCost: 11.5}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := []report.Item{{Service: "EC2", Utilization: map[string][]report.Series{
+		"i-web": {
+			{Metric: "CPUUtilization", Datapoints: []types2.Datapoint{
+				{Timestamp: aws.Time(start), Average: aws.Float64(12.5)},
+				{Timestamp: aws.Time(start.Add(time.Minute)), Average: aws.Float64(40)},
+			}},
+			{Metric: "NetworkIn"},
+		},
+	}}}
+
+	exposition := openmetrics.New("123456789012", []history.Record{web, volume, compute, storage}, items)
+
+	ts.Equal(9, exposition.Samples())
+	ts.Equal(`# HELP kaytu_aws_current_monthly_cost Current monthly cost of the resource in USD.
+# TYPE kaytu_aws_current_monthly_cost gauge
+kaytu_aws_current_monthly_cost{account="123456789012",instance_type="db.m5.large",region="eu-west-1",resource_id="db-1-compute",resource_type="RDS Instance Compute"} 100
+kaytu_aws_current_monthly_cost{account="123456789012",instance_type="db.m5.large",region="eu-west-1",resource_id="db-1-storage",resource_type="RDS Instance Storage"} 11.5
+kaytu_aws_current_monthly_cost{account="123456789012",instance_type="m5.xlarge",region="us-east-1",resource_id="i-web",resource_type="EC2 Instance"} 140.16
+kaytu_aws_current_monthly_cost{account="123456789012",instance_type="m5.xlarge",region="us-east-1",resource_id="vol-web",resource_type="EBS Volume"} 10
+# HELP kaytu_aws_potential_savings Monthly savings of the recommendation for the resource in USD.
+# TYPE kaytu_aws_potential_savings gauge
+kaytu_aws_potential_savings{account="123456789012",instance_type="db.m5.large",region="eu-west-1",resource_id="db-1-compute",resource_type="RDS Instance Compute"} 50
+kaytu_aws_potential_savings{account="123456789012",instance_type="db.m5.large",region="eu-west-1",resource_id="db-1-storage",resource_type="RDS Instance Storage"} 0
+kaytu_aws_potential_savings{account="123456789012",instance_type="m5.xlarge",region="us-east-1",resource_id="i-web",resource_type="EC2 Instance"} 70.08
+kaytu_aws_potential_savings{account="123456789012",instance_type="m5.xlarge",region="us-east-1",resource_id="vol-web",resource_type="EBS Volume"} 0
+# HELP kaytu_aws_resource_utilization_p99 99th percentile of the utilization metric of the resource over the observability window, in the unit of the metric.
+# TYPE kaytu_aws_resource_utilization_p99 gauge
+kaytu_aws_resource_utilization_p99{account="123456789012",instance_type="m5.xlarge",metric="CPUUtilization",region="us-east-1",resource_id="i-web",resource_type="EC2 Instance"} 40
+# EOF
+`, exposition.String())
+}
+
+func (ts *OpenMetricsTestSuite) TestEscapesLabels() {
+	exposition := openmetrics.New("123456789012", []history.Record{
+		{ResourceType: "EC2 Instance", ResourceId: `i-"quoted"\`, Region: "us-east-1", CurrentType: "m5.large"},
+	}, nil)
+
+	ts.Contains(exposition.String(), `resource_id="i-\"quoted\"\\"`)
+}
+
+func (ts *OpenMetricsTestSuite) TestWrite() {
+	dir := ts.T().TempDir()
+	path := filepath.Join(dir, "kaytu.prom")
+	ts.Require().NoError(os.WriteFile(path, []byte("stale"), 0644))
+	exposition := openmetrics.New("123456789012", []history.Record{
+		{ResourceType: "EC2 Instance", ResourceId: "i-web", Region: "us-east-1", CurrentType: "m5.large", CurrentCost: 70.08},
+	}, nil)
+
+	ts.Require().NoError(exposition.Write(path))
+
+	content, err := os.ReadFile(path)
+	ts.Require().NoError(err)
+	ts.Equal(exposition.String(), string(content))
+	entries, err := os.ReadDir(dir)
+	ts.Require().NoError(err)
+	ts.Len(entries, 1, "the temporary file is renamed over the file")
+	ts.Error(exposition.Write(filepath.Join(dir, "missing", "kaytu.prom")))
+}
+
+func (ts *AWSTestSuite) TestOpenMetrics() {
+	web := withVolumes(ec2Instance("i-web", types.InstanceStateNameRunning), "vol-web")
+	ts.aws.Instances["us-east-1"] = []types.Instance{web}
+	ts.aws.Volumes["vol-web"] = goldenVolume("vol-web", 100)
+	ts.client.EC2InstanceResponses[utils.HashString("i-web")] = &golang2.EC2InstanceOptimizationResponse{
+		RightSizing:       goldenEC2Recommendation(),
+		VolumeRightSizing: map[string]*golang2.EBSVolumeRecommendation{utils.HashString("vol-web"): goldenEBSRecommendation()},
+	}
+
+	ec2 := ts.runEC2()
+	exposition := openmetrics.New("123456789012", ec2.Records(), ec2.ReportItems())
+
+	var metrics []string
+	for _, s := range exposition.Gauges[openmetrics.MetricResourceUtilizationP99] {
+		metrics = append(metrics, s.Labels["resource_id"]+"/"+s.Labels["metric"])
+	}
+	ts.ElementsMatch([]string{
+		"i-web/CPUUtilization", "i-web/mem_used_percent", "i-web/NetworkIn", "i-web/NetworkOut",
+		"vol-web/VolumeReadOps", "vol-web/VolumeWriteOps", "vol-web/VolumeReadBytes", "vol-web/VolumeWriteBytes",
+	}, metrics)
+	for _, s := range exposition.Gauges[openmetrics.MetricPotentialSavings] {
+		if s.Labels["resource_id"] == "vol-web" {
+			ts.Equal("m5.xlarge", s.Labels["instance_type"], "volumes are labelled with the type of their instance")
+			ts.InDelta(3.6, s.Value, 0.001)
+		}
+	}
+}
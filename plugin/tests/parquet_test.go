@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/parquet"
+	"github.com/opengovern/plugin-aws/plugin/report"
+	parquetgo "github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ParquetTestSuite struct {
+	suite.Suite
+}
+
+func TestParquet(t *testing.T) {
+	suite.Run(t, &ParquetTestSuite{})
+}
+
+func (ts *ParquetTestSuite) TestExport() {
+	now := time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)
+	records := []history.Record{
+		{ResourceType: "EC2 Instance", ResourceId: "i-web", Region: "us-east-1", CurrentSpec: "m5.xlarge",
+			RecommendedSpec: "m5.large", CurrentCost: 140.16, Savings: 70.08, Tags: map[string]string{"team": "web"}},
+		{ResourceType: "EBS Volume", ResourceId: "vol-web", Region: "us-east-1", ParentId: "i-web", CurrentSize: aws.Int32(100),
+			CurrentCost: 10},
+		{ResourceType: "RDS Instance Compute", ResourceId: "db-1-compute", Region: "eu-west-1", ParentId: "db-1",
+			ClusterId: "cluster-1", MultiAZ: true, CurrentCost: 100, Savings: 50},
+	}
+	items := []report.Item{{Service: "EC2", Utilization: map[string][]report.Series{
+		"i-web": {{Metric: "CPUUtilization", Datapoints: []types2.Datapoint{
+			{Timestamp: aws.Time(now.Add(-time.Hour)), Average: aws.Float64(40), Unit: types2.StandardUnitPercent},
+			{Timestamp: aws.Time(now.Add(-2 * time.Hour)), Maximum: aws.Float64(12.5), Unit: types2.StandardUnitPercent},
+			{Average: aws.Float64(99)},
+		}}},
+		"i-unknown": {{Metric: "CPUUtilization", Datapoints: []types2.Datapoint{{Timestamp: aws.Time(now), Average: aws.Float64(1)}}}},
+	}}}
+
+	export, err := parquet.New("123456789012", records, items, true, now)
+	ts.Require().NoError(err)
+	dir := ts.T().TempDir()
+	ts.Require().NoError(export.Write(dir))
+
+	ts.Equal(3, export.Rows(parquet.DatasetRecommendations))
+	ts.Equal(2, export.Rows(parquet.DatasetUtilization))
+	ts.Equal("3 recommendation records and 2 utilization datapoints in 3 Parquet files", export.Summary())
+
+	ec2, names, _ := readParquet[parquet.Recommendation](ts.T(), filepath.Join(dir, "recommendations", "account=123456789012", "region=us-east-1", "date=2024-03-05", "part-20240305T103000Z.parquet"))
+	ts.Require().Len(ec2, 2)
+	ts.Equal([]string{"generated_at", "resource_type", "resource_id", "resource_name", "parent_id", "cluster_id", "platform",
+		"multi_az", "runtime_hours", "current_spec", "recommended_spec", "current_type", "recommended_type", "current_size",
+		"recommended_size", "recommended_iops", "recommended_throughput", "current_cost", "recommended_cost", "savings",
+		"description", "tags"}, names)
+	ts.Equal([]string{"i-web", "vol-web"}, []string{ec2[0].ResourceId, ec2[1].ResourceId})
+	ts.Equal([]string{"", ""}, []string{ec2[0].ClusterId, ec2[1].ClusterId})
+	ts.Equal([]string{`{"team":"web"}`, "{}"}, []string{ec2[0].Tags, ec2[1].Tags})
+	ts.Nil(ec2[0].CurrentSize)
+	ts.Equal(aws.Int64(100), ec2[1].CurrentSize)
+	ts.Equal([]float64{70.08, 0}, []float64{ec2[0].Savings, ec2[1].Savings})
+	ts.Equal(now.UnixMilli(), ec2[0].GeneratedAt.UnixMilli())
+
+	rds, _, _ := readParquet[parquet.Recommendation](ts.T(), filepath.Join(dir, "recommendations", "account=123456789012", "region=eu-west-1", "date=2024-03-05", "part-20240305T103000Z.parquet"))
+	ts.Require().Len(rds, 1)
+	ts.True(rds[0].MultiAZ)
+	ts.Equal("cluster-1", rds[0].ClusterId)
+
+	datapoints, names, _ := readParquet[parquet.Datapoint](ts.T(), filepath.Join(dir, "utilization", "account=123456789012", "region=us-east-1", "date=2024-03-05", "part-20240305T103000Z.parquet"))
+	ts.Equal([]string{"generated_at", "resource_type", "resource_id", "metric", "timestamp", "average", "maximum", "minimum",
+		"sum", "sample_count", "unit"}, names)
+	ts.Require().Len(datapoints, 2)
+	ts.Equal([]int64{now.Add(-2 * time.Hour).UnixMilli(), now.Add(-time.Hour).UnixMilli()},
+		[]int64{datapoints[0].Timestamp.UnixMilli(), datapoints[1].Timestamp.UnixMilli()}, "datapoints are in time order")
+	ts.Equal([]*float64{nil, aws.Float64(40)}, []*float64{datapoints[0].Average, datapoints[1].Average})
+	ts.Equal([]*float64{aws.Float64(12.5), nil}, []*float64{datapoints[0].Maximum, datapoints[1].Maximum})
+	ts.Equal([]string{"Percent", "Percent"}, []string{datapoints[0].Unit, datapoints[1].Unit})
+	ts.Equal([]string{"EC2 Instance", "EC2 Instance"}, []string{datapoints[0].ResourceType, datapoints[1].ResourceType})
+}
+
+func (ts *ParquetTestSuite) TestUtilizationRowGroups() {
+	now := time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)
+	records := []history.Record{{ResourceType: "EC2 Instance", ResourceId: "i-web", Region: "us-east-1"}}
+	// more than a row group of one minute datapoints
+	datapoints := make([]types2.Datapoint, 150_000)
+	for i := range datapoints {
+		datapoints[i] = types2.Datapoint{Timestamp: aws.Time(now.Add(-time.Duration(i) * time.Minute)), Average: aws.Float64(float64(i % 100))}
+	}
+	items := []report.Item{{Service: "EC2", Utilization: map[string][]report.Series{
+		"i-web": {{Metric: "CPUUtilization", Datapoints: datapoints}},
+	}}}
+
+	export, err := parquet.New("123456789012", records, items, true, now)
+	ts.Require().NoError(err)
+	dir := ts.T().TempDir()
+	ts.Require().NoError(export.Write(dir))
+
+	rows, _, rowGroups := readParquet[parquet.Datapoint](ts.T(), filepath.Join(dir, "utilization", "account=123456789012", "region=us-east-1", "date=2024-03-05", "part-20240305T103000Z.parquet"))
+	ts.Len(rows, 150_000)
+	ts.Equal(2, rowGroups)
+	ts.Equal(now.UnixMilli(), rows[len(rows)-1].Timestamp.UnixMilli())
+}
+
+func (ts *ParquetTestSuite) TestExportWithoutUtilization() {
+	now := time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)
+	records := []history.Record{{ResourceType: "EC2 Instance", ResourceId: "i-web", Region: "us-east-1"}}
+	items := []report.Item{{Service: "EC2", Utilization: map[string][]report.Series{
+		"i-web": {{Metric: "CPUUtilization", Datapoints: []types2.Datapoint{{Timestamp: aws.Time(now), Average: aws.Float64(1)}}}},
+	}}}
+
+	export, err := parquet.New("123456789012", records, items, false, now)
+	ts.Require().NoError(err)
+	dir := ts.T().TempDir()
+	ts.Require().NoError(export.Write(dir))
+
+	ts.Equal("1 recommendation records in 1 Parquet files", export.Summary())
+	entries, err := os.ReadDir(dir)
+	ts.Require().NoError(err)
+	ts.Len(entries, 1)
+	ts.Equal("recommendations", entries[0].Name())
+}
+
+// readParquet reads back the rows of a file of the export with the Parquet library, and the names of its
+// columns and its number of row groups from the file metadata.
+func readParquet[T any](t *testing.T, path string) ([]T, []string, int) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := parquetgo.OpenFile(f, info.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, column := range file.Schema().Columns() {
+		names = append(names, strings.Join(column, "."))
+	}
+	rows, err := parquetgo.Read[T](f, info.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows, names, len(file.RowGroups())
+}
@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"github.com/opengovern/plugin-aws/plugin/policy"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type PolicyTestSuite struct {
+	suite.Suite
+}
+
+func TestPolicy(t *testing.T) {
+	suite.Run(t, &PolicyTestSuite{})
+}
+
+func (ts *PolicyTestSuite) load(content string) (*policy.Policy, error) {
+	path := filepath.Join(ts.T().TempDir(), "policy.yaml")
+	ts.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+	return policy.Load(path)
+}
+
+const policyFile = `version: 1
+rules:
+  - name: stay on nitro
+    resource: EC2Instance
+    when: family(recommended.instance_type) in ["t2", "m4", "c4", "r4"]
+    action: reject
+    message: previous generation families are not nitro based
+  - name: per core licenses
+    resource: EC2Instance
+    when: '"license" in tags && current.processor != recommended.processor'
+    action: reject
+  - name: small memory
+    resource: EC2Instance
+    when: recommended.memory < 8.0
+    action: warn
+    message: less than 8 GiB of memory
+  - name: minimum memory
+    resource: RDSInstance
+    when: recommended.memory_gb < 4
+    action: reject
+  - name: storage change
+    resource: RDSInstance
+    when: recommended.storage_type != current.storage_type
+    action: warn
+  - name: owner
+    resource: RDSInstance
+    when: tags["owner"] == "payments"
+    action: warn
+`
+
+func ec2Rightsizing(recommendedType, processor string, memory float64) *golang2.EC2InstanceRightSizingRecommendation {
+	return &golang2.EC2InstanceRightSizingRecommendation{
+		Current:     &golang2.RightsizingEC2Instance{InstanceType: "m5.xlarge", Processor: "Intel Xeon", Memory: 16, Cost: 140.16},
+		Recommended: &golang2.RightsizingEC2Instance{InstanceType: recommendedType, Processor: processor, Memory: memory, Cost: 70.08},
+		Description: "cpu usage is low",
+	}
+}
+
+func (ts *PolicyTestSuite) TestEC2Instance() {
+	p, err := ts.load(policyFile)
+	ts.Require().NoError(err)
+
+	rec := ec2Rightsizing("m5.large", "Intel Xeon", 8)
+	d := p.ApplyEC2Instance(rec, nil)
+	ts.Empty(d.Rejection)
+	ts.Empty(d.Warnings)
+	ts.Equal("m5.large", rec.Recommended.InstanceType)
+	ts.Equal("cpu usage is low", rec.Description)
+
+	rec = ec2Rightsizing("m4.large", "Intel Xeon", 4)
+	d = p.ApplyEC2Instance(rec, nil)
+	ts.Equal("Rejected by policy rule stay on nitro: previous generation families are not nitro based", d.Rejection)
+	ts.Equal("m5.xlarge", rec.Recommended.InstanceType)
+	ts.Equal(rec.Current.Cost, rec.Recommended.Cost)
+	ts.Equal("Rejected by policy rule stay on nitro: previous generation families are not nitro based\n"+
+		"Policy warning small memory: less than 8 GiB of memory\n"+
+		"cpu usage is low", rec.Description)
+
+	rec = ec2Rightsizing("m6a.large", "AMD EPYC", 8)
+	d = p.ApplyEC2Instance(rec, map[string]string{"license": "sql-server"})
+	ts.Equal("Rejected by policy rule per core licenses: \"license\" in tags && current.processor != recommended.processor", d.Rejection)
+	rec = ec2Rightsizing("m6a.large", "AMD EPYC", 8)
+	ts.Empty(p.ApplyEC2Instance(rec, map[string]string{"env": "prod"}).Rejection)
+	ts.Equal("m6a.large", rec.Recommended.InstanceType)
+
+	rec = &golang2.EC2InstanceRightSizingRecommendation{Current: rec.Current, Description: "no recommendation"}
+	ts.Empty(p.ApplyEC2Instance(rec, nil))
+	ts.Nil(rec.Recommended)
+
+	var none *policy.Policy
+	rec = ec2Rightsizing("m4.large", "Intel Xeon", 4)
+	ts.Empty(none.ApplyEC2Instance(rec, nil))
+	ts.Equal("m4.large", rec.Recommended.InstanceType)
+}
+
+func (ts *PolicyTestSuite) TestRDSInstance() {
+	p, err := ts.load(policyFile)
+	ts.Require().NoError(err)
+
+	rec := rdsRecommendation("db.m5.large", "db.t3.large")
+	d := p.ApplyRDSInstance(rec, map[string]string{"owner": "payments"})
+	ts.Empty(d.Rejection)
+	ts.Equal([]string{
+		"Policy warning storage change: recommended.storage_type != current.storage_type",
+		"Policy warning owner: tags[\"owner\"] == \"payments\"",
+	}, d.Warnings)
+	ts.Equal("db.t3.large", rec.Recommended.InstanceType)
+
+	// a tag missing from the map is an evaluation error, reported without stopping the other rules
+	rec = rdsRecommendation("db.m5.large", "db.t3.micro")
+	rec.Recommended.MemoryGb = 1
+	d = p.ApplyRDSInstance(rec, nil)
+	ts.Equal("Rejected by policy rule minimum memory: recommended.memory_gb < 4", d.Rejection)
+	ts.Require().Len(d.Warnings, 2)
+	ts.Contains(d.Warnings[1], "policy rule owner could not be evaluated: no such key: owner")
+	ts.Equal("db.m5.large", rec.Recommended.InstanceType)
+	ts.Equal("gp2", rec.Recommended.StorageType.GetValue())
+	rec.Recommended.StorageSize = wrapperspb.Int32(200)
+	ts.Equal(int32(100), rec.Current.StorageSize.GetValue(), "the recommendation is a copy of current")
+}
+
+func (ts *PolicyTestSuite) TestValidation() {
+	_, err := ts.load(`version: 1
+rules:
+  - name: memory
+    resource: EC2Instance
+    when: recommended.memory_gb < 4
+    action: reject
+  - name: memory
+    resource: Lambda
+    when: "true"
+    action: reject
+  - resource: RDSInstance
+    when: recommended.vcpu
+    action: veto
+  - name: empty
+    resource: RDSInstance
+    action: warn
+`)
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "rule memory: invalid when expression: ERROR: <input>:1:12: undefined field 'memory_gb'")
+	ts.Contains(err.Error(), "rule memory: name is used by another rule")
+	ts.Contains(err.Error(), "rule memory: unknown resource \"Lambda\", expected EC2Instance or RDSInstance")
+	ts.Contains(err.Error(), "rule 3: has no name")
+	ts.Contains(err.Error(), "rule 3: action must be reject or warn, got \"veto\"")
+	ts.Contains(err.Error(), "rule 3: when expression must be a bool, got int")
+	ts.Contains(err.Error(), "rule empty: has no when expression")
+
+	_, err = ts.load("version: 2\nrules: []\n")
+	ts.ErrorContains(err, "unsupported version 2")
+	_, err = ts.load("version: 1\nrules: []\n")
+	ts.ErrorContains(err, "has no rules")
+	_, err = ts.load("version: 1\nrule: []\n")
+	ts.ErrorContains(err, "field rule not found")
+}
+
+func (ts *PolicyTestSuite) TestFamily() {
+	ts.Equal("m5", policy.Family("m5.large"))
+	ts.Equal("r6g", policy.Family("db.r6g.xlarge"))
+	ts.Equal("", policy.Family(""))
+	family, size := policy.SplitInstanceType("db.r6g.2xlarge")
+	ts.Equal([]string{"r6g", "2xlarge"}, []string{family, size})
+}
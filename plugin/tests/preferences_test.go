@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
+	"github.com/opengovern/plugin-aws/plugin/preferences"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type PreferencesTestSuite struct {
+	suite.Suite
+}
+
+func TestPreferences(t *testing.T) {
+	suite.Run(t, &PreferencesTestSuite{})
+}
+
+func (ts *PreferencesTestSuite) write(content string) string {
+	path := filepath.Join(ts.T().TempDir(), "preferences.yaml")
+	ts.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func findPreference(items []*golang.PreferenceItem, service, key string) *golang.PreferenceItem {
+	for _, item := range items {
+		if item.Service == service && item.Key == key {
+			return item
+		}
+	}
+	return nil
+}
+
+const preferencesFile = `version: 1
+profiles:
+  prod:
+    EC2Instance:
+      CPUBreathingRoom: 30
+      Region: {pinned: true}
+      ProcessorArchitecture: {pinned: true}
+      InstanceFamily: General purpose
+      vCPU: ""
+    RDSInstance:
+      CpuBreathingRoom: 30
+  dev:
+    EC2Instance:
+      CPUBreathingRoom: 5
+      ProcessorArchitecture: {value: arm64}
+`
+
+func (ts *PreferencesTestSuite) TestApplyProfile() {
+	file, err := preferences.LoadFile(ts.write(preferencesFile))
+	ts.Require().NoError(err)
+	ts.Equal([]string{"dev", "prod"}, file.ProfileNames())
+
+	profile, err := file.Profile("prod")
+	ts.Require().NoError(err)
+	items := profile.Apply(preferences.DefaultEC2Preferences)
+
+	ts.Len(items, len(preferences.DefaultEC2Preferences))
+	ts.Equal("30", findPreference(items, "EC2Instance", "CPUBreathingRoom").Value.GetValue())
+	ts.True(findPreference(items, "EC2Instance", "Region").Pinned)
+	architecture := findPreference(items, "EC2Instance", "ProcessorArchitecture")
+	ts.True(architecture.Pinned)
+	ts.Equal([]string{"", "x86_64", "arm64", "arm64_mac"}, architecture.PossibleValues)
+	ts.Equal("General purpose", findPreference(items, "EC2Instance", "InstanceFamily").Value.GetValue())
+	ts.Nil(findPreference(items, "EC2Instance", "vCPU").Value)
+	// untouched items are shared, the defaults are never modified
+	ts.Same(findPreference(preferences.DefaultEC2Preferences, "EC2Instance", "Tenancy"), findPreference(items, "EC2Instance", "Tenancy"))
+	ts.Equal("10", findPreference(preferences.DefaultEC2Preferences, "EC2Instance", "CPUBreathingRoom").Value.GetValue())
+	ts.Nil(findPreference(preferences.DefaultEC2Preferences, "EC2Instance", "InstanceFamily").Value)
+
+	rds := profile.Apply(preferences.DefaultRDSPreferences)
+	ts.Equal("30", findPreference(rds, "RDSInstance", "CpuBreathingRoom").Value.GetValue())
+
+	dev, err := file.Profile("dev")
+	ts.Require().NoError(err)
+	architecture = findPreference(dev.Apply(preferences.DefaultEC2Preferences), "EC2Instance", "ProcessorArchitecture")
+	ts.False(architecture.Pinned)
+	ts.Equal("arm64", architecture.Value.GetValue())
+}
+
+func (ts *PreferencesTestSuite) TestProfileSelection() {
+	file, err := preferences.LoadFile(ts.write(preferencesFile))
+	ts.Require().NoError(err)
+	_, err = file.Profile("")
+	ts.EqualError(err, "select one of the preference profiles dev, prod")
+	_, err = file.Profile("staging")
+	ts.EqualError(err, "unknown preference profile staging, expected one of dev, prod")
+
+	file, err = preferences.LoadFile(ts.write("version: 1\nprofiles:\n  team:\n    EBSVolume:\n      IOPSBreathingRoom: 20\n"))
+	ts.Require().NoError(err)
+	profile, err := file.Profile("")
+	ts.Require().NoError(err)
+	ts.Equal("20", *profile["EBSVolume"]["IOPSBreathingRoom"].Value)
+}
+
+func (ts *PreferencesTestSuite) TestValidation() {
+	_, err := preferences.LoadFile(ts.write(`version: 1
+profiles:
+  prod:
+    EC2Instance:
+      CPUBreathingRoom: lots
+      OperatingSystem: {pinned: true}
+      Tenancy: Cloud
+      Region: {value: us-east-1, pinned: true}
+      GPU: 1
+    Lambda:
+      Memory: 128
+`))
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "profile prod: line 5: EC2Instance.CPUBreathingRoom must be a number, got \"lots\"")
+	ts.Contains(err.Error(), "profile prod: line 6: EC2Instance.OperatingSystem can not be pinned")
+	ts.Contains(err.Error(), "profile prod: line 7: EC2Instance.Tenancy must be one of [\"\" \"Host\" \"Shared\" \"Dedicated\"], got \"Cloud\"")
+	ts.Contains(err.Error(), "profile prod: line 8: EC2Instance.Region sets both a value and pinned")
+	ts.Contains(err.Error(), "profile prod: line 9: unknown EC2Instance preference GPU")
+	ts.Contains(err.Error(), "profile prod: unknown service Lambda, expected one of EBSVolume, EC2Instance, RDSInstance")
+
+	_, err = preferences.LoadFile(ts.write("version: 2\nprofiles: {}\n"))
+	ts.ErrorContains(err, "unsupported version 2")
+	_, err = preferences.LoadFile(ts.write("version: 1\nprofile:\n  prod: {}\n"))
+	ts.ErrorContains(err, "field profile not found")
+	_, err = preferences.LoadFile(ts.write("version: 1\nprofiles:\n  prod:\n    EC2Instance:\n      Region:\n"))
+	ts.ErrorContains(err, "profile prod: EC2Instance.Region sets neither a value nor pinned")
+}
+
+const rulesFile = `version: 1
+rules:
+  - name: prod
+    tags: {env: prod}
+    preferences:
+      RDSInstance:
+        CpuBreathingRoom: 30
+  - tags: {env: prod, team: payments}
+    preferences:
+      RDSInstance:
+        CpuBreathingRoom: 40
+        MemoryBreathingRoom: ""
+  - name: owned
+    tags: {owner: "*"}
+    preferences:
+      RDSInstance:
+        InstanceType: {pinned: true}
+`
+
+func (ts *PreferencesTestSuite) TestRules() {
+	file, err := preferences.LoadFile(ts.write(rulesFile))
+	ts.Require().NoError(err)
+	profile, err := file.Profile("")
+	ts.Require().NoError(err)
+	ts.Empty(profile)
+
+	items, overrides := file.Rules.Apply(preferences.DefaultRDSPreferences, map[string]string{"env": "dev"})
+	ts.Nil(overrides)
+	ts.Equal(preferences.DefaultRDSPreferences, items)
+
+	items, overrides = file.Rules.Apply(preferences.DefaultRDSPreferences, map[string]string{"env": "prod"})
+	ts.Equal([]string{"RDSInstance.CpuBreathingRoom=30 (prod)"}, overrides)
+	ts.Equal("30", findPreference(items, "RDSInstance", "CpuBreathingRoom").Value.GetValue())
+
+	items, overrides = file.Rules.Apply(preferences.DefaultRDSPreferences, map[string]string{"env": "prod", "team": "payments", "owner": "ana"})
+	ts.Equal([]string{
+		"RDSInstance.CpuBreathingRoom=40 (env=prod,team=payments)",
+		"RDSInstance.InstanceType=pinned (owned)",
+		"RDSInstance.MemoryBreathingRoom=any (env=prod,team=payments)",
+	}, overrides)
+	ts.Equal("40", findPreference(items, "RDSInstance", "CpuBreathingRoom").Value.GetValue())
+	ts.Nil(findPreference(items, "RDSInstance", "MemoryBreathingRoom").Value)
+	ts.True(findPreference(items, "RDSInstance", "InstanceType").Pinned)
+	ts.Equal("10", findPreference(preferences.DefaultRDSPreferences, "RDSInstance", "CpuBreathingRoom").Value.GetValue())
+
+	_, err = preferences.LoadFile(ts.write(`version: 1
+rules:
+  - name: everything
+    preferences:
+      RDSInstance:
+        CpuBreathingRoom: 30
+  - tags: {env: prod}
+    preferences:
+      RDSInstance:
+        CpuBreathingRoom: high
+`))
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "rule everything: has no tags")
+	ts.Contains(err.Error(), "rule 2: line 10: RDSInstance.CpuBreathingRoom must be a number, got \"high\"")
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/pricing"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type PricingTestSuite struct {
+	suite.Suite
+}
+
+func TestPricing(t *testing.T) {
+	suite.Run(t, &PricingTestSuite{})
+}
+
+func (ts *PricingTestSuite) load(content string) (*pricing.Adjustments, error) {
+	path := filepath.Join(ts.T().TempDir(), "pricing.yaml")
+	ts.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+	return pricing.Load(path)
+}
+
+const pricingFile = `version: 1
+adjustments:
+  - name: private m5 pricing
+    service: EC2Instance
+    region: us-east-1
+    instanceType: m5.*
+    usageType: compute
+    override: 100
+  - name: EDP
+    discount: 10
+  - name: cheap storage
+    service: RDSInstance
+    usageType: Storage*
+    discount: 50
+  - name: gov cloud
+    region: us-gov-*
+    discount: 5
+`
+
+func (ts *PricingTestSuite) TestApply() {
+	adjustments, err := ts.load(pricingFile)
+	ts.Require().NoError(err)
+
+	res := &golang2.EC2InstanceOptimizationResponse{
+		RightSizing: &golang2.EC2InstanceRightSizingRecommendation{
+			Current:     &golang2.RightsizingEC2Instance{InstanceType: "m5.xlarge", Cost: 150, CostComponents: map[string]float64{"Compute": 140, "License": 10}},
+			Recommended: &golang2.RightsizingEC2Instance{InstanceType: "c6i.large", Cost: 70, CostComponents: map[string]float64{"Compute": 70}},
+		},
+		VolumeRightSizing: map[string]*golang2.EBSVolumeRecommendation{
+			"vol": {Current: &golang2.RightsizingEBSVolume{Tier: "gp2", Cost: 10}},
+		},
+	}
+	adjustments.ApplyEC2Instance(res, "us-east-1")
+	// the private price replaces the compute cost, the discount is taken off it
+	ts.InDelta(99, res.RightSizing.Current.Cost, 0.001)
+	ts.InDelta(90, res.RightSizing.Current.CostComponents["Compute"], 0.001)
+	ts.InDelta(9, res.RightSizing.Current.CostComponents["License"], 0.001)
+	ts.InDelta(63, res.RightSizing.Recommended.Cost, 0.001)
+	ts.InDelta(9, res.VolumeRightSizing["vol"].Current.Cost, 0.001)
+
+	rec := &golang2.RDSInstanceRightSizingRecommendation{
+		Current: &golang2.RightsizingAwsRds{
+			InstanceType: "db.m5.large", Cost: 140, CostComponents: map[string]float64{"Compute": 120, "StorageGP2": 20},
+			ComputeCost: 120, ComputeCostComponents: map[string]float64{"Compute": 120},
+			StorageCost: 20, StorageCostComponents: map[string]float64{"StorageGP2": 20},
+		},
+	}
+	adjustments.ApplyRDSInstance(rec, "eu-west-1")
+	ts.InDelta(117, rec.Current.Cost, 0.001)
+	ts.InDelta(108, rec.Current.ComputeCost, 0.001)
+	ts.InDelta(9, rec.Current.StorageCost, 0.001)
+	ts.InDelta(9, rec.Current.CostComponents["StorageGP2"], 0.001)
+
+	ts.Equal([]string{
+		"Pricing Adjustments",
+		"private m5 pricing: $100.00/month for compute of EC2Instance m5.* in us-east-1",
+		"EDP: 10% discount on all usage types of all services in all regions",
+		"cheap storage: 50% discount on Storage* of RDSInstance in all regions",
+		"gov cloud: 5% discount on all usage types of all services in us-gov-* (not applied to any cost)",
+	}, adjustments.ExportRow(nil))
+	ts.Equal("private m5 pricing: €50.00/month for compute of EC2Instance m5.* in us-east-1",
+		adjustments.ExportRow(&currency.Currency{Code: "EUR", Symbol: "€", Rate: 0.5})[1])
+
+	var none *pricing.Adjustments
+	rec = rdsRecommendation("db.m5.large", "db.t3.large")
+	none.ApplyRDSInstance(rec, "us-east-1")
+	ts.Equal(124.1, rec.Current.ComputeCost)
+}
+
+func (ts *PricingTestSuite) TestValidation() {
+	_, err := ts.load(`version: 1
+adjustments:
+  - name: both
+    discount: 10
+    override: 5
+  - name: flat
+    override: 5
+  - name: too much
+    service: Lambda
+    discount: 120
+  - region: "[us"
+    discount: 5
+`)
+	ts.Require().Error(err)
+	ts.Contains(err.Error(), "adjustment both: needs either a discount or an override")
+	ts.Contains(err.Error(), "adjustment flat: override needs the usage type it sets the cost of")
+	ts.Contains(err.Error(), "adjustment too much: unknown service \"Lambda\", expected EC2Instance, EBSVolume or RDSInstance")
+	ts.Contains(err.Error(), "adjustment too much: discount must be a percentage above 0 and up to 100, got 120")
+	ts.Contains(err.Error(), "adjustment 4: has no name")
+	ts.Contains(err.Error(), "adjustment 4: invalid pattern \"[us\"")
+
+	_, err = ts.load("version: 2\nadjustments: []\n")
+	ts.ErrorContains(err, "unsupported version 2")
+	_, err = ts.load("version: 1\nadjustments: []\n")
+	ts.ErrorContains(err, "has no adjustments")
+}
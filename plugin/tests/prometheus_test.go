@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/opengovern/plugin-aws/plugin/prometheus"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type PrometheusTestSuite struct {
+	suite.Suite
+
+	server  *httptest.Server
+	queries []string
+}
+
+func TestPrometheus(t *testing.T) {
+	suite.Run(t, &PrometheusTestSuite{})
+}
+
+func (ts *PrometheusTestSuite) SetupTest() {
+	ts.queries = nil
+	ts.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		ts.queries = append(ts.queries, query)
+
+		start := r.URL.Query().Get("start")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(query, "sum by (serial)"):
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{"serial":"vol0123456789abcdef0"},"values":[[%s,"2048"]]},
+				{"metric":{"serial":"vol0000000000000000f"},"values":[[%s,"1"]]}
+			]}}`, start, start)
+		default:
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{},"values":[[%s,"42.5"],[%s,"NaN"]]}
+			]}}`, start, start)
+		}
+	}))
+}
+
+func (ts *PrometheusTestSuite) TearDownTest() {
+	ts.server.Close()
+}
+
+func (ts *PrometheusTestSuite) TestGetInstanceMetrics() {
+	prv, err := prometheus.NewPrometheus(ts.server.URL, "instance", prometheus.InstanceMatchPrivateIP)
+	ts.Require().NoError(err)
+
+	instance := types.Instance{
+		InstanceId:       aws.String("i-0123456789abcdef0"),
+		PrivateIpAddress: aws.String("10.0.0.12"),
+	}
+	volumes := []types.Volume{{VolumeId: aws.String("vol-0123456789abcdef0")}}
+
+	metrics, volumeMetrics, err := prv.GetInstanceMetrics(context.Background(), "us-east-1", instance, volumes, 2)
+	ts.Require().NoError(err)
+
+	for _, name := range []string{"CPUUtilization", "mem_used_percent", "NetworkIn", "NetworkOut"} {
+		ts.Require().Len(metrics[name], 2, name)
+		ts.Equal(42.5, *metrics[name][0].Average)
+	}
+	ts.Nil(metrics["CPUUtilization"][0].Sum)
+	ts.Equal(42.5*60, *metrics["NetworkIn"][0].Sum)
+
+	ts.Len(volumeMetrics, 1)
+	ts.Require().Len(volumeMetrics["vol-0123456789abcdef0"]["VolumeReadBytes"], 2)
+	ts.Equal(2048.0, *volumeMetrics["vol-0123456789abcdef0"]["VolumeReadBytes"][0].Average)
+
+	for _, q := range ts.queries {
+		ts.Contains(q, `instance=~"10\\.0\\.0\\.12(:[0-9]+)?"`)
+	}
+}
+
+func (ts *PrometheusTestSuite) TestInstanceIdMatch() {
+	prv, err := prometheus.NewPrometheus(ts.server.URL, "instance_id", prometheus.InstanceMatchInstanceID)
+	ts.Require().NoError(err)
+
+	_, _, err = prv.GetInstanceMetrics(context.Background(), "us-east-1", types.Instance{InstanceId: aws.String("i-1")}, nil, 1)
+	ts.Require().NoError(err)
+	ts.Contains(ts.queries[0], `instance_id="i-1"`)
+}
+
+func (ts *PrometheusTestSuite) TestInvalidConfig() {
+	_, err := prometheus.NewPrometheus("", "", "")
+	ts.Error(err)
+	_, err = prometheus.NewPrometheus("localhost:9090", "", "")
+	ts.Error(err)
+	_, err = prometheus.NewPrometheus(ts.server.URL, "", "hostname")
+	ts.Error(err)
+}
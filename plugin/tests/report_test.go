@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/kaytu-io/kaytu/pkg/plugin/proto/src/golang"
+	"github.com/kaytu-io/kaytu/pkg/utils"
+	"github.com/opengovern/plugin-aws/plugin"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"github.com/opengovern/plugin-aws/plugin/report"
+	"github.com/opengovern/plugin-aws/plugin/summary"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ReportTestSuite struct {
+	suite.Suite
+}
+
+func TestReport(t *testing.T) {
+	suite.Run(t, &ReportTestSuite{})
+}
+
+func (ts *ReportTestSuite) TestSparkline() {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	series := report.Series{Metric: "CPUUtilization", Datapoints: []types2.Datapoint{
+		{Timestamp: aws.Time(start.Add(2 * time.Minute)), Maximum: aws.Float64(30)},
+		{Timestamp: aws.Time(start), Average: aws.Float64(10)},
+		{Timestamp: aws.Time(start.Add(time.Minute)), Average: aws.Float64(20), Maximum: aws.Float64(90)},
+		{Average: aws.Float64(99)},
+	}}
+
+	ts.Equal([]float64{10, 20, 30}, series.Values())
+	ts.Equal("0.0,24.0 60.0,12.0 120.0,0.0", series.Points())
+	ts.Equal("min 10.0, avg 20.0, max 30.0", series.Range())
+
+	flat := report.Series{Metric: "NetworkIn", Datapoints: []types2.Datapoint{
+		{Timestamp: aws.Time(start), Sum: aws.Float64(2500)},
+	}}
+	ts.Equal("0.0,12.0 120.0,12.0", flat.Points())
+	ts.Equal("min 2.5k, avg 2.5k, max 2.5k", flat.Range())
+	ts.Empty(report.Series{}.Points())
+}
+
+func (ts *ReportTestSuite) TestSparklineDownsample() {
+	// a week of one minute datapoints with a single spike
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var datapoints []types2.Datapoint
+	for i := 0; i < 7*24*60; i++ {
+		value := 10.0
+		if i == 5000 {
+			value = 95
+		}
+		datapoints = append(datapoints, types2.Datapoint{Timestamp: aws.Time(start.Add(time.Duration(i) * time.Minute)), Average: aws.Float64(value)})
+	}
+
+	points := strings.Fields(report.Series{Metric: "CPUUtilization", Datapoints: datapoints}.Points())
+	ts.Len(points, 120)
+	ts.Equal("0.0,24.0", points[0])
+	ts.Equal("120.0,24.0", points[119])
+	ts.Contains(points, "59.5,0.0")
+}
+
+func (ts *ReportTestSuite) TestNewSeries() {
+	metrics := fakeDatapoints([]string{"CPUUtilization", "NetworkIn"}, 2, time.Time{})
+	metrics["NetworkOut"] = nil
+
+	series := report.NewSeries(metrics, "NetworkIn", "NetworkOut", "CPUUtilization")
+
+	ts.Require().Len(series, 2)
+	ts.Equal("NetworkIn", series[0].Metric)
+	ts.Equal("CPUUtilization", series[1].Metric)
+}
+
+func (ts *ReportTestSuite) TestTotals() {
+	r := report.New("123456789012", nil, nil, nil, []summary.Resource{
+		{Id: "i-1", Region: "us-east-1", ResourceType: "EC2 Instance", Status: summary.StatusAnalysed, CurrentCost: 140, Savings: 70},
+		{Id: "i-2", Region: "us-east-1", ResourceType: "EC2 Instance", Status: summary.StatusAnalysed, CurrentCost: 60, Savings: 10},
+		{Id: "i-3", Region: "us-east-1", ResourceType: "EC2 Instance", Status: summary.StatusSkipped},
+		{Id: "db-1", Region: "eu-west-1", ResourceType: "RDS Instance", Status: summary.StatusAnalysed, CurrentCost: 100, Savings: 90},
+		{Id: "aurora-1", Region: "us-east-1", ResourceType: "RDS Cluster", Status: summary.StatusAnalysed, CurrentCost: 50},
+	}, time.Now())
+
+	ts.Equal([]report.Total{
+		{Service: "RDS", Region: "eu-west-1", Resources: 1, CurrentCost: 100, Savings: 90},
+		{Service: "EC2", Region: "us-east-1", Resources: 2, CurrentCost: 200, Savings: 80},
+		{Service: "RDS", Region: "us-east-1", Resources: 1, CurrentCost: 50},
+	}, r.Totals)
+	ts.Equal(report.Total{Service: "Total", Resources: 4, CurrentCost: 350, Savings: 170}, r.Total)
+}
+
+func (ts *AWSTestSuite) TestHTMLReport() {
+	web := withVolumes(ec2Instance("i-web", types.InstanceStateNameRunning, types.Tag{Key: aws.String("Name"), Value: aws.String("web")}), "vol-web")
+	ts.aws.Instances["us-east-1"] = []types.Instance{web}
+	ts.aws.Volumes["vol-web"] = goldenVolume("vol-web", 100)
+	ts.client.EC2InstanceResponses[utils.HashString("i-web")] = &golang2.EC2InstanceOptimizationResponse{
+		RightSizing:       goldenEC2Recommendation(),
+		VolumeRightSizing: map[string]*golang2.EBSVolumeRecommendation{utils.HashString("vol-web"): goldenEBSRecommendation()},
+	}
+	ts.goldenRDS()
+
+	ec2 := ts.runEC2()
+	rds := ts.runRDS()
+	items := append(ec2.ReportItems(), rds.ReportItems()...)
+	config := plugin.NewPlugin().GetConfig(context.Background())
+	r := report.New("123456789012", config.OverviewChart, config.DevicesChart, items,
+		append(ec2.SummaryResources(), rds.SummaryResources()...), time.Now())
+	path := filepath.Join(ts.T().TempDir(), "report.html")
+	ts.Require().NoError(r.Write(path, ts.currency))
+
+	ts.Len(r.Items, 5)
+	var utilization map[string][]report.Series
+	for _, i := range r.Items {
+		if i.Optimization.OverviewChartRow.RowId == "i-web" {
+			utilization = i.Utilization
+		}
+	}
+	ts.Require().NotEmpty(utilization["i-web"])
+	ts.Equal("CPUUtilization", utilization["i-web"][0].Metric)
+	ts.NotEmpty(utilization["vol-web"])
+
+	content, err := os.ReadFile(path)
+	ts.Require().NoError(err)
+	html := string(content)
+	for _, column := range append(config.OverviewChart.Columns, config.DevicesChart.Columns...) {
+		if column.Name != "" {
+			ts.Contains(html, "<th>"+column.Name+"</th>")
+		}
+	}
+	ts.NotContains(html, "→")
+	ts.Contains(html, `<details id="resource-i-web">`)
+	ts.Contains(html, `<a href="#resource-i-web">i-web</a>`)
+	ts.Contains(html, `<h4>aurora-1-a-compute</h4>`)
+	ts.Contains(html, `<polyline points="`)
+	ts.Contains(html, "<td>CPUUtilization</td>")
+	ts.Contains(html, "<td>$140.16</td>")
+	ts.NotContains(html, "<link")
+	ts.NotContains(html, "<script src")
+	ts.True(strings.HasPrefix(html, "<!DOCTYPE html>"))
+}
+
+func (ts *ReportTestSuite) TestDetails() {
+	item := report.Item{
+		Service: "EC2",
+		Optimization: &golang.ChartOptimizationItem{
+			OverviewChartRow: &golang.ChartRow{RowId: "i-web", Values: map[string]*golang.ChartRowItem{
+				"resource_id": {Value: "i-web"}, "resource_name": {Value: "<web>"},
+			}},
+			DevicesChartRows: []*golang.ChartRow{{RowId: "i-web", Values: map[string]*golang.ChartRowItem{"resource_id": {Value: "i-web"}}}},
+			DevicesProperties: map[string]*golang.Properties{"i-web": {Properties: []*golang.Property{
+				{Key: "vCPU", Current: "4", Average: "12.5%", Max: "40%", Recommended: "2"},
+				{Key: "secret", Hidden: true},
+			}}},
+		},
+	}
+	chart := &golang.ChartDefinition{Columns: []*golang.ChartColumnItem{{Id: "resource_id", Name: "Resource ID"}, {Id: "resource_name", Name: "Resource Name"}}}
+	r := report.New("123456789012", chart, chart, []report.Item{item, {Service: "EC2"}}, nil, time.Now())
+	path := filepath.Join(ts.T().TempDir(), "report.html")
+
+	ts.Require().NoError(r.Write(path, nil))
+
+	ts.Len(r.Items, 1)
+	content, err := os.ReadFile(path)
+	ts.Require().NoError(err)
+	html := string(content)
+	ts.Contains(html, "<tr><td>vCPU</td><td>4</td><td>12.5%</td><td>40%</td><td>2</td></tr>")
+	ts.NotContains(html, "secret")
+	ts.Contains(html, "&lt;web&gt;")
+	ts.Contains(html, "monthly costs in USD")
+}
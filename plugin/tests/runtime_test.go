@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	types2 "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/opengovern/plugin-aws/plugin/processor/shared"
+	golang2 "github.com/opengovern/plugin-aws/plugin/proto/src/golang"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type RuntimeTestSuite struct {
+	suite.Suite
+
+	now time.Time
+}
+
+func TestRuntime(t *testing.T) {
+	suite.Run(t, &RuntimeTestSuite{})
+}
+
+func (ts *RuntimeTestSuite) SetupTest() {
+	ts.now = time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)
+}
+
+// hourly returns a datapoint every 10 minutes of the hours from start.
+func hourly(start time.Time, hours int) []types2.Datapoint {
+	var datapoints []types2.Datapoint
+	for i := 0; i < hours*6; i++ {
+		datapoints = append(datapoints, types2.Datapoint{Timestamp: aws.Time(start.Add(time.Duration(i) * 10 * time.Minute))})
+	}
+	return datapoints
+}
+
+func (ts *RuntimeTestSuite) TestStoppedNightly() {
+	var datapoints []types2.Datapoint
+	for day := 7; day > 0; day-- {
+		datapoints = append(datapoints, hourly(ts.now.Add(-time.Duration(day*24-8)*time.Hour), 12)...)
+	}
+	runtime := shared.ObserveRuntime(datapoints, nil, nil, nil, 7, ts.now)
+	ts.Equal(shared.Runtime{ObservedHours: 84, WindowHours: 168}, runtime)
+	ts.Equal(365.0, runtime.MonthlyHours())
+	ts.Equal("365 hours", runtime.String())
+}
+
+func (ts *RuntimeTestSuite) TestCreatedDuringWindow() {
+	created := ts.now.Add(-48 * time.Hour)
+	launched := ts.now.Add(-12 * time.Hour)
+	datapoints := append(hourly(created, 12), hourly(launched, 1)...)
+	runtime := shared.ObserveRuntime(datapoints, &created, &launched, nil, 7, ts.now)
+	ts.Equal(shared.Runtime{ObservedHours: 24, WindowHours: 48}, runtime)
+	ts.Equal("365 hours", runtime.String())
+
+	// the datapoints before creation are the ones of another instance with the same id
+	runtime = shared.ObserveRuntime(hourly(ts.now.Add(-72*time.Hour), 72), &created, nil, nil, 7, ts.now)
+	ts.Equal("730 hours", runtime.String())
+}
+
+func (ts *RuntimeTestSuite) TestStoppedSinceLastLaunch() {
+	launched := ts.now.Add(-48 * time.Hour)
+	stopped := shared.EC2StateTransitionTime("User initiated (2024-03-07 00:00:00 GMT)")
+	ts.Require().NotNil(stopped)
+	ts.Equal(ts.now.Add(-24*time.Hour), *stopped)
+
+	// the metrics miss most of the day it ran, the state transition says until when it did
+	runtime := shared.ObserveRuntime(hourly(launched, 2), nil, &launched, stopped, 7, ts.now)
+	ts.Equal(shared.Runtime{ObservedHours: 24, WindowHours: 168}, runtime)
+
+	ts.Nil(shared.EC2StateTransitionTime(""))
+	ts.Nil(shared.EC2StateTransitionTime("Server.ScheduledStop: Stopped due to scheduled retirement"))
+}
+
+func (ts *RuntimeTestSuite) TestUnknown() {
+	ts.Equal("730 hours", shared.ObserveRuntime(nil, nil, nil, nil, 7, ts.now).String())
+	ts.Equal("730 hours", shared.ObserveRuntime(hourly(ts.now.Add(-30*24*time.Hour), 24), nil, nil, nil, 7, ts.now).String())
+	created := ts.now.Add(-10 * time.Minute)
+	ts.Equal("730 hours", shared.ObserveRuntime(hourly(created, 1), &created, nil, nil, 7, ts.now).String())
+	ts.Equal(shared.Runtime{}, shared.ObserveRuntime(nil, nil, nil, nil, 7, ts.now))
+}
+
+func (ts *RuntimeTestSuite) TestScale() {
+	runtime := shared.Runtime{ObservedHours: 12, WindowHours: 24}
+
+	ec2 := &golang2.EC2InstanceRightSizingRecommendation{
+		Current:     &golang2.RightsizingEC2Instance{Cost: 140.16, LicensePrice: 20, CostComponents: map[string]float64{"Compute": 140.16}},
+		Recommended: &golang2.RightsizingEC2Instance{Cost: 70.08, CostComponents: map[string]float64{"Compute": 70.08}},
+	}
+	runtime.ScaleEC2Instance(ec2)
+	ts.InDelta(70.08, ec2.Current.Cost, 0.001)
+	ts.InDelta(10, ec2.Current.LicensePrice, 0.001)
+	ts.InDelta(70.08, ec2.Current.CostComponents["Compute"], 0.001)
+	ts.InDelta(35.04, ec2.Recommended.Cost, 0.001)
+
+	rds := &golang2.RDSInstanceRightSizingRecommendation{
+		Current: &golang2.RightsizingAwsRds{
+			Cost: 135.6, ComputeCost: 124.1, StorageCost: 11.5,
+			CostComponents:        map[string]float64{"Instance": 124.1, "Storage": 11.5},
+			ComputeCostComponents: map[string]float64{"Instance": 124.1},
+			StorageCostComponents: map[string]float64{"Storage": 11.5},
+		},
+	}
+	runtime.ScaleRDSInstance(rds)
+	ts.InDelta(73.55, rds.Current.Cost, 0.001)
+	ts.InDelta(62.05, rds.Current.ComputeCost, 0.001)
+	ts.InDelta(11.5, rds.Current.StorageCost, 0.001)
+	ts.Equal(map[string]float64{"Instance": 62.05, "Storage": 11.5}, rds.Current.CostComponents)
+	ts.Equal(map[string]float64{"Instance": 62.05}, rds.Current.ComputeCostComponents)
+
+	// an unobserved runtime keeps the monthly prices
+	shared.Runtime{}.ScaleEC2Instance(ec2)
+	ts.InDelta(70.08, ec2.Current.Cost, 0.001)
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/remediation"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type ScriptTestSuite struct {
+	suite.Suite
+}
+
+func TestScript(t *testing.T) {
+	suite.Run(t, &ScriptTestSuite{})
+}
+
+func (ts *ScriptTestSuite) TestScript() {
+	records := terraformRecords()
+	for i := range records {
+		records[i].Region = "us-east-1"
+		records[i].Description = "CPU usage peaked at 20%.\nMemory usage peaked at 35%."
+		switch records[i].ResourceId {
+		case "vol-data":
+			records[i].CurrentSize = aws.Int32(100)
+		case "db-1-storage":
+			records[i].CurrentSize = aws.Int32(50)
+		case "i-cache":
+			records[i].Region = "eu-west-1"
+			records[i].ResourceName = "cache 'blue'\nnode"
+		}
+	}
+
+	script := remediation.NewScript("123456789012", remediation.Targets(records), nil)
+
+	assertGolden(&ts.Suite, "aws_cli_script.sh", script.Content)
+	ts.Equal(8, script.Resources)
+	ts.Equal("AWS CLI script: 8 resources in 2 regions, saving $487.95/month", script.Summary(nil))
+	ts.Equal("AWS CLI script: 8 resources in 2 regions, saving €975.90/month", script.Summary(&currency.Currency{Code: "EUR", Symbol: "€", Rate: 2}))
+
+	path := filepath.Join(ts.T().TempDir(), "kaytu.sh")
+	ts.Require().NoError(script.Write(path))
+	info, err := os.Stat(path)
+	ts.Require().NoError(err)
+	ts.NotZero(info.Mode() & 0o100)
+}
+
+func (ts *ScriptTestSuite) TestScriptClusterMember() {
+	records := terraformRecords()
+	for i := range records {
+		if records[i].ParentId == "db-1" {
+			records[i].ClusterId = "cluster-1"
+		}
+	}
+
+	var db remediation.Target
+	for _, t := range remediation.Targets(records) {
+		if t.Kind == remediation.TargetRDSInstance {
+			db = t
+		}
+	}
+	script := string(remediation.NewScript("123456789012", []remediation.Target{db}, nil).Content)
+
+	ts.Contains(script, "# storage belongs to cluster cluster-1 and is left as is\n")
+	ts.Contains(script, "aws rds modify-db-instance --region '' --db-instance-identifier db-1 --db-instance-class db.t3.large \"$RDS_APPLY\"")
+	ts.NotContains(script, "--storage-type")
+	ts.NotContains(script, "--allocated-storage")
+}
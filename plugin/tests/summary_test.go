@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"github.com/opengovern/plugin-aws/plugin/currency"
+	"github.com/opengovern/plugin-aws/plugin/policy"
+	"github.com/opengovern/plugin-aws/plugin/summary"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type SummaryTestSuite struct {
+	suite.Suite
+}
+
+func TestSummary(t *testing.T) {
+	suite.Run(t, &SummaryTestSuite{})
+}
+
+func summaryResource(id, region, resourceType, instanceType string, status summary.Status, cost, savings float64) summary.Resource {
+	return summary.Resource{Id: id, Account: "123456789012", Region: region, ResourceType: resourceType,
+		InstanceFamily: policy.Family(instanceType), Status: status, CurrentCost: cost, Savings: savings}
+}
+
+func (ts *SummaryTestSuite) TestBreakdowns() {
+	tagged := summaryResource("db-1", "eu-west-1", "RDS Instance", "db.m5.large", summary.StatusAnalysed, 135.6, 64.35)
+	tagged.Tags = map[string]string{"cost-center": "data"}
+	s := summary.New([]summary.Resource{
+		summaryResource("i-1", "us-east-1", "EC2 Instance", "m5.xlarge", summary.StatusAnalysed, 150.16, 72.08),
+		summaryResource("i-2", "us-east-1", "EC2 Instance", "c5.large", summary.StatusAnalysed, 62.05, 0),
+		summaryResource("i-3", "us-east-1", "EC2 Instance", "m5.large", summary.StatusSkipped, 0, 0),
+		tagged,
+	}, "cost-center")
+
+	ts.Equal([]int{3, 1, 0}, []int{s.Total.Analysed, s.Total.Skipped, s.Total.Failed})
+	ts.InDelta(347.81, s.Total.CurrentCost, 0.001)
+	ts.InDelta(136.43, s.Total.Savings, 0.001)
+	ts.Require().Len(s.Breakdowns, 5)
+
+	families := s.Breakdowns[3]
+	ts.Equal(summary.DimensionInstanceFamily, families.Dimension)
+	ts.Equal([]summary.Group{
+		{Key: "m5", Analysed: 2, Skipped: 1, CurrentCost: 285.76, Savings: 136.43},
+		{Key: "c5", Analysed: 1, CurrentCost: 62.05},
+	}, families.Groups)
+
+	tags := s.Breakdowns[4]
+	ts.Equal("Tag cost-center", tags.Dimension)
+	ts.Equal([]string{"(untagged)", "data"}, []string{tags.Groups[0].Key, tags.Groups[1].Key})
+	ts.Equal(3, tags.Groups[0].Analysed+tags.Groups[0].Skipped)
+
+	ts.Equal("By Region: us-east-1 $212.21 saving $72.08 (34.0%), eu-west-1 $135.60 saving $64.35 (47.5%)", s.Lines(nil)[2])
+}
+
+func (ts *SummaryTestSuite) TestRows() {
+	s := summary.New([]summary.Resource{
+		summaryResource("i-1", "us-east-1", "EC2 Instance", "m5.xlarge", summary.StatusAnalysed, 140.16, 70.08),
+		summaryResource("i-2", "us-east-1", "EC2 Instance", "m5.large", summary.StatusFailed, 0, 0),
+	}, "")
+
+	rows := s.Rows(&currency.Currency{Code: "EUR", Symbol: "€", Rate: 0.5})
+	ts.Require().Len(rows, 6)
+	ts.Equal([]string{"Summary", "Dimension", "Key", "Analysed", "Skipped", "Failed", "Current Cost", "Savings", "Savings %"}, rows[0])
+	ts.Equal([]string{"Summary", "Total", "", "1", "0", "1", "€70.08", "€35.04", "50.0"}, rows[1])
+	ts.Equal([]string{"Summary", "Instance Family", "m5", "1", "0", "1", "€70.08", "€35.04", "50.0"}, rows[5])
+}
@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/opengovern/plugin-aws/plugin/history"
+	"github.com/opengovern/plugin-aws/plugin/remediation"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type TerraformTestSuite struct {
+	suite.Suite
+
+	dir string
+}
+
+func TestTerraform(t *testing.T) {
+	suite.Run(t, &TerraformTestSuite{})
+}
+
+func (ts *TerraformTestSuite) SetupTest() {
+	ts.dir = ts.T().TempDir()
+}
+
+func (ts *TerraformTestSuite) write(name, content string) string {
+	path := filepath.Join(ts.dir, name)
+	ts.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+const terraformMain = `# web servers
+resource "aws_instance" "web" {
+  ami           = "ami-1"
+  instance_type = "m5.xlarge" # sized for launch
+
+  user_data = <<-EOT
+    #!/bin/bash
+    echo "}" > /tmp/brace
+  EOT
+
+  tags = {
+    Name = "web ${var.env}"
+  }
+}
+
+resource "aws_instance" "api" {
+  ami           = "ami-1"
+  instance_type = var.api_instance_type
+}
+
+resource "aws_ebs_volume" "data" {
+  availability_zone = "us-east-1a"
+  size              = 100
+  type              = "gp2"
+}
+
+resource "aws_db_instance" "db" {
+  identifier        = "db-1"
+  instance_class    = "db.m5.large"
+  allocated_storage = 50
+  storage_type      = "gp2"
+}
+`
+
+const terraformState = `{
+  "version": 4,
+  "resources": [
+    {"mode": "managed", "type": "aws_instance", "name": "web", "instances": [{"attributes": {"id": "i-web"}}]},
+    {"mode": "managed", "type": "aws_instance", "name": "api", "instances": [{"attributes": {"id": "i-api"}}]},
+    {"mode": "managed", "type": "aws_instance", "name": "workers", "instances": [{"index_key": 0, "attributes": {"id": "i-worker"}}]},
+    {"mode": "managed", "type": "aws_ebs_volume", "name": "data", "instances": [{"attributes": {"id": "vol-data"}}]},
+    {"mode": "managed", "type": "aws_db_instance", "name": "db", "instances": [{"attributes": {"id": "db-ABCDEF", "identifier": "db-1"}}]},
+    {"module": "module.cache", "mode": "managed", "type": "aws_instance", "name": "this", "instances": [{"attributes": {"id": "i-cache"}}]},
+    {"mode": "data", "type": "aws_instance", "name": "lookup", "instances": [{"attributes": {"id": "i-lookup"}}]}
+  ]
+}`
+
+func terraformRecords() []history.Record {
+	instance := func(id, current, recommended string) history.Record {
+		r := instanceRecord(id, current, recommended, 140, 70)
+		r.CurrentType, r.RecommendedType = current, recommended
+		return r
+	}
+	volume := history.Record{
+		ResourceType: "EBS Volume", ResourceId: "vol-data", ParentId: "i-web", CurrentSpec: "gp2/100 GB/300 IOPS",
+		RecommendedSpec: "gp3/80 GB/3000 IOPS", CurrentType: "gp2", RecommendedType: "gp3", RecommendedSize: aws.Int32(80),
+		RecommendedIops: aws.Int32(3000), RecommendedThroughput: aws.Float64(125), CurrentCost: 10, RecommendedCost: 6.4, Savings: 3.6,
+	}
+	compute := history.Record{
+		ResourceType: "RDS Instance Compute", ResourceId: "db-1-compute", ParentId: "db-1", CurrentSpec: "db.m5.large",
+		RecommendedSpec: "db.t3.large", CurrentType: "db.m5.large", RecommendedType: "db.t3.large", CurrentCost: 124.1,
+		RecommendedCost: 62.05, Savings: 62.05,
+	}
+	storage := history.Record{
+		ResourceType: "RDS Instance Storage", ResourceId: "db-1-storage", ParentId: "db-1", CurrentSpec: "gp2/50 GB/150 IOPS",
+		RecommendedSpec: "gp3/60 GB/3000 IOPS", CurrentType: "gp2", RecommendedType: "gp3", RecommendedSize: aws.Int32(60),
+		CurrentCost: 11.5, RecommendedCost: 9.2, Savings: 2.3,
+	}
+	return []history.Record{
+		instance("i-web", "m5.xlarge", "m5.large"),
+		instance("i-api", "m5.xlarge", "m5.large"),
+		instance("i-worker", "m5.xlarge", "m5.large"),
+		instance("i-cache", "m5.xlarge", "m5.large"),
+		instance("i-unmanaged", "m5.xlarge", "m5.large"),
+		instance("i-lookup", "m5.xlarge", "m5.large"),
+		instance("i-norec", "m5.xlarge", ""),
+		volume, compute, storage,
+	}
+}
+
+func (ts *TerraformTestSuite) TestTargetsMergeRDSRecords() {
+	targets := remediation.Targets(terraformRecords())
+
+	ts.Len(targets, 8)
+	var db remediation.Target
+	for _, t := range targets {
+		ts.NotEqual("i-norec", t.Id)
+		if t.Kind == remediation.TargetRDSInstance {
+			db = t
+		}
+	}
+	ts.Equal("db-1", db.Id)
+	ts.Equal("db.t3.large", db.InstanceType)
+	ts.Equal("gp3", db.VolumeType)
+	ts.Equal(aws.Int32(60), db.Size)
+	ts.InDelta(64.35, db.Savings, 0.001)
+}
+
+func (ts *TerraformTestSuite) TestPatch() {
+	ts.write("main.tf", terraformMain)
+	state := ts.write("terraform.tfstate", terraformState)
+
+	patch, err := remediation.NewTerraformPatch(ts.dir, state, remediation.Targets(terraformRecords()))
+	ts.Require().NoError(err)
+
+	assertGolden(&ts.Suite, "terraform_main.patch", []byte(patch.Diff))
+
+	reasons := map[string]string{}
+	for _, s := range patch.Skipped {
+		reasons[s.ResourceId] += s.Reason
+	}
+	ts.Equal(map[string]string{
+		"i-api":    "instance_type is set to var.api_instance_type, change it to \"m5.large\" where it comes from",
+		"i-worker": "created with count or for_each, change the values it iterates over",
+		"i-cache":  "defined in module.cache, change the module inputs",
+		"vol-data": "size can not shrink from 100 to 80 without replacing the resource",
+	}, reasons)
+	ts.Len(patch.Changes, 7)
+	ts.Equal("Terraform patch: 7 changes to 3 resources in 1 files, 4 skipped", patch.Summary())
+
+	out := filepath.Join(ts.T().TempDir(), "out.patch")
+	ts.Require().NoError(patch.Write(out))
+	content, err := os.ReadFile(out)
+	ts.Require().NoError(err)
+	ts.True(strings.HasPrefix(string(content), "# skipped "))
+	ts.True(strings.HasSuffix(string(content), patch.Diff))
+}
+
+func (ts *TerraformTestSuite) TestPatchFromShowJson() {
+	ts.write("volumes.tf", `resource "aws_ebs_volume" "data" {
+  size       = 80
+  type       = "io1"
+  iops       = 4000
+  throughput = 250
+}
+`)
+	state := ts.write("show.json", `{
+  "format_version": "1.0",
+  "values": {"root_module": {
+    "resources": [{"address": "aws_ebs_volume.data", "mode": "managed", "type": "aws_ebs_volume", "name": "data", "values": {"id": "vol-data"}}],
+    "child_modules": [{"address": "module.db", "resources": [
+      {"address": "module.db.aws_db_instance.this", "mode": "managed", "type": "aws_db_instance", "name": "this", "values": {"id": "db-ABCDEF", "identifier": "db-1"}}
+    ]}]
+  }}
+}`)
+	records := terraformRecords()
+	for i, r := range records {
+		if r.ResourceId == "vol-data" {
+			// moving to gp2 drops the provisioned iops and throughput
+			records[i].CurrentType, records[i].RecommendedType = "io1", "gp2"
+			records[i].RecommendedIops, records[i].RecommendedThroughput = nil, nil
+		}
+	}
+
+	patch, err := remediation.NewTerraformPatch(ts.dir, state, remediation.Targets(records))
+	ts.Require().NoError(err)
+
+	assertGolden(&ts.Suite, "terraform_show_json.patch", []byte(patch.Diff))
+	ts.Require().Len(patch.Skipped, 1)
+	ts.Equal("module.db.aws_db_instance.this", patch.Skipped[0].Resource)
+}
+
+func (ts *TerraformTestSuite) TestInvalidInput() {
+	state := ts.write("terraform.tfstate", terraformState)
+	_, err := remediation.NewTerraformPatch(ts.dir, state, nil)
+	ts.ErrorContains(err, "no terraform files")
+
+	ts.write("main.tf", terraformMain)
+	_, err = remediation.NewTerraformPatch(ts.dir, ts.write("plan.json", `{"planned_values": {}}`), nil)
+	ts.ErrorContains(err, "unsupported terraform state")
+}
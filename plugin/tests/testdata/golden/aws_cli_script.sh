@@ -0,0 +1,107 @@
+#!/usr/bin/env bash
+# Changes 8 resources of account 123456789012 to their recommended settings, saving $487.95/month.
+# Review it before running, running instances are stopped while their type changes.
+set -euo pipefail
+
+# RDS changes wait for the next maintenance window, set to --apply-immediately to make them now
+RDS_APPLY=--no-apply-immediately
+
+# ==== account 123456789012, region eu-west-1 ====
+
+# EC2 Instance i-cache (cache 'blue' node), saves $70.00/month
+# CPU usage peaked at 20%.
+# Memory usage peaked at 35%.
+state=$(aws ec2 describe-instances --region eu-west-1 --instance-ids i-cache --query 'Reservations[0].Instances[0].State.Name' --output text)
+if [ "$state" = running ]; then
+  aws ec2 stop-instances --region eu-west-1 --instance-ids i-cache > /dev/null
+  aws ec2 wait instance-stopped --region eu-west-1 --instance-ids i-cache
+fi
+aws ec2 modify-instance-attribute --region eu-west-1 --instance-id i-cache --instance-type Value=m5.large
+if [ "$state" = running ]; then
+  aws ec2 start-instances --region eu-west-1 --instance-ids i-cache > /dev/null
+  aws ec2 wait instance-running --region eu-west-1 --instance-ids i-cache
+fi
+
+# ==== account 123456789012, region us-east-1 ====
+
+# EBS Volume vol-data, saves $3.60/month
+# CPU usage peaked at 20%.
+# Memory usage peaked at 35%.
+# size stays at 100 GiB, storage can not shrink to 80 GiB without replacing it
+aws ec2 modify-volume --region us-east-1 --volume-id vol-data --volume-type gp3 --iops 3000 --throughput 125 > /dev/null
+
+# EC2 Instance i-api, saves $70.00/month
+# CPU usage peaked at 20%.
+# Memory usage peaked at 35%.
+state=$(aws ec2 describe-instances --region us-east-1 --instance-ids i-api --query 'Reservations[0].Instances[0].State.Name' --output text)
+if [ "$state" = running ]; then
+  aws ec2 stop-instances --region us-east-1 --instance-ids i-api > /dev/null
+  aws ec2 wait instance-stopped --region us-east-1 --instance-ids i-api
+fi
+aws ec2 modify-instance-attribute --region us-east-1 --instance-id i-api --instance-type Value=m5.large
+if [ "$state" = running ]; then
+  aws ec2 start-instances --region us-east-1 --instance-ids i-api > /dev/null
+  aws ec2 wait instance-running --region us-east-1 --instance-ids i-api
+fi
+
+# EC2 Instance i-lookup, saves $70.00/month
+# CPU usage peaked at 20%.
+# Memory usage peaked at 35%.
+state=$(aws ec2 describe-instances --region us-east-1 --instance-ids i-lookup --query 'Reservations[0].Instances[0].State.Name' --output text)
+if [ "$state" = running ]; then
+  aws ec2 stop-instances --region us-east-1 --instance-ids i-lookup > /dev/null
+  aws ec2 wait instance-stopped --region us-east-1 --instance-ids i-lookup
+fi
+aws ec2 modify-instance-attribute --region us-east-1 --instance-id i-lookup --instance-type Value=m5.large
+if [ "$state" = running ]; then
+  aws ec2 start-instances --region us-east-1 --instance-ids i-lookup > /dev/null
+  aws ec2 wait instance-running --region us-east-1 --instance-ids i-lookup
+fi
+
+# EC2 Instance i-unmanaged, saves $70.00/month
+# CPU usage peaked at 20%.
+# Memory usage peaked at 35%.
+state=$(aws ec2 describe-instances --region us-east-1 --instance-ids i-unmanaged --query 'Reservations[0].Instances[0].State.Name' --output text)
+if [ "$state" = running ]; then
+  aws ec2 stop-instances --region us-east-1 --instance-ids i-unmanaged > /dev/null
+  aws ec2 wait instance-stopped --region us-east-1 --instance-ids i-unmanaged
+fi
+aws ec2 modify-instance-attribute --region us-east-1 --instance-id i-unmanaged --instance-type Value=m5.large
+if [ "$state" = running ]; then
+  aws ec2 start-instances --region us-east-1 --instance-ids i-unmanaged > /dev/null
+  aws ec2 wait instance-running --region us-east-1 --instance-ids i-unmanaged
+fi
+
+# EC2 Instance i-web, saves $70.00/month
+# CPU usage peaked at 20%.
+# Memory usage peaked at 35%.
+state=$(aws ec2 describe-instances --region us-east-1 --instance-ids i-web --query 'Reservations[0].Instances[0].State.Name' --output text)
+if [ "$state" = running ]; then
+  aws ec2 stop-instances --region us-east-1 --instance-ids i-web > /dev/null
+  aws ec2 wait instance-stopped --region us-east-1 --instance-ids i-web
+fi
+aws ec2 modify-instance-attribute --region us-east-1 --instance-id i-web --instance-type Value=m5.large
+if [ "$state" = running ]; then
+  aws ec2 start-instances --region us-east-1 --instance-ids i-web > /dev/null
+  aws ec2 wait instance-running --region us-east-1 --instance-ids i-web
+fi
+
+# EC2 Instance i-worker, saves $70.00/month
+# CPU usage peaked at 20%.
+# Memory usage peaked at 35%.
+state=$(aws ec2 describe-instances --region us-east-1 --instance-ids i-worker --query 'Reservations[0].Instances[0].State.Name' --output text)
+if [ "$state" = running ]; then
+  aws ec2 stop-instances --region us-east-1 --instance-ids i-worker > /dev/null
+  aws ec2 wait instance-stopped --region us-east-1 --instance-ids i-worker
+fi
+aws ec2 modify-instance-attribute --region us-east-1 --instance-id i-worker --instance-type Value=m5.large
+if [ "$state" = running ]; then
+  aws ec2 start-instances --region us-east-1 --instance-ids i-worker > /dev/null
+  aws ec2 wait instance-running --region us-east-1 --instance-ids i-worker
+fi
+
+# RDS Instance db-1, saves $64.35/month
+# CPU usage peaked at 20%.
+# Memory usage peaked at 35%.
+aws rds modify-db-instance --region us-east-1 --db-instance-identifier db-1 --db-instance-class db.t3.large --storage-type gp3 --allocated-storage 60 "$RDS_APPLY" > /dev/null
+aws rds wait db-instance-available --region us-east-1 --db-instance-identifier db-1